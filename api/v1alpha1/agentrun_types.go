@@ -1,6 +1,11 @@
 package v1alpha1
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -23,6 +28,12 @@ type AgentRunSpec struct {
 	// Task is the task description for the agent.
 	Task string `json:"task"`
 
+	// Attachments are files sent with the task, such as the images and
+	// voice notes of a channel message. Their contents are in the run's
+	// "<name>-attachments" ConfigMap.
+	// +optional
+	Attachments []AgentRunAttachment `json:"attachments,omitempty"`
+
 	// SystemPrompt is the system prompt for the agent.
 	// +optional
 	SystemPrompt string `json:"systemPrompt,omitempty"`
@@ -30,6 +41,10 @@ type AgentRunSpec struct {
 	// Model specifies the LLM configuration for this run.
 	Model ModelSpec `json:"model"`
 
+	// Transcription configures speech-to-text for audio attachments.
+	// +optional
+	Transcription *TranscriptionSpec `json:"transcription,omitempty"`
+
 	// Sandbox defines sandbox configuration for this run.
 	// +optional
 	Sandbox *AgentRunSandboxSpec `json:"sandbox,omitempty"`
@@ -52,6 +67,20 @@ type AgentRunSpec struct {
 	Cleanup string `json:"cleanup,omitempty"`
 }
 
+// AgentRunAttachment is a file sent with a run's task.
+type AgentRunAttachment struct {
+	// Name is the file's name under /workspace/attachments in the agent pod.
+	Name string `json:"name"`
+
+	// Type is the kind of media.
+	// +kubebuilder:validation:Enum=image;audio;video;file
+	Type string `json:"type"`
+
+	// MimeType is the file's MIME type.
+	// +optional
+	MimeType string `json:"mimeType,omitempty"`
+}
+
 // ParentRunRef links a sub-agent to its parent.
 type ParentRunRef struct {
 	// RunName is the name of the parent AgentRun.
@@ -88,6 +117,29 @@ type ModelSpec struct {
 
 	// AuthSecretRef references the secret containing the API key.
 	AuthSecretRef string `json:"authSecretRef"`
+
+	// Fallbacks is an ordered list of models to try when the primary model
+	// fails with a rate-limit, overload or auth error.
+	// +optional
+	Fallbacks []ModelFallback `json:"fallbacks,omitempty"`
+}
+
+// ModelFallback is one entry in a model failover chain.
+type ModelFallback struct {
+	// Provider is the AI provider (openai, anthropic, azure-openai, ollama, etc.).
+	Provider string `json:"provider"`
+
+	// Model is the model identifier.
+	Model string `json:"model"`
+
+	// BaseURL overrides the provider's default API endpoint.
+	// +optional
+	BaseURL string `json:"baseURL,omitempty"`
+
+	// AuthSecretRef references the secret containing the API key for this
+	// provider. Leave empty for providers that need no key (e.g. Ollama).
+	// +optional
+	AuthSecretRef string `json:"authSecretRef,omitempty"`
 }
 
 // AgentRunSandboxSpec defines sandbox settings for an individual agent run.
@@ -145,7 +197,7 @@ type ResourceSpec struct {
 
 // ToolPolicySpec defines which tools an agent may use.
 type ToolPolicySpec struct {
-	// Allow lists explicitly allowed tools.
+	// Allow lists explicitly allowed tools. When set, all other tools are denied.
 	Allow []string `json:"allow,omitempty"`
 
 	// Deny lists explicitly denied tools.
@@ -200,11 +252,146 @@ type AgentRunStatus struct {
 	// +optional
 	TokenUsage *TokenUsage `json:"tokenUsage,omitempty"`
 
+	// ServedModel is the "provider/model" that produced the final reply.
+	// Differs from spec.model when a fallback was used.
+	// +optional
+	ServedModel string `json:"servedModel,omitempty"`
+
+	// FailedAttempts lists each model in the failover chain that was tried
+	// and failed, in order.
+	// +optional
+	FailedAttempts []ModelAttempt `json:"failedAttempts,omitempty"`
+
+	// Approvals records tool calls that required human approval under a
+	// ToolGating "ask" rule, and the decision taken on each.
+	// +optional
+	Approvals []ToolApproval `json:"approvals,omitempty"`
+
+	// FollowUps are messages sent to the agent while it runs. They are
+	// delivered to the pod and injected into the conversation between
+	// tool-loop iterations.
+	// +optional
+	FollowUps []FollowUpMessage `json:"followUps,omitempty"`
+
 	// Conditions represent the latest available observations.
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+// ModelAttempt records a model that failed during a run's failover chain.
+type ModelAttempt struct {
+	// Provider is the AI provider that was tried.
+	Provider string `json:"provider"`
+
+	// Model is the model identifier that was tried.
+	Model string `json:"model"`
+
+	// Reason classifies the failure (rate_limit, overloaded, auth).
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Error is the (truncated) provider error message.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// Tool approval decisions.
+const (
+	ApprovalApproved = "approved"
+	ApprovalDenied   = "denied"
+	ApprovalTimeout  = "timeout"
+)
+
+// ToolApproval is a tool call awaiting, or decided by, a human approver.
+type ToolApproval struct {
+	// ID identifies the request within the run.
+	ID string `json:"id"`
+
+	// Tool is the name of the gated tool.
+	Tool string `json:"tool"`
+
+	// Arguments is the (truncated) JSON arguments of the call.
+	// +optional
+	Arguments string `json:"arguments,omitempty"`
+
+	// RequestedAt is when the agent asked for approval.
+	// +optional
+	RequestedAt *metav1.Time `json:"requestedAt,omitempty"`
+
+	// ExpiresAt is when the request times out and the call is denied.
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+
+	// Decision is approved, denied or timeout. Empty while pending.
+	// +kubebuilder:validation:Enum=approved;denied;timeout
+	// +optional
+	Decision string `json:"decision,omitempty"`
+
+	// Approver identifies who made the decision, e.g. "tui:alice",
+	// "api:ops-bot" or "telegram:12345".
+	// +optional
+	Approver string `json:"approver,omitempty"`
+
+	// Reason is an optional note from the approver.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// DecidedAt is when the decision was made.
+	// +optional
+	DecidedAt *metav1.Time `json:"decidedAt,omitempty"`
+}
+
+// DecideApproval records a decision on the pending approval with the given
+// ID. It returns false if there is no such approval or it is already decided.
+func (s *AgentRunStatus) DecideApproval(id, decision, approver, reason string, at metav1.Time) bool {
+	for i := range s.Approvals {
+		a := &s.Approvals[i]
+		if a.ID != id || a.Decision != "" {
+			continue
+		}
+		a.Decision = decision
+		a.Approver = approver
+		a.Reason = reason
+		a.DecidedAt = &at
+		return true
+	}
+	return false
+}
+
+// FollowUpMessage is a message sent to a running agent.
+type FollowUpMessage struct {
+	// ID identifies the message within the run.
+	ID string `json:"id"`
+
+	// Text is the message content.
+	Text string `json:"text"`
+
+	// Sender identifies who sent the message, e.g. "tui:alice" or "api:bob".
+	// +optional
+	Sender string `json:"sender,omitempty"`
+
+	// SentAt is when the message was sent.
+	// +optional
+	SentAt *metav1.Time `json:"sentAt,omitempty"`
+
+	// DeliveredAt is when the message was handed to the agent pod.
+	// +optional
+	DeliveredAt *metav1.Time `json:"deliveredAt,omitempty"`
+}
+
+// AddFollowUp appends a follow-up message, numbering it after the existing
+// ones, and returns it.
+func (s *AgentRunStatus) AddFollowUp(text, sender string, at metav1.Time) FollowUpMessage {
+	m := FollowUpMessage{
+		ID:     strconv.Itoa(len(s.FollowUps) + 1),
+		Text:   text,
+		Sender: sender,
+		SentAt: &at,
+	}
+	s.FollowUps = append(s.FollowUps, m)
+	return m
+}
+
 // TokenUsage tracks LLM token consumption and timing for an AgentRun.
 type TokenUsage struct {
 	// InputTokens is the total number of prompt/input tokens sent to the LLM.
@@ -213,6 +400,17 @@ type TokenUsage struct {
 	// OutputTokens is the total number of completion/output tokens received.
 	OutputTokens int `json:"outputTokens"`
 
+	// ThinkingTokens is the portion of OutputTokens spent on extended
+	// thinking / reasoning. Only set when the model reports it.
+	// +optional
+	ThinkingTokens int `json:"thinkingTokens,omitempty"`
+
+	// ThinkingTokensEstimated is an estimate of the portion of OutputTokens
+	// spent on extended thinking, for providers that fold thinking into
+	// the output tokens without reporting its share (Anthropic).
+	// +optional
+	ThinkingTokensEstimated int `json:"thinkingTokensEstimated,omitempty"`
+
 	// TotalTokens is InputTokens + OutputTokens.
 	TotalTokens int `json:"totalTokens"`
 
@@ -223,6 +421,23 @@ type TokenUsage struct {
 	DurationMs int64 `json:"durationMs"`
 }
 
+// MaxRunNameLength is the longest AgentRun name a Job can be created for:
+// the Job is named after the run and its job-name label value is limited to
+// 63 characters.
+const MaxRunNameLength = 63
+
+// ShortenRunName returns name if it fits in MaxRunNameLength. Otherwise it
+// cuts name and appends a hash of the whole, so the result is the same for
+// the same name and names that differ past the cut stay different.
+func ShortenRunName(name string) string {
+	if len(name) <= MaxRunNameLength {
+		return name
+	}
+	sum := sha256.Sum256([]byte(name))
+	hash := hex.EncodeToString(sum[:])[:10]
+	return strings.TrimRight(name[:MaxRunNameLength-len(hash)-1], "-.") + "-" + hash
+}
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Instance",type="string",JSONPath=".spec.instanceRef"
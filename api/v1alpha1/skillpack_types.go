@@ -2,6 +2,7 @@ package v1alpha1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // SkillPackSpec defines the desired state of SkillPack.
@@ -31,6 +32,17 @@ type SkillPackSpec struct {
 	// the controller creates scoped RBAC automatically.
 	// +optional
 	Sidecar *SkillSidecar `json:"sidecar,omitempty"`
+
+	// MCPServers declares Model Context Protocol servers whose tools are
+	// exposed to the agent alongside the built-in tools.
+	// +optional
+	MCPServers []MCPServer `json:"mcpServers,omitempty"`
+
+	// Tools declares typed tools whose command templates are run in the
+	// SkillPack's sidecar. Tool policy can then target a single tool
+	// instead of execute_command as a whole.
+	// +optional
+	Tools []SkillTool `json:"tools,omitempty"`
 }
 
 // Skill defines a single skill entry.
@@ -115,6 +127,68 @@ type SkillSidecar struct {
 	ClusterRBAC []RBACRule `json:"clusterRBAC,omitempty"`
 }
 
+// SkillTool defines a named tool backed by a command template. The sidecar
+// renders the template with the call's arguments and runs it with bash.
+type SkillTool struct {
+	// Name is the tool name shown to the model (e.g. "k8s_get_pods").
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9_-]{1,64}$`
+	Name string `json:"name"`
+
+	// Description tells the model what the tool does.
+	Description string `json:"description"`
+
+	// Parameters is a JSON Schema object describing the tool's arguments.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	Parameters *runtime.RawExtension `json:"parameters,omitempty"`
+
+	// Command is the bash command template. Each {{param}} is replaced by
+	// the shell-quoted argument; omitted arguments render as nothing.
+	Command string `json:"command"`
+
+	// TimeoutSeconds bounds the command. Defaults to 30, at most 120.
+	// +kubebuilder:validation:Maximum=120
+	// +optional
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+}
+
+// MCPServer defines a Model Context Protocol server provided by a SkillPack.
+// Its tools are exposed to the agent as "<name>__<tool>".
+type MCPServer struct {
+	// Name identifies the server and prefixes its tool names.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([a-z0-9_-]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=32
+	Name string `json:"name"`
+
+	// Transport is "stdio" for a server started in the SkillPack's sidecar,
+	// or "http" for a streamable-HTTP server reached at URL.
+	// +kubebuilder:validation:Enum=stdio;http
+	// +kubebuilder:default=stdio
+	// +optional
+	Transport string `json:"transport,omitempty"`
+
+	// Command starts a stdio server inside the sidecar (e.g.
+	// ["npx", "-y", "@modelcontextprotocol/server-github"]).
+	// +optional
+	Command []string `json:"command,omitempty"`
+
+	// URL is the endpoint of an http server.
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// Headers are sent with every request to an http server.
+	// +optional
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Tools limits which of the server's tools are exposed. Empty exposes all.
+	// +optional
+	Tools []string `json:"tools,omitempty"`
+
+	// TimeoutSeconds bounds each tool call. Defaults to 60.
+	// +optional
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+}
+
 // EnvVar is a simplified environment variable (name + value).
 type EnvVar struct {
 	Name  string `json:"name"`
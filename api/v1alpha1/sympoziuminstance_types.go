@@ -1,6 +1,10 @@
 package v1alpha1
 
 import (
+	"slices"
+	"strings"
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -36,6 +40,12 @@ type SympoziumInstanceSpec struct {
 	// Observability configures OpenTelemetry exports for runs of this instance.
 	// +optional
 	Observability *ObservabilitySpec `json:"observability,omitempty"`
+
+	// Transcription configures the speech-to-text endpoint that voice notes
+	// from channels are transcribed with. Without it they are attached as
+	// audio files only.
+	// +optional
+	Transcription *TranscriptionSpec `json:"transcription,omitempty"`
 }
 
 // MemorySpec configures persistent memory for a SympoziumInstance.
@@ -55,6 +65,28 @@ type MemorySpec struct {
 	SystemPrompt string `json:"systemPrompt,omitempty"`
 }
 
+// TranscriptionSpec configures an OpenAI-compatible speech-to-text endpoint.
+type TranscriptionSpec struct {
+	// BaseURL is the API's base URL; /audio/transcriptions is appended.
+	// Defaults to https://api.openai.com/v1.
+	// +optional
+	BaseURL string `json:"baseURL,omitempty"`
+
+	// Model is the transcription model. Defaults to whisper-1.
+	// +optional
+	Model string `json:"model,omitempty"`
+
+	// AuthSecretRef is a Secret holding the API key as API_KEY or
+	// OPENAI_API_KEY. Without one, the run's model API key is used.
+	// +optional
+	AuthSecretRef string `json:"authSecretRef,omitempty"`
+
+	// Language is the spoken language as an ISO-639-1 code, e.g. "en".
+	// It is detected when empty.
+	// +optional
+	Language string `json:"language,omitempty"`
+}
+
 // ObservabilitySpec configures OpenTelemetry for agent runs.
 type ObservabilitySpec struct {
 	// Enabled turns OpenTelemetry tracing/metrics on for this instance.
@@ -83,18 +115,104 @@ type ObservabilitySpec struct {
 
 // ChannelSpec defines a channel connection.
 type ChannelSpec struct {
-	// Type is the channel type (telegram, whatsapp, discord, slack).
+	// Type is the channel type (telegram, whatsapp, discord, slack, matrix,
+	// webchat).
 	Type string `json:"type"`
 
 	// ConfigRef references the secret containing channel credentials.
 	// Optional for channels that use alternative authentication (e.g. WhatsApp QR pairing).
+	// For webchat, its WEBCHAT_SECRET key turns on visitor tokens.
 	ConfigRef SecretRef `json:"configRef,omitempty"`
+
+	// Activation controls which messages start a run: "always" (every
+	// message), "mention" (direct messages and messages that mention the
+	// bot), "reply" (as mention, plus replies to the bot) or "dm" (direct
+	// messages only). Defaults to "always".
+	// +kubebuilder:validation:Enum=always;mention;reply;dm
+	// +kubebuilder:default=always
+	// +optional
+	Activation ChannelActivation `json:"activation,omitempty"`
+
+	// AllowFrom lists the sender IDs allowed to use the channel. Empty
+	// allows everyone.
+	// +optional
+	AllowFrom []string `json:"allowFrom,omitempty"`
+
+	// DenyFrom lists sender IDs whose messages are ignored. It takes
+	// precedence over AllowFrom.
+	// +optional
+	DenyFrom []string `json:"denyFrom,omitempty"`
+
+	// AllowChats lists the chat IDs (groups, channels or DMs) the channel
+	// responds in. Empty allows every chat.
+	// +optional
+	AllowChats []string `json:"allowChats,omitempty"`
+
+	// DenyChats lists chat IDs whose messages are ignored. It takes
+	// precedence over AllowChats.
+	// +optional
+	DenyChats []string `json:"denyChats,omitempty"`
+
+	// Pairing makes senders of direct messages who are not in AllowFrom or
+	// PairedSenders pair first: they are sent a one-time code and their
+	// messages are dropped until the owner approves it.
+	// +optional
+	Pairing bool `json:"pairing,omitempty"`
+
+	// PairedSenders lists the sender IDs whose pairing codes were approved.
+	// +optional
+	PairedSenders []string `json:"pairedSenders,omitempty"`
+
+	// Admins lists the sender IDs that may run the /model and /reset chat
+	// commands, which change the instance for every channel. Empty allows
+	// no one.
+	// +optional
+	Admins []string `json:"admins,omitempty"`
+
+	// ProgressNoteAfter sends a "still working on it" note to the chat
+	// when a run takes longer than this. Unset sends no note.
+	// +optional
+	ProgressNoteAfter *metav1.Duration `json:"progressNoteAfter,omitempty"`
+
+	// AutoJoin makes the bot accept invitations to rooms (Matrix).
+	// Invitations from senders that AllowFrom or DenyFrom refuse are
+	// declined.
+	// +optional
+	AutoJoin bool `json:"autoJoin,omitempty"`
+
+	// AllowedOrigins lists the origins of the web pages that may embed the
+	// chat widget (webchat), e.g. "https://intranet.example.com". "*"
+	// allows any page. Empty allows only the API server's own pages.
+	// +optional
+	AllowedOrigins []string `json:"allowedOrigins,omitempty"`
 }
 
+// ChannelActivation selects which group chat messages start an agent run.
+type ChannelActivation string
+
+const (
+	// ChannelActivationAlways starts a run for every message.
+	ChannelActivationAlways ChannelActivation = "always"
+	// ChannelActivationMention starts a run for direct messages and group
+	// messages that mention the bot.
+	ChannelActivationMention ChannelActivation = "mention"
+	// ChannelActivationReply is ChannelActivationMention plus replies to
+	// the bot's own messages.
+	ChannelActivationReply ChannelActivation = "reply"
+	// ChannelActivationDM starts a run for direct messages only.
+	ChannelActivationDM ChannelActivation = "dm"
+)
+
 // AgentsSpec defines agent configuration.
 type AgentsSpec struct {
 	// Default is the default agent configuration.
 	Default AgentConfig `json:"default"`
+
+	// Fallbacks is an ordered list of models to fail over to when the
+	// default model is rate-limited, overloaded or rejects its credentials.
+	// Each entry may use a different provider and auth secret.
+	// +optional
+	Fallbacks []ModelFallback `json:"fallbacks,omitempty"`
 }
 
 // AgentConfig defines configuration for an agent.
@@ -140,7 +258,8 @@ type SubagentsSpec struct {
 	// +kubebuilder:default=2
 	MaxDepth int `json:"maxDepth,omitempty"`
 
-	// MaxConcurrent is the maximum number of concurrent agent runs.
+	// MaxConcurrent is the maximum number of active sub-agents under one
+	// top-level run.
 	// +kubebuilder:default=5
 	MaxConcurrent int `json:"maxConcurrent,omitempty"`
 
@@ -190,6 +309,116 @@ type SympoziumInstanceStatus struct {
 	// Conditions represent the latest available observations of an object's state.
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// PendingPairings lists the pairing codes sent to unknown senders that
+	// are waiting for the owner's approval.
+	// +optional
+	PendingPairings []PairingRequest `json:"pendingPairings,omitempty"`
+}
+
+// PairingTTL is how long a pairing code can be approved.
+const PairingTTL = time.Hour
+
+// PairingRequest is a pairing code sent to an unknown sender.
+type PairingRequest struct {
+	// Code is the one-time code the owner approves.
+	Code string `json:"code"`
+
+	// Channel is the channel type the sender wrote from.
+	Channel string `json:"channel"`
+
+	// SenderID is the sender's ID on the channel.
+	SenderID string `json:"senderId"`
+
+	// SenderName is the sender's display name.
+	// +optional
+	SenderName string `json:"senderName,omitempty"`
+
+	// ChatID is the direct chat with the sender.
+	// +optional
+	ChatID string `json:"chatId,omitempty"`
+
+	// ExpiresAt is when the code can no longer be approved.
+	ExpiresAt metav1.Time `json:"expiresAt"`
+}
+
+// FindPairing returns the pending pairing with the given code, or nil if
+// there is none or it has expired. Codes are not case-sensitive.
+func (s *SympoziumInstanceStatus) FindPairing(code string, now time.Time) *PairingRequest {
+	for i := range s.PendingPairings {
+		p := &s.PendingPairings[i]
+		if strings.EqualFold(p.Code, code) && now.Before(p.ExpiresAt.Time) {
+			return p
+		}
+	}
+	return nil
+}
+
+// Paired reports whether a sender may message the bot directly on a
+// pairing channel.
+func (c *ChannelSpec) Paired(senderID string) bool {
+	return slices.Contains(c.AllowFrom, senderID) || slices.Contains(c.PairedSenders, senderID)
+}
+
+// Admin reports whether a sender may run chat commands that change the
+// instance.
+func (c *ChannelSpec) Admin(senderID string) bool {
+	return senderID != "" && slices.Contains(c.Admins, senderID)
+}
+
+// ApprovePairing adds the sender of the pending pairing with the given code
+// to its channel's PairedSenders. It returns the approved request, or nil
+// if there is no such pairing. The controller drops the pending entry.
+func (in *SympoziumInstance) ApprovePairing(code string, now time.Time) *PairingRequest {
+	p := in.Status.FindPairing(code, now)
+	if p == nil {
+		return nil
+	}
+	for i := range in.Spec.Channels {
+		ch := &in.Spec.Channels[i]
+		if ch.Type != p.Channel {
+			continue
+		}
+		if !slices.Contains(ch.PairedSenders, p.SenderID) {
+			ch.PairedSenders = append(ch.PairedSenders, p.SenderID)
+		}
+		return p
+	}
+	return nil
+}
+
+// RevokePairing removes a sender from a channel's PairedSenders. It
+// returns false if the sender was not paired.
+func (in *SympoziumInstance) RevokePairing(channel, senderID string) bool {
+	for i := range in.Spec.Channels {
+		ch := &in.Spec.Channels[i]
+		if ch.Type != channel || !slices.Contains(ch.PairedSenders, senderID) {
+			continue
+		}
+		ch.PairedSenders = slices.DeleteFunc(ch.PairedSenders, func(s string) bool { return s == senderID })
+		return true
+	}
+	return false
+}
+
+// Provider returns the AI provider of the instance's agents. It prefers
+// the explicit Provider field on AuthRefs, falling back to guessing from
+// the auth secret names.
+func (in *SympoziumInstance) Provider() string {
+	for _, ref := range in.Spec.AuthRefs {
+		if ref.Provider != "" {
+			return ref.Provider
+		}
+	}
+	// Fallback: guess from secret name (e.g. "<inst>-openai-key").
+	for _, ref := range in.Spec.AuthRefs {
+		for _, p := range []string{"anthropic", "azure-openai", "ollama", "openai"} {
+			if strings.Contains(ref.Secret, p) {
+				return p
+			}
+		}
+	}
+	return "openai"
 }
 
 // ChannelStatus reports the status of a channel.
@@ -27,6 +27,30 @@ type SympoziumPolicySpec struct {
 	// NetworkPolicy defines network isolation settings.
 	// +optional
 	NetworkPolicy *NetworkPolicySpec `json:"networkPolicy,omitempty"`
+
+	// AgentMessaging controls which instances agents may message with the
+	// send_agent_message tool. Messaging is denied unless configured.
+	// +optional
+	AgentMessaging *AgentMessagingSpec `json:"agentMessaging,omitempty"`
+}
+
+// AgentMessagingSpec defines agent-to-agent messaging between instances.
+type AgentMessagingSpec struct {
+	// AllowedTargets lists the instances this instance's agents may message.
+	// "*" allows any instance in the namespace.
+	// +optional
+	AllowedTargets []string `json:"allowedTargets,omitempty"`
+
+	// AllowedCallers lists the instances whose agents may message this
+	// instance. When empty, any caller permitted by its own policy may.
+	// +optional
+	AllowedCallers []string `json:"allowedCallers,omitempty"`
+
+	// MaxChainLength is the maximum number of agents in a call chain
+	// (A asks B asks C is a chain of 3).
+	// +kubebuilder:default=3
+	// +optional
+	MaxChainLength int `json:"maxChainLength,omitempty"`
 }
 
 // SandboxPolicySpec defines sandbox enforcement.
@@ -70,6 +94,11 @@ type ToolGatingSpec struct {
 
 	// Rules is the list of tool-specific rules.
 	Rules []ToolGatingRule `json:"rules,omitempty"`
+
+	// ApprovalTimeout is how long an "ask" call waits for a human decision
+	// before it is denied. Defaults to 5m.
+	// +optional
+	ApprovalTimeout *metav1.Duration `json:"approvalTimeout,omitempty"`
 }
 
 // ToolGatingRule defines a rule for a specific tool.
@@ -0,0 +1,216 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SympoziumTriggerSpec defines an HTTP webhook or a watch on cluster objects
+// that starts agent runs for a SympoziumInstance. Exactly one of Auth, for a
+// webhook, and Watch must be set.
+type SympoziumTriggerSpec struct {
+	// InstanceRef is the name of the SympoziumInstance this trigger belongs to.
+	InstanceRef string `json:"instanceRef"`
+
+	// Task is a Go template rendered over each delivery to make the run's
+	// task. The JSON payload is .Payload; the functions header (a request
+	// header by name) and json (a value as JSON) are available. For watch
+	// triggers .Payload is the matching object and .Reason says why it
+	// matched; the object and its recent events are appended to the task.
+	Task string `json:"task"`
+
+	// Auth configures how webhook deliveries are verified.
+	// +optional
+	Auth *TriggerAuth `json:"auth,omitempty"`
+
+	// Watch starts runs for cluster objects in a bad state instead of for
+	// webhook deliveries.
+	// +optional
+	Watch *TriggerWatch `json:"watch,omitempty"`
+
+	// DedupeKey is a Go template, rendered like Task, that identifies a
+	// delivery, e.g. `{{ header "X-GitHub-Delivery" }}`. A delivery whose
+	// key matches a run created within DedupeWindow is acknowledged
+	// without starting another. Empty keys are not deduplicated. Watch
+	// triggers use Watch.Cooldown instead.
+	// +optional
+	DedupeKey string `json:"dedupeKey,omitempty"`
+
+	// DedupeWindow is how long a key suppresses repeated deliveries.
+	// Unset, it does so for as long as the run it started exists.
+	// +optional
+	DedupeWindow *metav1.Duration `json:"dedupeWindow,omitempty"`
+
+	// Deliver sends the result of each run to a channel chat.
+	// +optional
+	Deliver *TriggerDelivery `json:"deliver,omitempty"`
+
+	// IncludeMemory injects the instance's MEMORY.md as context for each run.
+	// +optional
+	IncludeMemory bool `json:"includeMemory,omitempty"`
+
+	// Suspend rejects deliveries, and stops watching, when true.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+}
+
+// TriggerAuth configures how a trigger verifies that a delivery comes from
+// its sender.
+type TriggerAuth struct {
+	// Type is "hmac", a hex HMAC-SHA256 signature of the body as sent by
+	// GitHub and Gitea, or "bearer", a static token as sent by
+	// Alertmanager's authorization setting.
+	// +kubebuilder:validation:Enum=hmac;bearer
+	Type string `json:"type"`
+
+	// SecretRef is the name of the Secret whose WEBHOOK_SECRET key holds
+	// the HMAC key or token.
+	SecretRef string `json:"secretRef"`
+
+	// Header is the request header carrying the signature or token. Unset,
+	// it is X-Hub-Signature-256 for hmac and Authorization for bearer.
+	// +optional
+	Header string `json:"header,omitempty"`
+
+	// Prefix precedes the signature or token in Header. It defaults to
+	// "sha256=" for hmac and "Bearer " for bearer, unless Header is set.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// TriggerWatch selects the cluster objects a watch trigger starts runs for.
+type TriggerWatch struct {
+	// Condition is what the trigger watches for: "PodRestarts", a pod with
+	// a container restarted at least Threshold times; "NodeNotReady", a
+	// node whose Ready condition is not True; "JobFailed", a failed Job;
+	// or "Event", a Kubernetes Event matching Reasons.
+	// +kubebuilder:validation:Enum=PodRestarts;NodeNotReady;JobFailed;Event
+	Condition string `json:"condition"`
+
+	// Namespaces lists the namespaces to watch; "*" watches all of them.
+	// Empty watches the trigger's own namespace. Only triggers in the
+	// sympozium-system namespace may watch other namespaces, since runs see
+	// the full objects. Nodes are not namespaced.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// Selector matches the labels of watched pods, nodes or jobs. It does
+	// not apply to Events.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Reasons lists the event reasons that match, e.g. "BackOff" or
+	// "FailedScheduling". Empty matches every Warning event.
+	// +optional
+	Reasons []string `json:"reasons,omitempty"`
+
+	// Threshold is the number of restarts (PodRestarts) or occurrences
+	// (Event) needed to match. Defaults to 3 restarts and 1 occurrence.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Threshold int32 `json:"threshold,omitempty"`
+
+	// Cooldown is the least time between two runs for the same object, so
+	// a flapping pod does not start a run on every restart. Only changes
+	// within the cooldown match: a restart, failure, NotReady transition
+	// or event older than it does not start a run. Defaults to 30m.
+	// +optional
+	Cooldown *metav1.Duration `json:"cooldown,omitempty"`
+}
+
+// Watch trigger conditions.
+const (
+	WatchPodRestarts  = "PodRestarts"
+	WatchNodeNotReady = "NodeNotReady"
+	WatchJobFailed    = "JobFailed"
+	WatchEvent        = "Event"
+)
+
+// TriggerDelivery is the channel chat a trigger's results are sent to.
+type TriggerDelivery struct {
+	// Channel is the channel type, e.g. "slack". The instance must have a
+	// channel of this type.
+	Channel string `json:"channel"`
+
+	// ChatID is the chat to send to, as the channel identifies it.
+	ChatID string `json:"chatId"`
+
+	// ThreadID is the thread to reply in, for channels with threads.
+	// +optional
+	ThreadID string `json:"threadId,omitempty"`
+}
+
+// SympoziumTriggerStatus defines the observed state of a SympoziumTrigger.
+type SympoziumTriggerStatus struct {
+	// Phase is the current phase (Ready, Suspended, Error).
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// Message explains an Error phase.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// WebhookPath is the API server path deliveries are posted to, for
+	// webhook triggers.
+	// +optional
+	WebhookPath string `json:"webhookPath,omitempty"`
+
+	// LastTriggerTime is when the last AgentRun was started.
+	// +optional
+	LastTriggerTime *metav1.Time `json:"lastTriggerTime,omitempty"`
+
+	// LastRunName is the name of the most recently created AgentRun.
+	// +optional
+	LastRunName string `json:"lastRunName,omitempty"`
+
+	// TotalRuns is the total number of runs started by this trigger.
+	// +optional
+	TotalRuns int64 `json:"totalRuns,omitempty"`
+
+	// WatchFirings records when a watch trigger last started a run for
+	// each object, for the objects still in their cooldown.
+	// +optional
+	WatchFirings []WatchFiring `json:"watchFirings,omitempty"`
+}
+
+// WatchFiring records a run a watch trigger started for an object.
+type WatchFiring struct {
+	// Object identifies the object, e.g. "Pod/default/web-0".
+	Object string `json:"object"`
+
+	// Time is when the run was started.
+	Time metav1.Time `json:"time"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Instance",type="string",JSONPath=".spec.instanceRef"
+// +kubebuilder:printcolumn:name="Auth",type="string",JSONPath=".spec.auth.type"
+// +kubebuilder:printcolumn:name="Watch",type="string",JSONPath=".spec.watch.condition"
+// +kubebuilder:printcolumn:name="Path",type="string",JSONPath=".status.webhookPath"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Last Run",type="date",JSONPath=".status.lastTriggerTime"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// SympoziumTrigger is the Schema for the sympoziumtriggers API.
+// It starts AgentRuns for a SympoziumInstance from inbound webhooks or
+// from watched cluster objects.
+type SympoziumTrigger struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SympoziumTriggerSpec   `json:"spec,omitempty"`
+	Status SympoziumTriggerStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SympoziumTriggerList contains a list of SympoziumTrigger.
+type SympoziumTriggerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SympoziumTrigger `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SympoziumTrigger{}, &SympoziumTriggerList{})
+}
@@ -34,6 +34,31 @@ func (in *AgentConfig) DeepCopy() *AgentConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentMessagingSpec) DeepCopyInto(out *AgentMessagingSpec) {
+	*out = *in
+	if in.AllowedTargets != nil {
+		in, out := &in.AllowedTargets, &out.AllowedTargets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedCallers != nil {
+		in, out := &in.AllowedCallers, &out.AllowedCallers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentMessagingSpec.
+func (in *AgentMessagingSpec) DeepCopy() *AgentMessagingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentMessagingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AgentRun) DeepCopyInto(out *AgentRun) {
 	*out = *in
@@ -61,6 +86,21 @@ func (in *AgentRun) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentRunAttachment) DeepCopyInto(out *AgentRunAttachment) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentRunAttachment.
+func (in *AgentRunAttachment) DeepCopy() *AgentRunAttachment {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentRunAttachment)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AgentRunList) DeepCopyInto(out *AgentRunList) {
 	*out = *in
@@ -126,7 +166,17 @@ func (in *AgentRunSpec) DeepCopyInto(out *AgentRunSpec) {
 		*out = new(ParentRunRef)
 		**out = **in
 	}
-	out.Model = in.Model
+	if in.Attachments != nil {
+		in, out := &in.Attachments, &out.Attachments
+		*out = make([]AgentRunAttachment, len(*in))
+		copy(*out, *in)
+	}
+	in.Model.DeepCopyInto(&out.Model)
+	if in.Transcription != nil {
+		in, out := &in.Transcription, &out.Transcription
+		*out = new(TranscriptionSpec)
+		**out = **in
+	}
 	if in.Sandbox != nil {
 		in, out := &in.Sandbox, &out.Sandbox
 		*out = new(AgentRunSandboxSpec)
@@ -180,6 +230,25 @@ func (in *AgentRunStatus) DeepCopyInto(out *AgentRunStatus) {
 		*out = new(TokenUsage)
 		**out = **in
 	}
+	if in.FailedAttempts != nil {
+		in, out := &in.FailedAttempts, &out.FailedAttempts
+		*out = make([]ModelAttempt, len(*in))
+		copy(*out, *in)
+	}
+	if in.Approvals != nil {
+		in, out := &in.Approvals, &out.Approvals
+		*out = make([]ToolApproval, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.FollowUps != nil {
+		in, out := &in.FollowUps, &out.FollowUps
+		*out = make([]FollowUpMessage, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]v1.Condition, len(*in))
@@ -203,6 +272,11 @@ func (in *AgentRunStatus) DeepCopy() *AgentRunStatus {
 func (in *AgentsSpec) DeepCopyInto(out *AgentsSpec) {
 	*out = *in
 	in.Default.DeepCopyInto(&out.Default)
+	if in.Fallbacks != nil {
+		in, out := &in.Fallbacks, &out.Fallbacks
+		*out = make([]ModelFallback, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentsSpec.
@@ -239,6 +313,46 @@ func (in *CapabilitiesSpec) DeepCopy() *CapabilitiesSpec {
 func (in *ChannelSpec) DeepCopyInto(out *ChannelSpec) {
 	*out = *in
 	out.ConfigRef = in.ConfigRef
+	if in.AllowFrom != nil {
+		in, out := &in.AllowFrom, &out.AllowFrom
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DenyFrom != nil {
+		in, out := &in.DenyFrom, &out.DenyFrom
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowChats != nil {
+		in, out := &in.AllowChats, &out.AllowChats
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DenyChats != nil {
+		in, out := &in.DenyChats, &out.DenyChats
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PairedSenders != nil {
+		in, out := &in.PairedSenders, &out.PairedSenders
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Admins != nil {
+		in, out := &in.Admins, &out.Admins
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ProgressNoteAfter != nil {
+		in, out := &in.ProgressNoteAfter, &out.ProgressNoteAfter
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.AllowedOrigins != nil {
+		in, out := &in.AllowedOrigins, &out.AllowedOrigins
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChannelSpec.
@@ -300,6 +414,29 @@ func (in *EnvVar) DeepCopy() *EnvVar {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FollowUpMessage) DeepCopyInto(out *FollowUpMessage) {
+	*out = *in
+	if in.SentAt != nil {
+		in, out := &in.SentAt, &out.SentAt
+		*out = (*in).DeepCopy()
+	}
+	if in.DeliveredAt != nil {
+		in, out := &in.DeliveredAt, &out.DeliveredAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FollowUpMessage.
+func (in *FollowUpMessage) DeepCopy() *FollowUpMessage {
+	if in == nil {
+		return nil
+	}
+	out := new(FollowUpMessage)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InstalledPersona) DeepCopyInto(out *InstalledPersona) {
 	*out = *in
@@ -315,6 +452,38 @@ func (in *InstalledPersona) DeepCopy() *InstalledPersona {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MCPServer) DeepCopyInto(out *MCPServer) {
+	*out = *in
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tools != nil {
+		in, out := &in.Tools, &out.Tools
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MCPServer.
+func (in *MCPServer) DeepCopy() *MCPServer {
+	if in == nil {
+		return nil
+	}
+	out := new(MCPServer)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MemorySpec) DeepCopyInto(out *MemorySpec) {
 	*out = *in
@@ -330,9 +499,44 @@ func (in *MemorySpec) DeepCopy() *MemorySpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ModelAttempt) DeepCopyInto(out *ModelAttempt) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModelAttempt.
+func (in *ModelAttempt) DeepCopy() *ModelAttempt {
+	if in == nil {
+		return nil
+	}
+	out := new(ModelAttempt)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ModelFallback) DeepCopyInto(out *ModelFallback) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModelFallback.
+func (in *ModelFallback) DeepCopy() *ModelFallback {
+	if in == nil {
+		return nil
+	}
+	out := new(ModelFallback)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ModelSpec) DeepCopyInto(out *ModelSpec) {
 	*out = *in
+	if in.Fallbacks != nil {
+		in, out := &in.Fallbacks, &out.Fallbacks
+		*out = make([]ModelFallback, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModelSpec.
@@ -387,6 +591,22 @@ func (in *ObservabilitySpec) DeepCopy() *ObservabilitySpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PairingRequest) DeepCopyInto(out *PairingRequest) {
+	*out = *in
+	in.ExpiresAt.DeepCopyInto(&out.ExpiresAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PairingRequest.
+func (in *PairingRequest) DeepCopy() *PairingRequest {
+	if in == nil {
+		return nil
+	}
+	out := new(PairingRequest)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ParentRunRef) DeepCopyInto(out *ParentRunRef) {
 	*out = *in
@@ -901,6 +1121,20 @@ func (in *SkillPackSpec) DeepCopyInto(out *SkillPackSpec) {
 		*out = new(SkillSidecar)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.MCPServers != nil {
+		in, out := &in.MCPServers, &out.MCPServers
+		*out = make([]MCPServer, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Tools != nil {
+		in, out := &in.Tools, &out.Tools
+		*out = make([]SkillTool, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SkillPackSpec.
@@ -1019,6 +1253,26 @@ func (in *SkillSidecar) DeepCopy() *SkillSidecar {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SkillTool) DeepCopyInto(out *SkillTool) {
+	*out = *in
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SkillTool.
+func (in *SkillTool) DeepCopy() *SkillTool {
+	if in == nil {
+		return nil
+	}
+	out := new(SkillTool)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SubagentPolicySpec) DeepCopyInto(out *SubagentPolicySpec) {
 	*out = *in
@@ -1114,7 +1368,9 @@ func (in *SympoziumInstanceSpec) DeepCopyInto(out *SympoziumInstanceSpec) {
 	if in.Channels != nil {
 		in, out := &in.Channels, &out.Channels
 		*out = make([]ChannelSpec, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	in.Agents.DeepCopyInto(&out.Agents)
 	if in.Skills != nil {
@@ -1137,6 +1393,11 @@ func (in *SympoziumInstanceSpec) DeepCopyInto(out *SympoziumInstanceSpec) {
 		*out = new(ObservabilitySpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Transcription != nil {
+		in, out := &in.Transcription, &out.Transcription
+		*out = new(TranscriptionSpec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SympoziumInstanceSpec.
@@ -1166,6 +1427,13 @@ func (in *SympoziumInstanceStatus) DeepCopyInto(out *SympoziumInstanceStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.PendingPairings != nil {
+		in, out := &in.PendingPairings, &out.PendingPairings
+		*out = make([]PairingRequest, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SympoziumInstanceStatus.
@@ -1267,6 +1535,11 @@ func (in *SympoziumPolicySpec) DeepCopyInto(out *SympoziumPolicySpec) {
 		*out = new(NetworkPolicySpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.AgentMessaging != nil {
+		in, out := &in.AgentMessaging, &out.AgentMessaging
+		*out = new(AgentMessagingSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SympoziumPolicySpec.
@@ -1405,6 +1678,126 @@ func (in *SympoziumScheduleStatus) DeepCopy() *SympoziumScheduleStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SympoziumTrigger) DeepCopyInto(out *SympoziumTrigger) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SympoziumTrigger.
+func (in *SympoziumTrigger) DeepCopy() *SympoziumTrigger {
+	if in == nil {
+		return nil
+	}
+	out := new(SympoziumTrigger)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SympoziumTrigger) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SympoziumTriggerList) DeepCopyInto(out *SympoziumTriggerList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SympoziumTrigger, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SympoziumTriggerList.
+func (in *SympoziumTriggerList) DeepCopy() *SympoziumTriggerList {
+	if in == nil {
+		return nil
+	}
+	out := new(SympoziumTriggerList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SympoziumTriggerList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SympoziumTriggerSpec) DeepCopyInto(out *SympoziumTriggerSpec) {
+	*out = *in
+	if in.Auth != nil {
+		in, out := &in.Auth, &out.Auth
+		*out = new(TriggerAuth)
+		**out = **in
+	}
+	if in.Watch != nil {
+		in, out := &in.Watch, &out.Watch
+		*out = new(TriggerWatch)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DedupeWindow != nil {
+		in, out := &in.DedupeWindow, &out.DedupeWindow
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Deliver != nil {
+		in, out := &in.Deliver, &out.Deliver
+		*out = new(TriggerDelivery)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SympoziumTriggerSpec.
+func (in *SympoziumTriggerSpec) DeepCopy() *SympoziumTriggerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SympoziumTriggerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SympoziumTriggerStatus) DeepCopyInto(out *SympoziumTriggerStatus) {
+	*out = *in
+	if in.LastTriggerTime != nil {
+		in, out := &in.LastTriggerTime, &out.LastTriggerTime
+		*out = (*in).DeepCopy()
+	}
+	if in.WatchFirings != nil {
+		in, out := &in.WatchFirings, &out.WatchFirings
+		*out = make([]WatchFiring, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SympoziumTriggerStatus.
+func (in *SympoziumTriggerStatus) DeepCopy() *SympoziumTriggerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SympoziumTriggerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TokenUsage) DeepCopyInto(out *TokenUsage) {
 	*out = *in
@@ -1420,6 +1813,33 @@ func (in *TokenUsage) DeepCopy() *TokenUsage {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ToolApproval) DeepCopyInto(out *ToolApproval) {
+	*out = *in
+	if in.RequestedAt != nil {
+		in, out := &in.RequestedAt, &out.RequestedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	if in.DecidedAt != nil {
+		in, out := &in.DecidedAt, &out.DecidedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ToolApproval.
+func (in *ToolApproval) DeepCopy() *ToolApproval {
+	if in == nil {
+		return nil
+	}
+	out := new(ToolApproval)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ToolGatingRule) DeepCopyInto(out *ToolGatingRule) {
 	*out = *in
@@ -1443,6 +1863,11 @@ func (in *ToolGatingSpec) DeepCopyInto(out *ToolGatingSpec) {
 		*out = make([]ToolGatingRule, len(*in))
 		copy(*out, *in)
 	}
+	if in.ApprovalTimeout != nil {
+		in, out := &in.ApprovalTimeout, &out.ApprovalTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ToolGatingSpec.
@@ -1479,3 +1904,99 @@ func (in *ToolPolicySpec) DeepCopy() *ToolPolicySpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TranscriptionSpec) DeepCopyInto(out *TranscriptionSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TranscriptionSpec.
+func (in *TranscriptionSpec) DeepCopy() *TranscriptionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TranscriptionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TriggerAuth) DeepCopyInto(out *TriggerAuth) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TriggerAuth.
+func (in *TriggerAuth) DeepCopy() *TriggerAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(TriggerAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TriggerDelivery) DeepCopyInto(out *TriggerDelivery) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TriggerDelivery.
+func (in *TriggerDelivery) DeepCopy() *TriggerDelivery {
+	if in == nil {
+		return nil
+	}
+	out := new(TriggerDelivery)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TriggerWatch) DeepCopyInto(out *TriggerWatch) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Reasons != nil {
+		in, out := &in.Reasons, &out.Reasons
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Cooldown != nil {
+		in, out := &in.Cooldown, &out.Cooldown
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TriggerWatch.
+func (in *TriggerWatch) DeepCopy() *TriggerWatch {
+	if in == nil {
+		return nil
+	}
+	out := new(TriggerWatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WatchFiring) DeepCopyInto(out *WatchFiring) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WatchFiring.
+func (in *WatchFiring) DeepCopy() *WatchFiring {
+	if in == nil {
+		return nil
+	}
+	out := new(WatchFiring)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -3,6 +3,7 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"flag"
@@ -90,6 +91,15 @@ func main() {
 	defer cancel()
 
 	go dc.handleOutbound(ctx)
+	// Discord shows typing for about ten seconds.
+	go func() {
+		_ = dc.RunPresence(ctx, &channel.Presence{
+			Refresh: 8 * time.Second,
+			Show: func(ctx context.Context, ev channel.TypingEvent) error {
+				return dc.session.ChannelTyping(ev.ChatID, discordgo.WithContext(ctx))
+			},
+		})
+	}()
 
 	// Health server
 	mux := http.NewServeMux()
@@ -128,7 +138,7 @@ func (dc *DiscordChannel) messageCreate(s *discordgo.Session, m *discordgo.Messa
 	}
 
 	// Skip empty messages
-	if m.Content == "" {
+	if m.Content == "" && len(m.Attachments) == 0 {
 		return
 	}
 
@@ -141,6 +151,23 @@ func (dc *DiscordChannel) messageCreate(s *discordgo.Session, m *discordgo.Messa
 			"messageId": m.ID,
 			"guildId":   m.GuildID,
 		},
+		// Direct messages have no guild.
+		IsDirect:   m.GuildID == "",
+		Mentioned:  mentionsUser(m.Mentions, s.State.User.ID),
+		ReplyToBot: m.ReferencedMessage != nil && m.ReferencedMessage.Author != nil && m.ReferencedMessage.Author.ID == s.State.User.ID,
+	}
+
+	for _, att := range m.Attachments {
+		a := channel.Attachment{Filename: att.Filename, MimeType: att.ContentType}
+		if att.Size > msg.MediaRoom() {
+			msg.SkipAttachment(a, "it is too large")
+			continue
+		}
+		req, err := http.NewRequest(http.MethodGet, att.URL, nil)
+		if err != nil {
+			continue
+		}
+		msg.Download(s.Client, req, a)
 	}
 
 	if err := dc.PublishInbound(context.Background(), msg); err != nil {
@@ -148,6 +175,16 @@ func (dc *DiscordChannel) messageCreate(s *discordgo.Session, m *discordgo.Messa
 	}
 }
 
+// mentionsUser reports whether id is among a message's mentioned users.
+func mentionsUser(mentions []*discordgo.User, id string) bool {
+	for _, u := range mentions {
+		if u != nil && u.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
 // handleOutbound subscribes to outbound messages and sends them via Discord.
 func (dc *DiscordChannel) handleOutbound(ctx context.Context) {
 	events, err := dc.SubscribeOutbound(ctx)
@@ -175,8 +212,25 @@ func (dc *DiscordChannel) handleOutbound(ctx context.Context) {
 	}
 }
 
-// sendMessage sends a message to a Discord channel.
+// sendMessage sends a message to a Discord channel, split into several if
+// it is too long.
 func (dc *DiscordChannel) sendMessage(msg channel.OutboundMessage) error {
-	_, err := dc.session.ChannelMessageSend(msg.ChatID, msg.Text)
+	for _, text := range channel.Render(msg, channel.DialectDiscord) {
+		if _, err := dc.session.ChannelMessageSend(msg.ChatID, text); err != nil {
+			return err
+		}
+	}
+	if len(msg.Attachments) == 0 {
+		return nil
+	}
+	send := &discordgo.MessageSend{}
+	for _, a := range msg.Attachments {
+		send.Files = append(send.Files, &discordgo.File{
+			Name:        a.Filename,
+			ContentType: a.MimeType,
+			Reader:      bytes.NewReader(a.Data),
+		})
+	}
+	_, err := dc.session.ChannelMessageSendComplex(msg.ChatID, send)
 	return err
 }
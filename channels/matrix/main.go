@@ -0,0 +1,635 @@
+// Package main is the entry point for the Matrix channel pod.
+//
+// It uses the Matrix client-server API directly: the pod long-polls /sync
+// with an access token and sends replies as room messages. Messages in
+// threads are answered in the same thread. End-to-end encrypted rooms are
+// not supported.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/alexsjones/sympozium/internal/channel"
+	"github.com/alexsjones/sympozium/internal/eventbus"
+)
+
+// syncTimeout is how long the homeserver holds a /sync request open when
+// there is nothing new.
+const syncTimeout = 30 * time.Second
+
+// maxSentEvents bounds the bot's own event IDs kept to recognise replies
+// to it.
+const maxSentEvents = 1000
+
+// MatrixChannel implements the Matrix channel.
+type MatrixChannel struct {
+	channel.BaseChannel
+	Homeserver  string
+	AccessToken string
+	log         logr.Logger
+	client      *http.Client
+	healthy     atomic.Bool
+	txn         atomic.Int64
+
+	// AutoJoin accepts room invitations from senders allowed by
+	// allowFrom and denyFrom.
+	AutoJoin  bool
+	allowFrom []string
+	denyFrom  []string
+
+	// userID is the bot's own user.
+	userID string
+
+	mu sync.Mutex
+	// members is the number of joined members of each room, from the
+	// room summaries /sync sends when it changes.
+	members map[string]int
+	// sent holds the IDs of the bot's own messages, oldest first.
+	sent []string
+}
+
+// matrixEvent is a room event from /sync.
+type matrixEvent struct {
+	Type     string        `json:"type"`
+	Sender   string        `json:"sender"`
+	EventID  string        `json:"event_id"`
+	StateKey *string       `json:"state_key"`
+	Content  matrixContent `json:"content"`
+}
+
+// matrixContent is the content of the room events the channel reads.
+type matrixContent struct {
+	MsgType    string `json:"msgtype"`
+	Body       string `json:"body"`
+	Format     string `json:"format"`
+	Formatted  string `json:"formatted_body"`
+	FileName   string `json:"filename"`
+	URL        string `json:"url"`
+	Membership string `json:"membership"`
+	Info       struct {
+		MimeType string `json:"mimetype"`
+		Size     int    `json:"size"`
+	} `json:"info"`
+	RelatesTo *struct {
+		RelType   string `json:"rel_type"`
+		EventID   string `json:"event_id"`
+		InReplyTo *struct {
+			EventID string `json:"event_id"`
+		} `json:"m.in_reply_to"`
+	} `json:"m.relates_to"`
+	Mentions *struct {
+		UserIDs []string `json:"user_ids"`
+	} `json:"m.mentions"`
+	Voice *struct{} `json:"org.matrix.msc3245.voice"`
+}
+
+// syncResponse is the part of a /sync response the channel reads.
+type syncResponse struct {
+	NextBatch string `json:"next_batch"`
+	Rooms     struct {
+		Join map[string]struct {
+			Summary struct {
+				JoinedMembers *int `json:"m.joined_member_count"`
+			} `json:"summary"`
+			Timeline struct {
+				Events []matrixEvent `json:"events"`
+			} `json:"timeline"`
+		} `json:"join"`
+		Invite map[string]struct {
+			InviteState struct {
+				Events []matrixEvent `json:"events"`
+			} `json:"invite_state"`
+		} `json:"invite"`
+	} `json:"rooms"`
+}
+
+func main() {
+	var instanceName string
+	var eventBusURL string
+	var homeserver string
+	var accessToken string
+
+	flag.StringVar(&instanceName, "instance", os.Getenv("INSTANCE_NAME"), "SympoziumInstance name")
+	flag.StringVar(&eventBusURL, "event-bus-url", os.Getenv("EVENT_BUS_URL"), "Event bus URL")
+	flag.StringVar(&homeserver, "homeserver", os.Getenv("MATRIX_HOMESERVER_URL"), "Matrix homeserver URL (https://matrix.example.com)")
+	flag.StringVar(&accessToken, "access-token", os.Getenv("MATRIX_ACCESS_TOKEN"), "Matrix access token of the bot user")
+	flag.Parse()
+
+	if homeserver == "" || accessToken == "" {
+		fmt.Fprintln(os.Stderr, "MATRIX_HOMESERVER_URL and MATRIX_ACCESS_TOKEN are required")
+		os.Exit(1)
+	}
+
+	log := zap.New(zap.UseDevMode(false)).WithName("channel-matrix")
+
+	bus, err := eventbus.NewNATSEventBus(eventBusURL)
+	if err != nil {
+		log.Error(err, "failed to connect to event bus")
+		os.Exit(1)
+	}
+	defer bus.Close()
+
+	ch := &MatrixChannel{
+		BaseChannel: channel.BaseChannel{
+			ChannelType:  "matrix",
+			InstanceName: instanceName,
+			EventBus:     bus,
+		},
+		Homeserver:  strings.TrimRight(homeserver, "/"),
+		AccessToken: accessToken,
+		AutoJoin:    os.Getenv("AUTO_JOIN") == "true",
+		allowFrom:   splitList(os.Getenv("ALLOW_FROM")),
+		denyFrom:    splitList(os.Getenv("DENY_FROM")),
+		log:         log,
+		client:      &http.Client{Timeout: syncTimeout + 30*time.Second},
+		members:     map[string]int{},
+	}
+	ch.txn.Store(time.Now().UnixNano())
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	// Health server
+	go func() {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+			if ch.healthy.Load() {
+				w.WriteHeader(http.StatusOK)
+			} else {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		})
+		_ = http.ListenAndServe(":8080", mux)
+	}()
+
+	// Matrix typing notifications last for the timeout they are sent with.
+	go func() {
+		_ = ch.RunPresence(ctx, &channel.Presence{
+			Refresh: 20 * time.Second,
+			Show: func(ctx context.Context, ev channel.TypingEvent) error {
+				return ch.setTyping(ctx, ev.ChatID, true)
+			},
+			Clear: func(ctx context.Context, ev channel.TypingEvent) error {
+				return ch.setTyping(ctx, ev.ChatID, false)
+			},
+		})
+	}()
+
+	for {
+		err := ch.whoami(ctx)
+		if err == nil {
+			break
+		}
+		log.Error(err, "failed to look up the bot user")
+		ch.setHealthy(ctx, false, err.Error())
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(10 * time.Second):
+		}
+	}
+
+	go ch.handleOutbound(ctx)
+
+	log.Info("Starting Matrix channel", "instance", instanceName, "user", ch.userID, "autoJoin", ch.AutoJoin)
+	ch.syncLoop(ctx)
+}
+
+// splitList splits a comma-separated list, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// do sends a client-server API request and decodes the JSON response into
+// out, if it is not nil.
+func (mc *MatrixChannel) do(ctx context.Context, method, path string, body io.Reader, contentType string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, mc.Homeserver+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+mc.AccessToken)
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := mc.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			ErrCode string `json:"errcode"`
+			Error   string `json:"error"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&e)
+		return fmt.Errorf("%s %s: HTTP %d %s %s", method, strings.SplitN(path, "?", 2)[0], resp.StatusCode, e.ErrCode, e.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// doJSON sends a request with a JSON body.
+func (mc *MatrixChannel) doJSON(ctx context.Context, method, path string, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return mc.do(ctx, method, path, bytes.NewReader(body), "application/json", out)
+}
+
+// whoami looks up the bot's own user ID.
+func (mc *MatrixChannel) whoami(ctx context.Context) error {
+	var who struct {
+		UserID string `json:"user_id"`
+	}
+	if err := mc.do(ctx, http.MethodGet, "/_matrix/client/v3/account/whoami", nil, "", &who); err != nil {
+		return err
+	}
+	mc.userID = who.UserID
+	return nil
+}
+
+// syncLoop long-polls /sync until ctx is cancelled. Events from before the
+// pod started are skipped, so old messages are not answered again.
+func (mc *MatrixChannel) syncLoop(ctx context.Context) {
+	since := ""
+	backoff := time.Second
+	for ctx.Err() == nil {
+		q := url.Values{"timeout": {fmt.Sprint(syncTimeout.Milliseconds())}}
+		if since == "" {
+			// Only the position is needed from the first sync.
+			q = url.Values{"filter": {`{"room":{"timeline":{"limit":1}}}`}}
+		} else {
+			q.Set("since", since)
+		}
+		var resp syncResponse
+		if err := mc.do(ctx, http.MethodGet, "/_matrix/client/v3/sync?"+q.Encode(), nil, "", &resp); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			mc.log.Error(err, "sync failed")
+			mc.setHealthy(ctx, false, err.Error())
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, time.Minute)
+			continue
+		}
+		backoff = time.Second
+		mc.setHealthy(ctx, true, "")
+
+		first := since == ""
+		since = resp.NextBatch
+		for roomID, room := range resp.Rooms.Join {
+			if n := room.Summary.JoinedMembers; n != nil {
+				mc.mu.Lock()
+				mc.members[roomID] = *n
+				mc.mu.Unlock()
+			}
+			if first {
+				continue
+			}
+			for _, ev := range room.Timeline.Events {
+				mc.handleEvent(ctx, roomID, ev)
+			}
+		}
+		for roomID, room := range resp.Rooms.Invite {
+			mc.handleInvite(ctx, roomID, room.InviteState.Events)
+		}
+	}
+}
+
+// handleInvite joins a room the bot was invited to if auto-join is on and
+// the inviter is allowed, and declines the invitation otherwise.
+func (mc *MatrixChannel) handleInvite(ctx context.Context, roomID string, state []matrixEvent) {
+	if !mc.AutoJoin {
+		// Leave the invitation for someone to accept by hand.
+		return
+	}
+	inviter := ""
+	for _, ev := range state {
+		if ev.Type == "m.room.member" && ev.StateKey != nil && *ev.StateKey == mc.userID && ev.Content.Membership == "invite" {
+			inviter = ev.Sender
+		}
+	}
+	path := "/_matrix/client/v3/rooms/" + url.PathEscape(roomID)
+	if slices.Contains(mc.denyFrom, inviter) || (len(mc.allowFrom) > 0 && !slices.Contains(mc.allowFrom, inviter)) {
+		mc.log.Info("Declining room invitation", "room", roomID, "inviter", inviter)
+		if err := mc.doJSON(ctx, http.MethodPost, path+"/leave", map[string]any{}, nil); err != nil {
+			mc.log.Error(err, "failed to decline invitation", "room", roomID)
+		}
+		return
+	}
+	if err := mc.doJSON(ctx, http.MethodPost, path+"/join", map[string]any{}, nil); err != nil {
+		mc.log.Error(err, "failed to join room", "room", roomID)
+		return
+	}
+	mc.log.Info("Joined room", "room", roomID, "inviter", inviter)
+}
+
+// handleEvent publishes a room message from someone else.
+func (mc *MatrixChannel) handleEvent(ctx context.Context, roomID string, ev matrixEvent) {
+	if ev.Sender == mc.userID {
+		return
+	}
+	switch ev.Type {
+	case "m.room.message":
+	case "m.room.encrypted":
+		mc.log.V(1).Info("Ignoring encrypted message", "room", roomID)
+		return
+	default:
+		return
+	}
+	c := ev.Content
+	rel := c.RelatesTo
+	if rel != nil && rel.RelType == "m.replace" {
+		// Edits of earlier messages are not new requests.
+		return
+	}
+
+	msg := channel.InboundMessage{
+		SenderID: ev.Sender,
+		ChatID:   roomID,
+		Metadata: map[string]string{
+			"messageId": ev.EventID,
+		},
+		Mentioned: mc.mentioned(c),
+	}
+	if rel != nil && rel.RelType == "m.thread" {
+		msg.ThreadID = rel.EventID
+	}
+	if rel != nil && rel.InReplyTo != nil && !(msg.ThreadID != "" && rel.InReplyTo.EventID == msg.ThreadID) {
+		msg.ReplyToBot = mc.sentByBot(rel.InReplyTo.EventID)
+	}
+	mc.mu.Lock()
+	msg.IsDirect = mc.members[roomID] == 2
+	mc.mu.Unlock()
+
+	// m.notice is what bots send; answering it lets two bots loop forever.
+	switch c.MsgType {
+	case "m.text", "m.emote":
+		msg.Text = stripReplyFallback(c.Body)
+	case "m.image", "m.audio", "m.video", "m.file":
+		name := c.FileName
+		if name == "" {
+			name = c.Body
+		} else if c.Body != c.FileName {
+			// With a separate file name, the body is a caption.
+			msg.Text = c.Body
+		}
+		if c.Voice != nil {
+			name = "voice.ogg"
+		}
+		mc.attach(ctx, &msg, c, name)
+	default:
+		return
+	}
+	if msg.Text == "" && len(msg.Attachments) == 0 {
+		return
+	}
+	if err := mc.PublishInbound(ctx, msg); err != nil {
+		mc.log.Error(err, "failed to publish inbound")
+	}
+}
+
+// mentioned reports whether a message mentions the bot, either with an
+// intentional mention or by user ID.
+func (mc *MatrixChannel) mentioned(c matrixContent) bool {
+	if c.Mentions != nil && slices.Contains(c.Mentions.UserIDs, mc.userID) {
+		return true
+	}
+	return strings.Contains(c.Body, mc.userID) || strings.Contains(c.Formatted, "https://matrix.to/#/"+mc.userID)
+}
+
+// stripReplyFallback removes the quoted "> <@user> ..." lines that older
+// clients put before the text of a reply.
+func stripReplyFallback(body string) string {
+	if !strings.HasPrefix(body, "> <") {
+		return body
+	}
+	lines := strings.Split(body, "\n")
+	i := 0
+	for i < len(lines) && strings.HasPrefix(lines[i], ">") {
+		i++
+	}
+	return strings.TrimSpace(strings.Join(lines[i:], "\n"))
+}
+
+// attach downloads the file of a media message and attaches it to msg.
+func (mc *MatrixChannel) attach(ctx context.Context, msg *channel.InboundMessage, c matrixContent, name string) {
+	a := channel.Attachment{Filename: name, MimeType: c.Info.MimeType}
+	server, mediaID, ok := strings.Cut(strings.TrimPrefix(c.URL, "mxc://"), "/")
+	if !strings.HasPrefix(c.URL, "mxc://") || !ok {
+		msg.SkipAttachment(a, "it cannot be downloaded")
+		return
+	}
+	if c.Info.Size > msg.MediaRoom() {
+		msg.SkipAttachment(a, "it is too large")
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		mc.Homeserver+"/_matrix/client/v1/media/download/"+url.PathEscape(server)+"/"+url.PathEscape(mediaID), nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+mc.AccessToken)
+	msg.Download(mc.client, req, a)
+}
+
+// handleOutbound subscribes to outbound messages and sends them to their
+// rooms.
+func (mc *MatrixChannel) handleOutbound(ctx context.Context) {
+	events, err := mc.SubscribeOutbound(ctx)
+	if err != nil {
+		mc.log.Error(err, "failed to subscribe to outbound messages")
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-events:
+			var msg channel.OutboundMessage
+			if err := json.Unmarshal(event.Data, &msg); err != nil {
+				continue
+			}
+			if msg.Channel != "matrix" {
+				continue
+			}
+			if err := mc.sendMessage(ctx, msg); err != nil {
+				mc.log.Error(err, "failed to send matrix message", "room", msg.ChatID)
+			}
+		}
+	}
+}
+
+// sendMessage sends a message to a room, split into several if it is too
+// long, followed by its attachments. Markdown and HTML are sent as
+// formatted messages with a plain text body.
+func (mc *MatrixChannel) sendMessage(ctx context.Context, msg channel.OutboundMessage) error {
+	for _, text := range channel.Render(msg, channel.DialectMatrix) {
+		content := map[string]any{"msgtype": "m.text", "body": text}
+		if msg.Format != channel.FormatPlain {
+			content["body"] = htmlToText(text)
+			content["format"] = "org.matrix.custom.html"
+			content["formatted_body"] = text
+		}
+		if err := mc.send(ctx, msg, content); err != nil {
+			return err
+		}
+	}
+	for _, a := range msg.Attachments {
+		uri, err := mc.upload(ctx, a)
+		if err != nil {
+			return err
+		}
+		msgType := "m.file"
+		switch a.Type {
+		case channel.AttachmentImage:
+			msgType = "m.image"
+		case channel.AttachmentAudio:
+			msgType = "m.audio"
+		case channel.AttachmentVideo:
+			msgType = "m.video"
+		}
+		if err := mc.send(ctx, msg, map[string]any{
+			"msgtype":  msgType,
+			"body":     a.Filename,
+			"filename": a.Filename,
+			"url":      uri,
+			"info":     map[string]any{"mimetype": a.MimeType, "size": len(a.Data)},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send sends a room message, in the message's thread if it has one.
+func (mc *MatrixChannel) send(ctx context.Context, msg channel.OutboundMessage, content map[string]any) error {
+	if msg.ThreadID != "" {
+		content["m.relates_to"] = map[string]any{
+			"rel_type":        "m.thread",
+			"event_id":        msg.ThreadID,
+			"is_falling_back": true,
+			"m.in_reply_to":   map[string]string{"event_id": msg.ThreadID},
+		}
+	}
+	txn := mc.txn.Add(1)
+	path := fmt.Sprintf("/_matrix/client/v3/rooms/%s/send/m.room.message/%d", url.PathEscape(msg.ChatID), txn)
+	var resp struct {
+		EventID string `json:"event_id"`
+	}
+	if err := mc.doJSON(ctx, http.MethodPut, path, content, &resp); err != nil {
+		return err
+	}
+	mc.mu.Lock()
+	mc.sent = append(mc.sent, resp.EventID)
+	if len(mc.sent) > maxSentEvents {
+		mc.sent = mc.sent[len(mc.sent)-maxSentEvents:]
+	}
+	mc.mu.Unlock()
+	return nil
+}
+
+// sentByBot reports whether an event is one of the bot's recent messages.
+func (mc *MatrixChannel) sentByBot(eventID string) bool {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return slices.Contains(mc.sent, eventID)
+}
+
+// upload stores an attachment in the homeserver's media repository and
+// returns its mxc:// URI.
+func (mc *MatrixChannel) upload(ctx context.Context, a channel.Attachment) (string, error) {
+	contentType := a.MimeType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	var resp struct {
+		ContentURI string `json:"content_uri"`
+	}
+	path := "/_matrix/media/v3/upload?" + url.Values{"filename": {a.Filename}}.Encode()
+	if err := mc.do(ctx, http.MethodPost, path, bytes.NewReader(a.Data), contentType, &resp); err != nil {
+		return "", fmt.Errorf("uploading %s: %w", a.Filename, err)
+	}
+	return resp.ContentURI, nil
+}
+
+// setTyping starts or stops the bot's typing notification in a room.
+func (mc *MatrixChannel) setTyping(ctx context.Context, roomID string, typing bool) error {
+	body := map[string]any{"typing": typing}
+	if typing {
+		body["timeout"] = 30000
+	}
+	path := "/_matrix/client/v3/rooms/" + url.PathEscape(roomID) + "/typing/" + url.PathEscape(mc.userID)
+	return mc.doJSON(ctx, http.MethodPut, path, body, nil)
+}
+
+// setHealthy publishes the connection health when it changes.
+func (mc *MatrixChannel) setHealthy(ctx context.Context, connected bool, message string) {
+	if mc.healthy.Swap(connected) == connected && connected {
+		return
+	}
+	_ = mc.PublishHealth(ctx, channel.HealthStatus{Connected: connected, Message: message})
+}
+
+var htmlTextReplacer = strings.NewReplacer(
+	"<br>", "\n", "<pre>", "\n", "</pre>", "\n",
+	"&amp;", "&", "&lt;", "<", "&gt;", ">", "&#34;", `"`, "&#39;", "'", "&quot;", `"`,
+)
+
+// htmlToText turns formatted text into the plain body clients without HTML
+// support show.
+func htmlToText(s string) string {
+	var sb strings.Builder
+	for {
+		i := strings.IndexByte(s, '<')
+		if i < 0 {
+			sb.WriteString(s)
+			break
+		}
+		j := strings.IndexByte(s[i:], '>')
+		if j < 0 {
+			sb.WriteString(s)
+			break
+		}
+		sb.WriteString(s[:i])
+		switch tag := s[i : i+j+1]; tag {
+		case "<br>", "<pre>", "</pre>":
+			// Kept for the replacer below.
+			sb.WriteString(tag)
+		}
+		s = s[i+j+1:]
+	}
+	return strings.TrimSpace(htmlTextReplacer.Replace(sb.String()))
+}
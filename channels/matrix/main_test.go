@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	"github.com/alexsjones/sympozium/internal/channel"
+	"github.com/alexsjones/sympozium/internal/eventbus"
+)
+
+const botID = "@bot:example.org"
+
+// recordingBus records the inbound messages a channel publishes.
+type recordingBus struct {
+	mu      sync.Mutex
+	inbound []channel.InboundMessage
+}
+
+func (b *recordingBus) Publish(_ context.Context, topic string, event *eventbus.Event) error {
+	if topic != eventbus.TopicChannelMessageRecv {
+		return nil
+	}
+	var msg channel.InboundMessage
+	if err := json.Unmarshal(event.Data, &msg); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.inbound = append(b.inbound, msg)
+	return nil
+}
+
+func (b *recordingBus) Subscribe(context.Context, string) (<-chan *eventbus.Event, error) {
+	return make(chan *eventbus.Event), nil
+}
+
+func (b *recordingBus) Consume(context.Context, string, string, func(*eventbus.Event) error) error {
+	return nil
+}
+
+func (b *recordingBus) Close() error { return nil }
+
+// newTestChannel returns a channel whose homeserver records the paths it
+// is called with.
+func newTestChannel(t *testing.T) (*MatrixChannel, *recordingBus, func() []string) {
+	t.Helper()
+	var (
+		mu    sync.Mutex
+		paths []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"event_id":"$sent"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	bus := &recordingBus{}
+	mc := &MatrixChannel{
+		BaseChannel: channel.BaseChannel{ChannelType: "matrix", InstanceName: "bot", EventBus: bus},
+		Homeserver:  srv.URL,
+		AccessToken: "token",
+		log:         logr.Discard(),
+		client:      srv.Client(),
+		userID:      botID,
+		members:     map[string]int{},
+	}
+	return mc, bus, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(paths)
+	}
+}
+
+func TestHandleInvite(t *testing.T) {
+	invite := func(inviter string) []matrixEvent {
+		key := botID
+		return []matrixEvent{{
+			Type: "m.room.member", Sender: inviter, StateKey: &key,
+			Content: matrixContent{Membership: "invite"},
+		}}
+	}
+	tests := []struct {
+		name      string
+		autoJoin  bool
+		allowFrom []string
+		denyFrom  []string
+		inviter   string
+		want      []string
+	}{
+		{name: "auto-join off", inviter: "@alice:example.org"},
+		{name: "anyone", autoJoin: true, inviter: "@alice:example.org", want: []string{"POST /_matrix/client/v3/rooms/!room:example.org/join"}},
+		{name: "allowed", autoJoin: true, allowFrom: []string{"@alice:example.org"}, inviter: "@alice:example.org", want: []string{"POST /_matrix/client/v3/rooms/!room:example.org/join"}},
+		{name: "not allowed", autoJoin: true, allowFrom: []string{"@alice:example.org"}, inviter: "@mallory:example.org", want: []string{"POST /_matrix/client/v3/rooms/!room:example.org/leave"}},
+		{name: "denied", autoJoin: true, denyFrom: []string{"@mallory:example.org"}, inviter: "@mallory:example.org", want: []string{"POST /_matrix/client/v3/rooms/!room:example.org/leave"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mc, _, paths := newTestChannel(t)
+			mc.AutoJoin = tt.autoJoin
+			mc.allowFrom = tt.allowFrom
+			mc.denyFrom = tt.denyFrom
+
+			mc.handleInvite(context.Background(), "!room:example.org", invite(tt.inviter))
+			if got := paths(); !slices.Equal(got, tt.want) {
+				t.Errorf("requests = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleEvent(t *testing.T) {
+	type relatesTo = struct {
+		RelType   string `json:"rel_type"`
+		EventID   string `json:"event_id"`
+		InReplyTo *struct {
+			EventID string `json:"event_id"`
+		} `json:"m.in_reply_to"`
+	}
+	replyTo := func(id string) *struct {
+		EventID string `json:"event_id"`
+	} {
+		return &struct {
+			EventID string `json:"event_id"`
+		}{EventID: id}
+	}
+	text := func(body string, rel *relatesTo) matrixEvent {
+		return matrixEvent{
+			Type: "m.room.message", Sender: "@alice:example.org", EventID: "$ev",
+			Content: matrixContent{MsgType: "m.text", Body: body, RelatesTo: rel},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		event      matrixEvent
+		want       bool
+		thread     string
+		replyToBot bool
+	}{
+		{name: "text", event: text("hello", nil), want: true},
+		{name: "in thread", event: text("hello", &relatesTo{RelType: "m.thread", EventID: "$root"}), want: true, thread: "$root"},
+		{
+			// Thread messages carry a fallback reply to the thread root,
+			// which is not a reply to the bot.
+			name:   "thread fallback reply",
+			event:  text("hello", &relatesTo{RelType: "m.thread", EventID: "$sent", InReplyTo: replyTo("$sent")}),
+			want:   true,
+			thread: "$sent",
+		},
+		{name: "reply to bot", event: text("hello", &relatesTo{InReplyTo: replyTo("$sent")}), want: true, replyToBot: true},
+		{name: "reply to someone else", event: text("hello", &relatesTo{InReplyTo: replyTo("$other")}), want: true},
+		{name: "edit", event: text("* hello", &relatesTo{RelType: "m.replace", EventID: "$ev0"})},
+		{
+			name: "notice",
+			event: matrixEvent{Type: "m.room.message", Sender: "@otherbot:example.org", EventID: "$ev",
+				Content: matrixContent{MsgType: "m.notice", Body: "beep"}},
+		},
+		{
+			name: "own message",
+			event: matrixEvent{Type: "m.room.message", Sender: botID, EventID: "$ev",
+				Content: matrixContent{MsgType: "m.text", Body: "hi"}},
+		},
+		{name: "encrypted", event: matrixEvent{Type: "m.room.encrypted", Sender: "@alice:example.org"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mc, bus, _ := newTestChannel(t)
+			mc.sent = []string{"$sent"}
+
+			mc.handleEvent(context.Background(), "!room:example.org", tt.event)
+			if !tt.want {
+				if len(bus.inbound) != 0 {
+					t.Fatalf("published %+v, want nothing", bus.inbound)
+				}
+				return
+			}
+			if len(bus.inbound) != 1 {
+				t.Fatalf("published %d messages, want 1", len(bus.inbound))
+			}
+			msg := bus.inbound[0]
+			if msg.ThreadID != tt.thread || msg.ReplyToBot != tt.replyToBot {
+				t.Errorf("thread = %q, replyToBot = %v, want %q, %v", msg.ThreadID, msg.ReplyToBot, tt.thread, tt.replyToBot)
+			}
+			if msg.ChatID != "!room:example.org" || msg.SenderID != "@alice:example.org" || msg.Text != "hello" {
+				t.Errorf("message = %+v", msg)
+			}
+		})
+	}
+}
+
+func TestStripReplyFallback(t *testing.T) {
+	tests := []struct {
+		body, want string
+	}{
+		{"hello", "hello"},
+		{"> <@alice:example.org> what's up?\n> second line\n\nnot much", "not much"},
+		{"> quoted without sender\nreply", "> quoted without sender\nreply"},
+		{"> <@alice:example.org> only a quote", ""},
+	}
+	for _, tt := range tests {
+		if got := stripReplyFallback(tt.body); got != tt.want {
+			t.Errorf("stripReplyFallback(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
@@ -12,14 +12,17 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -42,6 +45,32 @@ type SlackChannel struct {
 	client   *http.Client
 	healthy  bool
 	mu       sync.RWMutex
+
+	// botUserID is the bot's own user, used to detect mentions and replies
+	// to it.
+	botUserID string
+}
+
+// slackMessageEvent is a message event from Socket Mode or the Events API.
+type slackMessageEvent struct {
+	Type         string      `json:"type"`
+	User         string      `json:"user"`
+	Text         string      `json:"text"`
+	Channel      string      `json:"channel"`
+	ChannelType  string      `json:"channel_type"`
+	TS           string      `json:"ts"`
+	ThreadTS     string      `json:"thread_ts"`
+	ParentUserID string      `json:"parent_user_id"`
+	BotID        string      `json:"bot_id"`
+	Files        []slackFile `json:"files"`
+}
+
+// slackFile is a file shared in a message.
+type slackFile struct {
+	Name        string `json:"name"`
+	MimeType    string `json:"mimetype"`
+	Size        int    `json:"size"`
+	URLDownload string `json:"url_private_download"`
 }
 
 func main() {
@@ -104,6 +133,22 @@ func main() {
 	}()
 
 	go ch.handleOutbound(ctx)
+	// Bots cannot show typing in Slack, so a reaction on the message marks
+	// it as being worked on.
+	go func() {
+		_ = ch.RunPresence(ctx, &channel.Presence{
+			Show: func(ctx context.Context, ev channel.TypingEvent) error {
+				return ch.react(ctx, "reactions.add", ev)
+			},
+			Clear: func(ctx context.Context, ev channel.TypingEvent) error {
+				return ch.react(ctx, "reactions.remove", ev)
+			},
+		})
+	}()
+
+	if err := ch.authTest(ctx); err != nil {
+		log.Error(err, "failed to look up bot user, mentions will not be detected")
+	}
 
 	if appToken != "" {
 		log.Info("Starting Slack channel in Socket Mode", "instance", instanceName)
@@ -117,6 +162,71 @@ func main() {
 	}
 }
 
+// authTest looks up the bot's own user ID.
+func (sc *SlackChannel) authTest(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://slack.com/api/auth.test", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+sc.BotToken)
+
+	resp, err := sc.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("auth.test: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		OK     bool   `json:"ok"`
+		UserID string `json:"user_id"`
+		Err    string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("decoding auth.test response: %w", err)
+	}
+	if !body.OK {
+		return fmt.Errorf("auth.test: %s", body.Err)
+	}
+	sc.botUserID = body.UserID
+	return nil
+}
+
+// inboundMessage converts a Slack message event, downloading the files
+// shared with it.
+func (sc *SlackChannel) inboundMessage(ctx context.Context, ev slackMessageEvent) channel.InboundMessage {
+	msg := channel.InboundMessage{
+		SenderID: ev.User,
+		ChatID:   ev.Channel,
+		ThreadID: ev.ThreadTS,
+		Text:     ev.Text,
+		Metadata: map[string]string{
+			"ts":        ev.TS,
+			"messageId": ev.TS,
+		},
+		IsDirect:   ev.ChannelType == "im",
+		Mentioned:  sc.botUserID != "" && strings.Contains(ev.Text, "<@"+sc.botUserID+">"),
+		ReplyToBot: sc.botUserID != "" && ev.ParentUserID == sc.botUserID,
+	}
+	for _, f := range ev.Files {
+		a := channel.Attachment{Filename: f.Name, MimeType: f.MimeType}
+		if f.URLDownload == "" {
+			msg.SkipAttachment(a, "it cannot be downloaded")
+			continue
+		}
+		if f.Size > msg.MediaRoom() {
+			msg.SkipAttachment(a, "it is too large")
+			continue
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.URLDownload, nil)
+		if err != nil {
+			continue
+		}
+		req.Header.Set("Authorization", "Bearer "+sc.BotToken)
+		msg.Download(sc.client, req, a)
+	}
+	return msg
+}
+
 // ---------------------------------------------------------------------------
 // Socket Mode — outbound WebSocket, no public URL needed
 // ---------------------------------------------------------------------------
@@ -237,22 +347,14 @@ func (sc *SlackChannel) readSocketMode(ctx context.Context, conn *websocket.Conn
 // The payload wraps an Events API envelope with type "event_callback".
 func (sc *SlackChannel) handleSocketEvent(ctx context.Context, payload json.RawMessage) {
 	var inner struct {
-		Type  string `json:"type"`
-		Event struct {
-			Type     string `json:"type"`
-			User     string `json:"user"`
-			Text     string `json:"text"`
-			Channel  string `json:"channel"`
-			TS       string `json:"ts"`
-			ThreadTS string `json:"thread_ts"`
-			BotID    string `json:"bot_id"`
-		} `json:"event"`
+		Type  string            `json:"type"`
+		Event slackMessageEvent `json:"event"`
 	}
 	if err := json.Unmarshal(payload, &inner); err != nil {
 		return
 	}
 
-	if inner.Event.Type != "message" || inner.Event.User == "" || inner.Event.Text == "" {
+	if inner.Event.Type != "message" || inner.Event.User == "" || (inner.Event.Text == "" && len(inner.Event.Files) == 0) {
 		return
 	}
 	// Ignore bot messages to avoid loops.
@@ -260,17 +362,7 @@ func (sc *SlackChannel) handleSocketEvent(ctx context.Context, payload json.RawM
 		return
 	}
 
-	msg := channel.InboundMessage{
-		SenderID: inner.Event.User,
-		ChatID:   inner.Event.Channel,
-		ThreadID: inner.Event.ThreadTS,
-		Text:     inner.Event.Text,
-		Metadata: map[string]string{
-			"ts": inner.Event.TS,
-		},
-	}
-
-	if err := sc.PublishInbound(ctx, msg); err != nil {
+	if err := sc.PublishInbound(ctx, sc.inboundMessage(ctx, inner.Event)); err != nil {
 		sc.log.Error(err, "failed to publish inbound from Socket Mode")
 	}
 }
@@ -324,17 +416,9 @@ func (sc *SlackChannel) handleSlackEvents(w http.ResponseWriter, r *http.Request
 	defer r.Body.Close()
 
 	var envelope struct {
-		Type      string `json:"type"`
-		Challenge string `json:"challenge"`
-		Event     struct {
-			Type     string `json:"type"`
-			User     string `json:"user"`
-			Text     string `json:"text"`
-			Channel  string `json:"channel"`
-			TS       string `json:"ts"`
-			ThreadTS string `json:"thread_ts"`
-			BotID    string `json:"bot_id"`
-		} `json:"event"`
+		Type      string            `json:"type"`
+		Challenge string            `json:"challenge"`
+		Event     slackMessageEvent `json:"event"`
 	}
 
 	if err := json.Unmarshal(body, &envelope); err != nil {
@@ -351,7 +435,7 @@ func (sc *SlackChannel) handleSlackEvents(w http.ResponseWriter, r *http.Request
 
 	// Process message events
 	if envelope.Type == "event_callback" && envelope.Event.Type == "message" {
-		if envelope.Event.User == "" || envelope.Event.Text == "" {
+		if envelope.Event.User == "" || (envelope.Event.Text == "" && len(envelope.Event.Files) == 0) {
 			w.WriteHeader(http.StatusOK)
 			return
 		}
@@ -361,17 +445,7 @@ func (sc *SlackChannel) handleSlackEvents(w http.ResponseWriter, r *http.Request
 			return
 		}
 
-		msg := channel.InboundMessage{
-			SenderID: envelope.Event.User,
-			ChatID:   envelope.Event.Channel,
-			ThreadID: envelope.Event.ThreadTS,
-			Text:     envelope.Event.Text,
-			Metadata: map[string]string{
-				"ts": envelope.Event.TS,
-			},
-		}
-
-		if err := sc.PublishInbound(r.Context(), msg); err != nil {
+		if err := sc.PublishInbound(r.Context(), sc.inboundMessage(r.Context(), envelope.Event)); err != nil {
 			fmt.Fprintf(os.Stderr, "failed to publish inbound: %v\n", err)
 		}
 	}
@@ -407,20 +481,131 @@ func (sc *SlackChannel) handleOutbound(ctx context.Context) {
 	}
 }
 
-// sendMessage sends a message via the Slack chat.postMessage API.
+// sendMessage sends a message via the Slack chat.postMessage API, split
+// into several if it is too long.
 func (sc *SlackChannel) sendMessage(ctx context.Context, msg channel.OutboundMessage) error {
-	payload := map[string]interface{}{
-		"channel": msg.ChatID,
-		"text":    msg.Text,
+	for _, text := range channel.Render(msg, channel.DialectSlack) {
+		payload := map[string]interface{}{
+			"channel": msg.ChatID,
+			"text":    text,
+		}
+		if msg.ThreadID != "" {
+			payload["thread_ts"] = msg.ThreadID
+		}
+
+		body, _ := json.Marshal(payload)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+			"https://slack.com/api/chat.postMessage",
+			strings.NewReader(string(body)))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+sc.BotToken)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := sc.client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+	}
+	for _, a := range msg.Attachments {
+		if err := sc.uploadFile(ctx, msg, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uploadFile shares an attachment in the chat using Slack's external
+// upload flow: get an upload URL, post the bytes, then complete the upload.
+func (sc *SlackChannel) uploadFile(ctx context.Context, msg channel.OutboundMessage, a channel.Attachment) error {
+	form := url.Values{
+		"filename": {a.Filename},
+		"length":   {strconv.Itoa(len(a.Data))},
+	}
+	var upload struct {
+		OK        bool   `json:"ok"`
+		Error     string `json:"error"`
+		UploadURL string `json:"upload_url"`
+		FileID    string `json:"file_id"`
+	}
+	if err := sc.callAPI(ctx, "files.getUploadURLExternal", "application/x-www-form-urlencoded",
+		strings.NewReader(form.Encode()), &upload); err != nil {
+		return err
+	}
+	if !upload.OK {
+		return fmt.Errorf("files.getUploadURLExternal: %s", upload.Error)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, upload.UploadURL, bytes.NewReader(a.Data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := sc.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("uploading %s: HTTP %d", a.Filename, resp.StatusCode)
+	}
+
+	complete := map[string]interface{}{
+		"files":      []map[string]string{{"id": upload.FileID, "title": a.Filename}},
+		"channel_id": msg.ChatID,
 	}
 	if msg.ThreadID != "" {
-		payload["thread_ts"] = msg.ThreadID
+		complete["thread_ts"] = msg.ThreadID
+	}
+	body, _ := json.Marshal(complete)
+	var done struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := sc.callAPI(ctx, "files.completeUploadExternal", "application/json", bytes.NewReader(body), &done); err != nil {
+		return err
+	}
+	if !done.OK {
+		return fmt.Errorf("files.completeUploadExternal: %s", done.Error)
+	}
+	return nil
+}
+
+// callAPI posts body to a Slack Web API method and decodes the response
+// into out.
+func (sc *SlackChannel) callAPI(ctx context.Context, method, contentType string, body io.Reader, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://slack.com/api/"+method, body)
+	if err != nil {
+		return err
 	}
+	req.Header.Set("Authorization", "Bearer "+sc.BotToken)
+	req.Header.Set("Content-Type", contentType)
+	resp, err := sc.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(out)
+}
 
-	body, _ := json.Marshal(payload)
+// workingReaction marks a message whose run is still working.
+const workingReaction = "hourglass_flowing_sand"
+
+// react adds or removes the working reaction on the message that started a
+// run, using the given reactions API method.
+func (sc *SlackChannel) react(ctx context.Context, method string, ev channel.TypingEvent) error {
+	if ev.MessageID == "" {
+		return nil
+	}
+	body, _ := json.Marshal(map[string]string{
+		"channel":   ev.ChatID,
+		"timestamp": ev.MessageID,
+		"name":      workingReaction,
+	})
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
-		"https://slack.com/api/chat.postMessage",
-		strings.NewReader(string(body)))
+		"https://slack.com/api/"+method, strings.NewReader(string(body)))
 	if err != nil {
 		return err
 	}
@@ -2,10 +2,12 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"os/signal"
@@ -25,6 +27,10 @@ type TelegramChannel struct {
 	BotToken string
 	client   *http.Client
 	healthy  bool
+
+	// The bot's own user, used to detect mentions and replies to it.
+	botID       int64
+	botUsername string
 }
 
 func main() {
@@ -78,13 +84,124 @@ func main() {
 	}()
 
 	go ch.handleOutbound(ctx)
+	// Telegram shows a chat action for about five seconds.
+	go func() {
+		_ = ch.RunPresence(ctx, &channel.Presence{Refresh: 4 * time.Second, Show: ch.sendChatAction})
+	}()
 
-	log.Info("Starting Telegram channel", "instance", instanceName)
+	if err := ch.getMe(ctx); err != nil {
+		log.Error(err, "failed to look up bot user, mentions will not be detected")
+	}
+
+	log.Info("Starting Telegram channel", "instance", instanceName, "bot", ch.botUsername)
 	if err := ch.pollUpdates(ctx); err != nil {
 		log.Error(err, "telegram polling failed")
 	}
 }
 
+// getMe looks up the bot's own user.
+func (tc *TelegramChannel) getMe(ctx context.Context) error {
+	url := fmt.Sprintf("https://api.telegram.org/bot%s/getMe", tc.BotToken)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := tc.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK     bool `json:"ok"`
+		Result struct {
+			ID       int64  `json:"id"`
+			Username string `json:"username"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	if !result.OK {
+		return fmt.Errorf("getMe failed: %s", resp.Status)
+	}
+	tc.botID = result.Result.ID
+	tc.botUsername = result.Result.Username
+	return nil
+}
+
+// telegramFile is a file sent in a message, such as a voice note or a
+// document.
+type telegramFile struct {
+	FileID   string `json:"file_id"`
+	FileName string `json:"file_name"`
+	MimeType string `json:"mime_type"`
+	FileSize int    `json:"file_size"`
+}
+
+// telegramPhotoSize is one of the sizes Telegram keeps of a photo, smallest
+// first.
+type telegramPhotoSize struct {
+	FileID   string `json:"file_id"`
+	FileSize int    `json:"file_size"`
+}
+
+// attach downloads a file of a message and attaches it to msg.
+func (tc *TelegramChannel) attach(ctx context.Context, msg *channel.InboundMessage, f telegramFile, a channel.Attachment) {
+	if f.FileSize > msg.MediaRoom() {
+		msg.SkipAttachment(a, "it is too large")
+		return
+	}
+	url := fmt.Sprintf("https://api.telegram.org/bot%s/getFile?file_id=%s", tc.BotToken, f.FileID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return
+	}
+	resp, err := tc.client.Do(req)
+	if err != nil {
+		msg.SkipAttachment(a, "the download failed")
+		return
+	}
+	defer resp.Body.Close()
+
+	var file struct {
+		OK     bool `json:"ok"`
+		Result struct {
+			FilePath string `json:"file_path"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&file); err != nil || !file.OK {
+		msg.SkipAttachment(a, "the download failed")
+		return
+	}
+	url = fmt.Sprintf("https://api.telegram.org/file/bot%s/%s", tc.BotToken, file.Result.FilePath)
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return
+	}
+	msg.Download(tc.client, req, a)
+}
+
+// telegramEntity is a formatting entity of a message.
+type telegramEntity struct {
+	Type string `json:"type"`
+	User *struct {
+		ID int64 `json:"id"`
+	} `json:"user"`
+}
+
+// mentionsBot reports whether a message mentions the bot, either by
+// @username or with a text mention of its user.
+func (tc *TelegramChannel) mentionsBot(text string, entities []telegramEntity) bool {
+	for _, e := range entities {
+		if e.Type == "text_mention" && e.User != nil && e.User.ID == tc.botID {
+			return true
+		}
+	}
+	return tc.botUsername != "" &&
+		strings.Contains(strings.ToLower(text), "@"+strings.ToLower(tc.botUsername))
+}
+
 // pollUpdates uses Telegram's long-polling getUpdates API.
 func (tc *TelegramChannel) pollUpdates(ctx context.Context) error {
 	offset := 0
@@ -127,7 +244,20 @@ func (tc *TelegramChannel) pollUpdates(ctx context.Context) error {
 						ID   int64  `json:"id"`
 						Type string `json:"type"`
 					} `json:"chat"`
-					Text string `json:"text"`
+					Text            string              `json:"text"`
+					Entities        []telegramEntity    `json:"entities"`
+					Caption         string              `json:"caption"`
+					CaptionEntities []telegramEntity    `json:"caption_entities"`
+					Photo           []telegramPhotoSize `json:"photo"`
+					Voice           *telegramFile       `json:"voice"`
+					Audio           *telegramFile       `json:"audio"`
+					Video           *telegramFile       `json:"video"`
+					Document        *telegramFile       `json:"document"`
+					ReplyToMessage  *struct {
+						From struct {
+							ID int64 `json:"id"`
+						} `json:"from"`
+					} `json:"reply_to_message"`
 				} `json:"message"`
 			} `json:"result"`
 		}
@@ -142,7 +272,17 @@ func (tc *TelegramChannel) pollUpdates(ctx context.Context) error {
 
 		for _, update := range result.Result {
 			offset = update.UpdateID + 1
-			if update.Message == nil || update.Message.Text == "" {
+			if update.Message == nil {
+				continue
+			}
+			// Media messages carry their text as a caption.
+			text, entities := update.Message.Text, update.Message.Entities
+			if text == "" {
+				text, entities = update.Message.Caption, update.Message.CaptionEntities
+			}
+			hasMedia := len(update.Message.Photo) > 0 || update.Message.Voice != nil || update.Message.Audio != nil ||
+				update.Message.Video != nil || update.Message.Document != nil
+			if text == "" && !hasMedia {
 				continue
 			}
 
@@ -150,12 +290,36 @@ func (tc *TelegramChannel) pollUpdates(ctx context.Context) error {
 				SenderID:   fmt.Sprintf("%d", update.Message.From.ID),
 				SenderName: update.Message.From.Name,
 				ChatID:     fmt.Sprintf("%d", update.Message.Chat.ID),
-				Text:       update.Message.Text,
+				Text:       text,
 				Metadata: map[string]string{
 					"messageId": fmt.Sprintf("%d", update.Message.MessageID),
 					"username":  update.Message.From.Username,
 					"chatType":  update.Message.Chat.Type,
 				},
+				IsDirect:   update.Message.Chat.Type == "private",
+				Mentioned:  tc.mentionsBot(text, entities),
+				ReplyToBot: update.Message.ReplyToMessage != nil && tc.botID != 0 && update.Message.ReplyToMessage.From.ID == tc.botID,
+			}
+
+			// Take the largest size of a photo that fits.
+			if photos := update.Message.Photo; len(photos) > 0 {
+				best := photos[0]
+				for _, p := range photos[1:] {
+					if p.FileSize <= msg.MediaRoom() {
+						best = p
+					}
+				}
+				tc.attach(ctx, &msg, telegramFile{FileID: best.FileID, FileSize: best.FileSize},
+					channel.Attachment{Filename: "photo.jpg", MimeType: "image/jpeg"})
+			}
+			if v := update.Message.Voice; v != nil {
+				// Voice notes are Ogg Opus, which Telegram names .oga.
+				tc.attach(ctx, &msg, *v, channel.Attachment{Filename: "voice.ogg", MimeType: "audio/ogg"})
+			}
+			for _, f := range []*telegramFile{update.Message.Audio, update.Message.Video, update.Message.Document} {
+				if f != nil {
+					tc.attach(ctx, &msg, *f, channel.Attachment{Filename: f.FileName, MimeType: f.MimeType})
+				}
 			}
 
 			if err := tc.PublishInbound(ctx, msg); err != nil {
@@ -184,29 +348,113 @@ func (tc *TelegramChannel) handleOutbound(ctx context.Context) {
 			if msg.Channel != "telegram" {
 				continue
 			}
-			_ = tc.sendMessage(ctx, msg)
+			if err := tc.sendMessage(ctx, msg); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to send telegram message: %v\n", err)
+			}
 		}
 	}
 }
 
-// sendMessage sends a message via the Telegram Bot API.
-func (tc *TelegramChannel) sendMessage(ctx context.Context, msg channel.OutboundMessage) error {
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", tc.BotToken)
+// sendChatAction shows "typing…" in the chat of a working run.
+func (tc *TelegramChannel) sendChatAction(ctx context.Context, ev channel.TypingEvent) error {
+	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendChatAction", tc.BotToken)
+	body, _ := json.Marshal(map[string]string{"chat_id": ev.ChatID, "action": "typing"})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(string(body)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := tc.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return nil
+}
 
-	parseMode := "Markdown"
-	if msg.Format == "html" {
+// sendMessage sends a message via the Telegram Bot API, split into several
+// if it is too long.
+func (tc *TelegramChannel) sendMessage(ctx context.Context, msg channel.OutboundMessage) error {
+	parseMode := "MarkdownV2"
+	switch msg.Format {
+	case channel.FormatPlain:
+		parseMode = ""
+	case channel.FormatHTML:
 		parseMode = "HTML"
 	}
 
-	payload := map[string]interface{}{
-		"chat_id":    msg.ChatID,
-		"text":       msg.Text,
-		"parse_mode": parseMode,
+	for i, text := range channel.Render(msg, channel.DialectTelegram) {
+		payload := map[string]interface{}{
+			"chat_id": msg.ChatID,
+			"text":    text,
+		}
+		if parseMode != "" {
+			payload["parse_mode"] = parseMode
+		}
+		if msg.ReplyTo != "" && i == 0 {
+			payload["reply_to_message_id"] = msg.ReplyTo
+		}
+		err := tc.postMessage(ctx, payload)
+		if err != nil && parseMode != "" {
+			// Send text Telegram cannot parse as it is rather than not at
+			// all.
+			delete(payload, "parse_mode")
+			err = tc.postMessage(ctx, payload)
+		}
+		if err != nil {
+			return err
+		}
 	}
-	if msg.ReplyTo != "" {
-		payload["reply_to_message_id"] = msg.ReplyTo
+	for _, a := range msg.Attachments {
+		if err := tc.sendFile(ctx, msg.ChatID, a); err != nil {
+			return err
+		}
 	}
+	return nil
+}
+
+// sendFile uploads an attachment, as a photo if it is an image.
+func (tc *TelegramChannel) sendFile(ctx context.Context, chatID string, a channel.Attachment) error {
+	method, field := "sendDocument", "document"
+	if a.Type == channel.AttachmentImage {
+		method, field = "sendPhoto", "photo"
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	_ = mw.WriteField("chat_id", chatID)
+	fw, err := mw.CreateFormFile(field, a.Filename)
+	if err != nil {
+		return err
+	}
+	_, _ = fw.Write(a.Data)
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("https://api.telegram.org/bot%s/%s", tc.BotToken, method)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
 
+	resp, err := tc.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: HTTP %d", method, resp.StatusCode)
+	}
+	return nil
+}
+
+// postMessage calls sendMessage with payload.
+func (tc *TelegramChannel) postMessage(ctx context.Context, payload map[string]interface{}) error {
+	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", tc.BotToken)
 	body, _ := json.Marshal(payload)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url,
 		strings.NewReader(string(body)))
@@ -221,5 +469,15 @@ func (tc *TelegramChannel) sendMessage(ctx context.Context, msg channel.Outbound
 	}
 	defer resp.Body.Close()
 
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("decoding sendMessage response: %w", err)
+	}
+	if !result.OK {
+		return fmt.Errorf("sendMessage: %s", result.Description)
+	}
 	return nil
 }
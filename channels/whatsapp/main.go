@@ -139,6 +139,18 @@ func main() {
 	_ = wc.PublishHealth(ctx, channel.HealthStatus{Connected: true})
 
 	go wc.handleOutbound(ctx)
+	// WhatsApp clears "typing…" after about 25 seconds.
+	go func() {
+		_ = wc.RunPresence(ctx, &channel.Presence{
+			Refresh: 10 * time.Second,
+			Show: func(ctx context.Context, ev channel.TypingEvent) error {
+				return wc.client.SendChatPresence(ctx, resolveJID(ev.ChatID), types.ChatPresenceComposing, types.ChatPresenceMediaText)
+			},
+			Clear: func(ctx context.Context, ev channel.TypingEvent) error {
+				return wc.client.SendChatPresence(ctx, resolveJID(ev.ChatID), types.ChatPresencePaused, types.ChatPresenceMediaText)
+			},
+		})
+	}()
 
 	log.Info("WhatsApp channel running", "instance", instanceName, "addr", listenAddr)
 
@@ -207,8 +219,9 @@ func (wc *WhatsAppChannel) handleInboundMessage(evt *events.Message) {
 	}
 
 	text := extractText(evt.Message)
-	if text == "" {
-		return // skip non-text messages for now
+	media, mediaName := mediaOf(evt.Message)
+	if text == "" && media == nil {
+		return
 	}
 
 	senderName := evt.Info.PushName
@@ -227,6 +240,11 @@ func (wc *WhatsAppChannel) handleInboundMessage(evt *events.Message) {
 			"timestamp": fmt.Sprintf("%d", evt.Info.Timestamp.Unix()),
 			"isGroup":   fmt.Sprintf("%t", evt.Info.IsGroup),
 		},
+		IsDirect:  !evt.Info.IsGroup,
+		Mentioned: wc.mentionsSelf(evt.Message),
+	}
+	if media != nil {
+		wc.attach(&msg, media, mediaName)
 	}
 
 	if err := wc.PublishInbound(context.Background(), msg); err != nil {
@@ -261,7 +279,8 @@ func (wc *WhatsAppChannel) handleOutbound(ctx context.Context) {
 	}
 }
 
-// sendMessage sends a text message via WhatsApp.
+// sendMessage sends a text message via WhatsApp, split into several if it
+// is too long.
 // If ChatID is empty, the message is sent to the device owner (self-chat).
 func (wc *WhatsAppChannel) sendMessage(ctx context.Context, msg channel.OutboundMessage) error {
 	var jid types.JID
@@ -279,13 +298,112 @@ func (wc *WhatsAppChannel) sendMessage(ctx context.Context, msg channel.Outbound
 		jid = resolveJID(msg.ChatID)
 	}
 
-	text := fmt.Sprintf("[%s] %s", wc.InstanceName, msg.Text)
-	_, err := wc.client.SendMessage(ctx, jid, &waE2E.Message{
-		Conversation: proto.String(text),
-	})
+	for i, text := range channel.Render(msg, channel.DialectWhatsApp) {
+		if i == 0 {
+			text = fmt.Sprintf("[%s] %s", wc.InstanceName, text)
+		}
+		if _, err := wc.client.SendMessage(ctx, jid, &waE2E.Message{
+			Conversation: proto.String(text),
+		}); err != nil {
+			return err
+		}
+	}
+	for _, a := range msg.Attachments {
+		if err := wc.sendFile(ctx, jid, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendFile uploads an attachment and sends it as an image or a document.
+func (wc *WhatsAppChannel) sendFile(ctx context.Context, jid types.JID, a channel.Attachment) error {
+	mediaType := whatsmeow.MediaDocument
+	if a.Type == channel.AttachmentImage {
+		mediaType = whatsmeow.MediaImage
+	}
+	up, err := wc.client.Upload(ctx, a.Data, mediaType)
+	if err != nil {
+		return fmt.Errorf("uploading %s: %w", a.Filename, err)
+	}
+	var m waE2E.Message
+	if mediaType == whatsmeow.MediaImage {
+		m.ImageMessage = &waE2E.ImageMessage{
+			Mimetype:      proto.String(a.MimeType),
+			URL:           proto.String(up.URL),
+			DirectPath:    proto.String(up.DirectPath),
+			MediaKey:      up.MediaKey,
+			FileEncSHA256: up.FileEncSHA256,
+			FileSHA256:    up.FileSHA256,
+			FileLength:    proto.Uint64(up.FileLength),
+		}
+	} else {
+		m.DocumentMessage = &waE2E.DocumentMessage{
+			Title:         proto.String(a.Filename),
+			FileName:      proto.String(a.Filename),
+			Mimetype:      proto.String(a.MimeType),
+			URL:           proto.String(up.URL),
+			DirectPath:    proto.String(up.DirectPath),
+			MediaKey:      up.MediaKey,
+			FileEncSHA256: up.FileEncSHA256,
+			FileSHA256:    up.FileSHA256,
+			FileLength:    proto.Uint64(up.FileLength),
+		}
+	}
+	_, err = wc.client.SendMessage(ctx, jid, &m)
 	return err
 }
 
+// mediaMessage is a WhatsApp media message that can be downloaded.
+type mediaMessage interface {
+	whatsmeow.DownloadableMessage
+	GetMimetype() string
+	GetFileLength() uint64
+}
+
+// mediaOf returns the media in a message and the file name to save it as,
+// or nil if it has none.
+func mediaOf(msg *waE2E.Message) (mediaMessage, string) {
+	switch {
+	case msg.GetImageMessage() != nil:
+		return msg.GetImageMessage(), "photo.jpg"
+	case msg.GetAudioMessage() != nil:
+		if msg.GetAudioMessage().GetPTT() {
+			return msg.GetAudioMessage(), "voice.ogg"
+		}
+		return msg.GetAudioMessage(), "audio.ogg"
+	case msg.GetVideoMessage() != nil:
+		return msg.GetVideoMessage(), "video.mp4"
+	case msg.GetDocumentMessage() != nil:
+		name := msg.GetDocumentMessage().GetFileName()
+		if name == "" {
+			name = "document"
+		}
+		return msg.GetDocumentMessage(), name
+	}
+	return nil, ""
+}
+
+// attach downloads the media of a message and adds it to msg.
+func (wc *WhatsAppChannel) attach(msg *channel.InboundMessage, m mediaMessage, name string) {
+	mimeType := m.GetMimetype()
+	if i := strings.Index(mimeType, ";"); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+	a := channel.Attachment{Filename: name, MimeType: mimeType}
+	if m.GetFileLength() > uint64(msg.MediaRoom()) {
+		msg.SkipAttachment(a, "it is too large")
+		return
+	}
+	data, err := wc.client.Download(context.Background(), m)
+	if err != nil {
+		msg.SkipAttachment(a, "the download failed")
+		return
+	}
+	a.Data = data
+	msg.Attach(a)
+}
+
 // resolveJID converts a chat ID string to a WhatsApp JID.
 // If the ID already contains an @, assume it's a full JID.
 // Otherwise treat it as a phone number (user JID).
@@ -298,6 +416,24 @@ func resolveJID(chatID string) types.JID {
 }
 
 // extractText pulls the text content from a WhatsApp message proto.
+// mentionsSelf reports whether a message @-mentions the linked account.
+func (wc *WhatsAppChannel) mentionsSelf(msg *waE2E.Message) bool {
+	if msg.GetExtendedTextMessage() == nil {
+		return false
+	}
+	for _, mentioned := range msg.GetExtendedTextMessage().GetContextInfo().GetMentionedJID() {
+		jid, err := types.ParseJID(mentioned)
+		if err != nil {
+			continue
+		}
+		if (wc.client.Store.ID != nil && jid.User == wc.client.Store.ID.User) ||
+			(!wc.client.Store.LID.IsEmpty() && jid.User == wc.client.Store.LID.User) {
+			return true
+		}
+	}
+	return false
+}
+
 func extractText(msg *waE2E.Message) string {
 	if msg == nil {
 		return ""
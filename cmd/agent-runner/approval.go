@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// approvalsDir is where approval requests and decisions are exchanged with
+// the IPC bridge.
+var approvalsDir = "/ipc/approvals"
+
+// approvalPollInterval is how often a pending approval checks for a decision.
+var approvalPollInterval = 500 * time.Millisecond
+
+// defaultApprovalTimeout applies when the tool gating sets no timeout.
+const defaultApprovalTimeout = 5 * time.Minute
+
+// Approval decisions, matching sympoziumv1alpha1.ToolApproval.Decision.
+const (
+	approvalApproved = "approved"
+	approvalDenied   = "denied"
+	approvalTimeout  = "timeout"
+)
+
+// approvalRequest mirrors ipc.ApprovalRequest.
+type approvalRequest struct {
+	ID             string `json:"id"`
+	ToolName       string `json:"toolName"`
+	Arguments      string `json:"arguments,omitempty"`
+	TimeoutSeconds int    `json:"timeoutSeconds"`
+}
+
+// approvalResponse mirrors ipc.ApprovalResponse.
+type approvalResponse struct {
+	ID       string `json:"id"`
+	Decision string `json:"decision"`
+	Approver string `json:"approver,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// approvalTimeout returns how long an "ask" call waits for a decision.
+func (p *toolPolicy) approvalTimeout() time.Duration {
+	if p != nil && p.Gating != nil && p.Gating.ApprovalTimeout != "" {
+		if d, err := time.ParseDuration(p.Gating.ApprovalTimeout); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultApprovalTimeout
+}
+
+// requestApproval asks a human to approve a tool call and blocks until a
+// decision arrives or the timeout passes. A timeout is reported back through
+// the bridge so the run's status records it.
+func requestApproval(ctx context.Context, name, argsJSON string, timeout time.Duration) approvalResponse {
+	id := newRequestID()
+	req := approvalRequest{
+		ID:             id,
+		ToolName:       name,
+		Arguments:      truncate(argsJSON, 1000),
+		TimeoutSeconds: int(timeout.Seconds()),
+	}
+	if err := os.MkdirAll(approvalsDir, 0o755); err == nil {
+		err = writeJSONAtomic(filepath.Join(approvalsDir, "request-"+id+".json"), req)
+		if err != nil {
+			log.Printf("WARNING: failed to write approval request: %v", err)
+		}
+	}
+	log.Printf("waiting up to %s for approval of %s (request %s)", timeout, name, id)
+
+	responsePath := filepath.Join(approvalsDir, "response-"+id+".json")
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(approvalPollInterval)
+	defer ticker.Stop()
+	for {
+		if b, err := os.ReadFile(responsePath); err == nil {
+			var resp approvalResponse
+			if err := json.Unmarshal(b, &resp); err == nil && resp.Decision != "" {
+				log.Printf("approval %s for %s: %s by %s", id, name, resp.Decision, resp.Approver)
+				return resp
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return approvalResponse{ID: id, Decision: approvalDenied, Approver: "system", Reason: "run cancelled"}
+		case <-deadline.C:
+			resp := approvalResponse{ID: id, Decision: approvalTimeout, Approver: "system", Reason: fmt.Sprintf("no decision within %s", timeout)}
+			if err := writeJSONAtomic(filepath.Join(approvalsDir, "timeout-"+id+".json"), resp); err != nil {
+				log.Printf("WARNING: failed to write approval timeout: %v", err)
+			}
+			log.Printf("approval %s for %s timed out", id, name)
+			return resp
+		case <-ticker.C:
+		}
+	}
+}
+
+// newRequestID returns a short random ID for an IPC request, easy to type in a chat.
+func newRequestID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// toolNotApprovedPrefix starts the tool result of a call that was not approved.
+const toolNotApprovedPrefix = "Error: tool call not approved"
+
+// toolNotApprovedMessage is the tool result returned when approval is refused
+// or times out.
+func toolNotApprovedMessage(name string, resp approvalResponse) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s: %s was %s", toolNotApprovedPrefix, name, resp.Decision)
+	if resp.Approver != "" {
+		fmt.Fprintf(&sb, " by %s", resp.Approver)
+	}
+	if resp.Reason != "" {
+		fmt.Fprintf(&sb, " (%s)", resp.Reason)
+	}
+	sb.WriteString(". Do not retry this call; continue without it or explain what you needed it for.")
+	return sb.String()
+}
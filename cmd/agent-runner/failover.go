@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fallbackSecretsDir is where the controller mounts each fallback's auth
+// secret, as model-fallback-<index>/<key>.
+var fallbackSecretsDir = "/secrets"
+
+// Failover reasons recorded on each failed attempt.
+const (
+	failoverRateLimit  = "rate_limit"
+	failoverOverloaded = "overloaded"
+	failoverAuth       = "auth"
+)
+
+// modelTarget is one model in the failover chain. The JSON shape matches
+// sympoziumv1alpha1.ModelFallback as passed in MODEL_FALLBACKS.
+type modelTarget struct {
+	Provider      string `json:"provider"`
+	Model         string `json:"model"`
+	BaseURL       string `json:"baseURL,omitempty"`
+	AuthSecretRef string `json:"authSecretRef,omitempty"`
+
+	apiKey string
+}
+
+// name returns the "provider/model" label used in results and status.
+func (m modelTarget) name() string {
+	return m.Provider + "/" + m.Model
+}
+
+// modelAttempt records a model in the chain that was tried and failed.
+type modelAttempt struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Reason   string `json:"reason,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// providerError is returned by callAnthropic/callOpenAI when the provider
+// responded with an HTTP error, so the status code can drive failover.
+type providerError struct {
+	StatusCode int
+	msg        string
+}
+
+func (e *providerError) Error() string { return e.msg }
+
+// failoverReason classifies err and returns "" when the next model in the
+// chain should not be tried (e.g. a bad request would fail everywhere).
+func failoverReason(err error) string {
+	var pe *providerError
+	if !errors.As(err, &pe) {
+		return ""
+	}
+	switch pe.StatusCode {
+	case 429:
+		return failoverRateLimit
+	case 401, 403:
+		return failoverAuth
+	case 500, 502, 503, 504, 529:
+		return failoverOverloaded
+	default:
+		return ""
+	}
+}
+
+// loadFallbacks parses MODEL_FALLBACKS and resolves each entry's API key
+// from its mounted secret directory.
+func loadFallbacks(raw string) []modelTarget {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	var fallbacks []modelTarget
+	if err := json.Unmarshal([]byte(raw), &fallbacks); err != nil {
+		log.Printf("WARNING: ignoring invalid MODEL_FALLBACKS: %v", err)
+		return nil
+	}
+	for i := range fallbacks {
+		fallbacks[i].Provider = strings.ToLower(fallbacks[i].Provider)
+		fallbacks[i].BaseURL = strings.TrimRight(fallbacks[i].BaseURL, "/")
+		if fallbacks[i].AuthSecretRef != "" {
+			fallbacks[i].apiKey = readAPIKey(filepath.Join(fallbackSecretsDir, fmt.Sprintf("model-fallback-%d", i)))
+		}
+	}
+	return fallbacks
+}
+
+// readAPIKey returns the first API key found in a mounted secret directory,
+// checking the same key names the primary model reads from the environment.
+func readAPIKey(dir string) string {
+	for _, key := range []string{"API_KEY", "OPENAI_API_KEY", "ANTHROPIC_API_KEY", "AZURE_OPENAI_API_KEY"} {
+		if b, err := os.ReadFile(filepath.Join(dir, key)); err == nil {
+			if v := strings.TrimSpace(string(b)); v != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// callModel dispatches a single model to the matching provider client.
+func callModel(ctx context.Context, m modelTarget, systemPrompt, task, thinking string, tools []ToolDef) (string, llmUsage, error) {
+	switch m.Provider {
+	case "anthropic":
+		return callAnthropic(ctx, m.apiKey, m.BaseURL, m.Model, systemPrompt, task, thinking, tools)
+	default:
+		// OpenAI, Azure OpenAI, Ollama, and any OpenAI-compatible provider
+		return callOpenAI(ctx, m.Provider, m.apiKey, m.BaseURL, m.Model, systemPrompt, task, thinking, tools)
+	}
+}
+
+// callWithFailover tries each model in order until one succeeds or fails
+// with an error that failover cannot help with. Usage is summed across all
+// attempts and every failed model is returned as an attempt. The next model
+// starts again from the original task, so a model that already ran tools or
+// streamed output is not failed over: its tools would run twice and the
+// user would see the output again.
+func callWithFailover(ctx context.Context, chain []modelTarget, systemPrompt, task, thinking string, tools []ToolDef) (string, modelTarget, llmUsage, []modelAttempt, error) {
+	var (
+		total    llmUsage
+		attempts []modelAttempt
+		lastErr  error
+	)
+	for i, m := range chain {
+		streamed := streamedChunks()
+		text, usage, err := callModel(ctx, m, systemPrompt, task, thinking, tools)
+		total.InputTokens += usage.InputTokens
+		total.OutputTokens += usage.OutputTokens
+		total.ThinkingTokens += usage.ThinkingTokens
+		total.ThinkingTokensEstimated += usage.ThinkingTokensEstimated
+		total.ToolCalls += usage.ToolCalls
+		if err == nil {
+			return text, m, total, attempts, nil
+		}
+		lastErr = err
+
+		reason := failoverReason(err)
+		attempts = append(attempts, modelAttempt{
+			Provider: m.Provider,
+			Model:    m.Model,
+			Reason:   reason,
+			Error:    truncate(err.Error(), 300),
+		})
+		if reason == "" || i == len(chain)-1 {
+			break
+		}
+		if usage.ToolCalls > 0 || streamedChunks() > streamed {
+			log.Printf("model %s failed (%s) after producing output, not failing over", m.name(), reason)
+			break
+		}
+		log.Printf("model %s failed (%s), failing over to %s", m.name(), reason, chain[i+1].name())
+	}
+	return "", modelTarget{}, total, attempts, lastErr
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// followUpDir is where the IPC bridge writes follow-up messages.
+var followUpDir = "/ipc/input"
+
+// followUp mirrors ipc.FollowUp.
+type followUp struct {
+	ID     string `json:"id"`
+	Text   string `json:"text"`
+	Sender string `json:"sender,omitempty"`
+}
+
+// Follow-up files and IDs already injected. The controller may deliver a
+// message twice, so both are tracked.
+var (
+	seenFollowUpFiles = map[string]bool{}
+	seenFollowUpIDs   = map[string]bool{}
+)
+
+// takeFollowUps returns follow-ups that arrived since the last call, in the
+// order they were written.
+func takeFollowUps() []followUp {
+	matches, _ := filepath.Glob(filepath.Join(followUpDir, "followup-*.json"))
+	sort.Strings(matches)
+
+	var out []followUp
+	for _, path := range matches {
+		if seenFollowUpFiles[path] {
+			continue
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		seenFollowUpFiles[path] = true
+		var f followUp
+		if err := json.Unmarshal(b, &f); err != nil || strings.TrimSpace(f.Text) == "" {
+			log.Printf("WARNING: ignoring malformed follow-up %s", filepath.Base(path))
+			continue
+		}
+		if f.ID != "" {
+			if seenFollowUpIDs[f.ID] {
+				continue
+			}
+			seenFollowUpIDs[f.ID] = true
+		}
+		log.Printf("follow-up received from %s: %s", f.Sender, truncate(f.Text, 100))
+		out = append(out, f)
+	}
+	return out
+}
+
+// followUpPrompt renders follow-ups as a user turn.
+func followUpPrompt(fs []followUp) string {
+	var sb strings.Builder
+	sb.WriteString("[Follow-up message received while you were working. Take it into account before continuing.]")
+	for _, f := range fs {
+		sb.WriteString("\n\n")
+		if f.Sender != "" {
+			fmt.Fprintf(&sb, "From %s:\n", f.Sender)
+		}
+		sb.WriteString(f.Text)
+	}
+	return sb.String()
+}
@@ -2,12 +2,14 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -26,21 +28,34 @@ import (
 const maxToolIterations = 25
 
 type agentResult struct {
-	Status   string `json:"status"`
-	Response string `json:"response,omitempty"`
-	Error    string `json:"error,omitempty"`
-	Metrics  struct {
-		DurationMs   int64 `json:"durationMs"`
-		InputTokens  int   `json:"inputTokens"`
-		OutputTokens int   `json:"outputTokens"`
-		ToolCalls    int   `json:"toolCalls"`
+	Status         string         `json:"status"`
+	Response       string         `json:"response,omitempty"`
+	Error          string         `json:"error,omitempty"`
+	Model          string         `json:"model,omitempty"`
+	FailedAttempts []modelAttempt `json:"failedAttempts,omitempty"`
+	Metrics        struct {
+		DurationMs     int64 `json:"durationMs"`
+		InputTokens    int   `json:"inputTokens"`
+		OutputTokens   int   `json:"outputTokens"`
+		ThinkingTokens int   `json:"thinkingTokens,omitempty"`
+		// ThinkingTokensEstimated is set instead of ThinkingTokens for
+		// providers that don't report the thinking share.
+		ThinkingTokensEstimated int      `json:"thinkingTokensEstimated,omitempty"`
+		ToolCalls               int      `json:"toolCalls"`
+		SubagentSpawns          int      `json:"subagentSpawns,omitempty"`
+		SkillsLoaded            []string `json:"skillsLoaded,omitempty"`
 	} `json:"metrics"`
 }
 
-type streamChunk struct {
-	Type    string `json:"type"`
-	Content string `json:"content"`
-	Index   int    `json:"index"`
+// llmUsage accumulates token and tool-call counts across a tool-calling loop.
+type llmUsage struct {
+	InputTokens    int
+	OutputTokens   int
+	ThinkingTokens int // subset of OutputTokens spent on reasoning
+	// ThinkingTokensEstimated estimates ThinkingTokens from the thinking
+	// text when the provider doesn't report it.
+	ThinkingTokensEstimated int
+	ToolCalls               int
 }
 
 func main() {
@@ -59,7 +74,13 @@ func main() {
 		}
 	}
 	if task == "" {
-		fatal("TASK env var is empty and no /ipc/input/task.json found")
+		// Long tasks are mounted from the run's input ConfigMap.
+		if b, err := os.ReadFile("/input/task"); err == nil {
+			task = string(b)
+		}
+	}
+	if task == "" {
+		fatal("TASK env var is empty and no /ipc/input/task.json or /input/task found")
 	}
 
 	systemPrompt := getEnv("SYSTEM_PROMPT", "You are a helpful AI assistant.")
@@ -68,10 +89,19 @@ func main() {
 	baseURL := strings.TrimRight(getEnv("MODEL_BASE_URL", ""), "/")
 	memoryEnabled := getEnv("MEMORY_ENABLED", "") == "true"
 	toolsEnabled := getEnv("TOOLS_ENABLED", "") == "true"
+	thinkingMode := normalizeThinkingMode(getEnv("THINKING_MODE", thinkingOff))
 
-	// Load skill files and build enhanced system prompt.
-	skills := loadSkills(defaultSkillsDir)
-	systemPrompt = buildSystemPrompt(systemPrompt, skills, toolsEnabled)
+	activeToolPolicy = loadToolPolicy(getEnv("TOOL_POLICY", ""), getEnv("TOOL_GATING", ""))
+
+	// Skills are listed as a compact index and fetched with load_skill when
+	// needed. Without that tool the full instructions go into the prompt.
+	var skills string
+	skillIdx := loadSkillIndex(defaultSkillsDir)
+	if !toolsEnabled || !activeToolPolicy.permits(ToolLoadSkill) {
+		skillIdx = nil
+		skills = loadSkills(defaultSkillsDir)
+	}
+	systemPrompt = buildSystemPrompt(systemPrompt, skills, skillIdx, toolsEnabled)
 
 	// If this run was triggered from a channel, inject context so the
 	// agent knows how to reply through the originating channel.
@@ -90,35 +120,56 @@ func main() {
 		log.Printf("channel context injected: channel=%s chatId=%s", sourceChannel, sourceChatID)
 	}
 
-	// Resolve tool definitions.
+	// Resolve tool definitions, dropping any the tool policy denies.
 	var tools []ToolDef
 	if toolsEnabled {
-		tools = defaultTools()
+		var denied, gated []string
+		builtin := defaultTools()
+		if len(skillIdx) == 0 {
+			builtin = slices.DeleteFunc(builtin, func(t ToolDef) bool { return t.Name == ToolLoadSkill })
+		}
+		if !memoryEnabled {
+			builtin = slices.DeleteFunc(builtin, func(t ToolDef) bool { return slices.Contains(memoryTools, t.Name) })
+		}
+		extra := discoverMCPTools(loadMCPServers(getEnv("MCP_SERVERS", "")))
+		extra = append(extra, loadSkillTools(getEnv("SKILL_TOOLS", ""))...)
+		tools, denied = activeToolPolicy.filterTools(append(builtin, extra...))
+		for _, t := range tools {
+			if activeToolPolicy.action(t.Name) == toolActionAsk {
+				gated = append(gated, t.Name)
+			}
+		}
+		var notes []string
+		if len(denied) > 0 {
+			log.Printf("tool policy denies: %s", strings.Join(denied, ", "))
+			notes = append(notes, "These tools are disabled for this run and any call to them will be refused: "+
+				strings.Join(denied, ", ")+".")
+		}
+		if len(gated) > 0 {
+			log.Printf("tool policy requires approval for: %s", strings.Join(gated, ", "))
+			notes = append(notes, "These tools need human approval before each call, which may be refused: "+
+				strings.Join(gated, ", ")+". Only call them when they are really needed.")
+		}
+		if len(notes) > 0 {
+			systemPrompt += "\n\n## Tool Policy\n\n" + strings.Join(notes, "\n")
+		}
 		log.Printf("tools enabled: %d tool(s) registered", len(tools))
 	}
 
-	// Read existing memory if available.
-	var memoryContent string
+	// Prepend existing memory to the task. The memory tools edit a working
+	// copy that is sent to the controller when the run ends.
 	if memoryEnabled {
-		if b, err := os.ReadFile("/memory/MEMORY.md"); err == nil {
-			memoryContent = strings.TrimSpace(string(b))
-			log.Printf("loaded memory (%d bytes)", len(memoryContent))
+		if memoryContent := loadMemory("/memory/MEMORY.md"); memoryContent != "" {
+			task = fmt.Sprintf("## Your Memory\nThe following is your persistent memory from prior interactions:\n\n%s\n\n## Current Task\n%s", memoryContent, task)
 		}
 	}
-
-	// Prepend memory context to the task if present.
-	if memoryContent != "" && memoryContent != "# Agent Memory\n\nNo memories recorded yet." {
-		task = fmt.Sprintf("## Your Memory\nThe following is your persistent memory from prior interactions:\n\n%s\n\n## Current Task\n%s", memoryContent, task)
-	}
-
-	// If memory is enabled, add memory instructions to system prompt.
-	if memoryEnabled {
-		memoryInstruction := "\n\nYou have persistent memory. After completing your task, " +
-			"output a memory update block wrapped in markers like this:\n" +
-			"__SYMPOZIUM_MEMORY__\n<your updated MEMORY.md content>\n__SYMPOZIUM_MEMORY_END__\n" +
-			"Include key facts, preferences, and context from this and past interactions. " +
-			"Keep it concise (under 256KB). Use markdown format."
-		systemPrompt += memoryInstruction
+	if memoryEnabled && slices.ContainsFunc(tools, func(t ToolDef) bool { return t.Name == ToolMemoryAppend || t.Name == ToolMemoryUpdate }) {
+		systemPrompt += "\n\n## Memory\n\n" +
+			"You have persistent memory that carries over between runs. When you learn key facts, " +
+			"preferences or decisions worth keeping, record them with `memory_append`, and use " +
+			"`memory_update_section` to correct or condense a section that is out of date. " +
+			"Use `memory_search` and `memory_read` to look things up. Only record what will " +
+			"still be useful in future runs; your changes are saved when this run ends."
 	}
 
 	apiKey := firstNonEmpty(
@@ -128,14 +179,33 @@ func main() {
 		os.Getenv("AZURE_OPENAI_API_KEY"),
 	)
 
-	log.Printf("provider=%s model=%s baseURL=%s tools=%v task=%q",
-		provider, modelName, baseURL, toolsEnabled, truncate(task, 80))
+	// The primary model is followed by any fallbacks; each is tried in
+	// turn on rate-limit, overload or auth errors.
+	chain := append([]modelTarget{{
+		Provider: provider,
+		Model:    modelName,
+		BaseURL:  baseURL,
+		apiKey:   apiKey,
+	}}, loadFallbacks(getEnv("MODEL_FALLBACKS", ""))...)
+	if len(chain) > 1 {
+		log.Printf("model failover chain: %d fallback(s)", len(chain)-1)
+	}
+
+	log.Printf("provider=%s model=%s baseURL=%s tools=%v thinking=%s task=%q",
+		provider, modelName, baseURL, toolsEnabled, thinkingMode, truncate(task, 80))
 
 	_ = os.MkdirAll("/ipc/output", 0o755)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
 
+	// Files sent with a channel message are described in the task; images
+	// are also sent to the model and voice notes transcribed.
+	if section := prepareAttachments(ctx, getEnv("ATTACHMENTS", ""), loadTranscriber(apiKey)); section != "" {
+		task += "\n\n" + section
+		log.Printf("attachments: %d image(s) sent to the model", len(taskImages))
+	}
+
 	obs := initObservability(ctx)
 	defer func() {
 		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -149,6 +219,7 @@ func main() {
 		attribute.String("instance", getEnv("INSTANCE_NAME", "")),
 		attribute.String("tenant.namespace", getEnv("AGENT_NAMESPACE", "")),
 		attribute.String("model", modelName),
+		attribute.String("thinking", thinkingMode),
 		attribute.String("task.summary", truncate(task, 200)),
 	)
 	writeTraceContextMetadata(ctx)
@@ -160,30 +231,38 @@ func main() {
 	})
 
 	start := time.Now()
-
-	var (
-		responseText string
-		inputTokens  int
-		outputTokens int
-		toolCalls    int
-		err          error
-	)
-
-	switch provider {
-	case "anthropic":
-		responseText, inputTokens, outputTokens, toolCalls, err = callAnthropic(ctx, apiKey, baseURL, modelName, systemPrompt, task, tools)
-	default:
-		// OpenAI, Azure OpenAI, Ollama, and any OpenAI-compatible provider
-		responseText, inputTokens, outputTokens, toolCalls, err = callOpenAI(ctx, provider, apiKey, baseURL, modelName, systemPrompt, task, tools)
+	writeStatus(statusThinking, "", "")
+
+	responseText, served, usage, attempts, err := callWithFailover(ctx, chain, systemPrompt, task, thinkingMode, tools)
+	if err != nil && len(taskImages) > 0 && imagesRejected(err) && usage.ToolCalls == 0 {
+		// Models without vision reject image input; the task still lists
+		// the images as files.
+		log.Printf("model rejected the request with images, retrying without them: %v", err)
+		taskImages = nil
+		var retryUsage llmUsage
+		var retryAttempts []modelAttempt
+		responseText, served, retryUsage, retryAttempts, err = callWithFailover(ctx, chain, systemPrompt, task, thinkingMode, tools)
+		usage.InputTokens += retryUsage.InputTokens
+		usage.OutputTokens += retryUsage.OutputTokens
+		usage.ThinkingTokens += retryUsage.ThinkingTokens
+		usage.ThinkingTokensEstimated += retryUsage.ThinkingTokensEstimated
+		usage.ToolCalls += retryUsage.ToolCalls
+		attempts = append(attempts, retryAttempts...)
 	}
 
 	elapsed := time.Since(start)
+	inputTokens, outputTokens, toolCalls := usage.InputTokens, usage.OutputTokens, usage.ToolCalls
 
 	var res agentResult
 	res.Metrics.DurationMs = elapsed.Milliseconds()
 	res.Metrics.ToolCalls = toolCalls
-
-	debugMode := getEnv("DEBUG", "") == "true"
+	res.Metrics.SubagentSpawns = int(subagentSpawns.Load())
+	res.Metrics.SkillsLoaded = skillsLoaded()
+	runSpan.SetAttributes(
+		attribute.Int("sympozium.skills.indexed", len(skillIdx)),
+		attribute.StringSlice("sympozium.skills.loaded", res.Metrics.SkillsLoaded),
+	)
+	res.FailedAttempts = attempts
 
 	if err != nil {
 		log.Printf("LLM call failed: %v", err)
@@ -192,39 +271,29 @@ func main() {
 		markSpanError(runSpan, err)
 		runSpan.SetStatus(codes.Error, err.Error())
 	} else {
-		log.Printf("LLM call succeeded (tokens: in=%d out=%d, tool_calls=%d)", inputTokens, outputTokens, toolCalls)
+		log.Printf("LLM call succeeded (tokens: in=%d out=%d thinking=%d thinking_estimated=%d, tool_calls=%d)",
+			inputTokens, outputTokens, usage.ThinkingTokens, usage.ThinkingTokensEstimated, toolCalls)
 		res.Status = "success"
 		res.Response = responseText
+		res.Model = served.name()
 		res.Metrics.InputTokens = inputTokens
 		res.Metrics.OutputTokens = outputTokens
+		res.Metrics.ThinkingTokens = usage.ThinkingTokens
+		res.Metrics.ThinkingTokensEstimated = usage.ThinkingTokensEstimated
 		runSpan.SetAttributes(
+			attribute.String("gen_ai.response.model", served.Model),
 			attribute.Int("gen_ai.usage.input_tokens", inputTokens),
 			attribute.Int("gen_ai.usage.output_tokens", outputTokens),
+			attribute.Int("gen_ai.usage.reasoning_tokens", usage.ThinkingTokens),
 			attribute.Int("gen_ai.tool.call.count", toolCalls),
 		)
 		runSpan.SetStatus(codes.Ok, "")
 	}
 
-	// Extract and emit memory update before stripping markers from the response.
-	if memoryEnabled && res.Response != "" {
-		if memUpdate := extractMemoryUpdate(res.Response); memUpdate != "" {
-			fmt.Fprintf(os.Stdout, "\n__SYMPOZIUM_MEMORY__%s__SYMPOZIUM_MEMORY_END__\n", memUpdate)
-			log.Printf("emitted memory update (%d bytes)", len(memUpdate))
-		}
-	}
-
-	// Strip memory markers from the response so they don't appear in the
-	// TUI feed or channel messages. Keep them only if DEBUG is enabled.
-	if !debugMode && res.Response != "" {
-		res.Response = stripMemoryMarkers(res.Response)
-	}
-
-	if res.Response != "" {
-		writeJSON("/ipc/output/stream-0.json", streamChunk{
-			Type:    "text",
-			Content: res.Response,
-			Index:   0,
-		})
+	// Send the memory tools' changes before result.json, after which the
+	// bridge stops.
+	if memoryEnabled {
+		flushMemoryUpdate()
 	}
 
 	writeJSON("/ipc/output/result.json", res)
@@ -245,12 +314,15 @@ func main() {
 		log.Printf("agent-runner finished with error: %s", res.Error)
 		os.Exit(1)
 	}
-	obs.recordRunMetrics(ctx, "success", getEnv("INSTANCE_NAME", ""), modelName, getEnv("AGENT_NAMESPACE", ""), elapsed.Milliseconds(), inputTokens, outputTokens)
+	obs.recordRunMetrics(ctx, "success", getEnv("INSTANCE_NAME", ""), served.Model, getEnv("AGENT_NAMESPACE", ""), elapsed.Milliseconds(), inputTokens, outputTokens)
 	logWithTrace(ctx, "info", "agent run succeeded", map[string]any{
-		"duration_ms":   elapsed.Milliseconds(),
-		"input_tokens":  inputTokens,
-		"output_tokens": outputTokens,
-		"tool_calls":    toolCalls,
+		"model":                     res.Model,
+		"duration_ms":               elapsed.Milliseconds(),
+		"input_tokens":              inputTokens,
+		"output_tokens":             outputTokens,
+		"thinking_tokens":           usage.ThinkingTokens,
+		"thinking_tokens_estimated": usage.ThinkingTokensEstimated,
+		"tool_calls":                toolCalls,
 	})
 	runSpan.End()
 	log.Println("agent-runner finished successfully")
@@ -259,8 +331,14 @@ func main() {
 // callAnthropic uses the official Anthropic Go SDK with optional tool calling.
 // When tools is non-empty, the function enters a loop: call the LLM, execute
 // any tool_use blocks, feed results back, and repeat until the model produces
-// a final text response or the iteration limit is reached.
-func callAnthropic(ctx context.Context, apiKey, baseURL, model, systemPrompt, task string, tools []ToolDef) (string, int, int, int, error) {
+// a final text response or the iteration limit is reached. Each turn is
+// streamed, so text and thinking reach /ipc/output as they are generated.
+// Follow-up messages that arrive meanwhile are injected as user turns.
+//
+// When thinking is low/medium/high, extended thinking is enabled with the
+// matching budget; thinking blocks are streamed as "thinking" chunks and kept
+// in the conversation history so tool-use turns stay valid.
+func callAnthropic(ctx context.Context, apiKey, baseURL, model, systemPrompt, task, thinking string, tools []ToolDef) (string, llmUsage, error) {
 	opts := []anthropicoption.RequestOption{
 		anthropicoption.WithMaxRetries(5),
 	}
@@ -287,18 +365,22 @@ func callAnthropic(ctx context.Context, apiKey, baseURL, model, systemPrompt, ta
 		anthropicTools = append(anthropicTools, tool)
 	}
 
+	var first []anthropic.ContentBlockParamUnion
+	for _, img := range taskImages {
+		first = append(first, anthropic.NewImageBlockBase64(img.MimeType, base64.StdEncoding.EncodeToString(img.Data)))
+	}
 	messages := []anthropic.MessageParam{
-		anthropic.NewUserMessage(anthropic.NewTextBlock(task)),
+		anthropic.NewUserMessage(append(first, anthropic.NewTextBlock(task))...),
 	}
 
-	totalInputTokens := 0
-	totalOutputTokens := 0
-	totalToolCalls := 0
+	thinkingBudget := anthropicThinkingBudget(thinking)
+
+	var usage llmUsage
 
 	for i := 0; i < maxToolIterations; i++ {
 		params := anthropic.MessageNewParams{
 			Model:     anthropic.Model(model),
-			MaxTokens: int64(8192),
+			MaxTokens: int64(anthropicMaxTokens),
 			System: []anthropic.TextBlockParam{
 				{Text: systemPrompt},
 			},
@@ -307,26 +389,31 @@ func callAnthropic(ctx context.Context, apiKey, baseURL, model, systemPrompt, ta
 		if len(anthropicTools) > 0 {
 			params.Tools = anthropicTools
 		}
+		if thinkingBudget > 0 {
+			params.Thinking = anthropic.ThinkingConfigParamOfEnabled(thinkingBudget)
+			params.MaxTokens = thinkingBudget + anthropicMaxTokens
+		}
 
 		chatCtx, chatSpan := obs.startChatSpan(ctx,
 			attribute.String("gen_ai.system", "anthropic"),
 			attribute.String("gen_ai.request.model", model),
 		)
-		message, err := client.Messages.New(chatCtx, params)
+		message, err := streamAnthropicMessage(chatCtx, client, params)
 		if err != nil {
 			markSpanError(chatSpan, err)
 			chatSpan.End()
 			var apiErr *anthropic.Error
 			if errors.As(err, &apiErr) {
-				return "", totalInputTokens, totalOutputTokens, totalToolCalls,
-					fmt.Errorf("Anthropic API error (HTTP %d): %s", apiErr.StatusCode, truncate(apiErr.Error(), 500))
+				return "", usage, &providerError{
+					StatusCode: apiErr.StatusCode,
+					msg:        fmt.Sprintf("Anthropic API error (HTTP %d): %s", apiErr.StatusCode, truncate(apiErr.Error(), 500)),
+				}
 			}
-			return "", totalInputTokens, totalOutputTokens, totalToolCalls,
-				fmt.Errorf("Anthropic API error: %w", err)
+			return "", usage, streamError("Anthropic", err)
 		}
 
-		totalInputTokens += int(message.Usage.InputTokens)
-		totalOutputTokens += int(message.Usage.OutputTokens)
+		usage.InputTokens += int(message.Usage.InputTokens)
+		usage.OutputTokens += int(message.Usage.OutputTokens)
 		chatSpan.SetAttributes(
 			attribute.Int("gen_ai.usage.input_tokens", int(message.Usage.InputTokens)),
 			attribute.Int("gen_ai.usage.output_tokens", int(message.Usage.OutputTokens)),
@@ -335,27 +422,43 @@ func callAnthropic(ctx context.Context, apiKey, baseURL, model, systemPrompt, ta
 		chatSpan.SetStatus(codes.Ok, "")
 		chatSpan.End()
 
-		// Separate text blocks and tool-use blocks.
+		// Separate text, thinking, and tool-use blocks. Text and thinking
+		// were already streamed as they arrived. The API folds thinking into
+		// output_tokens without reporting its share, so only an estimate
+		// is recorded.
 		var textContent strings.Builder
 		var toolUseBlocks []anthropic.ToolUseBlock
 		for _, block := range message.Content {
 			switch v := block.AsAny().(type) {
 			case anthropic.TextBlock:
 				textContent.WriteString(v.Text)
+			case anthropic.ThinkingBlock:
+				usage.ThinkingTokensEstimated += estimateTokens(v.Thinking)
 			case anthropic.ToolUseBlock:
 				toolUseBlocks = append(toolUseBlocks, v)
 			}
 		}
 
-		// If no tool calls, return the text.
-		if message.StopReason != anthropic.StopReasonToolUse || len(toolUseBlocks) == 0 {
-			return textContent.String(), totalInputTokens, totalOutputTokens, totalToolCalls, nil
+		// If no tool calls, return the text — unless follow-ups arrived
+		// while the model was answering, in which case keep going.
+		var followUps []followUp
+		finished := message.StopReason != anthropic.StopReasonToolUse || len(toolUseBlocks) == 0
+		if finished {
+			if followUps = takeFollowUps(); len(followUps) == 0 {
+				return textContent.String(), usage, nil
+			}
 		}
 
-		// Build the assistant message with all content blocks (text + tool_use).
+		// Build the assistant message with all content blocks. Thinking
+		// blocks must be passed back unmodified (with their signature) or
+		// the API rejects the follow-up turn.
 		var assistantBlocks []anthropic.ContentBlockParamUnion
 		for _, block := range message.Content {
 			switch v := block.AsAny().(type) {
+			case anthropic.ThinkingBlock:
+				assistantBlocks = append(assistantBlocks, anthropic.NewThinkingBlock(v.Signature, v.Thinking))
+			case anthropic.RedactedThinkingBlock:
+				assistantBlocks = append(assistantBlocks, anthropic.NewRedactedThinkingBlock(v.Data))
 			case anthropic.TextBlock:
 				assistantBlocks = append(assistantBlocks, anthropic.NewTextBlock(v.Text))
 			case anthropic.ToolUseBlock:
@@ -363,30 +466,45 @@ func callAnthropic(ctx context.Context, apiKey, baseURL, model, systemPrompt, ta
 					anthropic.NewToolUseBlock(v.ID, json.RawMessage(v.Input), v.Name))
 			}
 		}
-		messages = append(messages, anthropic.NewAssistantMessage(assistantBlocks...))
+		if len(assistantBlocks) > 0 {
+			messages = append(messages, anthropic.NewAssistantMessage(assistantBlocks...))
+		}
+		if finished {
+			messages = append(messages, anthropic.NewUserMessage(anthropic.NewTextBlock(followUpPrompt(followUps))))
+			continue
+		}
 
 		// Execute each tool call and build tool_result blocks.
 		var resultBlocks []anthropic.ContentBlockParamUnion
 		for _, tu := range toolUseBlocks {
-			totalToolCalls++
-			log.Printf("tool_use [%d]: %s id=%s", totalToolCalls, tu.Name, tu.ID)
+			usage.ToolCalls++
+			log.Printf("tool_use [%d]: %s id=%s", usage.ToolCalls, tu.Name, tu.ID)
 
 			result := executeToolCallWithTelemetry(ctx, tu.Name, string(tu.Input), tu.ID)
 			isErr := strings.HasPrefix(result, "Error:")
 			resultBlocks = append(resultBlocks, anthropic.NewToolResultBlock(tu.ID, result, isErr))
 		}
+		// Follow-ups ride along with the tool results in the same user turn.
+		if followUps := takeFollowUps(); len(followUps) > 0 {
+			resultBlocks = append(resultBlocks, anthropic.NewTextBlock(followUpPrompt(followUps)))
+		}
 		messages = append(messages, anthropic.NewUserMessage(resultBlocks...))
 	}
 
-	return "", totalInputTokens, totalOutputTokens, totalToolCalls,
+	return "", usage,
 		fmt.Errorf("exceeded maximum tool-call iterations (%d)", maxToolIterations)
 }
 
 // callOpenAI uses the official OpenAI Go SDK with optional tool calling.
 // When tools is non-empty, the function enters a loop: call the LLM, execute
 // any tool_calls, feed results back, and repeat until the model produces a
-// final text response or the iteration limit is reached.
-func callOpenAI(ctx context.Context, provider, apiKey, baseURL, model, systemPrompt, task string, tools []ToolDef) (string, int, int, int, error) {
+// final text response or the iteration limit is reached. Each turn is
+// streamed, so text and reasoning reach /ipc/output as they are generated.
+// Follow-up messages that arrive meanwhile are injected as user turns.
+//
+// For reasoning models (o-series, gpt-5) the thinking mode is sent as
+// reasoning_effort; other models ignore it.
+func callOpenAI(ctx context.Context, provider, apiKey, baseURL, model, systemPrompt, task, thinking string, tools []ToolDef) (string, llmUsage, error) {
 	opts := []openaioption.RequestOption{
 		openaioption.WithMaxRetries(5),
 	}
@@ -394,7 +512,7 @@ func callOpenAI(ctx context.Context, provider, apiKey, baseURL, model, systemPro
 	switch provider {
 	case "azure-openai":
 		if baseURL == "" {
-			return "", 0, 0, 0, fmt.Errorf("Azure OpenAI requires MODEL_BASE_URL to be set")
+			return "", llmUsage{}, fmt.Errorf("Azure OpenAI requires MODEL_BASE_URL to be set")
 		}
 		apiVersion := getEnv("AZURE_OPENAI_API_VERSION", "2024-06-01")
 		opts = append(opts,
@@ -428,10 +546,19 @@ func callOpenAI(ctx context.Context, provider, apiKey, baseURL, model, systemPro
 		openai.SystemMessage(systemPrompt),
 		openai.UserMessage(task),
 	}
+	if len(taskImages) > 0 {
+		var parts []openai.ChatCompletionContentPartUnionParam
+		for _, img := range taskImages {
+			parts = append(parts, openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{
+				URL: "data:" + img.MimeType + ";base64," + base64.StdEncoding.EncodeToString(img.Data),
+			}))
+		}
+		messages[1] = openai.UserMessage(append(parts, openai.TextContentPart(task)))
+	}
 
-	totalInputTokens := 0
-	totalOutputTokens := 0
-	totalToolCalls := 0
+	reasoningEffort := openAIReasoningEffort(model, thinking)
+
+	var usage llmUsage
 
 	for i := 0; i < maxToolIterations; i++ {
 		params := openai.ChatCompletionNewParams{
@@ -441,67 +568,171 @@ func callOpenAI(ctx context.Context, provider, apiKey, baseURL, model, systemPro
 		if len(oaiTools) > 0 {
 			params.Tools = oaiTools
 		}
+		if reasoningEffort != "" {
+			params.ReasoningEffort = reasoningEffort
+		}
+		if provider != "azure-openai" {
+			// Azure's default API version rejects stream_options.
+			params.StreamOptions = openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)}
+		}
 
 		chatCtx, chatSpan := obs.startChatSpan(ctx,
 			attribute.String("gen_ai.system", provider),
 			attribute.String("gen_ai.request.model", model),
 		)
-		completion, err := client.Chat.Completions.New(chatCtx, params)
+		turn, err := streamOpenAITurn(chatCtx, client, params)
 		if err != nil {
 			markSpanError(chatSpan, err)
 			chatSpan.End()
 			var apiErr *openai.Error
 			if errors.As(err, &apiErr) {
-				return "", totalInputTokens, totalOutputTokens, totalToolCalls,
-					fmt.Errorf("OpenAI API error (HTTP %d): %s", apiErr.StatusCode, truncate(apiErr.Error(), 500))
+				return "", usage, &providerError{
+					StatusCode: apiErr.StatusCode,
+					msg:        fmt.Sprintf("OpenAI API error (HTTP %d): %s", apiErr.StatusCode, truncate(apiErr.Error(), 500)),
+				}
 			}
-			return "", totalInputTokens, totalOutputTokens, totalToolCalls,
-				fmt.Errorf("OpenAI API error: %w", err)
+			return "", usage, streamError("OpenAI", err)
 		}
 
-		totalInputTokens += int(completion.Usage.PromptTokens)
-		totalOutputTokens += int(completion.Usage.CompletionTokens)
+		reasoningTokens := int(turn.Usage.CompletionTokensDetails.ReasoningTokens)
+		usage.InputTokens += int(turn.Usage.PromptTokens)
+		usage.OutputTokens += int(turn.Usage.CompletionTokens)
+		usage.ThinkingTokens += reasoningTokens
 		chatSpan.SetAttributes(
-			attribute.Int("gen_ai.usage.input_tokens", int(completion.Usage.PromptTokens)),
-			attribute.Int("gen_ai.usage.output_tokens", int(completion.Usage.CompletionTokens)),
+			attribute.Int("gen_ai.usage.input_tokens", int(turn.Usage.PromptTokens)),
+			attribute.Int("gen_ai.usage.output_tokens", int(turn.Usage.CompletionTokens)),
+			attribute.Int("gen_ai.usage.reasoning_tokens", reasoningTokens),
 		)
 
-		if len(completion.Choices) == 0 {
+		if !turn.hasChoice {
 			markSpanError(chatSpan, fmt.Errorf("no choices in completion response"))
 			chatSpan.End()
-			return "", totalInputTokens, totalOutputTokens, totalToolCalls,
+			return "", usage,
 				fmt.Errorf("no choices in completion response")
 		}
-		choice := completion.Choices[0]
-		chatSpan.SetAttributes(attribute.String("gen_ai.response.finish_reasons", choice.FinishReason))
+		chatSpan.SetAttributes(attribute.String("gen_ai.response.finish_reasons", turn.FinishReason))
 		chatSpan.SetStatus(codes.Ok, "")
 		chatSpan.End()
 
 		// If model made tool calls, execute them and loop.
-		if choice.FinishReason == "tool_calls" && len(choice.Message.ToolCalls) > 0 {
+		if turn.FinishReason == "tool_calls" && len(turn.ToolCalls) > 0 {
 			// Add the assistant message (with tool calls) to history.
-			messages = append(messages, choice.Message.ToParam())
+			messages = append(messages, turn.assistantParam())
 
 			// Execute each tool call and add results.
-			for _, tc := range choice.Message.ToolCalls {
-				fc := tc.AsFunction()
-				totalToolCalls++
-				log.Printf("tool_call [%d]: %s id=%s", totalToolCalls, fc.Function.Name, fc.ID)
+			for _, tc := range turn.ToolCalls {
+				usage.ToolCalls++
+				log.Printf("tool_call [%d]: %s id=%s", usage.ToolCalls, tc.Name, tc.ID)
 
-				result := executeToolCallWithTelemetry(ctx, fc.Function.Name, fc.Function.Arguments, fc.ID)
-				messages = append(messages, openai.ToolMessage(result, fc.ID))
+				result := executeToolCallWithTelemetry(ctx, tc.Name, tc.Arguments, tc.ID)
+				messages = append(messages, openai.ToolMessage(result, tc.ID))
+			}
+			if followUps := takeFollowUps(); len(followUps) > 0 {
+				messages = append(messages, openai.UserMessage(followUpPrompt(followUps)))
 			}
 			continue
 		}
 
-		// No tool calls — return the text response.
-		return choice.Message.Content, totalInputTokens, totalOutputTokens, totalToolCalls, nil
+		// No tool calls — return the text response, unless follow-ups
+		// arrived while the model was answering.
+		if followUps := takeFollowUps(); len(followUps) > 0 {
+			messages = append(messages, turn.assistantParam(), openai.UserMessage(followUpPrompt(followUps)))
+			continue
+		}
+		return turn.Content, usage, nil
 	}
 
-	return "", totalInputTokens, totalOutputTokens, totalToolCalls,
+	return "", usage,
 		fmt.Errorf("exceeded maximum tool-call iterations (%d)", maxToolIterations)
 }
 
+// streamAnthropicMessage runs one Messages request with streaming, writing
+// text and thinking deltas as stream chunks, and returns the accumulated
+// message.
+func streamAnthropicMessage(ctx context.Context, client anthropic.Client, params anthropic.MessageNewParams) (anthropic.Message, error) {
+	var body []byte
+	stream := client.Messages.NewStreaming(ctx, params, anthropicoption.WithMiddleware(nonStreamingBody(&body)))
+	defer stream.Close()
+	defer flushStream()
+
+	var message anthropic.Message
+	for stream.Next() {
+		event := stream.Current()
+		if err := message.Accumulate(event); err != nil {
+			return message, err
+		}
+		if delta, ok := event.AsAny().(anthropic.ContentBlockDeltaEvent); ok {
+			switch d := delta.Delta.AsAny().(type) {
+			case anthropic.TextDelta:
+				writeStreamChunk(chunkText, d.Text)
+			case anthropic.ThinkingDelta:
+				writeStreamChunk(chunkThinking, d.Thinking)
+			}
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return message, err
+	}
+
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &message); err != nil {
+			return message, fmt.Errorf("decoding message response: %w", err)
+		}
+		for _, block := range message.Content {
+			switch v := block.AsAny().(type) {
+			case anthropic.ThinkingBlock:
+				writeStreamChunk(chunkThinking, v.Thinking)
+			case anthropic.TextBlock:
+				writeStreamChunk(chunkText, v.Text)
+			}
+		}
+	}
+	return message, nil
+}
+
+// streamOpenAITurn runs one chat completion with streaming, writing text
+// and reasoning deltas as stream chunks, and returns the assembled turn.
+func streamOpenAITurn(ctx context.Context, client openai.Client, params openai.ChatCompletionNewParams) (openAITurn, error) {
+	var body []byte
+	stream := client.Chat.Completions.NewStreaming(ctx, params, openaioption.WithMiddleware(nonStreamingBody(&body)))
+	defer stream.Close()
+	defer flushStream()
+
+	var turn openAITurn
+	for stream.Next() {
+		chunk := stream.Current()
+		if chunk.Usage.PromptTokens > 0 || chunk.Usage.CompletionTokens > 0 {
+			turn.Usage = chunk.Usage
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		choice := chunk.Choices[0]
+		turn.hasChoice = true
+		if choice.Delta.Content != "" {
+			turn.Content += choice.Delta.Content
+			writeStreamChunk(chunkText, choice.Delta.Content)
+		}
+		// OpenAI-compatible servers (vLLM, Ollama, DeepSeek) stream the
+		// reasoning trace as a non-standard delta field.
+		writeStreamChunk(chunkThinking, openAIReasoningContent(choice.Delta.JSON.ExtraFields))
+		for _, tc := range choice.Delta.ToolCalls {
+			turn.addToolCallDelta(int(tc.Index), tc.ID, tc.Function.Name, tc.Function.Arguments)
+		}
+		if choice.FinishReason != "" {
+			turn.FinishReason = choice.FinishReason
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return turn, err
+	}
+
+	if len(body) > 0 {
+		return openAITurnFromCompletion(body)
+	}
+	return turn, nil
+}
+
 func writeJSON(path string, v any) {
 	dir := filepath.Dir(path)
 	_ = os.MkdirAll(dir, 0o755)
@@ -515,6 +746,14 @@ func writeJSON(path string, v any) {
 	}
 }
 
+// writeJSONAtomic writes v under a dot-prefixed temp name and renames it to
+// path, so the IPC bridge never reads a partial file.
+func writeJSONAtomic(path string, v any) error {
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	writeJSON(tmp, v)
+	return os.Rename(tmp, path)
+}
+
 func getEnv(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -548,48 +787,3 @@ func fatal(msg string) {
 	})
 	os.Exit(1)
 }
-
-// extractMemoryUpdate looks for a memory update block in the LLM response.
-// The agent is instructed to wrap its memory updates in:
-//
-//	__SYMPOZIUM_MEMORY__
-//	<content>
-//	__SYMPOZIUM_MEMORY_END__
-func extractMemoryUpdate(response string) string {
-	const startMarker = "__SYMPOZIUM_MEMORY__"
-	const endMarker = "__SYMPOZIUM_MEMORY_END__"
-
-	startIdx := strings.LastIndex(response, startMarker)
-	if startIdx < 0 {
-		return ""
-	}
-	payload := response[startIdx+len(startMarker):]
-	endIdx := strings.Index(payload, endMarker)
-	if endIdx < 0 {
-		return ""
-	}
-	return strings.TrimSpace(payload[:endIdx])
-}
-
-// stripMemoryMarkers removes all __SYMPOZIUM_MEMORY__...END__ blocks from the
-// response text so they don't appear in the TUI feed or channel messages.
-func stripMemoryMarkers(response string) string {
-	const startMarker = "__SYMPOZIUM_MEMORY__"
-	const endMarker = "__SYMPOZIUM_MEMORY_END__"
-
-	for {
-		startIdx := strings.Index(response, startMarker)
-		if startIdx < 0 {
-			break
-		}
-		endIdx := strings.Index(response[startIdx:], endMarker)
-		if endIdx < 0 {
-			// Unclosed marker — strip from startMarker to end of string.
-			response = strings.TrimSpace(response[:startIdx])
-			break
-		}
-		// Remove the entire marker block.
-		response = response[:startIdx] + response[startIdx+endIdx+len(endMarker):]
-	}
-	return strings.TrimSpace(response)
-}
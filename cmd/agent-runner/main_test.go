@@ -1,15 +1,39 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
+// TestMain points all IPC files at a temp dir so tests never touch /ipc.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "agent-runner-stream")
+	if err != nil {
+		panic(err)
+	}
+	streamDir = dir
+	approvalsDir = filepath.Join(dir, "approvals")
+	approvalPollInterval = 10 * time.Millisecond
+	spawnDir = filepath.Join(dir, "spawn")
+	spawnPollInterval = 10 * time.Millisecond
+	followUpDir = filepath.Join(dir, "input")
+	toolsDir = filepath.Join(dir, "tools")
+	memoryDir = filepath.Join(dir, "memory")
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 func TestGetEnv(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -213,18 +237,18 @@ func TestCallOpenAI_MockServer(t *testing.T) {
 	defer srv.Close()
 
 	ctx := t.Context()
-	text, inTok, outTok, _, err := callOpenAI(ctx, "openai", "test-key", srv.URL, "gpt-4o-mini", "You are helpful.", "Say hello", nil)
+	text, usage, err := callOpenAI(ctx, "openai", "test-key", srv.URL, "gpt-4o-mini", "You are helpful.", "Say hello", "", nil)
 	if err != nil {
 		t.Fatalf("callOpenAI error: %v", err)
 	}
 	if text != "Hello from mock!" {
 		t.Errorf("text = %q, want %q", text, "Hello from mock!")
 	}
-	if inTok != 5 {
-		t.Errorf("input tokens = %d, want 5", inTok)
+	if usage.InputTokens != 5 {
+		t.Errorf("input tokens = %d, want 5", usage.InputTokens)
 	}
-	if outTok != 10 {
-		t.Errorf("output tokens = %d, want 10", outTok)
+	if usage.OutputTokens != 10 {
+		t.Errorf("output tokens = %d, want 10", usage.OutputTokens)
 	}
 }
 
@@ -245,7 +269,7 @@ func TestCallOpenAI_ServerError(t *testing.T) {
 	defer srv.Close()
 
 	ctx := t.Context()
-	_, _, _, _, err := callOpenAI(ctx, "openai", "bad-key", srv.URL, "gpt-4", "sys", "task", nil)
+	_, _, err := callOpenAI(ctx, "openai", "bad-key", srv.URL, "gpt-4", "sys", "task", "", nil)
 	if err == nil {
 		t.Fatal("expected error for 401 response")
 	}
@@ -289,18 +313,18 @@ func TestCallAnthropic_MockServer(t *testing.T) {
 	defer srv.Close()
 
 	ctx := t.Context()
-	text, inTok, outTok, _, err := callAnthropic(ctx, "test-anthropic-key", srv.URL, "claude-sonnet-4-20250514", "Be helpful.", "Say hello", nil)
+	text, usage, err := callAnthropic(ctx, "test-anthropic-key", srv.URL, "claude-sonnet-4-20250514", "Be helpful.", "Say hello", "", nil)
 	if err != nil {
 		t.Fatalf("callAnthropic error: %v", err)
 	}
 	if text != "Hello from Anthropic mock!" {
 		t.Errorf("text = %q, want %q", text, "Hello from Anthropic mock!")
 	}
-	if inTok != 8 {
-		t.Errorf("input tokens = %d, want 8", inTok)
+	if usage.InputTokens != 8 {
+		t.Errorf("input tokens = %d, want 8", usage.InputTokens)
 	}
-	if outTok != 12 {
-		t.Errorf("output tokens = %d, want 12", outTok)
+	if usage.OutputTokens != 12 {
+		t.Errorf("output tokens = %d, want 12", usage.OutputTokens)
 	}
 }
 
@@ -321,7 +345,7 @@ func TestCallAnthropic_ServerError(t *testing.T) {
 	defer srv.Close()
 
 	ctx := t.Context()
-	_, _, _, _, err := callAnthropic(ctx, "bad-key", srv.URL, "claude-sonnet-4-20250514", "sys", "task", nil)
+	_, _, err := callAnthropic(ctx, "bad-key", srv.URL, "claude-sonnet-4-20250514", "sys", "task", "", nil)
 	if err == nil {
 		t.Fatal("expected error for 400 response")
 	}
@@ -332,7 +356,7 @@ func TestCallAnthropic_ServerError(t *testing.T) {
 
 func TestCallOpenAI_AzureRequiresBaseURL(t *testing.T) {
 	ctx := t.Context()
-	_, _, _, _, err := callOpenAI(ctx, "azure-openai", "key", "", "gpt-4", "sys", "task", nil)
+	_, _, err := callOpenAI(ctx, "azure-openai", "key", "", "gpt-4", "sys", "task", "", nil)
 	if err == nil {
 		t.Fatal("expected error when azure-openai has no base URL")
 	}
@@ -374,12 +398,12 @@ func TestProviderRouting(t *testing.T) {
 
 	ctx := t.Context()
 
-	callOpenAI(ctx, "openai", "k", openaiSrv.URL, "m", "s", "t", nil)
+	callOpenAI(ctx, "openai", "k", openaiSrv.URL, "m", "s", "t", "", nil)
 	if !openAICalled {
 		t.Error("expected OpenAI server to be called for openai provider")
 	}
 
-	callAnthropic(ctx, "k", anthropicSrv.URL, "m", "s", "t", nil)
+	callAnthropic(ctx, "k", anthropicSrv.URL, "m", "s", "t", "", nil)
 	if !anthropicCalled {
 		t.Error("expected Anthropic server to be called for anthropic provider")
 	}
@@ -459,24 +483,24 @@ func TestCallAnthropic_ToolUseFlow(t *testing.T) {
 	}
 
 	ctx := t.Context()
-	text, inTok, outTok, toolCalls, err := callAnthropic(ctx, "key", srv.URL, "claude-sonnet-4-20250514", "sys", "Read /tmp/testfile.txt", tools)
+	text, usage, err := callAnthropic(ctx, "key", srv.URL, "claude-sonnet-4-20250514", "sys", "Read /tmp/testfile.txt", "", tools)
 	if err != nil {
 		t.Fatalf("callAnthropic tool-use error: %v", err)
 	}
 	if callCount != 2 {
 		t.Errorf("expected 2 API calls (tool_use + final), got %d", callCount)
 	}
-	if toolCalls != 1 {
-		t.Errorf("expected 1 tool call, got %d", toolCalls)
+	if usage.ToolCalls != 1 {
+		t.Errorf("expected 1 tool call, got %d", usage.ToolCalls)
 	}
 	if text != "The file contains: hello world" {
 		t.Errorf("text = %q, want %q", text, "The file contains: hello world")
 	}
-	if inTok != 70 { // 20 + 50
-		t.Errorf("input tokens = %d, want 70", inTok)
+	if usage.InputTokens != 70 { // 20 + 50
+		t.Errorf("input tokens = %d, want 70", usage.InputTokens)
 	}
-	if outTok != 45 { // 30 + 15
-		t.Errorf("output tokens = %d, want 45", outTok)
+	if usage.OutputTokens != 45 { // 30 + 15
+		t.Errorf("output tokens = %d, want 45", usage.OutputTokens)
 	}
 }
 
@@ -546,12 +570,12 @@ func TestCallAnthropic_MultipleToolCalls(t *testing.T) {
 	}
 
 	ctx := t.Context()
-	text, _, _, toolCalls, err := callAnthropic(ctx, "key", srv.URL, "claude-sonnet-4-20250514", "sys", "Read both", tools)
+	text, usage, err := callAnthropic(ctx, "key", srv.URL, "claude-sonnet-4-20250514", "sys", "Read both", "", tools)
 	if err != nil {
 		t.Fatalf("callAnthropic multi-tool error: %v", err)
 	}
-	if toolCalls != 2 {
-		t.Errorf("expected 2 tool calls, got %d", toolCalls)
+	if usage.ToolCalls != 2 {
+		t.Errorf("expected 2 tool calls, got %d", usage.ToolCalls)
 	}
 	if text != "Both files read." {
 		t.Errorf("text = %q, want %q", text, "Both files read.")
@@ -607,7 +631,7 @@ func TestCallAnthropic_ToolErrorIsError(t *testing.T) {
 	}
 
 	ctx := t.Context()
-	text, _, _, _, err := callAnthropic(ctx, "key", srv.URL, "claude-sonnet-4-20250514", "sys", "Read /nonexistent/file.txt", tools)
+	text, _, err := callAnthropic(ctx, "key", srv.URL, "claude-sonnet-4-20250514", "sys", "Read /nonexistent/file.txt", "", tools)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -629,3 +653,1057 @@ func TestCallAnthropic_ToolErrorIsError(t *testing.T) {
 		}
 	}
 }
+
+func TestNormalizeThinkingMode(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", "off"},
+		{"off", "off"},
+		{"LOW", "low"},
+		{" medium ", "medium"},
+		{"high", "high"},
+		{"extreme", "off"},
+	}
+	for _, tt := range tests {
+		if got := normalizeThinkingMode(tt.in); got != tt.want {
+			t.Errorf("normalizeThinkingMode(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestOpenAIReasoningEffort(t *testing.T) {
+	tests := []struct {
+		model string
+		mode  string
+		want  string
+	}{
+		{"o3-mini", "high", "high"},
+		{"o4-mini", "low", "low"},
+		{"gpt-5", "medium", "medium"},
+		{"openai/o1", "medium", "medium"},
+		{"o3-mini", "off", ""},
+		{"gpt-4o", "high", ""},
+		{"llama3", "high", ""},
+	}
+	for _, tt := range tests {
+		if got := string(openAIReasoningEffort(tt.model, tt.mode)); got != tt.want {
+			t.Errorf("openAIReasoningEffort(%q, %q) = %q, want %q", tt.model, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestCallAnthropic_ThinkingEnabled(t *testing.T) {
+	streamDir = t.TempDir()
+	streamIndex = 0
+	callCount := 0
+	var firstBody, secondBody map[string]any
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount++
+		w.Header().Set("Content-Type", "application/json")
+
+		if callCount == 1 {
+			json.NewDecoder(r.Body).Decode(&firstBody)
+			json.NewEncoder(w).Encode(map[string]any{
+				"id": "msg_think", "type": "message", "role": "assistant", "model": "claude-sonnet-4-20250514",
+				"content": []map[string]any{
+					{"type": "thinking", "thinking": "I should read the file first.", "signature": "sig-abc"},
+					{"type": "tool_use", "id": "toolu_think", "name": "read_file",
+						"input": map[string]string{"path": "/nonexistent/file.txt"}},
+				},
+				"stop_reason": "tool_use",
+				"usage":       map[string]int{"input_tokens": 10, "output_tokens": 40},
+			})
+			return
+		}
+
+		json.NewDecoder(r.Body).Decode(&secondBody)
+		json.NewEncoder(w).Encode(map[string]any{
+			"id": "msg_done", "type": "message", "role": "assistant", "model": "claude-sonnet-4-20250514",
+			"content":     []map[string]any{{"type": "text", "text": "Done."}},
+			"stop_reason": "end_turn",
+			"usage":       map[string]int{"input_tokens": 20, "output_tokens": 5},
+		})
+	})
+
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	tools := []ToolDef{{
+		Name:        "read_file",
+		Description: "Read a file",
+		Parameters: map[string]any{
+			"properties": map[string]any{
+				"path": map[string]any{"type": "string"},
+			},
+			"required": []string{"path"},
+		},
+	}}
+
+	text, usage, err := callAnthropic(t.Context(), "key", srv.URL, "claude-sonnet-4-20250514", "sys", "task", "medium", tools)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if text != "Done." {
+		t.Errorf("text = %q, want %q", text, "Done.")
+	}
+
+	// The request must enable thinking with the medium budget and leave room
+	// for a visible answer on top of it.
+	thinking, _ := firstBody["thinking"].(map[string]any)
+	if thinking["type"] != "enabled" {
+		t.Errorf("thinking.type = %v, want enabled", thinking["type"])
+	}
+	if budget, _ := thinking["budget_tokens"].(float64); int64(budget) != anthropicThinkingBudgets["medium"] {
+		t.Errorf("budget_tokens = %v, want %d", budget, anthropicThinkingBudgets["medium"])
+	}
+	if maxTok, _ := firstBody["max_tokens"].(float64); int64(maxTok) <= anthropicThinkingBudgets["medium"] {
+		t.Errorf("max_tokens = %v, must exceed the thinking budget", maxTok)
+	}
+
+	// The thinking block must be replayed with its signature on the next turn.
+	messages, _ := secondBody["messages"].([]any)
+	assistant, _ := messages[1].(map[string]any)
+	content, _ := assistant["content"].([]any)
+	first, _ := content[0].(map[string]any)
+	if first["type"] != "thinking" || first["signature"] != "sig-abc" {
+		t.Errorf("first assistant block = %v, want thinking block with signature", first)
+	}
+
+	// Anthropic doesn't report the thinking share, so it is only estimated.
+	if usage.ThinkingTokens != 0 {
+		t.Errorf("thinking tokens = %d, want 0: Anthropic does not report them", usage.ThinkingTokens)
+	}
+	if want := estimateTokens("I should read the file first."); usage.ThinkingTokensEstimated != want {
+		t.Errorf("estimated thinking tokens = %d, want %d", usage.ThinkingTokensEstimated, want)
+	}
+
+	var chunk streamChunk
+	data, err := os.ReadFile(filepath.Join(streamDir, "stream-0.json"))
+	if err != nil {
+		t.Fatalf("expected thinking stream chunk: %v", err)
+	}
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		t.Fatal(err)
+	}
+	if chunk.Type != "thinking" || chunk.Content != "I should read the file first." {
+		t.Errorf("chunk = %+v, want thinking chunk", chunk)
+	}
+}
+
+func TestCallAnthropic_ThinkingOffOmitsConfig(t *testing.T) {
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"id": "msg", "type": "message", "role": "assistant", "model": "m",
+			"content":     []map[string]string{{"type": "text", "text": "ok"}},
+			"stop_reason": "end_turn",
+			"usage":       map[string]int{"input_tokens": 1, "output_tokens": 1},
+		})
+	}))
+	defer srv.Close()
+
+	if _, _, err := callAnthropic(t.Context(), "k", srv.URL, "m", "s", "t", "off", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := body["thinking"]; ok {
+		t.Errorf("thinking should be omitted when mode is off, got %v", body["thinking"])
+	}
+}
+
+func TestCallOpenAI_ReasoningEffort(t *testing.T) {
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"id": "test", "object": "chat.completion", "model": "o3-mini",
+			"choices": []map[string]any{{
+				"index":         0,
+				"message":       map[string]string{"role": "assistant", "content": "ok"},
+				"finish_reason": "stop",
+			}},
+			"usage": map[string]any{
+				"prompt_tokens": 5, "completion_tokens": 30, "total_tokens": 35,
+				"completion_tokens_details": map[string]int{"reasoning_tokens": 24},
+			},
+		})
+	}))
+	defer srv.Close()
+
+	_, usage, err := callOpenAI(t.Context(), "openai", "k", srv.URL, "o3-mini", "s", "t", "high", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body["reasoning_effort"] != "high" {
+		t.Errorf("reasoning_effort = %v, want high", body["reasoning_effort"])
+	}
+	if usage.ThinkingTokens != 24 {
+		t.Errorf("thinking tokens = %d, want 24", usage.ThinkingTokens)
+	}
+	if usage.OutputTokens != 30 {
+		t.Errorf("output tokens = %d, want 30", usage.OutputTokens)
+	}
+}
+
+func TestFailoverReason(t *testing.T) {
+	tests := []struct {
+		status int
+		want   string
+	}{
+		{429, failoverRateLimit},
+		{401, failoverAuth},
+		{403, failoverAuth},
+		{503, failoverOverloaded},
+		{529, failoverOverloaded},
+		{400, ""},
+	}
+	for _, tt := range tests {
+		if got := failoverReason(&providerError{StatusCode: tt.status}); got != tt.want {
+			t.Errorf("failoverReason(%d) = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+	if got := failoverReason(os.ErrNotExist); got != "" {
+		t.Errorf("non-provider error should not fail over, got %q", got)
+	}
+}
+
+func TestLoadFallbacks(t *testing.T) {
+	dir := t.TempDir()
+	fallbackSecretsDir = dir
+	t.Cleanup(func() { fallbackSecretsDir = "/secrets" })
+
+	os.MkdirAll(filepath.Join(dir, "model-fallback-0"), 0o755)
+	os.WriteFile(filepath.Join(dir, "model-fallback-0", "ANTHROPIC_API_KEY"), []byte("sk-ant\n"), 0o644)
+
+	got := loadFallbacks(`[{"provider":"Anthropic","model":"claude-sonnet-4-20250514","authSecretRef":"anthropic-key"},{"provider":"ollama","model":"llama3","baseURL":"http://ollama:11434/v1/"}]`)
+	if len(got) != 2 {
+		t.Fatalf("fallbacks = %d, want 2", len(got))
+	}
+	if got[0].Provider != "anthropic" || got[0].apiKey != "sk-ant" {
+		t.Errorf("fallback[0] = %+v", got[0])
+	}
+	if got[1].apiKey != "" || got[1].BaseURL != "http://ollama:11434/v1" {
+		t.Errorf("fallback[1] = %+v", got[1])
+	}
+	if loadFallbacks("not json") != nil {
+		t.Error("invalid MODEL_FALLBACKS should be ignored")
+	}
+}
+
+func TestCallWithFailover_RateLimitFallsBack(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Should-Retry", "false")
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(map[string]any{
+			"error": map[string]string{"message": "quota exceeded", "type": "insufficient_quota"},
+		})
+	}))
+	defer primary.Close()
+
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "fallback-key" {
+			t.Errorf("fallback x-api-key = %q", r.Header.Get("X-Api-Key"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"id": "msg_test", "type": "message", "role": "assistant", "model": "claude",
+			"content":     []map[string]string{{"type": "text", "text": "served by fallback"}},
+			"stop_reason": "end_turn",
+			"usage":       map[string]int{"input_tokens": 3, "output_tokens": 4},
+		})
+	}))
+	defer fallback.Close()
+
+	chain := []modelTarget{
+		{Provider: "openai", Model: "gpt-4o", BaseURL: primary.URL, apiKey: "k"},
+		{Provider: "anthropic", Model: "claude-sonnet-4-20250514", BaseURL: fallback.URL, apiKey: "fallback-key"},
+	}
+	text, served, usage, attempts, err := callWithFailover(t.Context(), chain, "s", "t", "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if text != "served by fallback" {
+		t.Errorf("text = %q", text)
+	}
+	if served.name() != "anthropic/claude-sonnet-4-20250514" {
+		t.Errorf("served = %q", served.name())
+	}
+	if usage.OutputTokens != 4 {
+		t.Errorf("output tokens = %d, want 4", usage.OutputTokens)
+	}
+	if len(attempts) != 1 || attempts[0].Reason != failoverRateLimit || attempts[0].Model != "gpt-4o" {
+		t.Errorf("attempts = %+v", attempts)
+	}
+}
+
+func TestCallWithFailover_BadRequestDoesNotFailOver(t *testing.T) {
+	fallbackCalled := false
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]any{
+			"error": map[string]string{"message": "bad request", "type": "invalid_request_error"},
+		})
+	}))
+	defer primary.Close()
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fallbackCalled = true
+	}))
+	defer fallback.Close()
+
+	chain := []modelTarget{
+		{Provider: "openai", Model: "gpt-4o", BaseURL: primary.URL, apiKey: "k"},
+		{Provider: "openai", Model: "gpt-4o-mini", BaseURL: fallback.URL, apiKey: "k"},
+	}
+	_, _, _, attempts, err := callWithFailover(t.Context(), chain, "s", "t", "", nil)
+	if err == nil || !strings.Contains(err.Error(), "HTTP 400") {
+		t.Fatalf("err = %v, want HTTP 400", err)
+	}
+	if fallbackCalled {
+		t.Error("fallback should not be called for a 400")
+	}
+	if len(attempts) != 1 || attempts[0].Reason != "" {
+		t.Errorf("attempts = %+v", attempts)
+	}
+}
+
+func TestCallWithFailover_NoFailoverAfterToolCalls(t *testing.T) {
+	streamDir = t.TempDir()
+	streamIndex = 0
+	callCount := 0
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount++
+		w.Header().Set("Content-Type", "application/json")
+		if callCount > 1 {
+			w.Header().Set("X-Should-Retry", "false")
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(map[string]any{
+				"error": map[string]string{"message": "quota exceeded", "type": "insufficient_quota"},
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"id": "c", "object": "chat.completion", "model": "gpt-4o",
+			"choices": []map[string]any{{
+				"index": 0,
+				"message": map[string]any{"role": "assistant", "tool_calls": []map[string]any{{
+					"id": "call_1", "type": "function",
+					"function": map[string]string{"name": "read_file", "arguments": `{"path":"/nonexistent"}`},
+				}}},
+				"finish_reason": "tool_calls",
+			}},
+			"usage": map[string]int{"prompt_tokens": 1, "completion_tokens": 1, "total_tokens": 2},
+		})
+	}))
+	defer primary.Close()
+	fallbackCalled := false
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fallbackCalled = true
+	}))
+	defer fallback.Close()
+
+	tools := []ToolDef{{
+		Name:        "read_file",
+		Description: "Read a file",
+		Parameters:  map[string]any{"type": "object", "properties": map[string]any{"path": map[string]any{"type": "string"}}},
+	}}
+	chain := []modelTarget{
+		{Provider: "openai", Model: "gpt-4o", BaseURL: primary.URL, apiKey: "k"},
+		{Provider: "openai", Model: "gpt-4o-mini", BaseURL: fallback.URL, apiKey: "k"},
+	}
+	_, _, usage, attempts, err := callWithFailover(t.Context(), chain, "s", "t", "", tools)
+	if err == nil || !strings.Contains(err.Error(), "HTTP 429") {
+		t.Fatalf("err = %v, want HTTP 429", err)
+	}
+	if fallbackCalled {
+		t.Error("fallback should not be called once tools have run")
+	}
+	if usage.ToolCalls != 1 || len(attempts) != 1 {
+		t.Errorf("usage = %+v, attempts = %+v", usage, attempts)
+	}
+}
+
+// readStreamChunks returns every stream-<n>.json in dir, in index order.
+func readStreamChunks(t *testing.T, dir string) []streamChunk {
+	t.Helper()
+	var chunks []streamChunk
+	for i := 0; ; i++ {
+		data, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf("stream-%d.json", i)))
+		if err != nil {
+			return chunks
+		}
+		var c streamChunk
+		if err := json.Unmarshal(data, &c); err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, c)
+	}
+}
+
+// writeSSE writes one server-sent event and flushes it.
+func writeSSE(w http.ResponseWriter, event string, data any) {
+	b, _ := json.Marshal(data)
+	if event != "" {
+		fmt.Fprintf(w, "event: %s\n", event)
+	}
+	fmt.Fprintf(w, "data: %s\n\n", b)
+	w.(http.Flusher).Flush()
+}
+
+func TestCallAnthropic_StreamsDeltas(t *testing.T) {
+	streamDir = t.TempDir()
+	streamIndex = 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["stream"] != true {
+			t.Errorf("stream = %v, want true", body["stream"])
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		writeSSE(w, "message_start", map[string]any{"type": "message_start", "message": map[string]any{
+			"id": "msg_s", "type": "message", "role": "assistant", "model": "claude", "content": []any{},
+			"usage": map[string]int{"input_tokens": 7, "output_tokens": 1},
+		}})
+		writeSSE(w, "content_block_start", map[string]any{"type": "content_block_start", "index": 0,
+			"content_block": map[string]any{"type": "text", "text": ""}})
+		for _, part := range []string{"Hel", "lo ", "world"} {
+			writeSSE(w, "content_block_delta", map[string]any{"type": "content_block_delta", "index": 0,
+				"delta": map[string]any{"type": "text_delta", "text": part}})
+		}
+		writeSSE(w, "content_block_stop", map[string]any{"type": "content_block_stop", "index": 0})
+		writeSSE(w, "message_delta", map[string]any{"type": "message_delta",
+			"delta": map[string]any{"stop_reason": "end_turn"}, "usage": map[string]int{"output_tokens": 9}})
+		writeSSE(w, "message_stop", map[string]any{"type": "message_stop"})
+	}))
+	defer srv.Close()
+
+	text, usage, err := callAnthropic(t.Context(), "k", srv.URL, "claude", "s", "t", "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "Hello world"; text != want {
+		t.Errorf("text = %q, want %q", text, want)
+	}
+	if usage.InputTokens != 7 || usage.OutputTokens != 9 {
+		t.Errorf("usage = %+v", usage)
+	}
+
+	var streamed strings.Builder
+	chunks := readStreamChunks(t, streamDir)
+	for i, c := range chunks {
+		if c.Index != i || c.Type != chunkText {
+			t.Errorf("chunk %d = %+v", i, c)
+		}
+		streamed.WriteString(c.Content)
+	}
+	if len(chunks) != 1 {
+		t.Errorf("expected the deltas coalesced into one chunk, got %d", len(chunks))
+	}
+	if streamed.String() != "Hello world" {
+		t.Errorf("streamed text = %q, want %q", streamed.String(), "Hello world")
+	}
+}
+
+func TestCallOpenAI_StreamsToolCalls(t *testing.T) {
+	streamDir = t.TempDir()
+	streamIndex = 0
+	callCount := 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount++
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		if opts, _ := body["stream_options"].(map[string]any); opts["include_usage"] != true {
+			t.Errorf("stream_options = %v, want include_usage", body["stream_options"])
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		chunk := func(delta map[string]any, finish any) map[string]any {
+			return map[string]any{"id": "c", "object": "chat.completion.chunk", "model": "gpt-4o",
+				"choices": []map[string]any{{"index": 0, "delta": delta, "finish_reason": finish}}}
+		}
+		if callCount == 1 {
+			writeSSE(w, "", chunk(map[string]any{"role": "assistant", "tool_calls": []map[string]any{{
+				"index": 0, "id": "call_1", "type": "function",
+				"function": map[string]string{"name": "read_file", "arguments": `{"path":`},
+			}}}, nil))
+			writeSSE(w, "", chunk(map[string]any{"tool_calls": []map[string]any{{
+				"index": 0, "function": map[string]string{"arguments": `"/nonexistent"}`},
+			}}}, "tool_calls"))
+		} else {
+			writeSSE(w, "", chunk(map[string]any{"role": "assistant", "content": "All "}, nil))
+			writeSSE(w, "", chunk(map[string]any{"content": "done."}, "stop"))
+		}
+		writeSSE(w, "", map[string]any{"id": "c", "object": "chat.completion.chunk", "model": "gpt-4o",
+			"choices": []any{}, "usage": map[string]int{"prompt_tokens": 4, "completion_tokens": 6, "total_tokens": 10}})
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	tools := []ToolDef{{
+		Name:        "read_file",
+		Description: "Read a file",
+		Parameters:  map[string]any{"type": "object", "properties": map[string]any{"path": map[string]any{"type": "string"}}},
+	}}
+	text, usage, err := callOpenAI(t.Context(), "openai", "k", srv.URL, "gpt-4o", "s", "t", "", tools)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if text != "All done." {
+		t.Errorf("text = %q", text)
+	}
+	if usage.ToolCalls != 1 || usage.InputTokens != 8 || usage.OutputTokens != 12 {
+		t.Errorf("usage = %+v", usage)
+	}
+
+	var types []string
+	for _, c := range readStreamChunks(t, streamDir) {
+		types = append(types, c.Type)
+		if c.Type == chunkToolUse && (c.ToolID != "call_1" || c.ToolName != "read_file" || c.Content != `{"path":"/nonexistent"}`) {
+			t.Errorf("tool_use chunk = %+v", c)
+		}
+	}
+	if got := strings.Join(types, ","); got != "tool_use,tool_result,text" {
+		t.Errorf("chunk types = %s", got)
+	}
+}
+
+func TestWriteStreamChunk_Coalesces(t *testing.T) {
+	streamDir = t.TempDir()
+	streamIndex = 0
+
+	writeStreamChunk(chunkThinking, "Let me ")
+	writeStreamChunk(chunkThinking, "think.")
+	writeStreamChunk(chunkText, "Answer")
+	if chunks := readStreamChunks(t, streamDir); len(chunks) != 1 || chunks[0].Content != "Let me think." {
+		t.Fatalf("chunks = %+v, want the thinking deltas in one chunk", chunks)
+	}
+
+	time.Sleep(3 * streamCoalesceDelay)
+	if chunks := readStreamChunks(t, streamDir); len(chunks) != 2 || chunks[1].Content != "Answer" {
+		t.Fatalf("chunks = %+v, want the text written after the delay", chunks)
+	}
+
+	writeStreamChunk(chunkText, strings.Repeat("x", streamCoalesceBytes))
+	if chunks := readStreamChunks(t, streamDir); len(chunks) != 3 {
+		t.Fatalf("got %d chunks, want a full chunk written at once", len(chunks))
+	}
+	if streamedChunks() != 3 {
+		t.Errorf("streamedChunks() = %d, want 3", streamedChunks())
+	}
+}
+
+func TestToolPolicyAction(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		gating string
+		tool   string
+		want   string
+	}{
+		{"no policy allows", "", "", ToolExecuteCommand, toolActionAllow},
+		{"run deny", `{"deny":["execute_command"]}`, "", ToolExecuteCommand, toolActionDeny},
+		{"run allowlist denies others", `{"allow":["read_file"]}`, "", ToolExecuteCommand, toolActionDeny},
+		{"run allowlist permits listed", `{"allow":["read_file"]}`, "", ToolReadFile, toolActionAllow},
+		{"gating rule deny", "", `{"defaultAction":"allow","rules":[{"tool":"fetch_url","action":"deny"}]}`, ToolFetchURL, toolActionDeny},
+		{"gating default deny", "", `{"defaultAction":"deny","rules":[{"tool":"read_file","action":"allow"}]}`, ToolWriteFile, toolActionDeny},
+		{"gating ask", "", `{"rules":[{"tool":"execute_command","action":"ask"}]}`, ToolExecuteCommand, toolActionAsk},
+		{"gating cannot widen run allowlist", `{"allow":["read_file"]}`, `{"rules":[{"tool":"write_file","action":"allow"}]}`, ToolWriteFile, toolActionDeny},
+		{"invalid policy denies", `{not json`, "", ToolReadFile, toolActionDeny},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := loadToolPolicy(tt.policy, tt.gating)
+			if got := p.action(tt.tool); got != tt.want {
+				t.Errorf("action(%q) = %q, want %q", tt.tool, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteToolCall_RefusesDeniedTool(t *testing.T) {
+	activeToolPolicy = loadToolPolicy(`{"deny":["write_file"]}`, "")
+	defer func() { activeToolPolicy = nil }()
+
+	tools, denied := activeToolPolicy.filterTools(defaultTools())
+	if len(denied) != 1 || denied[0] != ToolWriteFile {
+		t.Errorf("denied = %v, want [write_file]", denied)
+	}
+	for _, tool := range tools {
+		if tool.Name == ToolWriteFile {
+			t.Error("write_file should not be registered")
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "out.txt")
+	result := executeToolCall(t.Context(), ToolWriteFile, fmt.Sprintf(`{"path":%q,"content":"x"}`, path))
+	if !strings.Contains(result, "denied by the tool policy") {
+		t.Errorf("result = %q", result)
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Error("denied write_file call should not create the file")
+	}
+}
+
+func TestExecuteToolCall_AskWaitsForApproval(t *testing.T) {
+	activeToolPolicy = loadToolPolicy("", `{"rules":[{"tool":"write_file","action":"ask"}],"approvalTimeout":"5s"}`)
+	defer func() { activeToolPolicy = nil }()
+
+	// Play the bridge: answer the first request that appears.
+	go func() {
+		for range 200 {
+			matches, _ := filepath.Glob(filepath.Join(approvalsDir, "request-*.json"))
+			if len(matches) > 0 {
+				var req approvalRequest
+				b, _ := os.ReadFile(matches[0])
+				_ = json.Unmarshal(b, &req)
+				writeJSON(filepath.Join(approvalsDir, "response-"+req.ID+".json"),
+					approvalResponse{ID: req.ID, Decision: approvalDenied, Approver: "tui:alice", Reason: "not today"})
+				_ = os.Remove(matches[0])
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	path := filepath.Join(t.TempDir(), "out.txt")
+	result := executeToolCall(t.Context(), ToolWriteFile, fmt.Sprintf(`{"path":%q,"content":"x"}`, path))
+	if !strings.HasPrefix(result, toolNotApprovedPrefix) || !strings.Contains(result, "tui:alice") || !strings.Contains(result, "not today") {
+		t.Errorf("result = %q", result)
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Error("unapproved write_file call should not create the file")
+	}
+}
+
+func TestRequestApproval_TimesOut(t *testing.T) {
+	resp := requestApproval(t.Context(), ToolExecuteCommand, `{"command":"ls"}`, 50*time.Millisecond)
+	if resp.Decision != approvalTimeout {
+		t.Fatalf("decision = %q, want timeout", resp.Decision)
+	}
+	if _, err := os.Stat(filepath.Join(approvalsDir, "timeout-"+resp.ID+".json")); err != nil {
+		t.Errorf("timeout file not written: %v", err)
+	}
+}
+
+func TestSpawnAgentTool_WaitsForResult(t *testing.T) {
+	// Play the bridge: answer the spawn request with the child's result.
+	go func() {
+		for range 200 {
+			matches, _ := filepath.Glob(filepath.Join(spawnDir, "request-*.json"))
+			if len(matches) > 0 {
+				var req spawnRequest
+				b, _ := os.ReadFile(matches[0])
+				_ = json.Unmarshal(b, &req)
+				writeJSON(filepath.Join(spawnDir, "result-"+req.ID+".json"),
+					spawnResult{ID: req.ID, RunName: "sub-parent-" + req.ID, Status: "succeeded", Result: "42 pods"})
+				_ = os.Remove(matches[0])
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	result := executeToolCall(t.Context(), ToolSpawnAgent, `{"task":"count the pods"}`)
+	if !strings.Contains(result, "succeeded") || !strings.Contains(result, "42 pods") {
+		t.Errorf("result = %q", result)
+	}
+}
+
+func TestWaitForAgents_ReportsPendingAndRejected(t *testing.T) {
+	if err := os.MkdirAll(spawnDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeJSON(filepath.Join(spawnDir, "result-rej1.json"), spawnResult{ID: "rej1", Status: "rejected", Error: "sub-agent depth 3 exceeds max 2"})
+
+	result := waitForAgents(t.Context(), []string{"rej1", "slow1"}, 50*time.Millisecond)
+	if !strings.Contains(result, "rej1 was not started: sub-agent depth 3 exceeds max 2") {
+		t.Errorf("missing rejection in %q", result)
+	}
+	if !strings.Contains(result, "slow1: still running") {
+		t.Errorf("missing pending sub-agent in %q", result)
+	}
+}
+
+func TestSpawnWaitTimeout_BoundedByRunDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(t.Context(), 2*time.Minute)
+	defer cancel()
+
+	if got := spawnWaitTimeout(ctx, map[string]any{}); got != 90*time.Second {
+		t.Errorf("default wait = %s, want 1m30s", got)
+	}
+	if got := spawnWaitTimeout(ctx, map[string]any{"timeoutSeconds": 1800.0}); got != 90*time.Second {
+		t.Errorf("long wait = %s, want 1m30s", got)
+	}
+	if got := spawnWaitTimeout(ctx, map[string]any{"timeoutSeconds": 10.0}); got != 10*time.Second {
+		t.Errorf("short wait = %s, want 10s", got)
+	}
+	if got := spawnWaitTimeout(t.Context(), map[string]any{}); got != defaultSpawnWait {
+		t.Errorf("wait without deadline = %s, want %s", got, defaultSpawnWait)
+	}
+}
+
+func TestTakeFollowUps(t *testing.T) {
+	if err := os.MkdirAll(followUpDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeJSON(filepath.Join(followUpDir, "followup-100.json"), followUp{ID: "1", Text: "check the ingress too", Sender: "tui:alice"})
+	writeJSON(filepath.Join(followUpDir, "followup-200.json"), followUp{ID: "2", Text: "skip staging", Sender: "api"})
+
+	got := takeFollowUps()
+	if len(got) != 2 || got[0].ID != "1" || got[1].ID != "2" {
+		t.Fatalf("takeFollowUps() = %+v", got)
+	}
+	prompt := followUpPrompt(got)
+	if !strings.Contains(prompt, "From tui:alice:\ncheck the ingress too") || !strings.Contains(prompt, "skip staging") {
+		t.Errorf("prompt = %q", prompt)
+	}
+
+	// A redelivered message (same ID, new file) is not injected twice.
+	writeJSON(filepath.Join(followUpDir, "followup-300.json"), followUp{ID: "2", Text: "skip staging", Sender: "api"})
+	if again := takeFollowUps(); len(again) != 0 {
+		t.Errorf("expected no new follow-ups, got %+v", again)
+	}
+}
+
+func TestMCPTools_HTTP(t *testing.T) {
+	var sessions int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req jsonRPCRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Method != "initialize" && r.Header.Get("Mcp-Session-Id") != "sess-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch req.Method {
+		case "initialize":
+			sessions++
+			w.Header().Set("Mcp-Session-Id", "sess-1")
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":{"protocolVersion":%q,"capabilities":{"tools":{}}}}`, req.ID, mcpProtocolVersion)
+		case "notifications/initialized":
+			w.WriteHeader(http.StatusAccepted)
+		case "tools/list":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":{"tools":[
+				{"name":"search.issues","description":"Search issues","inputSchema":{"type":"object","properties":{"q":{"type":"string"}}}},
+				{"name":"delete_repo","description":"Delete a repository"}]}}`, req.ID)
+		case "tools/call":
+			// Answer on an event stream, after an unrelated notification.
+			w.Header().Set("Content-Type", "text/event-stream")
+			args, _ := json.Marshal(req.Params)
+			fmt.Fprint(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n\n")
+			fmt.Fprintf(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"id\":%d,\"result\":{\"content\":[{\"type\":\"text\",\"text\":%q}]}}\n\n", req.ID, string(args))
+		}
+	}))
+	defer srv.Close()
+	t.Cleanup(func() { mcpTools = map[string]mcpTool{} })
+
+	defs := discoverMCPTools(loadMCPServers(fmt.Sprintf(
+		`[{"name":"github","transport":"http","url":%q,"headers":{"Authorization":"Bearer s3cret"},"tools":["search.issues"]}]`, srv.URL)))
+	if len(defs) != 1 || defs[0].Name != "github__search_issues" {
+		t.Fatalf("discovered %+v, want only github__search_issues", defs)
+	}
+	if defs[0].Parameters["type"] != "object" || !strings.Contains(defs[0].Description, "Search issues") {
+		t.Errorf("unexpected tool definition %+v", defs[0])
+	}
+
+	got := executeToolCall(t.Context(), "github__search_issues", `{"q":"is:open"}`)
+	if !strings.Contains(got, `"name":"search.issues"`) || !strings.Contains(got, `"q":"is:open"`) {
+		t.Errorf("tool result = %q", got)
+	}
+	if sessions != 1 {
+		t.Errorf("initialized %d sessions, want 1", sessions)
+	}
+}
+
+// fakeSidecar plays the skill sidecar's tool-executor until the test ends,
+// answering each exec request with handle's result. It returns the requests
+// seen so far.
+func fakeSidecar(t *testing.T, handle func(execRequest) execResult) func() []execRequest {
+	t.Helper()
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+	var (
+		mu   sync.Mutex
+		seen []execRequest
+	)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+			matches, _ := filepath.Glob(filepath.Join(toolsDir, "exec-request-*.json"))
+			for _, path := range matches {
+				b, err := os.ReadFile(path)
+				if err != nil {
+					continue
+				}
+				var req execRequest
+				if json.Unmarshal(b, &req) != nil {
+					continue
+				}
+				_ = os.Remove(path)
+				mu.Lock()
+				seen = append(seen, req)
+				mu.Unlock()
+				res := handle(req)
+				res.ID = req.ID
+				writeJSON(filepath.Join(toolsDir, "exec-result-"+req.ID+".json"), res)
+			}
+		}
+	}()
+	return func() []execRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(seen)
+	}
+}
+
+func TestMCPTools_StdioRunsInSidecar(t *testing.T) {
+	t.Cleanup(func() { mcpTools = map[string]mcpTool{} })
+
+	// Answer with the responses a stdio server would print for the
+	// requests on its stdin.
+	requests := fakeSidecar(t, func(req execRequest) execResult {
+		var stdout strings.Builder
+		for _, line := range strings.Split(strings.TrimSpace(req.Stdin), "\n") {
+			var msg jsonRPCRequest
+			_ = json.Unmarshal([]byte(line), &msg)
+			switch msg.Method {
+			case "initialize":
+				fmt.Fprintf(&stdout, `{"jsonrpc":"2.0","id":%d,"result":{}}`+"\n", msg.ID)
+			case "tools/list":
+				fmt.Fprintf(&stdout, `{"jsonrpc":"2.0","id":%d,"result":{"tools":[{"name":"read_graph","inputSchema":{"type":"object"}}]}}`+"\n", msg.ID)
+			case "tools/call":
+				fmt.Fprintf(&stdout, `{"jsonrpc":"2.0","id":%d,"result":{"content":[{"type":"text","text":"no such entity"}],"isError":true}}`+"\n", msg.ID)
+			}
+		}
+		return execResult{Stdout: stdout.String()}
+	})
+
+	defs := discoverMCPTools([]mcpServerConfig{{
+		Name: "memory", Transport: "stdio", SkillPack: "knowledge",
+		Command: []string{"npx", "-y", "@modelcontextprotocol/server-memory", "it's"},
+	}})
+	if len(defs) != 1 || defs[0].Name != "memory__read_graph" {
+		t.Fatalf("discovered %+v, want memory__read_graph", defs)
+	}
+	if got := executeToolCall(t.Context(), "memory__read_graph", `{}`); got != "Error: no such entity" {
+		t.Errorf("tool result = %q", got)
+	}
+	if want := `npx -y @modelcontextprotocol/server-memory 'it'\''s'`; requests()[0].Command != want {
+		t.Errorf("command = %q, want %q", requests()[0].Command, want)
+	}
+	if got := requests()[0].SkillPack; got != "knowledge" {
+		t.Errorf("skillPack = %q, want the server's SkillPack", got)
+	}
+}
+
+func TestMCPTools_PolicyApplies(t *testing.T) {
+	t.Cleanup(func() {
+		mcpTools = map[string]mcpTool{}
+		activeToolPolicy = nil
+	})
+	mcpTools["github__delete_repo"] = mcpTool{server: mcpServerConfig{Name: "github"}, name: "delete_repo"}
+	activeToolPolicy = loadToolPolicy(`{"deny":["github__delete_repo"]}`, "")
+
+	got := executeToolCallWithTelemetry(t.Context(), "github__delete_repo", `{}`, "call-1")
+	if got != toolDeniedMessage("github__delete_repo") {
+		t.Errorf("got %q, want the policy denial", got)
+	}
+}
+
+func TestSkillTools_RenderedBySidecar(t *testing.T) {
+	t.Cleanup(func() { skillTools = map[string]skillToolConfig{} })
+	requests := fakeSidecar(t, func(req execRequest) execResult {
+		return execResult{Stdout: "NAME  READY\nweb-0  1/1"}
+	})
+
+	defs := loadSkillTools(`[
+		{"name":"k8s_get_pods","description":"List pods","skillPack":"k8s-ops","timeoutSeconds":300,
+		 "parameters":{"type":"object","properties":{"namespace":{"type":"string"}},"required":["namespace"]}},
+		{"name":"execute_command","description":"shadows a built-in","skillPack":"k8s-ops"}]`)
+	if len(defs) != 1 || defs[0].Name != "k8s_get_pods" {
+		t.Fatalf("registered %+v, want only k8s_get_pods", defs)
+	}
+
+	if got := executeToolCall(t.Context(), "k8s_get_pods", `{}`); got != "Error: 'namespace' is required" {
+		t.Errorf("missing argument: got %q", got)
+	}
+	if got := executeToolCall(t.Context(), "k8s_get_pods", `{"namespace":"prod"}`); !strings.Contains(got, "web-0") {
+		t.Errorf("tool result = %q", got)
+	}
+
+	reqs := requests()
+	if len(reqs) != 1 {
+		t.Fatalf("sidecar saw %d requests, want 1", len(reqs))
+	}
+	if r := reqs[0]; r.Tool != "k8s_get_pods" || r.Command != "" || r.Arguments["namespace"] != "prod" || r.Timeout != 120 {
+		t.Errorf("exec request = %+v", r)
+	}
+}
+
+func TestLoadSkillIndex_LoadSkillOnDemand(t *testing.T) {
+	t.Cleanup(func() {
+		skillIndex = map[string]skillEntry{}
+		loadedSkills = nil
+	})
+	dir := t.TempDir()
+	files := map[string]string{
+		"review-guidelines.md": "# review-guidelines\n\n> Structured code review methodology\n\n# Code Review Guidelines\n\nRead the PR first.",
+		"runbook.md":           "# Runbook\n\nRestart the pod, then check the logs.",
+		".hidden":              "ignored",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	index := loadSkillIndex(dir)
+	if len(index) != 2 {
+		t.Fatalf("indexed %d skills, want 2: %+v", len(index), index)
+	}
+	if index[0].Name != "review-guidelines" || index[0].Description != "Structured code review methodology" {
+		t.Errorf("index[0] = %+v", index[0])
+	}
+	if index[1].Name != "runbook" || index[1].Description != "Restart the pod, then check the logs." {
+		t.Errorf("index[1] = %+v", index[1])
+	}
+
+	prompt := buildSystemPrompt("base", "", index, true)
+	if !strings.Contains(prompt, "- **review-guidelines**: Structured code review methodology") || strings.Contains(prompt, "Read the PR first") {
+		t.Errorf("prompt should list skills without their content:\n%s", prompt)
+	}
+
+	got := executeToolCall(t.Context(), ToolLoadSkill, `{"name":"review-guidelines"}`)
+	if !strings.Contains(got, "Read the PR first.") {
+		t.Errorf("load_skill = %q", got)
+	}
+	_ = executeToolCall(t.Context(), ToolLoadSkill, `{"name":"review-guidelines"}`)
+	if got := executeToolCall(t.Context(), ToolLoadSkill, `{"name":"nope"}`); !strings.HasPrefix(got, "Error: unknown skill") || !strings.Contains(got, "review-guidelines, runbook") {
+		t.Errorf("unknown skill: %q", got)
+	}
+	if loaded := skillsLoaded(); len(loaded) != 1 || loaded[0] != "review-guidelines" {
+		t.Errorf("skillsLoaded = %v, want [review-guidelines]", loaded)
+	}
+}
+
+func TestMemoryTools_EditAndFlush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "MEMORY.md")
+	os.WriteFile(path, []byte("# Agent Memory\n\n## Preferences\n\n- Likes Go\n\n## Projects\n\nsympozium\n"), 0o644)
+	if got := loadMemory(path); !strings.HasPrefix(got, "# Agent Memory") {
+		t.Fatalf("loadMemory = %q", got)
+	}
+	flushMemoryUpdate()
+	if _, err := os.Stat(filepath.Join(memoryDir, "update.json")); !os.IsNotExist(err) {
+		t.Fatal("unchanged memory should not be sent")
+	}
+
+	memoryAppendTool(map[string]any{"section": "## preferences", "text": "- Uses metric units"})
+	memoryAppendTool(map[string]any{"section": "Contacts", "text": "Alice runs the platform team."})
+	memoryUpdateSectionTool(map[string]any{"heading": "Projects", "content": "sympozium, kubeclaw"})
+
+	want := "# Agent Memory\n\n## Preferences\n\n- Likes Go\n- Uses metric units\n\n" +
+		"## Projects\n\nsympozium, kubeclaw\n\n## Contacts\n\nAlice runs the platform team."
+	if got := memoryReadTool(map[string]any{}); got != want {
+		t.Fatalf("memory =\n%s\nwant\n%s", got, want)
+	}
+	if got := memoryReadTool(map[string]any{"section": "Projects"}); got != "## Projects\n\nsympozium, kubeclaw" {
+		t.Errorf("memory_read section = %q", got)
+	}
+	if got := memorySearchTool(map[string]any{"query": "METRIC"}); got != "6 [Preferences]: - Uses metric units" {
+		t.Errorf("memory_search = %q", got)
+	}
+
+	flushMemoryUpdate()
+	data, err := os.ReadFile(filepath.Join(memoryDir, "update.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var upd memoryUpdate
+	if err := json.Unmarshal(data, &upd); err != nil || upd.Content != want+"\n" {
+		t.Errorf("update = %q (%v)", upd.Content, err)
+	}
+
+	memoryUpdateSectionTool(map[string]any{"heading": "Contacts", "content": ""})
+	if got := memoryReadTool(map[string]any{"section": "Contacts"}); !strings.HasPrefix(got, "No memory section") {
+		t.Errorf("removed section still present: %q", got)
+	}
+}
+
+func TestPrepareAttachments(t *testing.T) {
+	attachmentsDir = t.TempDir()
+	workspaceDir = t.TempDir()
+	t.Cleanup(func() {
+		attachmentsDir, workspaceDir = "/attachments", "/workspace"
+		taskImages = nil
+	})
+	os.WriteFile(filepath.Join(attachmentsDir, "photo.jpg"), []byte("jpeg"), 0o644)
+	os.WriteFile(filepath.Join(attachmentsDir, "voice.oga"), []byte("ogg"), 0o644)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/audio/transcriptions" || r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("unexpected request %s with %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		_, fh, err := r.FormFile("file")
+		if err != nil || fh.Filename != "voice.ogg" || r.FormValue("model") != "whisper-1" {
+			t.Errorf("unexpected form: file=%v model=%q err=%v", fh, r.FormValue("model"), err)
+		}
+		json.NewEncoder(w).Encode(map[string]string{"text": "Turn on the lights"})
+	}))
+	defer srv.Close()
+
+	tr := &transcriber{baseURL: srv.URL, model: "whisper-1", apiKey: "key", client: srv.Client()}
+	section := prepareAttachments(t.Context(),
+		`[{"name":"photo.jpg","type":"image","mimeType":"image/jpeg"},{"name":"voice.oga","type":"audio","mimeType":"audio/ogg"}]`, tr)
+
+	for _, want := range []string{"photo.jpg (image/jpeg, 4 bytes), shown to you", "> Turn on the lights"} {
+		if !strings.Contains(section, want) {
+			t.Errorf("section missing %q:\n%s", want, section)
+		}
+	}
+	if len(taskImages) != 1 || string(taskImages[0].Data) != "jpeg" {
+		t.Errorf("taskImages = %+v", taskImages)
+	}
+	if b, err := os.ReadFile(filepath.Join(workspaceDir, "attachments", "voice.oga")); err != nil || string(b) != "ogg" {
+		t.Errorf("attachment not copied to the workspace: %v", err)
+	}
+}
+
+func TestLoadMessageFiles(t *testing.T) {
+	workspaceDir = t.TempDir()
+	t.Cleanup(func() { workspaceDir = "/workspace" })
+	outside := filepath.Join(t.TempDir(), "secret")
+	os.WriteFile(outside, []byte("key"), 0o644)
+	os.WriteFile(filepath.Join(workspaceDir, "chart.png"), []byte("\x89PNG\r\n\x1a\n"), 0o644)
+	os.WriteFile(filepath.Join(workspaceDir, "big.bin"), make([]byte, maxMessageFileBytes+1), 0o644)
+	os.Symlink(outside, filepath.Join(workspaceDir, "link"))
+
+	files, err := loadMessageFiles([]string{filepath.Join(workspaceDir, "chart.png")})
+	if err != nil {
+		t.Fatalf("loadMessageFiles: %v", err)
+	}
+	if len(files) != 1 || files[0].Filename != "chart.png" || files[0].MimeType != "image/png" || files[0].Type != "image" {
+		t.Errorf("files = %+v", files)
+	}
+
+	for _, path := range []string{outside, filepath.Join(workspaceDir, "link"), "../secret", "big.bin"} {
+		if _, err := loadMessageFiles([]string{path}); err == nil {
+			t.Errorf("loadMessageFiles(%q) succeeded, want an error", path)
+		}
+	}
+}
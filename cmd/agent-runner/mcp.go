@@ -0,0 +1,511 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mcpProtocolVersion is the Model Context Protocol revision the runner speaks.
+const mcpProtocolVersion = "2025-06-18"
+
+// defaultMCPTimeout bounds MCP calls when a server sets no TimeoutSeconds.
+const defaultMCPTimeout = 60 * time.Second
+
+// mcpServerConfig mirrors the controller's MCP_SERVERS entries.
+type mcpServerConfig struct {
+	Name           string            `json:"name"`
+	Transport      string            `json:"transport,omitempty"`
+	Command        []string          `json:"command,omitempty"`
+	URL            string            `json:"url,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	Tools          []string          `json:"tools,omitempty"`
+	TimeoutSeconds int               `json:"timeoutSeconds,omitempty"`
+	SkillPack      string            `json:"skillPack,omitempty"`
+}
+
+func (s mcpServerConfig) timeout() time.Duration {
+	if s.TimeoutSeconds > 0 {
+		return time.Duration(s.TimeoutSeconds) * time.Second
+	}
+	return defaultMCPTimeout
+}
+
+// mcpTool is an MCP server tool registered with the LLM.
+type mcpTool struct {
+	server mcpServerConfig
+	client mcpClient
+	name   string // the tool's name on the server
+}
+
+// mcpTools maps registered tool names ("<server>__<tool>") to their server.
+var mcpTools = map[string]mcpTool{}
+
+// mcpClient sends JSON-RPC requests to an MCP server, initializing the
+// session as needed.
+type mcpClient interface {
+	call(ctx context.Context, method string, params any) (json.RawMessage, error)
+}
+
+type jsonRPCRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      int    `json:"id,omitempty"` // omitted for notifications
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+type jsonRPCResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// outcome returns the response's result, or its error.
+func (r jsonRPCResponse) outcome() (json.RawMessage, error) {
+	if r.Error != nil {
+		return nil, fmt.Errorf("MCP error %d: %s", r.Error.Code, r.Error.Message)
+	}
+	return r.Result, nil
+}
+
+// isResponseTo reports whether msg is the response to request id.
+func isResponseTo(msg []byte, id int) (jsonRPCResponse, bool) {
+	var resp jsonRPCResponse
+	if json.Unmarshal(msg, &resp) != nil {
+		return resp, false
+	}
+	return resp, string(resp.ID) == fmt.Sprint(id)
+}
+
+func mcpInitializeParams() map[string]any {
+	return map[string]any{
+		"protocolVersion": mcpProtocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]any{"name": "sympozium-agent-runner", "version": "1.0"},
+	}
+}
+
+// loadMCPServers parses MCP_SERVERS.
+func loadMCPServers(raw string) []mcpServerConfig {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	var servers []mcpServerConfig
+	if err := json.Unmarshal([]byte(raw), &servers); err != nil {
+		log.Printf("WARNING: invalid MCP_SERVERS, no MCP tools will be available: %v", err)
+		return nil
+	}
+	for i := range servers {
+		if servers[i].Transport == "" {
+			servers[i].Transport = "stdio"
+		}
+	}
+	return servers
+}
+
+// newMCPClient returns the client for a server's transport.
+func newMCPClient(s mcpServerConfig) (mcpClient, error) {
+	switch s.Transport {
+	case "stdio":
+		if len(s.Command) == 0 {
+			return nil, fmt.Errorf("stdio server has no command")
+		}
+		return &stdioMCPClient{server: s}, nil
+	case "http":
+		if s.URL == "" {
+			return nil, fmt.Errorf("http server has no url")
+		}
+		return &httpMCPClient{server: s, http: &http.Client{}}, nil
+	default:
+		return nil, fmt.Errorf("unknown transport %q", s.Transport)
+	}
+}
+
+// discoverMCPTools lists the tools of each server and registers them in
+// mcpTools. A server that cannot be reached is skipped.
+func discoverMCPTools(servers []mcpServerConfig) []ToolDef {
+	builtin := map[string]bool{}
+	for _, t := range defaultTools() {
+		builtin[t.Name] = true
+	}
+
+	var defs []ToolDef
+	for _, srv := range servers {
+		client, err := newMCPClient(srv)
+		if err != nil {
+			log.Printf("WARNING: MCP server %s: %v", srv.Name, err)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), srv.timeout())
+		tools, err := listMCPTools(ctx, client)
+		cancel()
+		if err != nil {
+			log.Printf("WARNING: MCP server %s unavailable, skipping its tools: %v", srv.Name, err)
+			continue
+		}
+
+		count := 0
+		for _, t := range tools {
+			if len(srv.Tools) > 0 && !slices.Contains(srv.Tools, t.Name) {
+				continue
+			}
+			name := mcpToolName(srv.Name, t.Name)
+			if builtin[name] || mcpTools[name].client != nil {
+				log.Printf("WARNING: MCP tool %s from %s clashes with another tool, skipping", name, srv.Name)
+				continue
+			}
+			params := t.InputSchema
+			if params == nil {
+				params = map[string]any{"type": "object", "properties": map[string]any{}}
+			}
+			mcpTools[name] = mcpTool{server: srv, client: client, name: t.Name}
+			defs = append(defs, ToolDef{
+				Name:        name,
+				Description: fmt.Sprintf("[MCP server %s] %s", srv.Name, t.Description),
+				Parameters:  params,
+			})
+			count++
+		}
+		log.Printf("MCP server %s (%s): %d tool(s) registered", srv.Name, srv.Transport, count)
+	}
+	return defs
+}
+
+// mcpToolDef is an entry of a tools/list result.
+type mcpToolDef struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+// listMCPTools pages through tools/list.
+func listMCPTools(ctx context.Context, client mcpClient) ([]mcpToolDef, error) {
+	var tools []mcpToolDef
+	cursor := ""
+	for {
+		params := map[string]any{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		raw, err := client.call(ctx, "tools/list", params)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Tools      []mcpToolDef `json:"tools"`
+			NextCursor string       `json:"nextCursor"`
+		}
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return nil, fmt.Errorf("decoding tools/list: %w", err)
+		}
+		tools = append(tools, page.Tools...)
+		if page.NextCursor == "" || page.NextCursor == cursor {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+var invalidToolNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// mcpToolName returns the LLM-facing name of a server's tool. Providers
+// allow at most 64 characters from [a-zA-Z0-9_-].
+func mcpToolName(server, tool string) string {
+	name := server + "__" + invalidToolNameChars.ReplaceAllString(tool, "_")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// callMCPTool runs tools/call for a registered MCP tool.
+func callMCPTool(ctx context.Context, t mcpTool, args map[string]any) string {
+	ctx, cancel := context.WithTimeout(ctx, t.server.timeout())
+	defer cancel()
+
+	if args == nil {
+		args = map[string]any{}
+	}
+	raw, err := t.client.call(ctx, "tools/call", map[string]any{"name": t.name, "arguments": args})
+	if err != nil {
+		return fmt.Sprintf("Error: MCP server %s: %v", t.server.Name, err)
+	}
+	return formatMCPResult(raw)
+}
+
+// formatMCPResult renders a tools/call result as tool output.
+func formatMCPResult(raw json.RawMessage) string {
+	var res struct {
+		Content []struct {
+			Type     string `json:"type"`
+			Text     string `json:"text"`
+			Resource *struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"resource"`
+		} `json:"content"`
+		StructuredContent json.RawMessage `json:"structuredContent"`
+		IsError           bool            `json:"isError"`
+	}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return fmt.Sprintf("Error: decoding MCP tool result: %v", err)
+	}
+
+	var parts []string
+	for _, c := range res.Content {
+		switch {
+		case c.Type == "text":
+			parts = append(parts, c.Text)
+		case c.Type == "resource" && c.Resource != nil && c.Resource.Text != "":
+			parts = append(parts, fmt.Sprintf("[%s]\n%s", c.Resource.URI, c.Resource.Text))
+		default:
+			parts = append(parts, fmt.Sprintf("[%s content omitted]", c.Type))
+		}
+	}
+	if len(parts) == 0 && len(res.StructuredContent) > 0 {
+		parts = append(parts, string(res.StructuredContent))
+	}
+	output := strings.Join(parts, "\n")
+	if output == "" {
+		output = "(no output)"
+	}
+	if len(output) > 50_000 {
+		output = output[:50_000] + "\n... (output truncated)"
+	}
+	if res.IsError {
+		return "Error: " + output
+	}
+	return output
+}
+
+// --- stdio transport ---
+
+// stdioMCPClient runs a stdio server in its SkillPack's sidecar through the
+// exec bridge. Each call starts a fresh server process, writes the
+// initialize handshake and the request to its stdin, and reads the response
+// from its stdout, so servers that keep state between calls don't work.
+type stdioMCPClient struct {
+	server mcpServerConfig
+}
+
+func (c *stdioMCPClient) call(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	const requestID = 2
+	var stdin bytes.Buffer
+	enc := json.NewEncoder(&stdin)
+	for _, msg := range []jsonRPCRequest{
+		{JSONRPC: "2.0", ID: 1, Method: "initialize", Params: mcpInitializeParams()},
+		{JSONRPC: "2.0", Method: "notifications/initialized"},
+		{JSONRPC: "2.0", ID: requestID, Method: method, Params: params},
+	} {
+		if err := enc.Encode(msg); err != nil {
+			return nil, err
+		}
+	}
+
+	timeout := c.server.timeout()
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = min(timeout, time.Until(deadline))
+	}
+	quoted := make([]string, len(c.server.Command))
+	for i, arg := range c.server.Command {
+		quoted[i] = shellQuote(arg)
+	}
+	res, err := runExec(execRequest{
+		Command:   strings.Join(quoted, " "),
+		WorkDir:   "/workspace",
+		Timeout:   max(int(timeout.Seconds()), 1),
+		Stdin:     stdin.String(),
+		Meta:      traceMetadata(ctx),
+		SkillPack: c.server.SkillPack,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The server may have been killed at the timeout after answering, so
+	// look for the response before checking how it exited.
+	sc := bufio.NewScanner(strings.NewReader(res.Stdout))
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		if resp, ok := isResponseTo(sc.Bytes(), requestID); ok {
+			return resp.outcome()
+		}
+	}
+	if res.TimedOut {
+		return nil, fmt.Errorf("server timed out without responding to %s", method)
+	}
+	return nil, fmt.Errorf("server exited with code %d without responding to %s: %s",
+		res.ExitCode, method, truncateStr(strings.TrimSpace(res.Stderr), 500))
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\$`&|;<>()*?[]{}~#!") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// --- streamable HTTP transport ---
+
+// httpMCPClient talks to a streamable-HTTP server, keeping the session it
+// opened until the server expires it.
+type httpMCPClient struct {
+	server mcpServerConfig
+	http   *http.Client
+
+	mu          sync.Mutex
+	initialized bool
+	sessionID   string
+	nextID      int
+}
+
+func (c *httpMCPClient) call(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.initialized {
+		if err := c.initialize(ctx); err != nil {
+			return nil, fmt.Errorf("initialize: %w", err)
+		}
+	}
+	raw, err := c.request(ctx, method, params)
+	if errors.Is(err, errMCPSessionExpired) {
+		c.initialized, c.sessionID = false, ""
+		if err := c.initialize(ctx); err != nil {
+			return nil, fmt.Errorf("initialize: %w", err)
+		}
+		raw, err = c.request(ctx, method, params)
+	}
+	return raw, err
+}
+
+func (c *httpMCPClient) initialize(ctx context.Context) error {
+	if _, err := c.request(ctx, "initialize", mcpInitializeParams()); err != nil {
+		return err
+	}
+	if _, err := c.post(ctx, jsonRPCRequest{JSONRPC: "2.0", Method: "notifications/initialized"}); err != nil {
+		return err
+	}
+	c.initialized = true
+	return nil
+}
+
+// errMCPSessionExpired is returned when the server no longer knows the
+// session, which the client must then re-initialize.
+var errMCPSessionExpired = errors.New("MCP session expired")
+
+// request sends a JSON-RPC request and waits for its response, which may
+// arrive as a JSON body or on an SSE stream.
+func (c *httpMCPClient) request(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	c.nextID++
+	id := c.nextID
+	resp, err := c.post(ctx, jsonRPCRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return readSSEResponse(resp.Body, id)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 16*1024*1024))
+	if err != nil {
+		return nil, err
+	}
+	r, ok := isResponseTo(body, id)
+	if !ok {
+		return nil, fmt.Errorf("unexpected response to %s: %s", method, truncateStr(string(body), 200))
+	}
+	return r.outcome()
+}
+
+// post sends one JSON-RPC message. The caller closes the body of a non-nil
+// response; notification responses are closed here.
+func (c *httpMCPClient) post(ctx context.Context, msg jsonRPCRequest) (*http.Response, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.server.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range c.server.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if c.sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", c.sessionID)
+	}
+	if c.initialized {
+		req.Header.Set("MCP-Protocol-Version", mcpProtocolVersion)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound && c.sessionID != "" {
+		resp.Body.Close()
+		return nil, errMCPSessionExpired
+	}
+	if resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	if sid := resp.Header.Get("Mcp-Session-Id"); sid != "" {
+		c.sessionID = sid
+	}
+	if msg.ID == 0 {
+		resp.Body.Close()
+		return nil, nil
+	}
+	return resp, nil
+}
+
+// readSSEResponse reads server-sent events until the response to id.
+func readSSEResponse(r io.Reader, id int) (json.RawMessage, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	var data strings.Builder
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			if resp, ok := isResponseTo([]byte(data.String()), id); ok {
+				return resp.outcome()
+			}
+			data.Reset()
+			continue
+		}
+		if v, ok := strings.CutPrefix(line, "data:"); ok {
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(v, " "))
+		}
+	}
+	if resp, ok := isResponseTo([]byte(data.String()), id); ok {
+		return resp.outcome()
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("event stream ended without a response")
+}
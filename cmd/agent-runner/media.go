@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// attachmentsDir is where the controller mounts the run's attachments.
+var attachmentsDir = "/attachments"
+
+// workspaceDir is the agent's working directory; attachments are copied to
+// its attachments subdirectory, and send_channel_message only sends files
+// from inside it.
+var workspaceDir = "/workspace"
+
+// maxMessageFileBytes caps the files of one channel message. It mirrors
+// channel.MaxMediaBytes.
+const maxMessageFileBytes = 640 << 10
+
+// runAttachment mirrors v1alpha1.AgentRunAttachment.
+type runAttachment struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+// taskImage is an image attached to the task, sent to the model with it.
+type taskImage struct {
+	MimeType string
+	Data     []byte
+}
+
+// taskImages are the images sent with the first user message. main clears
+// them to retry a model that rejects images.
+var taskImages []taskImage
+
+// visionMimeTypes are the image types the providers accept inline.
+var visionMimeTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// prepareAttachments copies the run's attachments into the workspace,
+// queues images for the model and transcribes audio. It returns a task
+// section describing them, or "" when there are none.
+func prepareAttachments(ctx context.Context, raw string, tr *transcriber) string {
+	if strings.TrimSpace(raw) == "" {
+		return ""
+	}
+	var list []runAttachment
+	if err := json.Unmarshal([]byte(raw), &list); err != nil {
+		log.Printf("ignoring invalid ATTACHMENTS: %v", err)
+		return ""
+	}
+	if len(list) == 0 {
+		return ""
+	}
+
+	dst := filepath.Join(workspaceDir, "attachments")
+	_ = os.MkdirAll(dst, 0o755)
+
+	var sb strings.Builder
+	sb.WriteString("## Attachments\n\nThe user sent these files, saved under " + dst + ":\n")
+	for _, a := range list {
+		data, err := os.ReadFile(filepath.Join(attachmentsDir, a.Name))
+		if err != nil {
+			log.Printf("attachment %s: %v", a.Name, err)
+			fmt.Fprintf(&sb, "- %s (%s): could not be read\n", a.Name, a.Type)
+			continue
+		}
+		path := filepath.Join(dst, a.Name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			log.Printf("attachment %s: %v", a.Name, err)
+			path = filepath.Join(attachmentsDir, a.Name)
+		}
+		fmt.Fprintf(&sb, "- %s (%s, %s)", path, firstNonEmpty(a.MimeType, a.Type), formatSize(len(data)))
+
+		switch {
+		case a.Type == "image" && visionMimeTypes[a.MimeType]:
+			taskImages = append(taskImages, taskImage{MimeType: a.MimeType, Data: data})
+			sb.WriteString(", shown to you with this message")
+		case a.Type == "audio" && tr != nil:
+			text, err := tr.transcribe(ctx, a.Name, data)
+			if err != nil {
+				log.Printf("transcribing %s: %v", a.Name, err)
+				sb.WriteString(", could not be transcribed")
+				break
+			}
+			log.Printf("transcribed %s (%d characters)", a.Name, len(text))
+			sb.WriteString(". Transcript:\n\n  > " + strings.ReplaceAll(strings.TrimSpace(text), "\n", "\n  > "))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// imagesRejected reports whether err is a bad request, which is what models
+// without vision return for image input.
+func imagesRejected(err error) bool {
+	var pe *providerError
+	return errors.As(err, &pe) && pe.StatusCode == http.StatusBadRequest
+}
+
+// formatSize formats a file size for the task.
+func formatSize(n int) string {
+	if n < 1024 {
+		return fmt.Sprintf("%d bytes", n)
+	}
+	return fmt.Sprintf("%d KB", (n+1023)/1024)
+}
+
+// transcriber calls an OpenAI-compatible speech-to-text endpoint.
+type transcriber struct {
+	baseURL  string
+	model    string
+	language string
+	apiKey   string
+	client   *http.Client
+}
+
+// loadTranscriber returns the transcriber configured for the run, or nil
+// if transcription is off. Without its own key it uses fallbackKey, the
+// model's.
+func loadTranscriber(fallbackKey string) *transcriber {
+	if getEnv("TRANSCRIPTION_ENABLED", "") != "true" {
+		return nil
+	}
+	return &transcriber{
+		baseURL:  strings.TrimRight(getEnv("TRANSCRIPTION_BASE_URL", "https://api.openai.com/v1"), "/"),
+		model:    getEnv("TRANSCRIPTION_MODEL", "whisper-1"),
+		language: getEnv("TRANSCRIPTION_LANGUAGE", ""),
+		apiKey:   firstNonEmpty(readAPIKey(filepath.Join(fallbackSecretsDir, "transcription-auth")), fallbackKey),
+		client:   &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// transcribe returns the text spoken in an audio file.
+func (t *transcriber) transcribe(ctx context.Context, name string, data []byte) (string, error) {
+	// Telegram voice notes are Ogg files named .oga, which the OpenAI API
+	// only accepts as .ogg.
+	if strings.EqualFold(filepath.Ext(name), ".oga") {
+		name = strings.TrimSuffix(name, filepath.Ext(name)) + ".ogg"
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	fw, err := w.CreateFormFile("file", name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := fw.Write(data); err != nil {
+		return "", err
+	}
+	_ = w.WriteField("model", t.model)
+	if t.language != "" {
+		_ = w.WriteField("language", t.language)
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.baseURL+"/audio/transcriptions", &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	if t.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP %d: %s", resp.StatusCode, truncate(string(raw), 300))
+	}
+	var out struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return "", fmt.Errorf("decoding transcription: %w", err)
+	}
+	return out.Text, nil
+}
+
+// messageFile is a file attached to an outbound channel message. It
+// mirrors channel.Attachment.
+type messageFile struct {
+	Type     string `json:"type"`
+	Filename string `json:"filename"`
+	MimeType string `json:"mimeType,omitempty"`
+	Data     []byte `json:"data"`
+}
+
+// loadMessageFiles reads the files send_channel_message attaches. They must
+// be inside the workspace and together fit in one channel message.
+func loadMessageFiles(paths []string) ([]messageFile, error) {
+	root, err := filepath.EvalSymlinks(workspaceDir)
+	if err != nil {
+		return nil, err
+	}
+	var files []messageFile
+	total := 0
+	for _, p := range paths {
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(workspaceDir, p)
+		}
+		// Resolve links so a file cannot be sent from outside the
+		// workspace, such as a mounted secret.
+		resolved, err := filepath.EvalSymlinks(p)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(resolved, root+string(filepath.Separator)) {
+			return nil, fmt.Errorf("%s is not in %s", p, workspaceDir)
+		}
+		p = resolved
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.Mode().IsRegular() {
+			return nil, fmt.Errorf("%s is not a file", p)
+		}
+		total += int(info.Size())
+		if total > maxMessageFileBytes {
+			return nil, errors.New("the files are too large to send; they can be at most " + formatSize(maxMessageFileBytes) + " together")
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		mimeType := mime.TypeByExtension(filepath.Ext(p))
+		if mimeType == "" {
+			mimeType = http.DetectContentType(data)
+		}
+		mimeType, _, _ = mime.ParseMediaType(mimeType)
+		files = append(files, messageFile{
+			Type:     attachmentType(mimeType),
+			Filename: filepath.Base(p),
+			MimeType: mimeType,
+			Data:     data,
+		})
+	}
+	return files, nil
+}
+
+// attachmentType mirrors channel.AttachmentType.
+func attachmentType(mimeType string) string {
+	for _, t := range []string{"image", "audio", "video"} {
+		if strings.HasPrefix(mimeType, t+"/") {
+			return t
+		}
+	}
+	return "file"
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// memoryDir is where the updated memory is written for the IPC bridge to
+// send to the controller.
+var memoryDir = "/ipc/memory"
+
+// memoryUpdate mirrors ipc.MemoryUpdate.
+type memoryUpdate struct {
+	Content string `json:"content"`
+}
+
+// maxMemorySearchResults bounds the lines memory_search returns.
+const maxMemorySearchResults = 50
+
+// The run's working copy of MEMORY.md. The memory tools edit it and
+// flushMemoryUpdate sends it to the controller when the run ends.
+var (
+	memoryMu      sync.Mutex
+	memoryDoc     string
+	memoryChanged bool
+)
+
+// emptyMemory is the MEMORY.md the controller creates for a new instance.
+const emptyMemory = "# Agent Memory\n\nNo memories recorded yet."
+
+// loadMemory reads MEMORY.md into the working copy and returns it, or ""
+// if nothing has been recorded yet.
+func loadMemory(path string) string {
+	memoryMu.Lock()
+	defer memoryMu.Unlock()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	memoryDoc = strings.TrimSpace(string(b))
+	memoryChanged = false
+	log.Printf("loaded memory (%d bytes)", len(memoryDoc))
+	if memoryDoc == emptyMemory {
+		// Keep the title so the first entries land under it.
+		memoryDoc = "# Agent Memory"
+		return ""
+	}
+	return memoryDoc
+}
+
+// flushMemoryUpdate writes the working copy to /ipc/memory/update.json if
+// the memory tools changed it. It must run before result.json is written,
+// as the bridge stops soon after.
+func flushMemoryUpdate() {
+	memoryMu.Lock()
+	defer memoryMu.Unlock()
+	if !memoryChanged {
+		return
+	}
+	if err := os.MkdirAll(memoryDir, 0o755); err != nil {
+		log.Printf("failed to create memory dir: %v", err)
+		return
+	}
+	if err := writeJSONAtomic(filepath.Join(memoryDir, "update.json"), memoryUpdate{Content: memoryDoc + "\n"}); err != nil {
+		log.Printf("failed to write memory update: %v", err)
+		return
+	}
+	memoryChanged = false
+	log.Printf("sent memory update (%d bytes)", len(memoryDoc))
+}
+
+// markdownHeading returns the level and title of a markdown heading line.
+func markdownHeading(line string) (int, string, bool) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || (level < len(line) && line[level] != ' ') {
+		return 0, "", false
+	}
+	return level, strings.TrimSpace(line[level:]), true
+}
+
+// headingTitle normalises a section name given by the model, which may
+// include the leading #s.
+func headingTitle(s string) string {
+	s = strings.TrimSpace(s)
+	if _, title, ok := markdownHeading(s); ok {
+		return title
+	}
+	return s
+}
+
+// memoryHeadings returns the heading level of each line, or 0. Lines in
+// code fences are not headings.
+func memoryHeadings(lines []string) []int {
+	levels := make([]int, len(lines))
+	fenced := false
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fenced = !fenced
+			continue
+		}
+		if level, _, ok := markdownHeading(line); ok && !fenced {
+			levels[i] = level
+		}
+	}
+	return levels
+}
+
+// findMemorySection returns the line range of the section titled heading:
+// its heading line and the lines up to the next heading of the same or a
+// higher level.
+func findMemorySection(lines []string, heading string) (start, end int, ok bool) {
+	want := headingTitle(heading)
+	levels := memoryHeadings(lines)
+	for i, level := range levels {
+		if level == 0 {
+			continue
+		}
+		if _, title, _ := markdownHeading(lines[i]); !strings.EqualFold(title, want) {
+			continue
+		}
+		end = len(lines)
+		for j := i + 1; j < len(lines); j++ {
+			if levels[j] > 0 && levels[j] <= level {
+				end = j
+				break
+			}
+		}
+		return i, end, true
+	}
+	return 0, 0, false
+}
+
+// trimBlankLines drops blank lines at the end of lines.
+func trimBlankLines(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func isListItem(line string) bool {
+	line = strings.TrimSpace(line)
+	return strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ")
+}
+
+// setMemory replaces the working copy. The caller holds memoryMu.
+func setMemory(lines []string) {
+	doc := strings.TrimSpace(strings.Join(lines, "\n"))
+	if doc != memoryDoc {
+		memoryDoc = doc
+		memoryChanged = true
+	}
+}
+
+// memoryReadTool returns the whole memory or one section of it.
+func memoryReadTool(args map[string]any) string {
+	section, _ := args["section"].(string)
+	memoryMu.Lock()
+	defer memoryMu.Unlock()
+	if memoryDoc == "" {
+		return "Memory is empty."
+	}
+	if strings.TrimSpace(section) == "" {
+		return memoryDoc
+	}
+	lines := strings.Split(memoryDoc, "\n")
+	start, end, ok := findMemorySection(lines, section)
+	if !ok {
+		return fmt.Sprintf("No memory section %q.", headingTitle(section))
+	}
+	return strings.Join(trimBlankLines(lines[start:end]), "\n")
+}
+
+// memoryAppendTool adds text to the end of a section, creating it if
+// needed, or to the end of the memory.
+func memoryAppendTool(args map[string]any) string {
+	text, _ := args["text"].(string)
+	section, _ := args["section"].(string)
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "Error: 'text' is required"
+	}
+	memoryMu.Lock()
+	defer memoryMu.Unlock()
+
+	var lines []string
+	if memoryDoc != "" {
+		lines = strings.Split(memoryDoc, "\n")
+	}
+	add := strings.Split(text, "\n")
+	if strings.TrimSpace(section) == "" {
+		lines = trimBlankLines(lines)
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		setMemory(append(lines, add...))
+		return "Appended to memory."
+	}
+
+	start, end, ok := findMemorySection(lines, section)
+	if !ok {
+		lines = trimBlankLines(lines)
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "## "+headingTitle(section), "")
+		setMemory(append(lines, add...))
+		return fmt.Sprintf("Created memory section %q.", headingTitle(section))
+	}
+	// List items continue the section's list; other text is a new paragraph.
+	body := trimBlankLines(append([]string(nil), lines[start:end]...))
+	if len(body) == 1 || !isListItem(body[len(body)-1]) || !isListItem(add[0]) {
+		body = append(body, "")
+	}
+	body = append(body, add...)
+	if end < len(lines) {
+		body = append(body, "")
+	}
+	setMemory(append(append(lines[:start:start], body...), lines[end:]...))
+	return fmt.Sprintf("Appended to memory section %q.", headingTitle(section))
+}
+
+// memoryUpdateSectionTool replaces the content of a section, creating it if
+// needed. Empty content removes the section.
+func memoryUpdateSectionTool(args map[string]any) string {
+	heading, _ := args["heading"].(string)
+	content, _ := args["content"].(string)
+	if strings.TrimSpace(heading) == "" {
+		return "Error: 'heading' is required"
+	}
+	title := headingTitle(heading)
+	content = strings.TrimSpace(content)
+	memoryMu.Lock()
+	defer memoryMu.Unlock()
+
+	var lines []string
+	if memoryDoc != "" {
+		lines = strings.Split(memoryDoc, "\n")
+	}
+	start, end, ok := findMemorySection(lines, heading)
+	if !ok {
+		if content == "" {
+			return fmt.Sprintf("No memory section %q.", title)
+		}
+		lines = trimBlankLines(lines)
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		setMemory(append(append(lines, "## "+title, ""), strings.Split(content, "\n")...))
+		return fmt.Sprintf("Created memory section %q.", title)
+	}
+
+	var section []string
+	if content != "" {
+		section = append([]string{lines[start], ""}, strings.Split(content, "\n")...)
+		if end < len(lines) {
+			section = append(section, "")
+		}
+	}
+	setMemory(append(append(lines[:start:start], section...), lines[end:]...))
+	if content == "" {
+		return fmt.Sprintf("Removed memory section %q.", title)
+	}
+	return fmt.Sprintf("Updated memory section %q.", title)
+}
+
+// memorySearchTool returns the lines of memory containing query, with the
+// section each is in.
+func memorySearchTool(args map[string]any) string {
+	query, _ := args["query"].(string)
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return "Error: 'query' is required"
+	}
+	memoryMu.Lock()
+	defer memoryMu.Unlock()
+
+	lines := strings.Split(memoryDoc, "\n")
+	levels := memoryHeadings(lines)
+	var sb strings.Builder
+	section, found := "", 0
+	for i, line := range lines {
+		if levels[i] > 0 {
+			_, section, _ = markdownHeading(line)
+		}
+		if !strings.Contains(strings.ToLower(line), query) {
+			continue
+		}
+		if found == maxMemorySearchResults {
+			sb.WriteString("(more matches omitted; narrow the query)\n")
+			break
+		}
+		found++
+		if section != "" {
+			fmt.Fprintf(&sb, "%d [%s]: %s\n", i+1, section, strings.TrimSpace(line))
+		} else {
+			fmt.Fprintf(&sb, "%d: %s\n", i+1, strings.TrimSpace(line))
+		}
+	}
+	if found == 0 {
+		return fmt.Sprintf("No memory lines match %q.", query)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
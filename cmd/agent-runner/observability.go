@@ -38,6 +38,7 @@ type agentObservability struct {
 	outTok          metric.Int64Counter
 	toolInvocations metric.Int64Counter
 	skillDurMs      metric.Float64Histogram
+	skillLoads      metric.Int64Counter
 }
 
 var obs = &agentObservability{
@@ -252,6 +253,10 @@ func (o *agentObservability) initMetrics() {
 	if err != nil {
 		log.Printf("failed creating metric sympozium.skill.duration: %v", err)
 	}
+	o.skillLoads, err = meter.Int64Counter("sympozium.skill.loads")
+	if err != nil {
+		log.Printf("failed creating metric sympozium.skill.loads: %v", err)
+	}
 }
 
 func (o *agentObservability) startRunSpan(ctx context.Context, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
@@ -330,6 +335,16 @@ func (o *agentObservability) recordSkillDuration(ctx context.Context, skillName
 	))
 }
 
+func (o *agentObservability) recordSkillLoad(ctx context.Context, skillName string) {
+	if o == nil || !o.enabled || o.skillLoads == nil {
+		return
+	}
+	o.skillLoads.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("skill_name", skillName),
+		attribute.String("instance", getEnv("INSTANCE_NAME", "")),
+	))
+}
+
 func markSpanError(span trace.Span, err error) {
 	if span == nil || err == nil {
 		return
@@ -1,14 +1,129 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 )
 
 const defaultSkillsDir = "/skills"
 
+// skillEntry is a skill listed in the system prompt's skill index.
+type skillEntry struct {
+	Name        string
+	Description string
+	Path        string
+}
+
+// skillIndex holds the skills load_skill can fetch, keyed by name.
+var skillIndex = map[string]skillEntry{}
+
+// Skills fetched with load_skill during this run, in order.
+var (
+	loadedSkillsMu sync.Mutex
+	loadedSkills   []string
+)
+
+// loadSkillIndex reads the name and description of every skill file in
+// skillsDir and registers them for load_skill.
+func loadSkillIndex(skillsDir string) []skillEntry {
+	if skillsDir == "" {
+		skillsDir = defaultSkillsDir
+	}
+	entries, err := os.ReadDir(skillsDir)
+	if err != nil {
+		log.Printf("No skills directory at %s: %v", skillsDir, err)
+		return nil
+	}
+
+	var index []skillEntry
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(skillsDir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("Failed to read skill file %s: %v", path, err)
+			continue
+		}
+		if strings.TrimSpace(string(data)) == "" {
+			continue
+		}
+		e := skillEntry{
+			Name:        strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())),
+			Description: skillDescription(string(data)),
+			Path:        path,
+		}
+		skillIndex[e.Name] = e
+		index = append(index, e)
+	}
+	sort.Slice(index, func(i, j int) bool { return index[i].Name < index[j].Name })
+	if len(index) > 0 {
+		log.Printf("Indexed %d skill(s) from %s", len(index), skillsDir)
+	}
+	return index
+}
+
+// skillDescription returns the "> description" line the SkillPack
+// controller writes under a skill's title, or else the first line of prose.
+func skillDescription(content string) string {
+	var firstLine string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if desc, ok := strings.CutPrefix(line, "> "); ok {
+			return truncateStr(desc, 200)
+		}
+		if firstLine == "" && line != "" && !strings.HasPrefix(line, "#") {
+			firstLine = line
+		}
+	}
+	return truncateStr(firstLine, 200)
+}
+
+// loadSkillTool returns the full instructions of an indexed skill.
+func loadSkillTool(ctx context.Context, args map[string]any) string {
+	name, _ := args["name"].(string)
+	e, ok := skillIndex[strings.TrimSuffix(strings.TrimSpace(name), ".md")]
+	if !ok {
+		names := make([]string, 0, len(skillIndex))
+		for n := range skillIndex {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Sprintf("Error: unknown skill %q. Available skills: %s", name, strings.Join(names, ", "))
+	}
+	data, err := os.ReadFile(e.Path)
+	if err != nil {
+		return fmt.Sprintf("Error reading skill %s: %v", e.Name, err)
+	}
+
+	loadedSkillsMu.Lock()
+	seen := false
+	for _, n := range loadedSkills {
+		seen = seen || n == e.Name
+	}
+	if !seen {
+		loadedSkills = append(loadedSkills, e.Name)
+	}
+	loadedSkillsMu.Unlock()
+	obs.recordSkillLoad(ctx, e.Name)
+	log.Printf("loaded skill %s (%d bytes)", e.Name, len(data))
+	return strings.TrimSpace(string(data))
+}
+
+// skillsLoaded returns the skills fetched with load_skill so far.
+func skillsLoaded() []string {
+	loadedSkillsMu.Lock()
+	defer loadedSkillsMu.Unlock()
+	return append([]string(nil), loadedSkills...)
+}
+
 // loadSkills reads all skill files from the skills directory and returns
 // their concatenated content suitable for prepending to the system prompt.
 func loadSkills(skillsDir string) string {
@@ -54,13 +169,25 @@ func loadSkills(skillsDir string) string {
 }
 
 // buildSystemPrompt assembles the full system prompt from the base prompt,
-// loaded skills, and tool availability.
-func buildSystemPrompt(base string, skills string, toolsEnabled bool) string {
+// skills, and tool availability. Skills are given either in full or, when
+// load_skill is available, as an index of names and descriptions.
+func buildSystemPrompt(base string, skills string, index []skillEntry, toolsEnabled bool) string {
 	var sb strings.Builder
 
 	sb.WriteString(base)
 
-	if skills != "" {
+	if len(index) > 0 {
+		sb.WriteString("\n\n## Your Skills\n\n")
+		sb.WriteString("The following skills are available. When one is relevant to the task, call `load_skill` ")
+		sb.WriteString("with its name to read its full instructions before acting, then follow them:\n\n")
+		for _, e := range index {
+			fmt.Fprintf(&sb, "- **%s**", e.Name)
+			if e.Description != "" {
+				sb.WriteString(": " + e.Description)
+			}
+			sb.WriteString("\n")
+		}
+	} else if skills != "" {
 		sb.WriteString("\n\n## Your Skills\n\n")
 		sb.WriteString("The following skill instructions have been loaded. Follow them when they are relevant to the task:\n\n")
 		sb.WriteString(skills)
@@ -90,7 +217,7 @@ func buildSystemPrompt(base string, skills string, toolsEnabled bool) string {
 		sb.WriteString("Use this to save reports, create scripts, write configuration files, or produce any output artifacts.\n\n")
 		sb.WriteString("### Sending Messages Through Channels\n\n")
 		sb.WriteString("You have a `send_channel_message` tool that lets you send messages through connected channels ")
-		sb.WriteString("(WhatsApp, Telegram, Discord, Slack). Use it whenever the user asks you to notify someone, ")
+		sb.WriteString("(WhatsApp, Telegram, Discord, Slack, Matrix). Use it whenever the user asks you to notify someone, ")
 		sb.WriteString("send a summary, or deliver any message. You can send to specific chat IDs, phone numbers, ")
 		sb.WriteString("or leave the chatId empty to send to the device owner.\n")
 		sb.WriteString("For WhatsApp, use the phone number in international format without + (e.g. '447450248165' for +44 7450 248165).\n")
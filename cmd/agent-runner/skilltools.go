@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// skillToolConfig mirrors the controller's SKILL_TOOLS entries. The command
+// template is held by the sidecar, not the agent.
+type skillToolConfig struct {
+	Name           string         `json:"name"`
+	Description    string         `json:"description"`
+	Parameters     map[string]any `json:"parameters,omitempty"`
+	TimeoutSeconds int            `json:"timeoutSeconds,omitempty"`
+	SkillPack      string         `json:"skillPack"`
+}
+
+// skillTools maps declarative SkillPack tool names to their definitions.
+var skillTools = map[string]skillToolConfig{}
+
+// loadSkillTools parses SKILL_TOOLS and registers the tools, skipping any
+// whose name is already taken.
+func loadSkillTools(raw string) []ToolDef {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	var configs []skillToolConfig
+	if err := json.Unmarshal([]byte(raw), &configs); err != nil {
+		log.Printf("WARNING: invalid SKILL_TOOLS, no skill tools will be available: %v", err)
+		return nil
+	}
+
+	taken := map[string]bool{}
+	for _, t := range defaultTools() {
+		taken[t.Name] = true
+	}
+	var defs []ToolDef
+	for _, c := range configs {
+		if taken[c.Name] || mcpTools[c.Name].client != nil {
+			log.Printf("WARNING: skill tool %s from %s clashes with another tool, skipping", c.Name, c.SkillPack)
+			continue
+		}
+		taken[c.Name] = true
+		params := c.Parameters
+		if params == nil {
+			params = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+		skillTools[c.Name] = c
+		defs = append(defs, ToolDef{Name: c.Name, Description: c.Description, Parameters: params})
+	}
+	if len(defs) > 0 {
+		log.Printf("skill tools registered: %d", len(defs))
+	}
+	return defs
+}
+
+// runSkillTool asks the SkillPack's sidecar to render the tool's command
+// template with args and run it.
+func runSkillTool(ctx context.Context, t skillToolConfig, args map[string]any) string {
+	if required, ok := t.Parameters["required"].([]any); ok {
+		for _, r := range required {
+			name, _ := r.(string)
+			if v, ok := args[name]; name != "" && (!ok || v == nil) {
+				return fmt.Sprintf("Error: '%s' is required", name)
+			}
+		}
+	}
+
+	timeoutSec := t.TimeoutSeconds
+	if timeoutSec <= 0 {
+		timeoutSec = 30
+	}
+	if timeoutSec > 120 {
+		timeoutSec = 120
+	}
+	if args == nil {
+		args = map[string]any{}
+	}
+	result, err := runExec(execRequest{
+		Tool:      t.Name,
+		Arguments: args,
+		WorkDir:   "/workspace",
+		Timeout:   timeoutSec,
+		Meta:      traceMetadata(ctx),
+	})
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	return formatExecResult(result)
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// spawnDir is where spawn requests are written and sub-agent results are
+// delivered by the IPC bridge.
+var spawnDir = "/ipc/spawn"
+
+// spawnPollInterval is how often a waiting spawn checks for results.
+var spawnPollInterval = time.Second
+
+// spawnWaitMargin is how much of the run's time a sub-agent wait leaves
+// so the agent can still use what it collected.
+const spawnWaitMargin = 30 * time.Second
+
+// defaultSpawnWait bounds a wait when the run has no deadline.
+const defaultSpawnWait = 5 * time.Minute
+
+// subagentSpawns counts spawn requests for the run's result metrics.
+var subagentSpawns atomic.Int32
+
+// spawnRequest mirrors ipc.SpawnRequest.
+type spawnRequest struct {
+	ID             string   `json:"id"`
+	Task           string   `json:"task"`
+	SystemPrompt   string   `json:"systemPrompt,omitempty"`
+	AgentID        string   `json:"agentId"`
+	Skills         []string `json:"skills,omitempty"`
+	TargetInstance string   `json:"targetInstance,omitempty"`
+}
+
+// spawnResult mirrors ipc.SpawnResult.
+type spawnResult struct {
+	ID      string `json:"id"`
+	RunName string `json:"runName,omitempty"`
+	Status  string `json:"status"`
+	Result  string `json:"result,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// spawnAgentTool asks the control plane for a sub-agent. By default it
+// waits for the sub-agent to finish and returns its reply; with wait=false
+// it returns the spawn ID for a later wait_for_agents call.
+func spawnAgentTool(ctx context.Context, args map[string]any) string {
+	task, _ := args["task"].(string)
+	if strings.TrimSpace(task) == "" {
+		return "Error: 'task' is required — describe what the sub-agent should do"
+	}
+	systemPrompt, _ := args["systemPrompt"].(string)
+	var skills []string
+	if raw, ok := args["skills"].([]any); ok {
+		for _, s := range raw {
+			if name, ok := s.(string); ok && name != "" {
+				skills = append(skills, name)
+			}
+		}
+	}
+
+	req := spawnRequest{
+		ID:           newRequestID(),
+		Task:         task,
+		SystemPrompt: systemPrompt,
+		Skills:       skills,
+	}
+	if err := writeSpawnRequest(req); err != nil {
+		return fmt.Sprintf("Error writing spawn request: %v", err)
+	}
+	subagentSpawns.Add(1)
+	log.Printf("Wrote spawn request %s: %s", req.ID, truncateStr(task, 100))
+
+	if wait, ok := args["wait"].(bool); ok && !wait {
+		return fmt.Sprintf("Sub-agent requested with id %q. Call wait_for_agents with this id to collect its result.", req.ID)
+	}
+	return waitForAgents(ctx, []string{req.ID}, spawnWaitTimeout(ctx, args))
+}
+
+// sendAgentMessageTool asks the agent of another SympoziumInstance a
+// question and returns its reply. Whether the instances may talk is decided
+// by their SympoziumPolicies.
+func sendAgentMessageTool(ctx context.Context, args map[string]any) string {
+	instance, _ := args["instance"].(string)
+	message, _ := args["message"].(string)
+	if instance == "" {
+		return "Error: 'instance' is required — the name of the SympoziumInstance to message"
+	}
+	if strings.TrimSpace(message) == "" {
+		return "Error: 'message' is required"
+	}
+
+	req := spawnRequest{ID: newRequestID(), Task: message, TargetInstance: instance}
+	if err := writeSpawnRequest(req); err != nil {
+		return fmt.Sprintf("Error writing agent message: %v", err)
+	}
+	log.Printf("Sent agent message %s to %s: %s", req.ID, instance, truncateStr(message, 100))
+
+	timeout := spawnWaitTimeout(ctx, args)
+	res, ok := collectSpawnResults(ctx, []string{req.ID}, timeout)[req.ID]
+	switch {
+	case !ok:
+		return fmt.Sprintf("Error: no reply from %s within %s", instance, timeout)
+	case res.Status == "succeeded":
+		return fmt.Sprintf("Reply from %s (%s):\n%s", instance, res.RunName, res.Result)
+	case res.Status == "rejected":
+		return fmt.Sprintf("Error: message to %s was not delivered: %s", instance, res.Error)
+	default:
+		return fmt.Sprintf("Error: the %s agent failed (%s): %s", instance, res.RunName, res.Error)
+	}
+}
+
+// writeSpawnRequest writes /ipc/spawn/request-<id>.json for the bridge.
+func writeSpawnRequest(req spawnRequest) error {
+	if err := os.MkdirAll(spawnDir, 0o755); err != nil {
+		return err
+	}
+	return writeJSONAtomic(filepath.Join(spawnDir, "request-"+req.ID+".json"), req)
+}
+
+// waitForAgentsTool collects the results of sub-agents started with
+// spawn_agent(wait=false).
+func waitForAgentsTool(ctx context.Context, args map[string]any) string {
+	var ids []string
+	if raw, ok := args["ids"].([]any); ok {
+		for _, v := range raw {
+			if id, ok := v.(string); ok && id != "" && filepath.Base(id) == id {
+				ids = append(ids, id)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return "Error: 'ids' is required — the ids returned by spawn_agent"
+	}
+	return waitForAgents(ctx, ids, spawnWaitTimeout(ctx, args))
+}
+
+// spawnWaitTimeout reads timeoutSeconds. A wait never runs past the run's
+// deadline less spawnWaitMargin, which is also the default.
+func spawnWaitTimeout(ctx context.Context, args map[string]any) time.Duration {
+	limit := defaultSpawnWait
+	if deadline, ok := ctx.Deadline(); ok {
+		limit = max(time.Until(deadline)-spawnWaitMargin, 0).Round(time.Second)
+	}
+	if v, ok := args["timeoutSeconds"].(float64); ok && v > 0 {
+		return min(time.Duration(v)*time.Second, limit)
+	}
+	return limit
+}
+
+// waitForAgents blocks until every spawn ID has a result or the timeout
+// passes, and formats whatever arrived.
+func waitForAgents(ctx context.Context, ids []string, timeout time.Duration) string {
+	return formatSpawnResults(ids, collectSpawnResults(ctx, ids, timeout), timeout)
+}
+
+// collectSpawnResults polls for result-<id>.json files until all have
+// arrived, the timeout passes or ctx is cancelled.
+func collectSpawnResults(ctx context.Context, ids []string, timeout time.Duration) map[string]spawnResult {
+	results := make(map[string]spawnResult, len(ids))
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(spawnPollInterval)
+	defer ticker.Stop()
+
+wait:
+	for {
+		for _, id := range ids {
+			if _, ok := results[id]; ok {
+				continue
+			}
+			b, err := os.ReadFile(filepath.Join(spawnDir, "result-"+id+".json"))
+			if err != nil {
+				continue
+			}
+			var res spawnResult
+			if err := json.Unmarshal(b, &res); err == nil && res.Status != "" {
+				results[id] = res
+				log.Printf("sub-agent %s (%s) %s", id, res.RunName, res.Status)
+			}
+		}
+		if len(results) == len(ids) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			break wait
+		case <-deadline.C:
+			break wait
+		case <-ticker.C:
+		}
+	}
+	return results
+}
+
+// formatSpawnResults renders sub-agent results as tool output, noting any
+// that are still running.
+func formatSpawnResults(ids []string, results map[string]spawnResult, timeout time.Duration) string {
+	var sb strings.Builder
+	for i, id := range ids {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		res, ok := results[id]
+		if !ok {
+			fmt.Fprintf(&sb, "Sub-agent %s: still running after %s. Call wait_for_agents again to keep waiting.", id, timeout)
+			continue
+		}
+		switch res.Status {
+		case "succeeded":
+			fmt.Fprintf(&sb, "Sub-agent %s (%s) succeeded:\n%s", id, res.RunName, res.Result)
+		case "rejected":
+			fmt.Fprintf(&sb, "Error: sub-agent %s was not started: %s", id, res.Error)
+		default:
+			fmt.Fprintf(&sb, "Error: sub-agent %s (%s) failed: %s", id, res.RunName, res.Error)
+		}
+	}
+	return sb.String()
+}
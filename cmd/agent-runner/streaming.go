@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/packages/respjson"
+)
+
+// Stream chunk types, mirroring ipc.StreamChunk.
+const (
+	chunkText       = "text"
+	chunkThinking   = "thinking"
+	chunkToolUse    = "tool_use"
+	chunkToolResult = "tool_result"
+)
+
+// maxToolResultChunk caps tool output copied into a tool_result chunk; the
+// model still receives the full result.
+const maxToolResultChunk = 2000
+
+type streamChunk struct {
+	Type     string `json:"type"`
+	Content  string `json:"content"`
+	ToolID   string `json:"toolId,omitempty"`
+	ToolName string `json:"toolName,omitempty"`
+	Index    int    `json:"index"`
+}
+
+// streamDir is where stream chunk files are written; the IPC bridge
+// watches it and publishes each new file.
+var streamDir = "/ipc/output"
+
+// Text and thinking deltas are gathered into one chunk for up to
+// streamCoalesceDelay, or until streamCoalesceBytes have arrived, so a long
+// run writes tens of chunks a second at most rather than one per token.
+const (
+	streamCoalesceDelay = 100 * time.Millisecond
+	streamCoalesceBytes = 4096
+)
+
+var (
+	streamMu sync.Mutex
+	// streamIndex is the next index handed out by emitStreamChunk.
+	streamIndex int
+	// pendingChunk gathers text or thinking deltas until flushed.
+	pendingChunk *streamChunk
+	pendingTimer *time.Timer
+)
+
+// writeStreamChunk adds content to the pending text or thinking chunk.
+func writeStreamChunk(chunkType, content string) {
+	if content == "" {
+		return
+	}
+	streamMu.Lock()
+	defer streamMu.Unlock()
+
+	if pendingChunk != nil && pendingChunk.Type != chunkType {
+		flushPendingChunk()
+	}
+	if pendingChunk == nil {
+		pendingChunk = &streamChunk{Type: chunkType}
+		pendingTimer = time.AfterFunc(streamCoalesceDelay, flushStream)
+	}
+	pendingChunk.Content += content
+	if len(pendingChunk.Content) >= streamCoalesceBytes {
+		flushPendingChunk()
+	}
+}
+
+// writeToolStreamChunk writes a tool_use or tool_result chunk.
+func writeToolStreamChunk(chunkType, toolName, toolID, content string) {
+	streamMu.Lock()
+	defer streamMu.Unlock()
+
+	flushPendingChunk()
+	emitStreamChunk(streamChunk{Type: chunkType, Content: content, ToolID: toolID, ToolName: toolName})
+}
+
+// flushStream writes the pending text or thinking chunk, if any.
+func flushStream() {
+	streamMu.Lock()
+	defer streamMu.Unlock()
+	flushPendingChunk()
+}
+
+// streamedChunks returns the number of stream chunks written so far,
+// counting one still gathering deltas.
+func streamedChunks() int {
+	streamMu.Lock()
+	defer streamMu.Unlock()
+	if pendingChunk != nil {
+		return streamIndex + 1
+	}
+	return streamIndex
+}
+
+// flushPendingChunk writes the pending chunk. streamMu must be held.
+func flushPendingChunk() {
+	if pendingChunk == nil {
+		return
+	}
+	pendingTimer.Stop()
+	c := *pendingChunk
+	pendingChunk = nil
+	emitStreamChunk(c)
+}
+
+// emitStreamChunk assigns the next index and writes stream-<n>.json.
+// streamMu must be held.
+func emitStreamChunk(c streamChunk) {
+	c.Index = streamIndex
+	streamIndex++
+
+	path := filepath.Join(streamDir, fmt.Sprintf("stream-%d.json", c.Index))
+	if err := writeJSONAtomic(path, c); err != nil {
+		log.Printf("WARNING: failed to publish stream chunk %d: %v", c.Index, err)
+	}
+
+	switch c.Type {
+	case chunkThinking, chunkToolResult:
+		writeStatus(statusThinking, "", "")
+	case chunkToolUse:
+		writeStatus(statusToolUse, c.ToolName, c.ToolID)
+	case chunkText:
+		writeStatus(statusResponding, "", "")
+	}
+}
+
+// Agent status phases, mirroring ipc.StatusUpdate.
+const (
+	statusThinking   = "thinking"
+	statusToolUse    = "tool_use"
+	statusResponding = "responding"
+)
+
+type statusUpdate struct {
+	Phase   string `json:"phase"`
+	Message string `json:"message,omitempty"`
+	ToolID  string `json:"toolId,omitempty"`
+}
+
+var (
+	statusMu   sync.Mutex
+	lastStatus statusUpdate
+)
+
+// writeStatus writes status.json for the IPC bridge when the agent's phase
+// or tool changes. Channels show it as a typing indicator.
+func writeStatus(phase, message, toolID string) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+
+	st := statusUpdate{Phase: phase, Message: message, ToolID: toolID}
+	if st == lastStatus {
+		return
+	}
+	lastStatus = st
+	if err := writeJSONAtomic(filepath.Join(streamDir, "status.json"), st); err != nil {
+		log.Printf("WARNING: failed to write status: %v", err)
+	}
+}
+
+// nonStreamingBody is request middleware that captures a complete JSON
+// response when a server ignores "stream": true (some proxies and
+// OpenAI-compatible servers do). The caller decodes *dst instead of the
+// (then empty) event stream.
+func nonStreamingBody(dst *[]byte) func(*http.Request, func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	return func(req *http.Request, next func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+		res, err := next(req)
+		if err != nil || res.StatusCode >= 300 || !strings.HasPrefix(res.Header.Get("Content-Type"), "application/json") {
+			return res, err
+		}
+		body, err := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		*dst = body
+		res.Body = io.NopCloser(bytes.NewReader(nil))
+		return res, nil
+	}
+}
+
+// streamError maps an error event received mid-stream onto a providerError
+// so overload and rate-limit errors still trigger model failover.
+func streamError(provider string, err error) error {
+	msg := err.Error()
+	lower := strings.ToLower(msg)
+	switch {
+	case strings.Contains(lower, "overloaded"):
+		return &providerError{StatusCode: 529, msg: fmt.Sprintf("%s API error (HTTP 529): %s", provider, truncate(msg, 500))}
+	case strings.Contains(lower, "rate_limit"):
+		return &providerError{StatusCode: 429, msg: fmt.Sprintf("%s API error (HTTP 429): %s", provider, truncate(msg, 500))}
+	default:
+		return fmt.Errorf("%s API error: %w", provider, err)
+	}
+}
+
+// openAITurn is one assistant turn assembled from a chat completion stream
+// or from a complete (non-streamed) response.
+type openAITurn struct {
+	Content      string
+	ToolCalls    []openAIToolCall
+	FinishReason string
+	Usage        openai.CompletionUsage
+	hasChoice    bool
+}
+
+type openAIToolCall struct {
+	ID        string
+	Name      string
+	Arguments string
+}
+
+// addToolCallDelta merges a streamed tool-call fragment by its index.
+func (t *openAITurn) addToolCallDelta(index int, id, name, args string) {
+	for len(t.ToolCalls) <= index {
+		t.ToolCalls = append(t.ToolCalls, openAIToolCall{})
+	}
+	tc := &t.ToolCalls[index]
+	if id != "" {
+		tc.ID = id
+	}
+	tc.Name += name
+	tc.Arguments += args
+}
+
+// assistantParam rebuilds the assistant message for the conversation history.
+func (t *openAITurn) assistantParam() openai.ChatCompletionMessageParamUnion {
+	var asst openai.ChatCompletionAssistantMessageParam
+	if t.Content != "" {
+		asst.Content.OfString = openai.String(t.Content)
+	}
+	for _, tc := range t.ToolCalls {
+		asst.ToolCalls = append(asst.ToolCalls, openai.ChatCompletionMessageToolCallUnionParam{
+			OfFunction: &openai.ChatCompletionMessageFunctionToolCallParam{
+				ID: tc.ID,
+				Function: openai.ChatCompletionMessageFunctionToolCallFunctionParam{
+					Name:      tc.Name,
+					Arguments: tc.Arguments,
+				},
+			},
+		})
+	}
+	return openai.ChatCompletionMessageParamUnion{OfAssistant: &asst}
+}
+
+// openAITurnFromCompletion converts a complete response into a turn and
+// streams its thinking and text so output looks the same either way.
+func openAITurnFromCompletion(body []byte) (openAITurn, error) {
+	var turn openAITurn
+	var completion openai.ChatCompletion
+	if err := json.Unmarshal(body, &completion); err != nil {
+		return turn, fmt.Errorf("decoding completion response: %w", err)
+	}
+	turn.Usage = completion.Usage
+	if len(completion.Choices) == 0 {
+		return turn, nil
+	}
+	choice := completion.Choices[0]
+	turn.hasChoice = true
+	turn.Content = choice.Message.Content
+	turn.FinishReason = choice.FinishReason
+	for _, tc := range choice.Message.ToolCalls {
+		fc := tc.AsFunction()
+		turn.ToolCalls = append(turn.ToolCalls, openAIToolCall{ID: fc.ID, Name: fc.Function.Name, Arguments: fc.Function.Arguments})
+	}
+	writeStreamChunk(chunkThinking, openAIReasoningContent(choice.Message.JSON.ExtraFields))
+	writeStreamChunk(chunkText, turn.Content)
+	return turn, nil
+}
+
+// openAIReasoningContent extracts a reasoning trace from the extra
+// "reasoning_content" or "reasoning" fields some compatible servers add to
+// messages and stream deltas.
+func openAIReasoningContent(extra map[string]respjson.Field) string {
+	for _, key := range []string{"reasoning_content", "reasoning"} {
+		field, ok := extra[key]
+		if !ok || !field.Valid() {
+			continue
+		}
+		var text string
+		if err := json.Unmarshal([]byte(field.Raw()), &text); err == nil && text != "" {
+			return text
+		}
+	}
+	return ""
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/openai/openai-go/v3/shared"
+)
+
+// Thinking modes accepted in THINKING_MODE (mirrors ModelSpec.Thinking).
+const (
+	thinkingOff    = "off"
+	thinkingLow    = "low"
+	thinkingMedium = "medium"
+	thinkingHigh   = "high"
+)
+
+// anthropicMaxTokens is the output budget for a normal (non-thinking) turn.
+// When extended thinking is on, the thinking budget is added on top so the
+// model always has room for a visible answer.
+const anthropicMaxTokens = 8192
+
+// anthropicThinkingBudgets maps a thinking mode to the Anthropic extended
+// thinking budget_tokens. The API requires a budget of at least 1024, and
+// the SDK only accepts max_tokens above about 21k on streamed requests,
+// which callAnthropic always makes.
+var anthropicThinkingBudgets = map[string]int64{
+	thinkingLow:    2048,
+	thinkingMedium: 8192,
+	thinkingHigh:   24576,
+}
+
+// normalizeThinkingMode lower-cases the mode and maps unknown or empty
+// values to "off".
+func normalizeThinkingMode(mode string) string {
+	switch m := strings.ToLower(strings.TrimSpace(mode)); m {
+	case thinkingLow, thinkingMedium, thinkingHigh:
+		return m
+	default:
+		return thinkingOff
+	}
+}
+
+// anthropicThinkingBudget returns the budget_tokens for the given mode, or
+// 0 when extended thinking should stay disabled.
+func anthropicThinkingBudget(mode string) int64 {
+	return anthropicThinkingBudgets[normalizeThinkingMode(mode)]
+}
+
+// isOpenAIReasoningModel reports whether the model accepts the
+// reasoning_effort parameter (o-series and gpt-5 family). Sending it to
+// other chat models is rejected by the API, so it is only set for these.
+func isOpenAIReasoningModel(model string) bool {
+	m := strings.ToLower(model)
+	// Azure / proxy deployments are often prefixed, e.g. "openai/o3-mini".
+	if i := strings.LastIndex(m, "/"); i >= 0 {
+		m = m[i+1:]
+	}
+	for _, prefix := range []string{"o1", "o3", "o4", "gpt-5"} {
+		if strings.HasPrefix(m, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// openAIReasoningEffort maps a thinking mode to reasoning_effort. It returns
+// "" when the mode is off or the model is not a reasoning model.
+func openAIReasoningEffort(model, mode string) shared.ReasoningEffort {
+	if !isOpenAIReasoningModel(model) {
+		return ""
+	}
+	switch normalizeThinkingMode(mode) {
+	case thinkingLow:
+		return shared.ReasoningEffortLow
+	case thinkingMedium:
+		return shared.ReasoningEffortMedium
+	case thinkingHigh:
+		return shared.ReasoningEffortHigh
+	default:
+		return ""
+	}
+}
+
+// estimateTokens gives a rough token count for text (~4 chars per token).
+// The Anthropic Messages API folds thinking into output_tokens, so this is
+// used to report an estimate of the thinking share.
+func estimateTokens(s string) int {
+	if s == "" {
+		return 0
+	}
+	return (len(s) + 3) / 4
+}
@@ -12,19 +12,16 @@ import (
 
 func executeToolCallWithTelemetry(ctx context.Context, name, argsJSON, callID string) string {
 	start := time.Now()
+	writeToolStreamChunk(chunkToolUse, name, callID, argsJSON)
 	toolCtx, toolSpan := obs.startToolSpan(ctx,
 		attribute.String("gen_ai.tool.name", name),
 		attribute.String("gen_ai.tool.call.id", callID),
 	)
 
 	var result string
-	if name == ToolExecuteCommand {
+	if skill, attrs := skillSpanAttributes(name); skill != "" && activeToolPolicy.permits(name) {
 		skillStart := time.Now()
-		skillCtx, skillSpan := obs.startSkillSpan(toolCtx,
-			attribute.String("skill.name", "command-executor"),
-			attribute.String("sidecar.container", "tool-executor"),
-			attribute.String("rbac.scope", "namespace"),
-		)
+		skillCtx, skillSpan := obs.startSkillSpan(toolCtx, attrs...)
 		result = executeToolCall(skillCtx, name, argsJSON)
 		if strings.HasPrefix(result, "Error:") {
 			err := fmt.Errorf("%s", result)
@@ -34,12 +31,20 @@ func executeToolCallWithTelemetry(ctx context.Context, name, argsJSON, callID st
 		}
 		skillSpan.SetAttributes(attribute.Int64("duration_ms", time.Since(skillStart).Milliseconds()))
 		skillSpan.End()
-		obs.recordSkillDuration(toolCtx, "command-executor", time.Since(skillStart))
+		obs.recordSkillDuration(toolCtx, skill, time.Since(skillStart))
 	} else {
 		result = executeToolCall(toolCtx, name, argsJSON)
 	}
 
-	if strings.HasPrefix(result, "Error:") {
+	if !activeToolPolicy.permits(name) {
+		toolSpan.SetAttributes(attribute.Bool("sympozium.tool.denied", true))
+		markSpanError(toolSpan, fmt.Errorf("%s", result))
+		obs.recordToolInvocation(toolCtx, name, "denied")
+	} else if strings.HasPrefix(result, toolNotApprovedPrefix) {
+		toolSpan.SetAttributes(attribute.Bool("sympozium.tool.approved", false))
+		markSpanError(toolSpan, fmt.Errorf("%s", result))
+		obs.recordToolInvocation(toolCtx, name, "not_approved")
+	} else if strings.HasPrefix(result, "Error:") {
 		err := fmt.Errorf("%s", result)
 		markSpanError(toolSpan, err)
 		obs.recordToolInvocation(toolCtx, name, "error")
@@ -49,5 +54,40 @@ func executeToolCallWithTelemetry(ctx context.Context, name, argsJSON, callID st
 	}
 	toolSpan.SetAttributes(attribute.Int64("duration_ms", time.Since(start).Milliseconds()))
 	toolSpan.End()
+	writeToolStreamChunk(chunkToolResult, name, callID, truncate(result, maxToolResultChunk))
 	return result
 }
+
+// skillSpanAttributes returns the skill a tool runs in and its span
+// attributes, or "" for tools handled inside the agent container.
+func skillSpanAttributes(name string) (string, []attribute.KeyValue) {
+	if name == ToolExecuteCommand {
+		return "command-executor", []attribute.KeyValue{
+			attribute.String("skill.name", "command-executor"),
+			attribute.String("sidecar.container", "tool-executor"),
+			attribute.String("rbac.scope", "namespace"),
+		}
+	}
+	if t, ok := skillTools[name]; ok {
+		return t.SkillPack, []attribute.KeyValue{
+			attribute.String("skill.name", t.SkillPack),
+			attribute.String("skill.tool", name),
+			attribute.String("sidecar.container", "skill-"+t.SkillPack),
+		}
+	}
+	t, ok := mcpTools[name]
+	if !ok {
+		return "", nil
+	}
+	skill := "mcp:" + t.server.Name
+	attrs := []attribute.KeyValue{
+		attribute.String("skill.name", skill),
+		attribute.String("mcp.server", t.server.Name),
+		attribute.String("mcp.tool", t.name),
+		attribute.String("mcp.transport", t.server.Transport),
+	}
+	if t.server.Transport != "http" && t.server.SkillPack != "" {
+		attrs = append(attrs, attribute.String("sidecar.container", "skill-"+t.server.SkillPack))
+	}
+	return skill, attrs
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+)
+
+// Tool policy actions, matching sympoziumv1alpha1.ToolGatingRule.Action.
+const (
+	toolActionAllow = "allow"
+	toolActionDeny  = "deny"
+	toolActionAsk   = "ask"
+)
+
+// toolPolicy is the tool policy enforced for this run. It combines the
+// AgentRun's own ToolPolicy (TOOL_POLICY) with the instance's SympoziumPolicy
+// tool gating (TOOL_GATING); each call gets the stricter of the two actions.
+type toolPolicy struct {
+	Allow  []string    `json:"allow,omitempty"`
+	Deny   []string    `json:"deny,omitempty"`
+	Gating *toolGating `json:"-"`
+}
+
+// toolGating mirrors sympoziumv1alpha1.ToolGatingSpec.
+type toolGating struct {
+	DefaultAction   string           `json:"defaultAction,omitempty"`
+	Rules           []toolGatingRule `json:"rules,omitempty"`
+	ApprovalTimeout string           `json:"approvalTimeout,omitempty"`
+}
+
+type toolGatingRule struct {
+	Tool   string `json:"tool"`
+	Action string `json:"action"`
+}
+
+// activeToolPolicy is the policy enforced by executeToolCall. A nil policy
+// permits every tool.
+var activeToolPolicy *toolPolicy
+
+// loadToolPolicy parses TOOL_POLICY and TOOL_GATING. Invalid JSON denies
+// every tool rather than silently allowing everything.
+func loadToolPolicy(policyJSON, gatingJSON string) *toolPolicy {
+	if strings.TrimSpace(policyJSON) == "" && strings.TrimSpace(gatingJSON) == "" {
+		return nil
+	}
+	denyAll := &toolPolicy{Gating: &toolGating{DefaultAction: toolActionDeny}}
+	p := &toolPolicy{}
+	if strings.TrimSpace(policyJSON) != "" {
+		if err := json.Unmarshal([]byte(policyJSON), p); err != nil {
+			log.Printf("WARNING: invalid TOOL_POLICY, denying all tools: %v", err)
+			return denyAll
+		}
+	}
+	if strings.TrimSpace(gatingJSON) != "" {
+		p.Gating = &toolGating{}
+		if err := json.Unmarshal([]byte(gatingJSON), p.Gating); err != nil {
+			log.Printf("WARNING: invalid TOOL_GATING, denying all tools: %v", err)
+			return denyAll
+		}
+	}
+	return p
+}
+
+// action returns allow, deny or ask for a tool.
+func (p *toolPolicy) action(name string) string {
+	if p == nil {
+		return toolActionAllow
+	}
+	run := toolActionAllow
+	if slices.Contains(p.Deny, name) || (len(p.Allow) > 0 && !slices.Contains(p.Allow, name)) {
+		run = toolActionDeny
+	}
+	return stricterToolAction(run, p.Gating.action(name))
+}
+
+// action returns the gating action for a tool. When several rules match,
+// the strictest wins; unmatched tools get DefaultAction.
+func (g *toolGating) action(name string) string {
+	if g == nil {
+		return toolActionAllow
+	}
+	matched := ""
+	for _, r := range g.Rules {
+		if r.Tool == name {
+			matched = stricterToolAction(matched, normalizeToolAction(r.Action))
+		}
+	}
+	if matched != "" {
+		return matched
+	}
+	return normalizeToolAction(g.DefaultAction)
+}
+
+// normalizeToolAction maps an empty or unknown action to allow.
+func normalizeToolAction(action string) string {
+	switch a := strings.ToLower(strings.TrimSpace(action)); a {
+	case toolActionDeny, toolActionAsk:
+		return a
+	default:
+		return toolActionAllow
+	}
+}
+
+// stricterToolAction returns the more restrictive of two actions, treating
+// "" as no opinion.
+func stricterToolAction(a, b string) string {
+	rank := map[string]int{"": 0, toolActionAllow: 1, toolActionAsk: 2, toolActionDeny: 3}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+// permits reports whether a tool may be registered and called at all.
+// Tools that need approval are permitted; executeToolCall asks first.
+func (p *toolPolicy) permits(name string) bool {
+	return p.action(name) != toolActionDeny
+}
+
+// filterTools returns the tools the policy permits and the names of those
+// it removed.
+func (p *toolPolicy) filterTools(tools []ToolDef) (permitted []ToolDef, denied []string) {
+	for _, t := range tools {
+		if p.permits(t.Name) {
+			permitted = append(permitted, t)
+		} else {
+			denied = append(denied, t.Name)
+		}
+	}
+	return permitted, denied
+}
+
+// toolDeniedMessage is the tool result returned for a refused call.
+func toolDeniedMessage(name string) string {
+	return fmt.Sprintf("Error: tool %q is denied by the tool policy for this run", name)
+}
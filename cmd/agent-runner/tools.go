@@ -25,6 +25,14 @@ const (
 	ToolSendChannelMessage = "send_channel_message"
 	ToolFetchURL           = "fetch_url"
 	ToolScheduleTask       = "schedule_task"
+	ToolSpawnAgent         = "spawn_agent"
+	ToolWaitForAgents      = "wait_for_agents"
+	ToolSendAgentMessage   = "send_agent_message"
+	ToolLoadSkill          = "load_skill"
+	ToolMemoryRead         = "memory_read"
+	ToolMemoryAppend       = "memory_append"
+	ToolMemoryUpdate       = "memory_update_section"
+	ToolMemorySearch       = "memory_search"
 )
 
 // ToolDef describes a tool for LLM function calling.
@@ -92,16 +100,17 @@ func defaultTools() []ToolDef {
 		},
 		{
 			Name: ToolSendChannelMessage,
-			Description: "Send a message to the user via a connected channel (e.g. WhatsApp, Telegram, Discord, Slack). " +
+			Description: "Send a message to the user via a connected channel (e.g. WhatsApp, Telegram, Discord, Slack, Matrix). " +
 				"Use this when the user asks you to notify them, send a summary, or deliver any text outside of the task result. " +
+				"Files from /workspace, such as images or reports you created, can be attached. " +
 				"If no chatId is provided the message is sent to the device owner (self-chat).",
 			Parameters: map[string]any{
 				"type": "object",
 				"properties": map[string]any{
 					"channel": map[string]any{
 						"type":        "string",
-						"description": "Channel type to send through: whatsapp, telegram, discord, or slack.",
-						"enum":        []string{"whatsapp", "telegram", "discord", "slack"},
+						"description": "Channel type to send through: whatsapp, telegram, discord, slack, or matrix.",
+						"enum":        []string{"whatsapp", "telegram", "discord", "slack", "matrix"},
 					},
 					"text": map[string]any{
 						"type":        "string",
@@ -111,8 +120,13 @@ func defaultTools() []ToolDef {
 						"type":        "string",
 						"description": "Target chat or group ID. Leave empty to send to the device owner (self-chat).",
 					},
+					"files": map[string]any{
+						"type":        "array",
+						"items":       map[string]any{"type": "string"},
+						"description": "Paths of files under /workspace to attach, at most 640 KB together.",
+					},
 				},
-				"required": []string{"channel", "text"},
+				"required": []string{"channel"},
 			},
 		},
 		{
@@ -190,13 +204,183 @@ func defaultTools() []ToolDef {
 				"required": []string{"name", "action"},
 			},
 		},
+		{
+			Name: ToolSpawnAgent,
+			Description: "Delegate a self-contained subtask to a sub-agent that runs in its own pod with the same model, skills and tool policy. " +
+				"By default this waits for the sub-agent and returns its final reply. " +
+				"To run several sub-agents in parallel, call this with wait=false for each, then call wait_for_agents with the returned ids. " +
+				"Sub-agent depth, count and concurrency are limited by the instance.",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"task": map[string]any{
+						"type":        "string",
+						"description": "The complete task for the sub-agent. It does not see this conversation, so include all the context it needs.",
+					},
+					"systemPrompt": map[string]any{
+						"type":        "string",
+						"description": "Optional system prompt for the sub-agent.",
+					},
+					"skills": map[string]any{
+						"type":        "array",
+						"items":       map[string]any{"type": "string"},
+						"description": "Optional subset of this agent's skills to give the sub-agent. Defaults to all of them.",
+					},
+					"wait": map[string]any{
+						"type":        "boolean",
+						"description": "Wait for the sub-agent to finish (default true).",
+					},
+					"timeoutSeconds": map[string]any{
+						"type":        "integer",
+						"description": "How long to wait for the result (default: until shortly before this run times out).",
+					},
+				},
+				"required": []string{"task"},
+			},
+		},
+		{
+			Name:        ToolWaitForAgents,
+			Description: "Wait for sub-agents started with spawn_agent(wait=false) and return their results.",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"ids": map[string]any{
+						"type":        "array",
+						"items":       map[string]any{"type": "string"},
+						"description": "The ids returned by spawn_agent.",
+					},
+					"timeoutSeconds": map[string]any{
+						"type":        "integer",
+						"description": "How long to wait for all results (default: until shortly before this run times out).",
+					},
+				},
+				"required": []string{"ids"},
+			},
+		},
+		{
+			Name: ToolSendAgentMessage,
+			Description: "Ask the agent of another SympoziumInstance (for example a different persona) a question and wait for its reply. " +
+				"The other agent does not see this conversation, so make the message self-contained. " +
+				"Only instances allowed by the SympoziumPolicy can be messaged.",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"instance": map[string]any{
+						"type":        "string",
+						"description": "Name of the SympoziumInstance to message.",
+					},
+					"message": map[string]any{
+						"type":        "string",
+						"description": "The question or request for the other agent.",
+					},
+					"timeoutSeconds": map[string]any{
+						"type":        "integer",
+						"description": "How long to wait for the reply (default: until shortly before this run times out).",
+					},
+				},
+				"required": []string{"instance", "message"},
+			},
+		},
+		{
+			Name: ToolLoadSkill,
+			Description: "Load the full instructions of one of your skills, as listed under Your Skills in the system prompt. " +
+				"Call this before carrying out a task a skill covers.",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"name": map[string]any{
+						"type":        "string",
+						"description": "The skill name from the index (e.g. 'review-guidelines').",
+					},
+				},
+				"required": []string{"name"},
+			},
+		},
+		{
+			Name:        ToolMemoryRead,
+			Description: "Read your persistent memory (MEMORY.md), or one section of it.",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"section": map[string]any{
+						"type":        "string",
+						"description": "Heading of the section to read (e.g. 'User Preferences'). Omit to read everything.",
+					},
+				},
+			},
+		},
+		{
+			Name: ToolMemoryAppend,
+			Description: "Add a fact, preference or decision to your persistent memory so future runs know it. " +
+				"The text is added to the end of the section, which is created if it does not exist.",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"text": map[string]any{
+						"type":        "string",
+						"description": "Markdown to add, e.g. '- The user prefers metric units.'",
+					},
+					"section": map[string]any{
+						"type":        "string",
+						"description": "Heading of the section to add to. Omit to add to the end of the memory.",
+					},
+				},
+				"required": []string{"text"},
+			},
+		},
+		{
+			Name: ToolMemoryUpdate,
+			Description: "Replace the content of a section of your persistent memory, creating it if it does not exist. " +
+				"Use this to correct or condense what a section says. Empty content removes the section.",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"heading": map[string]any{
+						"type":        "string",
+						"description": "Heading of the section (e.g. 'Project Context').",
+					},
+					"content": map[string]any{
+						"type":        "string",
+						"description": "The new markdown content of the section, without its heading.",
+					},
+				},
+				"required": []string{"heading", "content"},
+			},
+		},
+		{
+			Name:        ToolMemorySearch,
+			Description: "Search your persistent memory for lines containing a word or phrase (case-insensitive).",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"query": map[string]any{
+						"type":        "string",
+						"description": "The text to look for.",
+					},
+				},
+				"required": []string{"query"},
+			},
+		},
 	}
 }
 
+// memoryTools are the tools that exist only when memory is enabled.
+var memoryTools = []string{ToolMemoryRead, ToolMemoryAppend, ToolMemoryUpdate, ToolMemorySearch}
+
 // executeToolCall dispatches a tool call and returns the result string.
 func executeToolCall(ctx context.Context, name string, argsJSON string) string {
 	log.Printf("tool call: %s args=%s", name, truncateStr(argsJSON, 200))
 
+	switch activeToolPolicy.action(name) {
+	case toolActionDeny:
+		log.Printf("tool call refused by policy: %s", name)
+		return toolDeniedMessage(name)
+	case toolActionAsk:
+		if resp := requestApproval(ctx, name, argsJSON, activeToolPolicy.approvalTimeout()); resp.Decision != approvalApproved {
+			return toolNotApprovedMessage(name, resp)
+		}
+	}
+
 	var args map[string]any
 	if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
 		return fmt.Sprintf("Error parsing tool arguments: %v", err)
@@ -217,7 +401,29 @@ func executeToolCall(ctx context.Context, name string, argsJSON string) string {
 		return fetchURLTool(args)
 	case ToolScheduleTask:
 		return scheduleTaskTool(args)
+	case ToolSpawnAgent:
+		return spawnAgentTool(ctx, args)
+	case ToolWaitForAgents:
+		return waitForAgentsTool(ctx, args)
+	case ToolSendAgentMessage:
+		return sendAgentMessageTool(ctx, args)
+	case ToolLoadSkill:
+		return loadSkillTool(ctx, args)
+	case ToolMemoryRead:
+		return memoryReadTool(args)
+	case ToolMemoryAppend:
+		return memoryAppendTool(args)
+	case ToolMemoryUpdate:
+		return memoryUpdateSectionTool(args)
+	case ToolMemorySearch:
+		return memorySearchTool(args)
 	default:
+		if t, ok := skillTools[name]; ok {
+			return runSkillTool(ctx, t, args)
+		}
+		if t, ok := mcpTools[name]; ok {
+			return callMCPTool(ctx, t, args)
+		}
 		return fmt.Sprintf("Unknown tool: %s", name)
 	}
 }
@@ -290,20 +496,34 @@ func sendChannelMessageTool(args map[string]any) string {
 	chatID, _ := args["chatId"].(string)
 
 	if channel == "" {
-		return "Error: 'channel' is required (whatsapp, telegram, discord, slack)"
+		return "Error: 'channel' is required (whatsapp, telegram, discord, slack, matrix)"
 	}
-	if text == "" {
-		return "Error: 'text' is required"
+	var paths []string
+	if raw, ok := args["files"].([]any); ok {
+		for _, v := range raw {
+			if s, ok := v.(string); ok && s != "" {
+				paths = append(paths, s)
+			}
+		}
+	}
+	if text == "" && len(paths) == 0 {
+		return "Error: 'text' or 'files' is required"
+	}
+	files, err := loadMessageFiles(paths)
+	if err != nil {
+		return fmt.Sprintf("Error attaching files: %v", err)
 	}
 
 	msg := struct {
-		Channel string `json:"channel"`
-		ChatID  string `json:"chatId,omitempty"`
-		Text    string `json:"text"`
+		Channel     string        `json:"channel"`
+		ChatID      string        `json:"chatId,omitempty"`
+		Text        string        `json:"text"`
+		Attachments []messageFile `json:"attachments,omitempty"`
 	}{
-		Channel: channel,
-		ChatID:  chatID,
-		Text:    text,
+		Channel:     channel,
+		ChatID:      chatID,
+		Text:        text,
+		Attachments: files,
 	}
 
 	data, err := json.Marshal(msg)
@@ -320,7 +540,7 @@ func sendChannelMessageTool(args map[string]any) string {
 		return fmt.Sprintf("Error writing message file: %v", err)
 	}
 
-	log.Printf("Wrote channel message: channel=%s chatId=%s len=%d", channel, chatID, len(text))
+	log.Printf("Wrote channel message: channel=%s chatId=%s len=%d files=%d", channel, chatID, len(text), len(files))
 	target := chatID
 	if target == "" {
 		target = "owner (self)"
@@ -607,7 +827,15 @@ type execRequest struct {
 	Args    []string          `json:"args,omitempty"`
 	WorkDir string            `json:"workDir,omitempty"`
 	Timeout int               `json:"timeout,omitempty"`
+	Stdin   string            `json:"stdin,omitempty"`
 	Meta    map[string]string `json:"_meta,omitempty"`
+	// Tool and Arguments name a SkillPack tool for the sidecar to render
+	// instead of running Command.
+	Tool      string         `json:"tool,omitempty"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+	// SkillPack, when set, addresses the request to that SkillPack's
+	// sidecar only.
+	SkillPack string `json:"skillPack,omitempty"`
 }
 
 // execResult matches the IPC ExecResult protocol.
@@ -619,6 +847,9 @@ type execResult struct {
 	TimedOut bool   `json:"timedOut,omitempty"`
 }
 
+// toolsDir is where exec requests are written for the skill sidecar.
+var toolsDir = "/ipc/tools"
+
 func executeCommand(ctx context.Context, args map[string]any) string {
 	command, _ := args["command"].(string)
 	if command == "" {
@@ -638,10 +869,7 @@ func executeCommand(ctx context.Context, args map[string]any) string {
 		timeoutSec = 120
 	}
 
-	id := fmt.Sprintf("%d", time.Now().UnixNano())
-
 	req := execRequest{
-		ID:      id,
 		Command: command,
 		Args:    nil,
 		WorkDir: workdir,
@@ -649,24 +877,38 @@ func executeCommand(ctx context.Context, args map[string]any) string {
 	}
 	req.Meta = traceMetadata(ctx)
 
-	toolsDir := "/ipc/tools"
-	reqPath := filepath.Join(toolsDir, fmt.Sprintf("exec-request-%s.json", id))
-	resPath := filepath.Join(toolsDir, fmt.Sprintf("exec-result-%s.json", id))
+	result, err := runExec(req)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	return formatExecResult(result)
+}
+
+// runExec hands req to the skill sidecar through /ipc/tools and waits for
+// its result, allowing 10 seconds beyond the command's own timeout.
+func runExec(req execRequest) (execResult, error) {
+	req.ID = fmt.Sprintf("%d", time.Now().UnixNano())
+	reqPath := filepath.Join(toolsDir, fmt.Sprintf("exec-request-%s.json", req.ID))
+	resPath := filepath.Join(toolsDir, fmt.Sprintf("exec-result-%s.json", req.ID))
 
 	data, err := json.Marshal(req)
 	if err != nil {
-		return fmt.Sprintf("Error marshalling exec request: %v", err)
+		return execResult{}, fmt.Errorf("marshalling exec request: %w", err)
 	}
 
 	_ = os.MkdirAll(toolsDir, 0o755)
 	if err := os.WriteFile(reqPath, data, 0o644); err != nil {
-		return fmt.Sprintf("Error writing exec request: %v", err)
+		return execResult{}, fmt.Errorf("writing exec request: %w", err)
 	}
 
-	log.Printf("Wrote exec request %s: %s", id, truncateStr(command, 120))
+	if req.Tool != "" {
+		log.Printf("Wrote exec request %s: tool %s", req.ID, req.Tool)
+	} else {
+		log.Printf("Wrote exec request %s: %s", req.ID, truncateStr(req.Command, 120))
+	}
 
 	// Poll for result with a deadline.
-	deadline := time.Now().Add(time.Duration(timeoutSec+10) * time.Second)
+	deadline := time.Now().Add(time.Duration(req.Timeout+10) * time.Second)
 	for time.Now().Before(deadline) {
 		resData, err := os.ReadFile(resPath)
 		if err == nil {
@@ -682,19 +924,19 @@ func executeCommand(ctx context.Context, args map[string]any) string {
 				time.Sleep(100 * time.Millisecond)
 				resData2, err2 := os.ReadFile(resPath)
 				if err2 != nil || json.Unmarshal(resData2, &result) != nil {
-					return fmt.Sprintf("Error parsing exec result: %v", err)
+					return execResult{}, fmt.Errorf("parsing exec result: %w", err)
 				}
 			}
 
 			_ = os.Remove(reqPath)
 			_ = os.Remove(resPath)
 
-			return formatExecResult(result)
+			return result, nil
 		}
 		time.Sleep(150 * time.Millisecond)
 	}
 
-	return "Error: timed out waiting for command execution result. The skill sidecar may not be running."
+	return execResult{}, fmt.Errorf("timed out waiting for command execution result; the skill sidecar may not be running")
 }
 
 func formatExecResult(r execResult) string {
@@ -3,6 +3,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 
@@ -19,6 +20,7 @@ import (
 	"github.com/alexsjones/sympozium/internal/controller"
 	"github.com/alexsjones/sympozium/internal/eventbus"
 	"github.com/alexsjones/sympozium/internal/orchestrator"
+	"github.com/alexsjones/sympozium/internal/session"
 )
 
 var (
@@ -37,6 +39,7 @@ func main() {
 	var probeAddr string
 	var enableLeaderElection bool
 	var natsURL string
+	var databaseURL string
 	var maxRunHistory int
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
@@ -45,6 +48,7 @@ func main() {
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
 	flag.StringVar(&natsURL, "nats-url", "", "NATS URL for channel message routing. If empty, reads NATS_URL env var.")
+	flag.StringVar(&databaseURL, "database-url", "", "PostgreSQL URL for session transcripts. If empty, reads DATABASE_URL env var.")
 	flag.IntVar(&maxRunHistory, "max-run-history", controller.DefaultRunHistoryLimit,
 		"Maximum number of completed AgentRuns to keep per instance before pruning oldest.")
 	flag.Parse()
@@ -126,6 +130,23 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err := (&controller.SympoziumTriggerReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+		Log:    ctrl.Log.WithName("controllers").WithName("SympoziumTrigger"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "SympoziumTrigger")
+		os.Exit(1)
+	}
+	if err := (&controller.WatchTriggerReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+		Log:    ctrl.Log.WithName("controllers").WithName("WatchTrigger"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "WatchTrigger")
+		os.Exit(1)
+	}
+
 	if err := (&controller.PersonaPackReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
@@ -135,6 +156,22 @@ func main() {
 		os.Exit(1)
 	}
 
+	// --- Session store (optional — requires PostgreSQL) ---
+	if databaseURL == "" {
+		databaseURL = os.Getenv("DATABASE_URL")
+	}
+	var sessions *session.Store
+	if databaseURL != "" {
+		sessions, err = session.NewStore(context.Background(), databaseURL)
+		if err != nil {
+			setupLog.Error(err, "unable to connect to database — session transcripts disabled")
+		} else {
+			defer sessions.Close()
+		}
+	} else {
+		setupLog.Info("No DATABASE_URL configured — session transcripts disabled")
+	}
+
 	// --- Channel message router (optional — requires NATS) ---
 	if natsURL == "" {
 		natsURL = os.Getenv("NATS_URL")
@@ -147,6 +184,7 @@ func main() {
 			router := &controller.ChannelRouter{
 				Client:   mgr.GetClient(),
 				EventBus: eb,
+				Sessions: sessions,
 				Log:      ctrl.Log.WithName("channel-router"),
 			}
 			if err := mgr.Add(router); err != nil {
@@ -164,6 +202,55 @@ func main() {
 				os.Exit(1)
 			}
 
+			if err := (&controller.ApprovalRouter{
+				Client:   mgr.GetClient(),
+				EventBus: eb,
+				Log:      ctrl.Log.WithName("approval-router"),
+			}).SetupWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to add approval router")
+				os.Exit(1)
+			}
+
+			if err := (&controller.SpawnRouter{
+				Client:   mgr.GetClient(),
+				EventBus: eb,
+				Log:      ctrl.Log.WithName("spawn-router"),
+			}).SetupWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to add spawn router")
+				os.Exit(1)
+			}
+
+			if err := (&controller.FollowUpRouter{
+				Client:   mgr.GetClient(),
+				EventBus: eb,
+				Log:      ctrl.Log.WithName("followup-router"),
+			}).SetupWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to add follow-up router")
+				os.Exit(1)
+			}
+
+			memRouter := &controller.MemoryRouter{
+				Client:   mgr.GetClient(),
+				EventBus: eb,
+				Log:      ctrl.Log.WithName("memory-router"),
+			}
+			if err := mgr.Add(memRouter); err != nil {
+				setupLog.Error(err, "unable to add memory router")
+				os.Exit(1)
+			}
+
+			if sessions != nil {
+				if err := mgr.Add(&controller.TranscriptRouter{
+					Client:   mgr.GetClient(),
+					EventBus: eb,
+					Sessions: sessions,
+					Log:      ctrl.Log.WithName("transcript-router"),
+				}); err != nil {
+					setupLog.Error(err, "unable to add transcript router")
+					os.Exit(1)
+				}
+			}
+
 			setupLog.Info("Channel message router enabled", "natsURL", natsURL)
 		}
 	} else {
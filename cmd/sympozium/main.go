@@ -12,6 +12,7 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"os/user"
 	"path/filepath"
 	"sort"
 	"strconv"
@@ -31,6 +32,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	sympoziumv1alpha1 "github.com/alexsjones/sympozium/api/v1alpha1"
@@ -84,6 +86,7 @@ Running without a subcommand launches the interactive TUI.`,
 		newRunsCmd(),
 		newPoliciesCmd(),
 		newSkillsCmd(),
+		newPairingCmd(),
 		newFeaturesCmd(),
 		newVersionCmd(),
 		newTUICmd(),
@@ -304,6 +307,123 @@ func newPoliciesCmd() *cobra.Command {
 	return cmd
 }
 
+func newPairingCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pairing",
+		Short: "Approve senders who message an instance's channels directly",
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "list [instance]",
+			Short: "List pending pairing codes and paired senders",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				ctx := context.Background()
+				var inst sympoziumv1alpha1.SympoziumInstance
+				if err := k8sClient.Get(ctx, types.NamespacedName{Name: args[0], Namespace: namespace}, &inst); err != nil {
+					return err
+				}
+				w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+				fmt.Fprintln(w, "CODE\tCHANNEL\tSENDER\tNAME\tEXPIRES")
+				now := time.Now()
+				for _, p := range inst.Status.PendingPairings {
+					if inst.Status.FindPairing(p.Code, now) == nil {
+						continue
+					}
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", p.Code, p.Channel, p.SenderID, p.SenderName, shortDuration(p.ExpiresAt.Sub(now)))
+				}
+				for _, ch := range inst.Spec.Channels {
+					for _, sender := range ch.PairedSenders {
+						fmt.Fprintf(w, "(paired)\t%s\t%s\t\t\n", ch.Type, sender)
+					}
+				}
+				return w.Flush()
+			},
+		},
+		&cobra.Command{
+			Use:   "approve [instance] [code]",
+			Short: "Pair the sender of a pairing code",
+			Args:  cobra.ExactArgs(2),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				p, err := pairingApprove(context.Background(), namespace, args[0], args[1])
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Paired %s sender %s with %s\n", p.Channel, pairingSenderLabel(p), args[0])
+				return nil
+			},
+		},
+		&cobra.Command{
+			Use:   "revoke [instance] [channel] [sender-id]",
+			Short: "Remove a paired sender",
+			Args:  cobra.ExactArgs(3),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				if err := pairingRevoke(context.Background(), namespace, args[0], args[1], args[2]); err != nil {
+					return err
+				}
+				fmt.Printf("Revoked %s sender %s from %s\n", args[1], args[2], args[0])
+				return nil
+			},
+		},
+	)
+	return cmd
+}
+
+// pairingApprove pairs the sender of a pending pairing code with an
+// instance.
+func pairingApprove(ctx context.Context, ns, instanceName, code string) (*sympoziumv1alpha1.PairingRequest, error) {
+	var approved *sympoziumv1alpha1.PairingRequest
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var inst sympoziumv1alpha1.SympoziumInstance
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: instanceName, Namespace: ns}, &inst); err != nil {
+			return err
+		}
+		approved = inst.ApprovePairing(code, time.Now())
+		if approved == nil {
+			return nil
+		}
+		return k8sClient.Update(ctx, &inst)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if approved == nil {
+		return nil, fmt.Errorf("no pending pairing %q on instance %q (codes expire after %s)", code, instanceName, shortDuration(sympoziumv1alpha1.PairingTTL))
+	}
+	return approved, nil
+}
+
+// pairingRevoke removes a paired sender from an instance.
+func pairingRevoke(ctx context.Context, ns, instanceName, channel, senderID string) error {
+	revoked := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var inst sympoziumv1alpha1.SympoziumInstance
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: instanceName, Namespace: ns}, &inst); err != nil {
+			return err
+		}
+		revoked = inst.RevokePairing(channel, senderID)
+		if !revoked {
+			return nil
+		}
+		return k8sClient.Update(ctx, &inst)
+	})
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return fmt.Errorf("%s sender %q is not paired with instance %q", channel, senderID, instanceName)
+	}
+	return nil
+}
+
+func pairingSenderLabel(p *sympoziumv1alpha1.PairingRequest) string {
+	if p.SenderName != "" {
+		return fmt.Sprintf("%s (%s)", p.SenderID, p.SenderName)
+	}
+	return p.SenderID
+}
+
 func newSkillsCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "skills",
@@ -1229,7 +1349,7 @@ func runUninstall() error {
 	// Strip finalizers from all Sympozium CRD instances so CRD deletion doesn't
 	// hang waiting for the (now-deleted) controller to reconcile them.
 	fmt.Println("  Removing finalizers from Sympozium resources...")
-	resources := []string{"agentruns", "sympoziuminstances", "sympoziumpolicies", "skillpacks", "sympoziumschedules", "personapacks"}
+	resources := []string{"agentruns", "sympoziuminstances", "sympoziumpolicies", "skillpacks", "sympoziumschedules", "sympoziumtriggers", "personapacks"}
 	for _, res := range resources {
 		stripFinalizers(res)
 	}
@@ -1246,6 +1366,7 @@ func runUninstall() error {
 		"sympozium.ai_sympoziumpolicies.yaml",
 		"sympozium.ai_skillpacks.yaml",
 		"sympozium.ai_sympoziumschedules.yaml",
+		"sympozium.ai_sympoziumtriggers.yaml",
 		"sympozium.ai_personapacks.yaml",
 	}
 	for _, c := range crds {
@@ -1579,6 +1700,10 @@ var slashCommandSuggestions = []suggestion{
 	{"/runs", "List AgentRuns"},
 	{"/run", "Create AgentRun: /run <inst> <task>"},
 	{"/abort", "Abort run: /abort <run>"},
+	{"/approvals", "List tool calls awaiting approval"},
+	{"/approve", "Approve tool call: /approve <run> <id>"},
+	{"/deny", "Deny tool call: /deny <run> <id> [reason]"},
+	{"/followup", "Message a running agent: /followup <run> <text>"},
 	{"/result", "Show run result: /result <run>"},
 	{"/status", "Cluster or run status"},
 	{"/channels", "View channels for instance"},
@@ -1593,7 +1718,8 @@ var slashCommandSuggestions = []suggestion{
 	{"/schedules", "View schedules"},
 	{"/personas", "View PersonaPacks"},
 	{"/persona", "Manage persona pack: /persona delete <name>"},
-	{"/memory", "View memory: /memory <inst>"},
+	{"/memory", "View memory: /memory <inst> [history | rollback <rev>]"},
+	{"/pairing", "Pairing: /pairing <inst> [approve <code> | revoke <channel> <sender>]"},
 	{"/ns", "Switch namespace: /ns <name>"},
 	{"/onboard", "Interactive setup wizard"},
 	{"/help", "Show help modal"},
@@ -1614,6 +1740,8 @@ var channelTypeSuggestions = []suggestion{
 	{"slack", "Slack integration"},
 	{"discord", "Discord bot channel"},
 	{"whatsapp", "WhatsApp channel"},
+	{"matrix", "Matrix channel"},
+	{"webchat", "Web chat widget served by the API server"},
 }
 
 var providerSuggestions = []suggestion{
@@ -1797,6 +1925,10 @@ var tuiCommands = []struct{ cmd, desc string }{
 	{"/runs", "List AgentRuns"},
 	{"/run <inst> <task>", "Create a new AgentRun"},
 	{"/abort <run>", "Abort a running AgentRun"},
+	{"/approvals", "Tool calls awaiting approval"},
+	{"/approve <run> <id>", "Approve a gated tool call"},
+	{"/deny <run> <id> [why]", "Deny a gated tool call"},
+	{"/followup <run> <text>", "Steer a running agent"},
 	{"/result <run>", "Show the LLM response"},
 	{"/status [run]", "Cluster / run status"},
 	{"/channels [inst]", "View channels (tab 5)"},
@@ -2082,7 +2214,7 @@ var editConcurrencyPolicies = []string{"Forbid", "Allow", "Replace"}
 var editMemoryFieldCount = 3    // enabled, maxSizeKB, systemPrompt
 var editHeartbeatFieldCount = 6 // schedule, task, type, concurrencyPolicy, includeMemory, suspend
 var editTabNames = []string{"Memory", "Heartbeat", "Skills", "Channels"}
-var availableChannelTypes = []string{"telegram", "slack", "discord", "whatsapp"}
+var availableChannelTypes = []string{"telegram", "slack", "discord", "whatsapp", "matrix", "webchat"}
 
 // channelTokenKeyFor returns the env var name used in the channel secret for the given type.
 func channelTokenKeyFor(chType string) string {
@@ -2093,6 +2225,10 @@ func channelTokenKeyFor(chType string) string {
 		return "SLACK_BOT_TOKEN"
 	case "discord":
 		return "DISCORD_BOT_TOKEN"
+	case "matrix":
+		return "MATRIX_ACCESS_TOKEN" // the secret also needs MATRIX_HOMESERVER_URL
+	case "webchat":
+		return "WEBCHAT_SECRET" // signs visitor tokens; optional
 	default:
 		return "" // whatsapp uses QR pairing, no token
 	}
@@ -4140,7 +4276,7 @@ func (m *tuiModel) updateSuggestions(input string) tea.Cmd {
 		if argIdx == 1 {
 			return m.fetchSuggestionsAsync(func() []suggestion { return fetchInstanceSuggestions(ns, prefix) })
 		}
-	case "/abort":
+	case "/abort", "/approve", "/deny", "/followup":
 		if argIdx == 1 {
 			return m.fetchSuggestionsAsync(func() []suggestion { return fetchRunSuggestions(ns, prefix, true) })
 		}
@@ -4507,11 +4643,60 @@ func (m tuiModel) handleCommand(input string) (tea.Model, tea.Cmd) {
 
 	case "/memory":
 		if len(args) < 1 {
-			m.addLog(tuiErrorStyle.Render("Usage: /memory <instance>"))
+			m.addLog(tuiErrorStyle.Render("Usage: /memory <instance> [history | rollback <revision>]"))
+			return m, nil
+		}
+		inst := args[0]
+		ns := m.namespace
+		switch {
+		case len(args) == 1:
+			return m, m.asyncCmd(func() (string, error) { return tuiShowMemory(ns, inst) })
+		case args[1] == "history":
+			return m, m.asyncCmd(func() (string, error) { return tuiMemoryHistory(ns, inst) })
+		case args[1] == "rollback" && len(args) == 3:
+			rev, err := strconv.ParseInt(args[2], 10, 64)
+			if err != nil {
+				m.addLog(tuiErrorStyle.Render("Revision must be a number (see /memory <instance> history)"))
+				return m, nil
+			}
+			return m, m.asyncCmd(func() (string, error) { return tuiRollbackMemory(ns, inst, rev) })
+		default:
+			m.addLog(tuiErrorStyle.Render("Usage: /memory <instance> [history | rollback <revision>]"))
+		}
+		return m, nil
+
+	case "/pairing":
+		usage := "Usage: /pairing <instance> [approve <code> | revoke <channel> <sender>]"
+		if len(args) < 1 {
+			m.addLog(tuiErrorStyle.Render(usage))
 			return m, nil
 		}
 		inst := args[0]
-		return m, m.asyncCmd(func() (string, error) { return tuiShowMemory(m.namespace, inst) })
+		ns := m.namespace
+		switch {
+		case len(args) == 1:
+			return m, m.asyncCmd(func() (string, error) { return tuiListPairings(ns, inst) })
+		case args[1] == "approve" && len(args) == 3:
+			code := args[2]
+			return m, m.asyncCmd(func() (string, error) {
+				p, err := pairingApprove(context.Background(), ns, inst, code)
+				if err != nil {
+					return "", err
+				}
+				return tuiSuccessStyle.Render(fmt.Sprintf("✓ Paired %s sender %s with %s", p.Channel, pairingSenderLabel(p), inst)), nil
+			})
+		case args[1] == "revoke" && len(args) == 4:
+			channel, sender := args[2], args[3]
+			return m, m.asyncCmd(func() (string, error) {
+				if err := pairingRevoke(context.Background(), ns, inst, channel, sender); err != nil {
+					return "", err
+				}
+				return tuiSuccessStyle.Render(fmt.Sprintf("✓ Revoked %s sender %s from %s", channel, sender, inst)), nil
+			})
+		default:
+			m.addLog(tuiErrorStyle.Render(usage))
+		}
+		return m, nil
 
 	case "/channel":
 		if len(args) < 3 {
@@ -4559,6 +4744,31 @@ func (m tuiModel) handleCommand(input string) (tea.Model, tea.Cmd) {
 		}
 		return m, m.asyncCmd(func() (string, error) { return tuiAbortRun(m.namespace, args[0]) })
 
+	case "/approvals":
+		return m, m.asyncCmd(func() (string, error) { return tuiListApprovals(m.namespace) })
+
+	case "/approve", "/deny":
+		if len(args) < 2 {
+			m.addLog(tuiErrorStyle.Render(fmt.Sprintf("Usage: %s <run-name> <approval-id> [reason]", cmd)))
+			return m, nil
+		}
+		decision := sympoziumv1alpha1.ApprovalApproved
+		if cmd == "/deny" {
+			decision = sympoziumv1alpha1.ApprovalDenied
+		}
+		reason := strings.Join(args[2:], " ")
+		return m, m.asyncCmd(func() (string, error) {
+			return tuiDecideApproval(m.namespace, args[0], args[1], decision, reason)
+		})
+
+	case "/followup":
+		if len(args) < 2 {
+			m.addLog(tuiErrorStyle.Render("Usage: /followup <run-name> <message>"))
+			return m, nil
+		}
+		text := strings.Join(args[1:], " ")
+		return m, m.asyncCmd(func() (string, error) { return tuiSendFollowUp(m.namespace, args[0], text) })
+
 	case "/result":
 		if len(args) < 1 {
 			m.addLog(tuiErrorStyle.Render("Usage: /result <run-name>  (or press Enter on a run)"))
@@ -6459,6 +6669,7 @@ func tuiCreateRun(ns, instance, task string) (string, error) {
 				Model:         inst.Spec.Agents.Default.Model,
 				BaseURL:       inst.Spec.Agents.Default.BaseURL,
 				AuthSecretRef: authSecret,
+				Fallbacks:     inst.Spec.Agents.Fallbacks,
 			},
 			Skills:  inst.Spec.Skills,
 			Timeout: &metav1.Duration{Duration: 10 * time.Minute},
@@ -6508,6 +6719,92 @@ func tuiAbortRun(ns, name string) (string, error) {
 	return tuiSuccessStyle.Render(fmt.Sprintf("✓ Aborted: %s", name)), nil
 }
 
+// tuiIdentity identifies the local user as a tool approver or message sender.
+func tuiIdentity() string {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		name = u.Username
+	}
+	if name == "" {
+		name = "unknown"
+	}
+	return "tui:" + name
+}
+
+func tuiListApprovals(ns string) (string, error) {
+	ctx := context.Background()
+	var runs sympoziumv1alpha1.AgentRunList
+	if err := k8sClient.List(ctx, &runs, client.InNamespace(ns)); err != nil {
+		return "", fmt.Errorf("list runs: %w", err)
+	}
+	var b strings.Builder
+	for _, run := range runs.Items {
+		if run.Status.Phase != sympoziumv1alpha1.AgentRunPhaseRunning {
+			continue
+		}
+		for _, a := range run.Status.Approvals {
+			if a.Decision != "" {
+				continue
+			}
+			if b.Len() > 0 {
+				b.WriteString("\n")
+			}
+			b.WriteString(fmt.Sprintf("⏸ %s │ %s │ %s %s", a.ID, run.Name, a.Tool, truncate(a.Arguments, 60)))
+		}
+	}
+	if b.Len() == 0 {
+		return tuiDimStyle.Render("No tool calls awaiting approval"), nil
+	}
+	b.WriteString("\n" + tuiDimStyle.Render("  ┊ /approve <run> <id> or /deny <run> <id> [reason]"))
+	return b.String(), nil
+}
+
+func tuiDecideApproval(ns, runName, id, decision, reason string) (string, error) {
+	ctx := context.Background()
+	decided := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var run sympoziumv1alpha1.AgentRun
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: runName, Namespace: ns}, &run); err != nil {
+			return err
+		}
+		decided = run.Status.DecideApproval(id, decision, tuiIdentity(), reason, metav1.Now())
+		if !decided {
+			return nil
+		}
+		return k8sClient.Status().Update(ctx, &run)
+	})
+	if err != nil {
+		return "", fmt.Errorf("record decision: %w", err)
+	}
+	if !decided {
+		return "", fmt.Errorf("no pending approval %q on run %q", id, runName)
+	}
+	return tuiSuccessStyle.Render(fmt.Sprintf("✓ Tool call %s %s", id, decision)), nil
+}
+
+// tuiSendFollowUp records a follow-up on a run; the controller delivers it
+// to the agent between tool-loop iterations.
+func tuiSendFollowUp(ns, runName, text string) (string, error) {
+	ctx := context.Background()
+	var msg sympoziumv1alpha1.FollowUpMessage
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var run sympoziumv1alpha1.AgentRun
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: runName, Namespace: ns}, &run); err != nil {
+			return err
+		}
+		switch run.Status.Phase {
+		case sympoziumv1alpha1.AgentRunPhaseSucceeded, sympoziumv1alpha1.AgentRunPhaseFailed:
+			return fmt.Errorf("run %q has already finished", runName)
+		}
+		msg = run.Status.AddFollowUp(text, tuiIdentity(), metav1.Now())
+		return k8sClient.Status().Update(ctx, &run)
+	})
+	if err != nil {
+		return "", fmt.Errorf("send follow-up: %w", err)
+	}
+	return tuiSuccessStyle.Render(fmt.Sprintf("✓ Follow-up #%s queued for %s", msg.ID, runName)), nil
+}
+
 func tuiRunStatus(ns, name string) (string, error) {
 	ctx := context.Background()
 	var run sympoziumv1alpha1.AgentRun
@@ -6548,6 +6845,31 @@ func tuiRunStatus(ns, name string) (string, error) {
 		u := run.Status.TokenUsage
 		b.WriteString("\n" + tuiDimStyle.Render(fmt.Sprintf("  ⟠ tokens: %d in / %d out (%d total) │ tools: %d │ %dms",
 			u.InputTokens, u.OutputTokens, u.TotalTokens, u.ToolCalls, u.DurationMs)))
+		if u.ThinkingTokens > 0 {
+			b.WriteString("\n" + tuiDimStyle.Render(fmt.Sprintf("  ⟠ thinking: %d of %d out", u.ThinkingTokens, u.OutputTokens)))
+		} else if u.ThinkingTokensEstimated > 0 {
+			b.WriteString("\n" + tuiDimStyle.Render(fmt.Sprintf("  ⟠ thinking: ~%d of %d out (estimated)", u.ThinkingTokensEstimated, u.OutputTokens)))
+		}
+	}
+	for _, a := range run.Status.FailedAttempts {
+		b.WriteString("\n" + tuiDimStyle.Render(fmt.Sprintf("  ↷ failed over from %s/%s (%s)", a.Provider, a.Model, a.Reason)))
+	}
+	if run.Status.ServedModel != "" && len(run.Status.FailedAttempts) > 0 {
+		b.WriteString("\n" + tuiDimStyle.Render("  ↷ served by "+run.Status.ServedModel))
+	}
+	for _, a := range run.Status.Approvals {
+		if a.Decision == "" {
+			b.WriteString("\n" + tuiDimStyle.Render(fmt.Sprintf("  ⏸ %s awaiting approval (/approve %s %s)", a.Tool, run.Name, a.ID)))
+		} else {
+			b.WriteString("\n" + tuiDimStyle.Render(fmt.Sprintf("  ⏸ %s %s by %s", a.Tool, a.Decision, a.Approver)))
+		}
+	}
+	for _, f := range run.Status.FollowUps {
+		state := "queued"
+		if f.DeliveredAt != nil {
+			state = "delivered"
+		}
+		b.WriteString("\n" + tuiDimStyle.Render(fmt.Sprintf("  ↳ follow-up #%s from %s (%s): %s", f.ID, f.Sender, state, truncate(f.Text, 50))))
 	}
 	if run.Status.Error != "" {
 		b.WriteString("\n" + tuiErrorStyle.Render("  ✗ "+run.Status.Error))
@@ -6849,6 +7171,90 @@ func tuiShowMemory(ns, instanceName string) (string, error) {
 	return fmt.Sprintf("Memory for %s:\n%s", instanceName, preview), nil
 }
 
+func tuiListPairings(ns, instanceName string) (string, error) {
+	ctx := context.Background()
+	var inst sympoziumv1alpha1.SympoziumInstance
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: instanceName, Namespace: ns}, &inst); err != nil {
+		return "", fmt.Errorf("instance %q not found: %w", instanceName, err)
+	}
+
+	now := time.Now()
+	var lines []string
+	for _, p := range inst.Status.PendingPairings {
+		if inst.Status.FindPairing(p.Code, now) == nil {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("  %s  %-9s %s  expires in %s", p.Code, p.Channel, pairingSenderLabel(&p), shortDuration(p.ExpiresAt.Sub(now))))
+	}
+	for _, ch := range inst.Spec.Channels {
+		for _, sender := range ch.PairedSenders {
+			lines = append(lines, fmt.Sprintf("  (paired)  %-9s %s", ch.Type, sender))
+		}
+	}
+	if len(lines) == 0 {
+		return tuiDimStyle.Render(fmt.Sprintf("No pending or paired senders for %s", instanceName)), nil
+	}
+	return fmt.Sprintf("Pairings for %s:\n%s", instanceName, strings.Join(lines, "\n")), nil
+}
+
+func tuiMemoryHistory(ns, instanceName string) (string, error) {
+	ctx := context.Background()
+
+	var current corev1.ConfigMap
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: instanceName + "-memory", Namespace: ns}, &current); err != nil {
+		return "", fmt.Errorf("memory ConfigMap for %q not found (is memory enabled?): %w", instanceName, err)
+	}
+	var list corev1.ConfigMapList
+	if err := k8sClient.List(ctx, &list,
+		client.InNamespace(ns),
+		client.MatchingLabels{"sympozium.ai/instance": instanceName, "sympozium.ai/component": "memory-history"},
+	); err != nil {
+		return "", fmt.Errorf("list memory history: %w", err)
+	}
+	if len(list.Items) == 0 {
+		return tuiDimStyle.Render(fmt.Sprintf("No memory revisions saved for %s yet", instanceName)), nil
+	}
+
+	revision := func(cm *corev1.ConfigMap) int64 {
+		rev, _ := strconv.ParseInt(cm.Annotations["sympozium.ai/memory-revision"], 10, 64)
+		return rev
+	}
+	sort.Slice(list.Items, func(i, j int) bool { return revision(&list.Items[i]) > revision(&list.Items[j]) })
+	lines := []string{fmt.Sprintf("Memory revisions for %s:", instanceName)}
+	for i := range list.Items {
+		cm := &list.Items[i]
+		marker := " "
+		if revision(cm) == revision(&current) {
+			marker = "*"
+		}
+		lines = append(lines, fmt.Sprintf(" %s r%-4d %6d bytes  %s  %s", marker, revision(cm),
+			len(cm.Data["MEMORY.md"]), shortDuration(time.Since(cm.CreationTimestamp.Time)), cm.Annotations["sympozium.ai/memory-source"]))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func tuiRollbackMemory(ns, instanceName string, rev int64) (string, error) {
+	ctx := context.Background()
+
+	var entry corev1.ConfigMap
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: fmt.Sprintf("%s-memory-r%d", instanceName, rev), Namespace: ns}, &entry); err != nil {
+		return "", fmt.Errorf("memory revision %d of %s is not in the history: %w", rev, instanceName, err)
+	}
+	var inst sympoziumv1alpha1.SympoziumInstance
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: instanceName, Namespace: ns}, &inst); err != nil {
+		return "", fmt.Errorf("instance %q not found: %w", instanceName, err)
+	}
+	patch := client.MergeFrom(inst.DeepCopy())
+	if inst.Annotations == nil {
+		inst.Annotations = map[string]string{}
+	}
+	inst.Annotations["sympozium.ai/memory-rollback"] = strconv.FormatInt(rev, 10)
+	if err := k8sClient.Patch(ctx, &inst, patch); err != nil {
+		return "", fmt.Errorf("request rollback: %w", err)
+	}
+	return tuiSuccessStyle.Render(fmt.Sprintf("✓ Rolling back memory of %s to revision %d", instanceName, rev)), nil
+}
+
 func tuiSetBaseURL(ns, instanceName, baseURL string) (string, error) {
 	ctx := context.Background()
 	var inst sympoziumv1alpha1.SympoziumInstance
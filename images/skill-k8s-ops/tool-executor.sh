@@ -8,10 +8,35 @@ set -euo pipefail
 TOOLS_DIR="/ipc/tools"
 POLL_INTERVAL=0.2  # seconds
 
+# Declarative SkillPack tools run by this sidecar, set by the controller as
+# a JSON array of {name, command, timeoutSeconds}.
+SKILL_TOOLS="${SKILL_TOOLS:-[]}"
+
+# The SkillPack this sidecar belongs to, set by the controller.
+SKILL_PACK="${SKILL_PACK:-}"
+
 mkdir -p "$TOOLS_DIR"
 
 echo "[tool-executor] started, watching $TOOLS_DIR for exec requests"
 
+# owns_tool succeeds when this sidecar defines the named tool.
+owns_tool() {
+    jq -e --arg name "$1" 'any(.[]; .name == $name)' <<<"$SKILL_TOOLS" >/dev/null 2>&1
+}
+
+# render_tool_command prints the tool's command template with each
+# {{param}} replaced by the shell-quoted argument. Placeholders without an
+# argument render as nothing.
+render_tool_command() {
+    jq -r --argjson tools "$SKILL_TOOLS" '
+        .tool as $name
+        | ($tools | map(select(.name == $name)) | first | .command) as $tpl
+        | reduce ((.arguments // {}) | to_entries[] | select(.key | test("^[A-Za-z0-9_]+$"))) as $e ($tpl;
+            gsub("\\{\\{\\s*" + $e.key + "\\s*\\}\\}";
+                 $e.value | if type == "string" then . else tojson end | @sh))
+        | gsub("\\{\\{\\s*[A-Za-z0-9_]+\\s*\\}\\}"; "")' "$1"
+}
+
 process_request() {
     local req_file="$1"
     local basename
@@ -31,13 +56,26 @@ process_request() {
     workdir=$(jq -r '.workDir // "/workspace"' "$req_file" 2>/dev/null) || return
     timeout_sec=$(jq -r '.timeout // 30' "$req_file" 2>/dev/null) || return
 
+    # Optional stdin for the command (e.g. JSON-RPC requests for a stdio
+    # MCP server). Commands without stdin read from /dev/null.
+    local tmp_stdin
+    tmp_stdin=$(mktemp)
+    jq -j '.stdin // ""' "$req_file" >"$tmp_stdin" 2>/dev/null || return
+
     # Sanitize timeout.
     if [[ "$timeout_sec" -lt 1 ]]; then timeout_sec=30; fi
     if [[ "$timeout_sec" -gt 120 ]]; then timeout_sec=120; fi
 
-    # Build the full command. If args are provided, append them.
-    local full_cmd="$command"
-    if [[ -n "$args" ]]; then
+    # Build the full command. A tool request is rendered from its template;
+    # otherwise args, if provided, are appended to the command.
+    local tool full_cmd="$command"
+    tool=$(jq -r '.tool // ""' "$req_file" 2>/dev/null) || return
+    if [[ -n "$tool" ]]; then
+        full_cmd=$(render_tool_command "$req_file") || full_cmd=""
+        if [[ -z "$full_cmd" ]]; then
+            full_cmd="echo 'unknown tool: ${tool//\'/}' >&2; exit 127"
+        fi
+    elif [[ -n "$args" ]]; then
         full_cmd="$command $args"
     fi
 
@@ -51,7 +89,7 @@ process_request() {
 
     cd "$workdir" 2>/dev/null || cd /
 
-    if timeout "$timeout_sec" bash -c "$full_cmd" >"$tmp_stdout" 2>"$tmp_stderr"; then
+    if timeout "$timeout_sec" bash -c "$full_cmd" <"$tmp_stdin" >"$tmp_stdout" 2>"$tmp_stderr"; then
         exit_code=0
     else
         exit_code=$?
@@ -63,7 +101,7 @@ process_request() {
 
     stdout=$(cat "$tmp_stdout")
     stderr=$(cat "$tmp_stderr")
-    rm -f "$tmp_stdout" "$tmp_stderr"
+    rm -f "$tmp_stdin" "$tmp_stdout" "$tmp_stderr"
 
     # Truncate output if too large (50KB limit per field).
     if [[ ${#stdout} -gt 51200 ]]; then
@@ -113,6 +151,17 @@ while true; do
             continue
         fi
 
+        # Leave tool requests for the sidecar that defines the tool.
+        req_tool=$(jq -r '.tool // ""' "$req_file" 2>/dev/null) || continue
+        if [[ -n "$req_tool" ]] && ! owns_tool "$req_tool"; then
+            continue
+        fi
+        # Leave requests addressed to another SkillPack's sidecar.
+        req_skill=$(jq -r '.skillPack // ""' "$req_file" 2>/dev/null) || continue
+        if [[ -n "$req_skill" && "$req_skill" != "$SKILL_PACK" ]]; then
+            continue
+        fi
+
         # Atomically claim this request to prevent duplicate processing.
         # mkdir is atomic on POSIX filesystems — only one process wins.
         claim_dir="$TOOLS_DIR/.claim-${local_id}"
@@ -24,6 +24,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	sympoziumv1alpha1 "github.com/alexsjones/sympozium/api/v1alpha1"
@@ -89,6 +90,11 @@ func (s *Server) buildMux(frontendFS fs.FS, token string) http.Handler {
 	mux.HandleFunc("GET /api/v1/instances/{name}", s.getInstance)
 	mux.HandleFunc("POST /api/v1/instances", s.createInstance)
 	mux.HandleFunc("DELETE /api/v1/instances/{name}", s.deleteInstance)
+	mux.HandleFunc("GET /api/v1/instances/{name}/memory/revisions", s.listMemoryRevisions)
+	mux.HandleFunc("POST /api/v1/instances/{name}/memory/rollback", s.rollbackMemory)
+	mux.HandleFunc("GET /api/v1/instances/{name}/pairings", s.listPairings)
+	mux.HandleFunc("POST /api/v1/instances/{name}/pairings/{code}/approve", s.approvePairing)
+	mux.HandleFunc("DELETE /api/v1/instances/{name}/paired/{channel}/{sender}", s.revokePairing)
 
 	// Run endpoints
 	mux.HandleFunc("GET /api/v1/runs", s.listRuns)
@@ -96,6 +102,11 @@ func (s *Server) buildMux(frontendFS fs.FS, token string) http.Handler {
 	mux.HandleFunc("GET /api/v1/runs/{name}/telemetry", s.getRunTelemetry)
 	mux.HandleFunc("POST /api/v1/runs", s.createRun)
 	mux.HandleFunc("DELETE /api/v1/runs/{name}", s.deleteRun)
+	mux.HandleFunc("POST /api/v1/runs/{name}/approvals/{id}", s.decideApproval)
+	mux.HandleFunc("POST /api/v1/runs/{name}/messages", s.sendFollowUp)
+
+	// Tool approvals
+	mux.HandleFunc("GET /api/v1/approvals", s.listApprovals)
 
 	// Observability endpoints
 	mux.HandleFunc("GET /api/v1/observability/metrics", s.getObservabilityMetrics)
@@ -114,6 +125,10 @@ func (s *Server) buildMux(frontendFS fs.FS, token string) http.Handler {
 	mux.HandleFunc("POST /api/v1/schedules", s.createSchedule)
 	mux.HandleFunc("DELETE /api/v1/schedules/{name}", s.deleteSchedule)
 
+	// Trigger endpoints
+	mux.HandleFunc("GET /api/v1/triggers", s.listTriggers)
+	mux.HandleFunc("GET /api/v1/triggers/{name}", s.getTrigger)
+
 	// PersonaPack endpoints
 	mux.HandleFunc("GET /api/v1/personapacks", s.listPersonaPacks)
 	mux.HandleFunc("POST /api/v1/personapacks/install-defaults", s.installDefaultPersonaPacks)
@@ -129,6 +144,12 @@ func (s *Server) buildMux(frontendFS fs.FS, token string) http.Handler {
 	// WebSocket streaming
 	mux.HandleFunc("/ws/stream", s.handleStream)
 
+	// Trigger webhooks; deliveries are verified with each trigger's secret.
+	mux.HandleFunc("POST /webhooks/{namespace}/{name}", s.handleTriggerWebhook)
+
+	// Embedded web chat; visitors authenticate with the channel's own tokens.
+	mux.HandleFunc("GET /ws/webchat/{instance}", s.handleWebChat)
+
 	// Health & metrics
 	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -165,6 +186,12 @@ func authMiddleware(expectedToken string, next http.Handler) http.Handler {
 			return
 		}
 
+		// Web chat visitors are checked against the channel's settings.
+		if strings.HasPrefix(path, "/ws/webchat/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		// Skip auth for non-API paths (frontend SPA assets).
 		if !strings.HasPrefix(path, "/api/") && !strings.HasPrefix(path, "/ws/") {
 			next.ServeHTTP(w, r)
@@ -237,16 +264,20 @@ func aggregateTokenUsageByInstance(runs []sympoziumv1alpha1.AgentRun) map[string
 		curr, ok := agg[run.Spec.InstanceRef]
 		if !ok {
 			agg[run.Spec.InstanceRef] = &sympoziumv1alpha1.TokenUsage{
-				InputTokens:  run.Status.TokenUsage.InputTokens,
-				OutputTokens: run.Status.TokenUsage.OutputTokens,
-				TotalTokens:  run.Status.TokenUsage.TotalTokens,
-				ToolCalls:    run.Status.TokenUsage.ToolCalls,
-				DurationMs:   run.Status.TokenUsage.DurationMs,
+				InputTokens:             run.Status.TokenUsage.InputTokens,
+				OutputTokens:            run.Status.TokenUsage.OutputTokens,
+				ThinkingTokens:          run.Status.TokenUsage.ThinkingTokens,
+				ThinkingTokensEstimated: run.Status.TokenUsage.ThinkingTokensEstimated,
+				TotalTokens:             run.Status.TokenUsage.TotalTokens,
+				ToolCalls:               run.Status.TokenUsage.ToolCalls,
+				DurationMs:              run.Status.TokenUsage.DurationMs,
 			}
 			continue
 		}
 		curr.InputTokens += run.Status.TokenUsage.InputTokens
 		curr.OutputTokens += run.Status.TokenUsage.OutputTokens
+		curr.ThinkingTokens += run.Status.TokenUsage.ThinkingTokens
+		curr.ThinkingTokensEstimated += run.Status.TokenUsage.ThinkingTokensEstimated
 		curr.TotalTokens += run.Status.TokenUsage.TotalTokens
 		curr.ToolCalls += run.Status.TokenUsage.ToolCalls
 		curr.DurationMs += run.Status.TokenUsage.DurationMs
@@ -629,11 +660,13 @@ func (s *Server) getRunTelemetry(w http.ResponseWriter, r *http.Request) {
 				Level:   "info",
 				Message: "token usage captured",
 				Fields: map[string]any{
-					"input_tokens":  run.Status.TokenUsage.InputTokens,
-					"output_tokens": run.Status.TokenUsage.OutputTokens,
-					"total_tokens":  run.Status.TokenUsage.TotalTokens,
-					"tool_calls":    run.Status.TokenUsage.ToolCalls,
-					"duration_ms":   run.Status.TokenUsage.DurationMs,
+					"input_tokens":              run.Status.TokenUsage.InputTokens,
+					"output_tokens":             run.Status.TokenUsage.OutputTokens,
+					"thinking_tokens":           run.Status.TokenUsage.ThinkingTokens,
+					"thinking_tokens_estimated": run.Status.TokenUsage.ThinkingTokensEstimated,
+					"total_tokens":              run.Status.TokenUsage.TotalTokens,
+					"tool_calls":                run.Status.TokenUsage.ToolCalls,
+					"duration_ms":               run.Status.TokenUsage.DurationMs,
 				},
 			})
 			if len(resp.MetricNames) == 0 {
@@ -1187,6 +1220,7 @@ func (s *Server) createRun(w http.ResponseWriter, r *http.Request) {
 				Model:         model,
 				BaseURL:       inst.Spec.Agents.Default.BaseURL,
 				AuthSecretRef: authSecret,
+				Fallbacks:     inst.Spec.Agents.Fallbacks,
 			},
 			Skills: inst.Spec.Skills,
 		},
@@ -1219,6 +1253,388 @@ func (s *Server) deleteRun(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// --- Tool approval handlers ---
+
+// PendingApproval is a tool call waiting for a human decision.
+type PendingApproval struct {
+	sympoziumv1alpha1.ToolApproval `json:",inline"`
+	RunName                        string `json:"runName"`
+	Namespace                      string `json:"namespace"`
+	InstanceRef                    string `json:"instanceRef"`
+}
+
+// ApprovalDecisionRequest is the request body for deciding on a tool call.
+type ApprovalDecisionRequest struct {
+	Decision string `json:"decision"` // "approved" or "denied"
+	Approver string `json:"approver"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+func (s *Server) listApprovals(w http.ResponseWriter, r *http.Request) {
+	ns := r.URL.Query().Get("namespace")
+	if ns == "" {
+		ns = "default"
+	}
+
+	var list sympoziumv1alpha1.AgentRunList
+	if err := s.client.List(r.Context(), &list, client.InNamespace(ns)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	pending := []PendingApproval{}
+	for _, run := range list.Items {
+		if run.Status.Phase != sympoziumv1alpha1.AgentRunPhaseRunning {
+			continue
+		}
+		for _, a := range run.Status.Approvals {
+			if a.Decision == "" {
+				pending = append(pending, PendingApproval{
+					ToolApproval: a,
+					RunName:      run.Name,
+					Namespace:    run.Namespace,
+					InstanceRef:  run.Spec.InstanceRef,
+				})
+			}
+		}
+	}
+
+	writeJSON(w, pending)
+}
+
+func (s *Server) decideApproval(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	id := r.PathValue("id")
+	ns := r.URL.Query().Get("namespace")
+	if ns == "" {
+		ns = "default"
+	}
+
+	var req ApprovalDecisionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Decision != sympoziumv1alpha1.ApprovalApproved && req.Decision != sympoziumv1alpha1.ApprovalDenied {
+		http.Error(w, `decision must be "approved" or "denied"`, http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Approver) == "" {
+		http.Error(w, "approver is required", http.StatusBadRequest)
+		return
+	}
+
+	var run sympoziumv1alpha1.AgentRun
+	decided := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := s.client.Get(r.Context(), types.NamespacedName{Name: name, Namespace: ns}, &run); err != nil {
+			return err
+		}
+		decided = run.Status.DecideApproval(id, req.Decision, "api:"+strings.TrimSpace(req.Approver), req.Reason, metav1.Now())
+		if !decided {
+			return nil
+		}
+		return s.client.Status().Update(r.Context(), &run)
+	})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	if !decided {
+		http.Error(w, fmt.Sprintf("no pending approval %q on run %q", id, name), http.StatusConflict)
+		return
+	}
+
+	writeJSON(w, run)
+}
+
+// FollowUpRequest is the request body for sending a message to a running agent.
+type FollowUpRequest struct {
+	Text   string `json:"text"`
+	Sender string `json:"sender,omitempty"`
+}
+
+// sendFollowUp records a follow-up on a pending or running AgentRun. The
+// controller delivers it to the agent pod.
+func (s *Server) sendFollowUp(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	ns := r.URL.Query().Get("namespace")
+	if ns == "" {
+		ns = "default"
+	}
+
+	var req FollowUpRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		http.Error(w, "text is required", http.StatusBadRequest)
+		return
+	}
+	sender := "api"
+	if from := strings.TrimSpace(req.Sender); from != "" {
+		sender = "api:" + from
+	}
+
+	var run sympoziumv1alpha1.AgentRun
+	var msg sympoziumv1alpha1.FollowUpMessage
+	finished := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := s.client.Get(r.Context(), types.NamespacedName{Name: name, Namespace: ns}, &run); err != nil {
+			return err
+		}
+		switch run.Status.Phase {
+		case sympoziumv1alpha1.AgentRunPhaseSucceeded, sympoziumv1alpha1.AgentRunPhaseFailed:
+			finished = true
+			return nil
+		}
+		msg = run.Status.AddFollowUp(req.Text, sender, metav1.Now())
+		return s.client.Status().Update(r.Context(), &run)
+	})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	if finished {
+		http.Error(w, fmt.Sprintf("run %q has already finished", name), http.StatusConflict)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	writeJSON(w, msg)
+}
+
+// --- Memory handlers ---
+
+// MemoryRevision describes a saved revision of an instance's memory.
+type MemoryRevision struct {
+	Revision  int64       `json:"revision"`
+	Source    string      `json:"source,omitempty"`
+	Bytes     int         `json:"bytes"`
+	CreatedAt metav1.Time `json:"createdAt"`
+	Current   bool        `json:"current,omitempty"`
+}
+
+// listMemoryRevisions returns the saved revisions of an instance's memory,
+// newest first.
+func (s *Server) listMemoryRevisions(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	ns := r.URL.Query().Get("namespace")
+	if ns == "" {
+		ns = "default"
+	}
+
+	var current corev1.ConfigMap
+	if err := s.client.Get(r.Context(), types.NamespacedName{Name: name + "-memory", Namespace: ns}, &current); err != nil {
+		if k8serrors.IsNotFound(err) {
+			http.Error(w, fmt.Sprintf("instance %q has no memory", name), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	var list corev1.ConfigMapList
+	if err := s.client.List(r.Context(), &list,
+		client.InNamespace(ns),
+		client.MatchingLabels{"sympozium.ai/instance": name, "sympozium.ai/component": "memory-history"},
+	); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	currentRev := current.Annotations["sympozium.ai/memory-revision"]
+	revisions := make([]MemoryRevision, 0, len(list.Items))
+	for _, cm := range list.Items {
+		rev, err := strconv.ParseInt(cm.Annotations["sympozium.ai/memory-revision"], 10, 64)
+		if err != nil {
+			continue
+		}
+		revisions = append(revisions, MemoryRevision{
+			Revision:  rev,
+			Source:    cm.Annotations["sympozium.ai/memory-source"],
+			Bytes:     len(cm.Data["MEMORY.md"]),
+			CreatedAt: cm.CreationTimestamp,
+			Current:   cm.Annotations["sympozium.ai/memory-revision"] == currentRev,
+		})
+	}
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].Revision > revisions[j].Revision })
+	writeJSON(w, revisions)
+}
+
+// MemoryRollbackRequest is the request body for rolling back an instance's
+// memory.
+type MemoryRollbackRequest struct {
+	Revision int64 `json:"revision"`
+}
+
+// rollbackMemory asks the controller to restore a saved memory revision. The
+// restored content becomes a new revision.
+func (s *Server) rollbackMemory(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	ns := r.URL.Query().Get("namespace")
+	if ns == "" {
+		ns = "default"
+	}
+
+	var req MemoryRollbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	var entry corev1.ConfigMap
+	if err := s.client.Get(r.Context(), types.NamespacedName{Name: fmt.Sprintf("%s-memory-r%d", name, req.Revision), Namespace: ns}, &entry); err != nil {
+		if k8serrors.IsNotFound(err) {
+			http.Error(w, fmt.Sprintf("memory revision %d of %q is not in the history", req.Revision, name), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	var inst sympoziumv1alpha1.SympoziumInstance
+	if err := s.client.Get(r.Context(), types.NamespacedName{Name: name, Namespace: ns}, &inst); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	patch := client.MergeFrom(inst.DeepCopy())
+	if inst.Annotations == nil {
+		inst.Annotations = map[string]string{}
+	}
+	inst.Annotations["sympozium.ai/memory-rollback"] = strconv.FormatInt(req.Revision, 10)
+	if err := s.client.Patch(r.Context(), &inst, patch); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	writeJSON(w, req)
+}
+
+// PairedSender is a sender allowed to message an instance directly on a
+// pairing channel.
+type PairedSender struct {
+	Channel  string `json:"channel"`
+	SenderID string `json:"senderId"`
+}
+
+// PairingsResponse lists an instance's pending pairing codes and paired
+// senders.
+type PairingsResponse struct {
+	Pending []sympoziumv1alpha1.PairingRequest `json:"pending"`
+	Paired  []PairedSender                     `json:"paired"`
+}
+
+// listPairings returns the unexpired pairing codes waiting for approval and
+// the senders paired so far.
+func (s *Server) listPairings(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	ns := r.URL.Query().Get("namespace")
+	if ns == "" {
+		ns = "default"
+	}
+
+	var inst sympoziumv1alpha1.SympoziumInstance
+	if err := s.client.Get(r.Context(), types.NamespacedName{Name: name, Namespace: ns}, &inst); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	resp := PairingsResponse{Pending: []sympoziumv1alpha1.PairingRequest{}, Paired: []PairedSender{}}
+	now := time.Now()
+	for _, p := range inst.Status.PendingPairings {
+		if inst.Status.FindPairing(p.Code, now) != nil {
+			resp.Pending = append(resp.Pending, p)
+		}
+	}
+	for _, ch := range inst.Spec.Channels {
+		for _, sender := range ch.PairedSenders {
+			resp.Paired = append(resp.Paired, PairedSender{Channel: ch.Type, SenderID: sender})
+		}
+	}
+	writeJSON(w, resp)
+}
+
+// approvePairing pairs the sender of a pending pairing code with the
+// instance.
+func (s *Server) approvePairing(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	code := r.PathValue("code")
+	ns := r.URL.Query().Get("namespace")
+	if ns == "" {
+		ns = "default"
+	}
+
+	var inst sympoziumv1alpha1.SympoziumInstance
+	var approved *sympoziumv1alpha1.PairingRequest
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := s.client.Get(r.Context(), types.NamespacedName{Name: name, Namespace: ns}, &inst); err != nil {
+			return err
+		}
+		approved = inst.ApprovePairing(code, time.Now())
+		if approved == nil {
+			return nil
+		}
+		return s.client.Update(r.Context(), &inst)
+	})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	if approved == nil {
+		http.Error(w, fmt.Sprintf("no pending pairing %q on instance %q", code, name), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, approved)
+}
+
+// revokePairing removes a paired sender from the instance.
+func (s *Server) revokePairing(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	channel := r.PathValue("channel")
+	sender := r.PathValue("sender")
+	ns := r.URL.Query().Get("namespace")
+	if ns == "" {
+		ns = "default"
+	}
+
+	var inst sympoziumv1alpha1.SympoziumInstance
+	revoked := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := s.client.Get(r.Context(), types.NamespacedName{Name: name, Namespace: ns}, &inst); err != nil {
+			return err
+		}
+		revoked = inst.RevokePairing(channel, sender)
+		if !revoked {
+			return nil
+		}
+		return s.client.Update(r.Context(), &inst)
+	})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	if !revoked {
+		http.Error(w, fmt.Sprintf("%s sender %q is not paired with instance %q", channel, sender, name), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // --- Policy handlers ---
 
 func (s *Server) listPolicies(w http.ResponseWriter, r *http.Request) {
@@ -1655,7 +2071,13 @@ func (s *Server) deletePersonaPack(w http.ResponseWriter, r *http.Request) {
 
 // --- WebSocket streaming ---
 
+// handleStream forwards agent stream chunks over a websocket. The optional
+// "run" and "instance" query parameters restrict it to a single AgentRun or
+// SympoziumInstance.
 func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	runFilter := r.URL.Query().Get("run")
+	instanceFilter := r.URL.Query().Get("instance")
+
 	if s.eventBus == nil {
 		http.Error(w, "streaming not available (no event bus)", http.StatusServiceUnavailable)
 		return
@@ -1706,6 +2128,12 @@ func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
 			if !ok {
 				return
 			}
+			if runFilter != "" && event.Metadata["agentRunID"] != runFilter {
+				continue
+			}
+			if instanceFilter != "" && event.Metadata["instanceName"] != instanceFilter {
+				continue
+			}
 			data, _ := json.Marshal(event)
 			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
 				return
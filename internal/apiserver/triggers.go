@@ -0,0 +1,167 @@
+package apiserver
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sympoziumv1alpha1 "github.com/alexsjones/sympozium/api/v1alpha1"
+	"github.com/alexsjones/sympozium/internal/trigger"
+)
+
+func (s *Server) listTriggers(w http.ResponseWriter, r *http.Request) {
+	ns := r.URL.Query().Get("namespace")
+	if ns == "" {
+		ns = "default"
+	}
+
+	var list sympoziumv1alpha1.SympoziumTriggerList
+	if err := s.client.List(r.Context(), &list, client.InNamespace(ns)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, list.Items)
+}
+
+func (s *Server) getTrigger(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	ns := r.URL.Query().Get("namespace")
+	if ns == "" {
+		ns = "default"
+	}
+
+	var t sympoziumv1alpha1.SympoziumTrigger
+	if err := s.client.Get(r.Context(), types.NamespacedName{Name: name, Namespace: ns}, &t); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, t)
+}
+
+// handleTriggerWebhook receives a delivery for a SympoziumTrigger, verifies
+// it against the trigger's secret and starts an AgentRun with the rendered
+// task.
+func (s *Server) handleTriggerWebhook(w http.ResponseWriter, r *http.Request) {
+	ns, name := r.PathValue("namespace"), r.PathValue("name")
+
+	var t sympoziumv1alpha1.SympoziumTrigger
+	if err := s.client.Get(r.Context(), types.NamespacedName{Name: name, Namespace: ns}, &t); err != nil {
+		if k8serrors.IsNotFound(err) {
+			http.Error(w, "trigger not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "failed to get trigger: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	if t.Spec.Auth == nil {
+		http.Error(w, "trigger does not accept webhooks", http.StatusNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, trigger.MaxPayloadBytes+1))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	if len(body) > trigger.MaxPayloadBytes {
+		http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var secret corev1.Secret
+	if err := s.client.Get(r.Context(), types.NamespacedName{Name: t.Spec.Auth.SecretRef, Namespace: ns}, &secret); err != nil {
+		s.log.Error(err, "failed to read trigger secret", "trigger", name, "secret", t.Spec.Auth.SecretRef)
+		http.Error(w, "trigger is not configured", http.StatusServiceUnavailable)
+		return
+	}
+	key := secret.Data[trigger.SecretKey]
+	if len(key) == 0 {
+		http.Error(w, "trigger is not configured", http.StatusServiceUnavailable)
+		return
+	}
+	if err := trigger.Verify(*t.Spec.Auth, key, r.Header, body); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if t.Spec.Suspend {
+		http.Error(w, "trigger is suspended", http.StatusConflict)
+		return
+	}
+
+	d := trigger.NewDelivery(r.Header, body)
+	task, err := trigger.Render("task", t.Spec.Task, d)
+	if err != nil {
+		http.Error(w, "rendering task: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if strings.TrimSpace(task) == "" {
+		http.Error(w, "rendered task is empty", http.StatusUnprocessableEntity)
+		return
+	}
+	dedupeKey, err := trigger.Render("dedupeKey", t.Spec.DedupeKey, d)
+	if err != nil {
+		http.Error(w, "rendering dedupe key: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	dedupeHash := ""
+	if dedupeKey = strings.TrimSpace(dedupeKey); dedupeKey != "" {
+		dedupeHash = trigger.DedupeHash(dedupeKey)
+		dup, err := trigger.RecentRun(r.Context(), s.client, &t, dedupeHash, t.Spec.DedupeWindow)
+		if err != nil {
+			http.Error(w, "checking for duplicates: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if dup != "" {
+			writeJSON(w, map[string]any{"run": dup, "duplicate": true})
+			return
+		}
+	}
+
+	run, err := trigger.NewRun(r.Context(), s.client, &t, task, dedupeHash, t.Spec.DedupeWindow)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	if err := s.client.Create(r.Context(), run); err != nil {
+		// A concurrent delivery of the same key created the run first.
+		if k8serrors.IsAlreadyExists(err) && dedupeHash != "" {
+			writeJSON(w, map[string]any{"run": run.Name, "duplicate": true})
+			return
+		}
+		http.Error(w, "failed to create run: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.log.Info("Created AgentRun from trigger", "trigger", name, "namespace", ns, "run", run.Name)
+
+	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var latest sympoziumv1alpha1.SympoziumTrigger
+		if err := s.client.Get(r.Context(), types.NamespacedName{Name: name, Namespace: ns}, &latest); err != nil {
+			return err
+		}
+		now := metav1.Now()
+		latest.Status.LastTriggerTime = &now
+		latest.Status.LastRunName = run.Name
+		latest.Status.TotalRuns++
+		return s.client.Status().Update(r.Context(), &latest)
+	}); err != nil {
+		s.log.Error(err, "failed to update trigger status", "trigger", name)
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	writeJSON(w, map[string]any{"run": run.Name})
+}
@@ -0,0 +1,277 @@
+package apiserver
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	sympoziumv1alpha1 "github.com/alexsjones/sympozium/api/v1alpha1"
+	"github.com/alexsjones/sympozium/internal/channel"
+	"github.com/alexsjones/sympozium/internal/eventbus"
+)
+
+// webchatChannel is the type of the channel the API server serves itself.
+const webchatChannel = "webchat"
+
+// webchatSecretKey is the key of the channel secret that visitor tokens are
+// signed with.
+const webchatSecretKey = "WEBCHAT_SECRET"
+
+// webchatReadLimit caps a frame sent by a visitor.
+const webchatReadLimit = 64 << 10
+
+// anonVisitorRe matches the random IDs the widget gives anonymous visitors.
+var anonVisitorRe = regexp.MustCompile(`^[A-Za-z0-9_-]{16,64}$`)
+
+// webchatFrame is a message on a web chat's websocket. Visitors send
+// "message" frames; the server sends "ready", "message" and "typing".
+type webchatFrame struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+	// HTML is a Markdown reply rendered for the browser.
+	HTML        string               `json:"html,omitempty"`
+	Attachments []channel.Attachment `json:"attachments,omitempty"`
+	// Visitor is the visitor ID the chat runs under, sent with "ready".
+	Visitor string `json:"visitor,omitempty"`
+	// Active tells whether the agent is working on a reply.
+	Active bool `json:"active,omitempty"`
+}
+
+// handleWebChat connects a visitor of an embedded chat widget to an
+// instance's webchat channel. Messages are published like those of any
+// other channel, and replies to the visitor's chat are streamed back.
+func (s *Server) handleWebChat(w http.ResponseWriter, r *http.Request) {
+	if s.eventBus == nil {
+		http.Error(w, "web chat not available (no event bus)", http.StatusServiceUnavailable)
+		return
+	}
+
+	name := r.PathValue("instance")
+	ns := r.URL.Query().Get("namespace")
+	if ns == "" {
+		ns = "default"
+	}
+	// The endpoint is unauthenticated, so every lookup failure looks the
+	// same to the caller.
+	var inst sympoziumv1alpha1.SympoziumInstance
+	if err := s.client.Get(r.Context(), types.NamespacedName{Name: name, Namespace: ns}, &inst); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			s.log.Error(err, "failed to get instance for web chat", "instance", name, "namespace", ns)
+		}
+		http.Error(w, "web chat not found", http.StatusNotFound)
+		return
+	}
+	var spec *sympoziumv1alpha1.ChannelSpec
+	for i := range inst.Spec.Channels {
+		if inst.Spec.Channels[i].Type == webchatChannel {
+			spec = &inst.Spec.Channels[i]
+			break
+		}
+	}
+	if spec == nil {
+		http.Error(w, "web chat not found", http.StatusNotFound)
+		return
+	}
+	if !webchatOriginAllowed(spec.AllowedOrigins, r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+	visitor, err := s.webchatVisitor(r.Context(), ns, spec, r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	// The origin was checked against the channel's list above.
+	upgrader := s.upgrader
+	upgrader.CheckOrigin = func(*http.Request) bool { return true }
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.log.Error(err, "failed to upgrade websocket")
+		return
+	}
+	defer conn.Close()
+	conn.SetReadLimit(webchatReadLimit)
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	sends, err := s.eventBus.Subscribe(ctx, eventbus.TopicChannelMessageSend)
+	if err != nil {
+		s.log.Error(err, "failed to subscribe to channel messages")
+		return
+	}
+	typing, err := s.eventBus.Subscribe(ctx, eventbus.TopicChannelTyping)
+	if err != nil {
+		s.log.Error(err, "failed to subscribe to typing events")
+		return
+	}
+
+	// Chat IDs of other channels are only unique per platform, so a web
+	// chat's includes the instance it belongs to.
+	chatID := ns + "/" + name + "/" + visitor
+	bc := &channel.BaseChannel{ChannelType: webchatChannel, InstanceName: name, EventBus: s.eventBus}
+
+	if err := conn.WriteJSON(webchatFrame{Type: "ready", Visitor: visitor}); err != nil {
+		return
+	}
+
+	// Read loop: publish the visitor's messages.
+	go func() {
+		defer cancel()
+		for {
+			var f webchatFrame
+			if err := conn.ReadJSON(&f); err != nil {
+				return
+			}
+			text := strings.TrimSpace(f.Text)
+			if f.Type != "message" || text == "" {
+				continue
+			}
+			if err := bc.PublishInbound(ctx, channel.InboundMessage{
+				SenderID: visitor,
+				ChatID:   chatID,
+				Text:     text,
+				IsDirect: true,
+			}); err != nil {
+				s.log.Error(err, "failed to publish web chat message", "instance", name)
+			}
+		}
+	}()
+
+	pingTicker := time.NewTicker(15 * time.Second)
+	defer pingTicker.Stop()
+
+	// Write loop: forward replies and typing indicators for this chat.
+	for {
+		var frame webchatFrame
+		select {
+		case <-ctx.Done():
+			return
+		case <-pingTicker.C:
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+			continue
+		case event, ok := <-sends:
+			if !ok {
+				return
+			}
+			var msg channel.OutboundMessage
+			if err := json.Unmarshal(event.Data, &msg); err != nil || msg.Channel != webchatChannel || msg.ChatID != chatID {
+				continue
+			}
+			frame = webchatReply(msg)
+		case event, ok := <-typing:
+			if !ok {
+				return
+			}
+			var ev channel.TypingEvent
+			if err := json.Unmarshal(event.Data, &ev); err != nil || ev.Channel != webchatChannel || ev.ChatID != chatID {
+				continue
+			}
+			frame = webchatFrame{Type: "typing", Active: ev.Active}
+		}
+		if err := conn.WriteJSON(frame); err != nil {
+			return
+		}
+	}
+}
+
+// webchatReply turns a reply into a frame for the widget. Markdown is
+// rendered to the small HTML subset Matrix uses, with all text escaped;
+// plain and HTML replies are shown as text.
+func webchatReply(msg channel.OutboundMessage) webchatFrame {
+	f := webchatFrame{Type: "message", Text: msg.Text, Attachments: msg.Attachments}
+	if strings.TrimSpace(msg.Text) != "" && (msg.Format == "" || msg.Format == channel.FormatMarkdown) {
+		f.HTML = strings.Join(channel.RenderMarkdown(msg.Text, channel.DialectMatrix), "<br><br>")
+	}
+	return f
+}
+
+// webchatOriginAllowed reports whether a page from r's origin may open a
+// chat. Requests without an Origin header do not come from a browser.
+func webchatOriginAllowed(allowed []string, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, o := range allowed {
+		if o == "*" || strings.EqualFold(strings.TrimRight(o, "/"), origin) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// webchatVisitor identifies the visitor opening a chat. When the channel's
+// secret has a WEBCHAT_SECRET, the visitor needs a token signed with it;
+// otherwise visitors are anonymous and named by the random ID the widget
+// keeps for them.
+func (s *Server) webchatVisitor(ctx context.Context, ns string, spec *sympoziumv1alpha1.ChannelSpec, q url.Values) (string, error) {
+	if spec.ConfigRef.Secret == "" {
+		id := q.Get("visitor")
+		if !anonVisitorRe.MatchString(id) {
+			return "", errors.New("missing or invalid visitor ID")
+		}
+		return "anon-" + id, nil
+	}
+
+	var secret corev1.Secret
+	if err := s.client.Get(ctx, types.NamespacedName{Name: spec.ConfigRef.Secret, Namespace: ns}, &secret); err != nil {
+		s.log.Error(err, "failed to read web chat secret", "secret", spec.ConfigRef.Secret)
+		return "", errors.New("web chat is not configured")
+	}
+	key := secret.Data[webchatSecretKey]
+	if len(key) == 0 {
+		s.log.Info("Web chat secret has no "+webchatSecretKey, "secret", spec.ConfigRef.Secret)
+		return "", errors.New("web chat is not configured")
+	}
+	return verifyVisitorToken(q.Get("token"), key, time.Now())
+}
+
+// verifyVisitorToken checks a visitor token and returns the visitor ID it
+// was issued for. A token is "<visitor>.<expiry>.<signature>": the ID, the
+// Unix time the token expires and the unpadded base64url HMAC-SHA256 of
+// "<visitor>.<expiry>" under the channel's secret.
+func verifyVisitorToken(token string, secret []byte, now time.Time) (string, error) {
+	invalid := errors.New("missing or invalid visitor token")
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return "", invalid
+	}
+	payload, sig := token[:i], token[i+1:]
+	j := strings.LastIndexByte(payload, '.')
+	if j <= 0 {
+		return "", invalid
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	if !hmac.Equal([]byte(sig), []byte(base64.RawURLEncoding.EncodeToString(mac.Sum(nil)))) {
+		return "", invalid
+	}
+	expiry, err := strconv.ParseInt(payload[j+1:], 10, 64)
+	if err != nil {
+		return "", invalid
+	}
+	if now.Unix() >= expiry {
+		return "", errors.New("visitor token expired")
+	}
+	return payload[:j], nil
+}
@@ -0,0 +1,142 @@
+package apiserver
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sympoziumv1alpha1 "github.com/alexsjones/sympozium/api/v1alpha1"
+	"github.com/alexsjones/sympozium/internal/eventbus"
+)
+
+// signVisitorToken mints a token the way an embedding page's backend does.
+func signVisitorToken(visitor string, expiry time.Time, secret []byte) string {
+	payload := visitor + "." + strconv.FormatInt(expiry.Unix(), 10)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyVisitorToken(t *testing.T) {
+	secret := []byte("s3cret")
+	now := time.Unix(1_800_000_000, 0)
+	valid := signVisitorToken("alice", now.Add(time.Hour), secret)
+	tampered := strings.Replace(valid, "alice", "mallory", 1)
+	// The signature covers the whole payload, so a dot in the visitor ID
+	// can't be used to slip in another expiry.
+	dotted := signVisitorToken("bob."+strconv.FormatInt(now.Add(24*time.Hour).Unix(), 10), now.Add(-time.Minute), secret)
+
+	tests := []struct {
+		name        string
+		token       string
+		wantVisitor string
+		wantErr     string
+	}{
+		{name: "valid", token: valid, wantVisitor: "alice"},
+		{name: "visitor with a dot", token: signVisitorToken("alice.smith", now.Add(time.Hour), secret), wantVisitor: "alice.smith"},
+		{name: "expired", token: signVisitorToken("alice", now, secret), wantErr: "visitor token expired"},
+		{name: "dot-injected expiry", token: dotted, wantErr: "visitor token expired"},
+		{name: "tampered visitor", token: tampered, wantErr: "missing or invalid visitor token"},
+		{name: "tampered expiry", token: strings.Replace(valid, strconv.FormatInt(now.Add(time.Hour).Unix(), 10), "9999999999", 1), wantErr: "missing or invalid visitor token"},
+		{name: "wrong secret", token: signVisitorToken("alice", now.Add(time.Hour), []byte("other")), wantErr: "missing or invalid visitor token"},
+		{name: "extra segment", token: valid + ".x", wantErr: "missing or invalid visitor token"},
+		{name: "no visitor", token: signVisitorToken("", now.Add(time.Hour), secret), wantErr: "missing or invalid visitor token"},
+		{name: "no expiry", token: "alice.sig", wantErr: "missing or invalid visitor token"},
+		{name: "missing", token: "", wantErr: "missing or invalid visitor token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			visitor, err := verifyVisitorToken(tt.token, secret, now)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("verifyVisitorToken() = %q, %v, want error %q", visitor, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || visitor != tt.wantVisitor {
+				t.Fatalf("verifyVisitorToken() = %q, %v, want %q", visitor, err, tt.wantVisitor)
+			}
+		})
+	}
+}
+
+func TestWebchatOriginAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		want    bool
+	}{
+		{name: "no origin", origin: "", want: true},
+		{name: "wildcard", allowed: []string{"*"}, origin: "https://evil.example", want: true},
+		{name: "listed", allowed: []string{"https://shop.example"}, origin: "https://shop.example", want: true},
+		{name: "listed with trailing slash", allowed: []string{"https://shop.example/"}, origin: "https://shop.example", want: true},
+		{name: "listed in other case", allowed: []string{"https://Shop.Example"}, origin: "https://shop.example", want: true},
+		{name: "same host", origin: "https://sympozium.example", want: true},
+		{name: "mismatch", allowed: []string{"https://shop.example"}, origin: "https://evil.example", want: false},
+		{name: "listed host on other scheme", allowed: []string{"https://shop.example"}, origin: "http://shop.example", want: false},
+		{name: "same host suffix", origin: "https://sympozium.example.evil.example", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://sympozium.example/ws/webchat/bot", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if got := webchatOriginAllowed(tt.allowed, r); got != tt.want {
+				t.Errorf("webchatOriginAllowed(%v, %q) = %v, want %v", tt.allowed, tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+// nopBus is an event bus that drops everything.
+type nopBus struct{}
+
+func (nopBus) Publish(context.Context, string, *eventbus.Event) error { return nil }
+func (nopBus) Subscribe(context.Context, string) (<-chan *eventbus.Event, error) {
+	return make(chan *eventbus.Event), nil
+}
+func (nopBus) Consume(context.Context, string, string, func(*eventbus.Event) error) error {
+	return nil
+}
+func (nopBus) Close() error { return nil }
+
+func TestHandleWebChat_NotFound(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = sympoziumv1alpha1.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&sympoziumv1alpha1.SympoziumInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: "no-webchat", Namespace: "default"},
+		Spec: sympoziumv1alpha1.SympoziumInstanceSpec{
+			Channels: []sympoziumv1alpha1.ChannelSpec{{Type: "slack"}},
+		},
+	}).Build()
+	s := NewServer(c, nopBus{}, nil, logr.Discard())
+
+	for _, target := range []string{
+		"/ws/webchat?namespace=kube-system",
+		"/ws/webchat?namespace=default",
+	} {
+		for _, instance := range []string{"missing", "no-webchat"} {
+			r := httptest.NewRequest(http.MethodGet, target, nil)
+			r.SetPathValue("instance", instance)
+			w := httptest.NewRecorder()
+			s.handleWebChat(w, r)
+			if w.Code != http.StatusNotFound || strings.TrimSpace(w.Body.String()) != "web chat not found" {
+				t.Errorf("%s %s: got %d %q, want 404 web chat not found", instance, target, w.Code, w.Body.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,679 @@
+package channel
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Formats of an OutboundMessage's text.
+const (
+	FormatPlain    = "plain"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// Dialect is the markup a chat platform understands.
+type Dialect string
+
+// Dialects of the supported platforms.
+const (
+	// DialectPlain strips all markup.
+	DialectPlain Dialect = "plain"
+	// DialectTelegram is Telegram's MarkdownV2.
+	DialectTelegram Dialect = "telegram"
+	// DialectSlack is Slack's mrkdwn.
+	DialectSlack Dialect = "slack"
+	// DialectDiscord is Discord's Markdown.
+	DialectDiscord Dialect = "discord"
+	// DialectWhatsApp is WhatsApp's text styling.
+	DialectWhatsApp Dialect = "whatsapp"
+	// DialectMatrix is the HTML subset of Matrix's formatted messages.
+	DialectMatrix Dialect = "matrix"
+)
+
+// Limit is the longest message, in characters, sent in the dialect. It
+// leaves some headroom below the platforms' own limits, which count
+// differently.
+func (d Dialect) Limit() int {
+	switch d {
+	case DialectDiscord:
+		return 2000
+	case DialectSlack:
+		// Slack accepts more, but truncates messages over 4000
+		// characters in some clients.
+		return 3900
+	default:
+		// Telegram's limit is 4096. WhatsApp accepts far more but folds
+		// long messages.
+		return 4000
+	}
+}
+
+// Render prepares msg's text for a platform: Markdown, the default format,
+// is converted to the dialect and split into messages no longer than the
+// dialect's limit. Split messages end and start at paragraph, line or word
+// boundaries, and a code block that has to be split is closed and reopened.
+// Plain and HTML text is escaped where the dialect needs it and split.
+func Render(msg OutboundMessage, d Dialect) []string {
+	switch msg.Format {
+	case FormatPlain, FormatHTML:
+		text := msg.Text
+		if d == DialectSlack {
+			text = escapeSlack(text)
+		}
+		return pack(plainBlocks(text), d.Limit())
+	default:
+		return RenderMarkdown(msg.Text, d)
+	}
+}
+
+// RenderMarkdown converts Markdown text to the dialect and splits it into
+// messages no longer than the dialect's limit.
+func RenderMarkdown(text string, d Dialect) []string {
+	var blocks []block
+	for _, b := range parseBlocks(text) {
+		blocks = append(blocks, b.render(d))
+	}
+	parts := pack(blocks, d.Limit())
+	if d == DialectMatrix {
+		for i, p := range parts {
+			parts[i] = htmlBreaks(p)
+		}
+	}
+	return parts
+}
+
+// htmlBreaks turns the newlines of rendered HTML into <br> tags, except in
+// code blocks, which are block elements and need no breaks around them.
+func htmlBreaks(s string) string {
+	var sb strings.Builder
+	for {
+		i := strings.Index(s, "<pre>")
+		if i < 0 {
+			sb.WriteString(strings.ReplaceAll(s, "\n", "<br>"))
+			return sb.String()
+		}
+		sb.WriteString(strings.ReplaceAll(strings.TrimRight(s[:i], "\n"), "\n", "<br>"))
+		j := strings.Index(s[i:], "</pre>")
+		if j < 0 {
+			sb.WriteString(s[i:])
+			return sb.String()
+		}
+		code := s[i : i+j+len("</pre>")]
+		code = strings.Replace(code, ">\n", ">", 1)
+		code = strings.Replace(code, "\n</code>", "</code>", 1)
+		sb.WriteString(code)
+		s = strings.TrimLeft(s[i+j+len("</pre>"):], "\n")
+	}
+}
+
+// block is a paragraph or code block, rendered line by line so it can be
+// split between lines.
+type block struct {
+	lines []string
+	// open and close fence a code block; they are repeated around each
+	// part of a split block.
+	open, close string
+}
+
+func (b block) String() string {
+	s := strings.Join(b.lines, "\n")
+	if b.open != "" {
+		s = b.open + "\n" + s + "\n" + b.close
+	}
+	return s
+}
+
+// pack joins blocks into as few messages as fit in limit.
+func pack(blocks []block, limit int) []string {
+	var out []string
+	var cur strings.Builder
+	size := 0
+	flush := func() {
+		if cur.Len() > 0 {
+			out = append(out, cur.String())
+			cur.Reset()
+			size = 0
+		}
+	}
+	add := func(s string) {
+		n := runes(s)
+		if cur.Len() > 0 && size+2+n > limit {
+			flush()
+		}
+		if cur.Len() > 0 {
+			cur.WriteString("\n\n")
+			size += 2
+		}
+		cur.WriteString(s)
+		size += n
+	}
+	for _, b := range blocks {
+		if s := b.String(); runes(s) <= limit {
+			add(s)
+			continue
+		}
+		for _, part := range splitBlock(b, limit) {
+			add(part)
+		}
+	}
+	flush()
+	return out
+}
+
+// splitBlock splits a block that is longer than limit between lines, and
+// lines that are longer than limit between words.
+func splitBlock(b block, limit int) []string {
+	room := limit
+	if b.open != "" {
+		room -= runes(b.open) + runes(b.close) + 2
+	}
+	var parts []string
+	var lines []string
+	size := 0
+	flush := func() {
+		if len(lines) > 0 {
+			parts = append(parts, block{lines: lines, open: b.open, close: b.close}.String())
+			lines, size = nil, 0
+		}
+	}
+	for _, line := range b.lines {
+		for _, piece := range splitLine(line, room) {
+			n := runes(piece)
+			if len(lines) > 0 && size+1+n > room {
+				flush()
+			}
+			if len(lines) > 0 {
+				size++
+			}
+			lines = append(lines, piece)
+			size += n
+		}
+	}
+	flush()
+	return parts
+}
+
+// splitLine splits a line into pieces no longer than limit, preferring to
+// break after a space. A piece never ends in an escaping backslash.
+func splitLine(line string, limit int) []string {
+	if limit < 2 {
+		limit = 2
+	}
+	var pieces []string
+	for runes(line) > limit {
+		r := []rune(line)
+		cut := limit
+		for i := limit; i > limit/2; i-- {
+			if r[i-1] == ' ' {
+				cut = i
+				break
+			}
+		}
+		backslashes := 0
+		for i := cut - 1; i >= 0 && r[i] == '\\'; i-- {
+			backslashes++
+		}
+		if backslashes%2 == 1 && cut > 1 {
+			cut--
+		}
+		pieces = append(pieces, strings.TrimRight(string(r[:cut]), " "))
+		line = string(r[cut:])
+	}
+	return append(pieces, line)
+}
+
+func runes(s string) int { return utf8.RuneCountInString(s) }
+
+// plainBlocks splits unformatted text into paragraphs.
+func plainBlocks(text string) []block {
+	var blocks []block
+	for _, para := range strings.Split(strings.TrimSpace(text), "\n\n") {
+		if para = strings.Trim(para, "\n"); para != "" {
+			blocks = append(blocks, block{lines: strings.Split(para, "\n")})
+		}
+	}
+	return blocks
+}
+
+// mdBlock is a block of Markdown source.
+type mdBlock struct {
+	lines []string
+	code  bool
+	lang  string
+}
+
+var fenceRe = regexp.MustCompile("^\\s*(```+|~~~+)\\s*([\\w+#.-]*)")
+
+// parseBlocks splits Markdown into code blocks, tables and paragraphs.
+// Tables are kept as code blocks, as no platform renders them.
+func parseBlocks(text string) []mdBlock {
+	var blocks []mdBlock
+	var cur *mdBlock
+	end := func() {
+		if cur != nil && len(cur.lines) > 0 {
+			blocks = append(blocks, *cur)
+		}
+		cur = nil
+	}
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if m := fenceRe.FindStringSubmatch(line); m != nil {
+			end()
+			code := mdBlock{code: true, lang: m[2]}
+			for i++; i < len(lines); i++ {
+				if strings.HasPrefix(strings.TrimSpace(lines[i]), m[1]) {
+					break
+				}
+				code.lines = append(code.lines, lines[i])
+			}
+			if len(code.lines) == 0 {
+				code.lines = []string{""}
+			}
+			blocks = append(blocks, code)
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		isTable := strings.HasPrefix(trimmed, "|")
+		switch {
+		case trimmed == "":
+			end()
+			continue
+		case cur != nil && cur.code != isTable:
+			end()
+		}
+		if cur == nil {
+			cur = &mdBlock{code: isTable}
+		}
+		cur.lines = append(cur.lines, line)
+	}
+	end()
+	return blocks
+}
+
+// render converts a Markdown block to the dialect.
+func (b mdBlock) render(d Dialect) block {
+	if b.code {
+		return renderCode(b.lines, b.lang, d)
+	}
+	out := block{lines: make([]string, 0, len(b.lines))}
+	for _, line := range b.lines {
+		out.lines = append(out.lines, renderLine(line, d))
+	}
+	return out
+}
+
+// renderCode renders the lines of a code block.
+func renderCode(lines []string, lang string, d Dialect) block {
+	out := block{lines: make([]string, len(lines))}
+	for i, line := range lines {
+		switch d {
+		case DialectTelegram:
+			line = escapeTelegramCode(line)
+		case DialectSlack:
+			line = escapeSlack(line)
+		case DialectMatrix:
+			line = html.EscapeString(line)
+		}
+		out.lines[i] = line
+	}
+	switch d {
+	case DialectPlain:
+	case DialectMatrix:
+		out.open, out.close = "<pre><code>", "</code></pre>"
+		if lang != "" {
+			out.open = `<pre><code class="language-` + html.EscapeString(lang) + `">`
+		}
+	case DialectTelegram, DialectDiscord:
+		out.open, out.close = "```"+lang, "```"
+	default:
+		// Slack and WhatsApp would show the language as code.
+		out.open, out.close = "```", "```"
+	}
+	return out
+}
+
+var (
+	headingRe = regexp.MustCompile(`^#{1,6}\s+`)
+	bulletRe  = regexp.MustCompile(`^(\s*)[-*+]\s+`)
+	orderedRe = regexp.MustCompile(`^(\s*)(\d+)[.)]\s+`)
+	ruleRe    = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+)
+
+// renderLine renders a line of a paragraph: headings, list items, quotes
+// and rules, and the inline markup within them.
+func renderLine(line string, d Dialect) string {
+	switch {
+	case ruleRe.MatchString(line):
+		return "──────────"
+	case headingRe.MatchString(line):
+		level := strings.IndexFunc(line, func(r rune) bool { return r != '#' })
+		text := strings.TrimRight(headingRe.ReplaceAllString(line, ""), " #")
+		if d == DialectDiscord && level <= 3 {
+			return strings.Repeat("#", level) + " " + renderInline(text, d)
+		}
+		if d == DialectPlain {
+			return renderInline(text, d)
+		}
+		return emitNode(node{kind: nodeBold, kids: parseInline(text)}, d, 0)
+	case strings.HasPrefix(strings.TrimSpace(line), ">"):
+		text := strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(line), ">"), " ")
+		return "> " + renderInline(text, d)
+	}
+	if m := bulletRe.FindStringSubmatch(line); m != nil {
+		return m[1] + "• " + renderInline(line[len(m[0]):], d)
+	}
+	if m := orderedRe.FindStringSubmatch(line); m != nil {
+		return m[1] + escapeText(m[2]+". ", d) + renderInline(line[len(m[0]):], d)
+	}
+	return renderInline(strings.TrimLeft(line, " \t"), d)
+}
+
+func renderInline(text string, d Dialect) string {
+	var sb strings.Builder
+	for _, n := range parseInline(text) {
+		sb.WriteString(emitNode(n, d, 0))
+	}
+	return sb.String()
+}
+
+// Kinds of inline Markdown.
+const (
+	nodeText = iota
+	nodeCode
+	nodeBold
+	nodeItalic
+	nodeStrike
+	nodeLink
+)
+
+// node is a piece of inline Markdown.
+type node struct {
+	kind int
+	text string // text, code, or a link's URL
+	kids []node
+}
+
+// parseInline parses emphasis, code spans and links. Unmatched markers are
+// kept as text.
+func parseInline(s string) []node {
+	var nodes []node
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			nodes = append(nodes, node{kind: nodeText, text: text.String()})
+			text.Reset()
+		}
+	}
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte("\\`*_~[]()#>-+.!|", s[i+1]) >= 0:
+			text.WriteByte(s[i+1])
+			i += 2
+			continue
+		case c == '`':
+			n := 1
+			for i+n < len(s) && s[i+n] == '`' {
+				n++
+			}
+			fence := s[i : i+n]
+			if end := strings.Index(s[i+n:], fence); end >= 0 {
+				flush()
+				code := s[i+n : i+n+end]
+				if len(code) > 1 && code[0] == ' ' && code[len(code)-1] == ' ' {
+					code = code[1 : len(code)-1]
+				}
+				nodes = append(nodes, node{kind: nodeCode, text: code})
+				i += 2*n + end
+				continue
+			}
+		case c == '[':
+			if label, url, n, ok := parseLink(s[i:]); ok {
+				flush()
+				nodes = append(nodes, node{kind: nodeLink, text: url, kids: parseInline(label)})
+				i += n
+				continue
+			}
+		case c == '*' || c == '_' || c == '~':
+			if kind, inner, n, ok := parseEmphasis(s, i); ok {
+				flush()
+				nodes = append(nodes, node{kind: kind, kids: parseInline(inner)})
+				i += n
+				continue
+			}
+		}
+		text.WriteByte(c)
+		i++
+	}
+	flush()
+	return nodes
+}
+
+// parseLink parses a [label](url) link at the start of s.
+func parseLink(s string) (label, url string, n int, ok bool) {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth > 0 {
+				continue
+			}
+			if i+1 >= len(s) || s[i+1] != '(' {
+				return "", "", 0, false
+			}
+			// URLs may contain balanced parentheses.
+			parens := 0
+			for j := i + 2; j < len(s); j++ {
+				switch s[j] {
+				case ' ', '\t':
+					return "", "", 0, false
+				case '(':
+					parens++
+				case ')':
+					if parens > 0 {
+						parens--
+						continue
+					}
+					return s[1:i], s[i+2 : j], j + 1, true
+				}
+			}
+			return "", "", 0, false
+		}
+	}
+	return "", "", 0, false
+}
+
+// parseEmphasis parses bold (** or __), italic (* or _) or strikethrough
+// (~~) starting at s[i]. Underscores only count at word boundaries, so
+// snake_case is left alone.
+func parseEmphasis(s string, i int) (kind int, inner string, n int, ok bool) {
+	c := s[i]
+	marker := string(c)
+	switch {
+	case strings.HasPrefix(s[i:], strings.Repeat(marker, 2)):
+		marker += marker
+		kind = nodeBold
+		if c == '~' {
+			kind = nodeStrike
+		}
+	case c == '~':
+		return 0, "", 0, false
+	default:
+		kind = nodeItalic
+	}
+	start := i + len(marker)
+	if start >= len(s) || isSpace(s[start]) {
+		return 0, "", 0, false
+	}
+	if c == '_' && i > 0 && isWord(s[i-1]) {
+		return 0, "", 0, false
+	}
+	for j := start + 1; j+len(marker) <= len(s); j++ {
+		if s[j:j+len(marker)] != marker || isSpace(s[j-1]) {
+			continue
+		}
+		after := j + len(marker)
+		if len(marker) == 1 && after < len(s) && s[after] == c {
+			// Part of a longer run, e.g. the end of "*a **b***".
+			continue
+		}
+		if c == '_' && after < len(s) && isWord(s[after]) {
+			continue
+		}
+		return kind, s[start:j], after - i, true
+	}
+	return 0, "", 0, false
+}
+
+func isSpace(c byte) bool { return c == ' ' || c == '\t' }
+
+func isWord(c byte) bool {
+	return c >= utf8.RuneSelf || c == '_' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
+
+// emitNode renders an inline node. style holds the styles of enclosing
+// nodes, so a style nested in itself is not repeated.
+func emitNode(n node, d Dialect, style int) string {
+	kids := func(style int) string {
+		var sb strings.Builder
+		for _, k := range n.kids {
+			sb.WriteString(emitNode(k, d, style))
+		}
+		return sb.String()
+	}
+	switch n.kind {
+	case nodeText:
+		return escapeText(n.text, d)
+	case nodeCode:
+		switch d {
+		case DialectPlain:
+			return n.text
+		case DialectTelegram:
+			return "`" + escapeTelegramCode(n.text) + "`"
+		case DialectSlack:
+			return "`" + escapeSlack(n.text) + "`"
+		case DialectWhatsApp:
+			return "```" + n.text + "```"
+		case DialectMatrix:
+			return "<code>" + html.EscapeString(n.text) + "</code>"
+		default:
+			return "`" + n.text + "`"
+		}
+	case nodeLink:
+		label := kids(style)
+		if !safeLinkURL(n.text) {
+			return label + " (" + escapeText(n.text, d) + ")"
+		}
+		switch d {
+		case DialectTelegram:
+			return "[" + label + "](" + escapeTelegramURL(n.text) + ")"
+		case DialectSlack:
+			return "<" + n.text + "|" + label + ">"
+		case DialectDiscord:
+			return "[" + label + "](" + n.text + ")"
+		case DialectMatrix:
+			return `<a href="` + html.EscapeString(n.text) + `">` + label + "</a>"
+		default:
+			if label == n.text {
+				return n.text
+			}
+			return label + " (" + n.text + ")"
+		}
+	}
+
+	bit := 1 << n.kind
+	if d == DialectPlain || style&bit != 0 {
+		return kids(style)
+	}
+	if d == DialectMatrix {
+		tag := "strong"
+		switch n.kind {
+		case nodeItalic:
+			tag = "em"
+		case nodeStrike:
+			tag = "del"
+		}
+		return "<" + tag + ">" + kids(style|bit) + "</" + tag + ">"
+	}
+	var marker string
+	switch n.kind {
+	case nodeBold:
+		marker = "*"
+		if d == DialectDiscord {
+			marker = "**"
+		}
+	case nodeItalic:
+		marker = "_"
+		if d == DialectDiscord {
+			marker = "*"
+		}
+	case nodeStrike:
+		marker = "~"
+		if d == DialectDiscord {
+			marker = "~~"
+		}
+	}
+	return marker + kids(style|bit) + marker
+}
+
+// telegramSpecial are the characters MarkdownV2 needs escaped in text.
+const telegramSpecial = "_*[]()~`>#+-=|{}.!\\"
+
+// escapeText escapes text so the dialect shows it literally.
+// safeLinkURL reports whether a link target may be rendered as a link.
+// Other schemes, such as javascript:, become plain text.
+func safeLinkURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+func escapeText(s string, d Dialect) string {
+	switch d {
+	case DialectTelegram:
+		return escapeAny(s, telegramSpecial)
+	case DialectSlack:
+		return escapeSlack(s)
+	case DialectDiscord:
+		return escapeAny(s, "\\*_~`|")
+	case DialectMatrix:
+		return html.EscapeString(s)
+	default:
+		return s
+	}
+}
+
+func escapeTelegramCode(s string) string { return escapeAny(s, "`\\") }
+
+func escapeTelegramURL(s string) string { return escapeAny(s, ")\\") }
+
+func escapeAny(s, special string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if r < utf8.RuneSelf && strings.ContainsRune(special, r) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// escapeSlack escapes the characters Slack reserves for links and mentions.
+func escapeSlack(s string) string { return slackEscaper.Replace(s) }
@@ -0,0 +1,107 @@
+package channel
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	src := "# Result\n\nThis is **bold**, *italic*, ~~gone~~ and `x := 1`.\n" +
+		"- see [docs](https://example.com/a_(b))\n- snake_case stays\n\n" +
+		"```go\nfmt.Println(\"a`b\")\n```"
+
+	tests := []struct {
+		d    Dialect
+		want string
+	}{
+		{DialectTelegram, "*Result*\n\n" +
+			"This is *bold*, _italic_, ~gone~ and `x := 1`\\.\n" +
+			"• see [docs](https://example.com/a_(b\\))\n• snake\\_case stays\n\n" +
+			"```go\nfmt.Println(\"a\\`b\")\n```"},
+		{DialectSlack, "*Result*\n\n" +
+			"This is *bold*, _italic_, ~gone~ and `x := 1`.\n" +
+			"• see <https://example.com/a_(b)|docs>\n• snake_case stays\n\n" +
+			"```\nfmt.Println(\"a`b\")\n```"},
+		{DialectDiscord, "# Result\n\n" +
+			"This is **bold**, *italic*, ~~gone~~ and `x := 1`.\n" +
+			"• see [docs](https://example.com/a_(b))\n• snake\\_case stays\n\n" +
+			"```go\nfmt.Println(\"a`b\")\n```"},
+		{DialectWhatsApp, "*Result*\n\n" +
+			"This is *bold*, _italic_, ~gone~ and ```x := 1```.\n" +
+			"• see docs (https://example.com/a_(b))\n• snake_case stays\n\n" +
+			"```\nfmt.Println(\"a`b\")\n```"},
+		{DialectMatrix, "<strong>Result</strong><br><br>" +
+			"This is <strong>bold</strong>, <em>italic</em>, <del>gone</del> and <code>x := 1</code>.<br>" +
+			"• see <a href=\"https://example.com/a_(b)\">docs</a><br>• snake_case stays" +
+			"<pre><code class=\"language-go\">fmt.Println(&#34;a`b&#34;)</code></pre>"},
+		{DialectPlain, "Result\n\n" +
+			"This is bold, italic, gone and x := 1.\n" +
+			"• see docs (https://example.com/a_(b))\n• snake_case stays\n\n" +
+			"fmt.Println(\"a`b\")"},
+	}
+	for _, tt := range tests {
+		got := RenderMarkdown(src, tt.d)
+		if len(got) != 1 || got[0] != tt.want {
+			t.Errorf("%s:\n got %q\nwant %q", tt.d, got, tt.want)
+		}
+	}
+
+	if got := RenderMarkdown("**a *b* c**", DialectSlack); got[0] != "*a _b_ c*" {
+		t.Errorf("nested emphasis = %q", got[0])
+	}
+	if got := RenderMarkdown("## **Done**", DialectTelegram); got[0] != "*Done*" {
+		t.Errorf("bold heading = %q", got[0])
+	}
+	if got := RenderMarkdown("2 * 3 * 4", DialectTelegram); got[0] != "2 \\* 3 \\* 4" {
+		t.Errorf("loose asterisks = %q", got[0])
+	}
+}
+
+func TestRenderMarkdown_UnsafeLinks(t *testing.T) {
+	src := "[x](javascript:alert(document.cookie)) [mail](mailto:ops@example.com)"
+	want := "x (javascript:alert(document.cookie)) <a href=\"mailto:ops@example.com\">mail</a>"
+	if got := RenderMarkdown(src, DialectMatrix); got[0] != want {
+		t.Errorf("matrix:\n got %q\nwant %q", got[0], want)
+	}
+	if got := RenderMarkdown("[x](JavaScript:alert(1))", DialectDiscord); got[0] != "x (JavaScript:alert(1))" {
+		t.Errorf("discord = %q", got[0])
+	}
+}
+
+func TestRenderSplitsLongMessages(t *testing.T) {
+	para := strings.Repeat("word ", 300)
+	code := "```\n" + strings.Repeat("line of code\n", 300) + "```"
+	msg := OutboundMessage{Text: para + "\n\n" + code + "\n\n" + para}
+
+	parts := Render(msg, DialectDiscord)
+	if len(parts) < 4 {
+		t.Fatalf("got %d parts, want at least 4", len(parts))
+	}
+	for i, p := range parts {
+		if n := len([]rune(p)); n > DialectDiscord.Limit() {
+			t.Errorf("part %d has %d characters", i, n)
+		}
+		if strings.Count(p, "```")%2 != 0 {
+			t.Errorf("part %d has an unclosed code block", i)
+		}
+		if strings.HasPrefix(p, "```") && !strings.HasPrefix(p, "```\nline of code") {
+			t.Errorf("part %d splits a code line: %.40q", i, p)
+		}
+		if strings.HasPrefix(p, "ord") || strings.HasSuffix(p, "wor") {
+			t.Errorf("part %d splits a word", i)
+		}
+	}
+
+	long := OutboundMessage{Text: strings.Repeat(".", 5000), Format: FormatPlain}
+	for _, p := range Render(long, DialectTelegram) {
+		if len(p) > DialectTelegram.Limit() {
+			t.Errorf("plain part has %d characters", len(p))
+		}
+	}
+	if got := RenderMarkdown(strings.Repeat(".", 4001), DialectTelegram); strings.HasSuffix(got[0], "\\") && !strings.HasSuffix(got[0], "\\.") {
+		t.Errorf("part ends in an escaping backslash")
+	}
+	if got := Render(OutboundMessage{Text: "a < b & c", Format: FormatPlain}, DialectSlack); got[0] != "a &lt; b &amp; c" {
+		t.Errorf("plain Slack text = %q", got[0])
+	}
+}
@@ -0,0 +1,104 @@
+package channel
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Attachment types.
+const (
+	AttachmentImage = "image"
+	AttachmentAudio = "audio"
+	AttachmentVideo = "video"
+	AttachmentFile  = "file"
+)
+
+// MaxMediaBytes caps the attachments of one message. Base64 encoded, they
+// have to fit in a NATS message, which is 1 MiB by default.
+const MaxMediaBytes = 640 << 10
+
+// AttachmentType returns the attachment type of a MIME type.
+func AttachmentType(mimeType string) string {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return AttachmentImage
+	case strings.HasPrefix(mimeType, "audio/"):
+		return AttachmentAudio
+	case strings.HasPrefix(mimeType, "video/"):
+		return AttachmentVideo
+	default:
+		return AttachmentFile
+	}
+}
+
+// MediaRoom returns how many more bytes of attachments m can carry.
+func (m *InboundMessage) MediaRoom() int {
+	room := MaxMediaBytes
+	for _, a := range m.Attachments {
+		room -= len(a.Data)
+	}
+	return room
+}
+
+// Attach adds a downloaded attachment to m. One that does not fit is left
+// out and mentioned in the text instead, so the agent knows about it.
+func (m *InboundMessage) Attach(a Attachment) bool {
+	if a.Type == "" {
+		a.Type = AttachmentType(a.MimeType)
+	}
+	if len(a.Data) > m.MediaRoom() {
+		m.SkipAttachment(a, "it is too large")
+		return false
+	}
+	m.Attachments = append(m.Attachments, a)
+	return true
+}
+
+// SkipAttachment notes in m's text that an attachment was left out.
+func (m *InboundMessage) SkipAttachment(a Attachment, reason string) {
+	name := a.Filename
+	if name == "" {
+		name = "an attachment"
+	}
+	if a.Type == "" {
+		a.Type = AttachmentType(a.MimeType)
+	}
+	note := fmt.Sprintf("[%s (%s) was not attached: %s]", name, a.Type, reason)
+	if m.Text != "" {
+		m.Text += "\n\n"
+	}
+	m.Text += note
+}
+
+// Download fetches an attachment with req and adds it to m. Files larger
+// than m has room for are not downloaded.
+func (m *InboundMessage) Download(client *http.Client, req *http.Request, a Attachment) bool {
+	room := m.MediaRoom()
+	resp, err := client.Do(req)
+	if err != nil {
+		m.SkipAttachment(a, "the download failed")
+		return false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		m.SkipAttachment(a, fmt.Sprintf("the download failed with HTTP %d", resp.StatusCode))
+		return false
+	}
+	if resp.ContentLength > int64(room) {
+		m.SkipAttachment(a, "it is too large")
+		return false
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(room)+1))
+	if err != nil {
+		m.SkipAttachment(a, "the download failed")
+		return false
+	}
+	if a.MimeType == "" {
+		a.MimeType, _, _ = mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	}
+	a.Data = data
+	return m.Attach(a)
+}
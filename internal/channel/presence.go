@@ -0,0 +1,123 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/alexsjones/sympozium/internal/eventbus"
+)
+
+// typingTimeout stops an indicator whose run has sent no event for this
+// long, e.g. because its pod died.
+const typingTimeout = 10 * time.Minute
+
+// Presence keeps a channel's native typing indicator running in each chat
+// where an agent run is working.
+type Presence struct {
+	// Refresh is how often Show is repeated while a run is active, as most
+	// platforms expire the indicator after a few seconds. Zero shows it once.
+	Refresh time.Duration
+	// Show starts or renews the indicator.
+	Show func(ctx context.Context, ev TypingEvent) error
+	// Clear removes the indicator when the run stops. It is optional.
+	Clear func(ctx context.Context, ev TypingEvent) error
+
+	mu     sync.Mutex
+	active map[string]*presenceRun
+}
+
+type presenceRun struct {
+	update chan TypingEvent
+	stop   chan struct{}
+}
+
+// RunPresence shows typing indicators for this channel's active runs until
+// ctx is cancelled.
+func (bc *BaseChannel) RunPresence(ctx context.Context, p *Presence) error {
+	events, err := bc.EventBus.Subscribe(ctx, eventbus.TopicChannelTyping)
+	if err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-events:
+			var ev TypingEvent
+			if err := json.Unmarshal(event.Data, &ev); err != nil || ev.Channel != bc.ChannelType {
+				continue
+			}
+			p.handle(ctx, ev)
+		}
+	}
+}
+
+// handle starts, updates or stops the indicator of ev's run.
+func (p *Presence) handle(ctx context.Context, ev TypingEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.active == nil {
+		p.active = map[string]*presenceRun{}
+	}
+	r, ok := p.active[ev.RunName]
+	switch {
+	case ok && ev.Active:
+		// Keep only the latest update.
+		select {
+		case <-r.update:
+		default:
+		}
+		r.update <- ev
+	case ok:
+		delete(p.active, ev.RunName)
+		close(r.stop)
+	case ev.Active:
+		r = &presenceRun{update: make(chan TypingEvent, 1), stop: make(chan struct{})}
+		p.active[ev.RunName] = r
+		go p.keep(ctx, ev, r)
+	}
+}
+
+// keep shows the indicator until the run stops or goes quiet.
+func (p *Presence) keep(ctx context.Context, ev TypingEvent, r *presenceRun) {
+	_ = p.Show(ctx, ev)
+
+	var refresh <-chan time.Time
+	if p.Refresh > 0 {
+		t := time.NewTicker(p.Refresh)
+		defer t.Stop()
+		refresh = t.C
+	}
+	idle := time.NewTimer(typingTimeout)
+	defer idle.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-refresh:
+			_ = p.Show(ctx, ev)
+		case ev = <-r.update:
+			idle.Reset(typingTimeout)
+		case <-idle.C:
+			p.mu.Lock()
+			if p.active[ev.RunName] == r {
+				delete(p.active, ev.RunName)
+			}
+			p.mu.Unlock()
+			p.clear(ctx, ev)
+			return
+		case <-r.stop:
+			p.clear(ctx, ev)
+			return
+		}
+	}
+}
+
+func (p *Presence) clear(ctx context.Context, ev TypingEvent) {
+	if p.Clear != nil {
+		_ = p.Clear(ctx, ev)
+	}
+}
@@ -1,5 +1,5 @@
 // Package channel provides base types and interfaces for Sympozium channel implementations.
-// Each channel type (Telegram, WhatsApp, Discord, Slack) runs as its own pod
+// Each channel type (Telegram, WhatsApp, Discord, Slack, Matrix) runs as its own pod
 // and uses this framework to connect to the event bus.
 package channel
 
@@ -20,6 +20,14 @@ type InboundMessage struct {
 	Text         string            `json:"text"`
 	Attachments  []Attachment      `json:"attachments,omitempty"`
 	Metadata     map[string]string `json:"metadata,omitempty"`
+
+	// IsDirect is true for a one-to-one chat with the bot.
+	IsDirect bool `json:"isDirect,omitempty"`
+	// Mentioned is true when the message mentions the bot.
+	Mentioned bool `json:"mentioned,omitempty"`
+	// ReplyToBot is true when the message replies to one of the bot's
+	// messages.
+	ReplyToBot bool `json:"replyToBot,omitempty"`
 }
 
 // OutboundMessage represents a message to send to an external channel.
@@ -28,8 +36,10 @@ type OutboundMessage struct {
 	ChatID   string `json:"chatId"`
 	ThreadID string `json:"threadId,omitempty"`
 	Text     string `json:"text"`
-	Format   string `json:"format,omitempty"` // plain, markdown, html
+	Format   string `json:"format,omitempty"` // plain, markdown (default), html
 	ReplyTo  string `json:"replyTo,omitempty"`
+	// Attachments are sent after the text.
+	Attachments []Attachment `json:"attachments,omitempty"`
 }
 
 // Attachment represents a file or media attachment.
@@ -38,6 +48,24 @@ type Attachment struct {
 	URL      string `json:"url,omitempty"`
 	Filename string `json:"filename,omitempty"`
 	MimeType string `json:"mimeType,omitempty"`
+	// Data is the file's content. Messages carry it on the event bus, so
+	// all of a message's attachments together are capped at
+	// MaxMediaBytes.
+	Data []byte `json:"data,omitempty"`
+}
+
+// TypingEvent tells a channel that an agent run is working on a reply in a
+// chat, or that it has stopped.
+type TypingEvent struct {
+	Channel  string `json:"channel"`
+	ChatID   string `json:"chatId"`
+	ThreadID string `json:"threadId,omitempty"`
+	// MessageID is the message the run is replying to.
+	MessageID string `json:"messageId,omitempty"`
+	RunName   string `json:"runName"`
+	Phase     string `json:"phase,omitempty"` // thinking, tool_use, responding
+	Tool      string `json:"tool,omitempty"`
+	Active    bool   `json:"active"`
 }
 
 // HealthStatus represents the connection health of a channel.
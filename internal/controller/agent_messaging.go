@@ -0,0 +1,159 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sympoziumv1alpha1 "github.com/alexsjones/sympozium/api/v1alpha1"
+	"github.com/alexsjones/sympozium/internal/ipc"
+	"github.com/alexsjones/sympozium/internal/orchestrator"
+)
+
+// Labels and annotations recording an agent-to-agent call on the AgentRun
+// it creates.
+const (
+	callerInstanceLabel = "sympozium.ai/caller-instance"
+	callerRunLabel      = "sympozium.ai/caller-run"
+	callChainAnnotation = orchestrator.CallChainAnnotation
+)
+
+// defaultMaxChainLength applies when a policy does not set MaxChainLength.
+const defaultMaxChainLength = 3
+
+// sendAgentMessage creates an AgentRun on the target instance for a
+// send_agent_message call. The reply is delivered to the caller by
+// Reconcile once the run finishes.
+func (sr *SpawnRouter) sendAgentMessage(ctx context.Context, caller *sympoziumv1alpha1.AgentRun, req ipc.SpawnRequest) error {
+	target := req.TargetInstance
+	if target == caller.Spec.InstanceRef {
+		return fmt.Errorf("cannot message your own instance %q; use spawn_agent instead", target)
+	}
+
+	callerInst := &sympoziumv1alpha1.SympoziumInstance{}
+	if err := sr.Client.Get(ctx, client.ObjectKey{Namespace: caller.Namespace, Name: caller.Spec.InstanceRef}, callerInst); err != nil {
+		return fmt.Errorf("instance %q not found", caller.Spec.InstanceRef)
+	}
+	targetInst := &sympoziumv1alpha1.SympoziumInstance{}
+	if err := sr.Client.Get(ctx, client.ObjectKey{Namespace: caller.Namespace, Name: target}, targetInst); err != nil {
+		return fmt.Errorf("instance %q not found", target)
+	}
+	callerPolicy, err := sr.agentMessagingPolicy(ctx, callerInst)
+	if err != nil {
+		return err
+	}
+	targetPolicy, err := sr.agentMessagingPolicy(ctx, targetInst)
+	if err != nil {
+		return err
+	}
+
+	chain := callChain(caller)
+	if err := checkAgentMessaging(caller.Spec.InstanceRef, callerPolicy, target, targetPolicy, chain); err != nil {
+		return err
+	}
+
+	authSecret := ""
+	if len(targetInst.Spec.AuthRefs) > 0 {
+		authSecret = targetInst.Spec.AuthRefs[0].Secret
+	}
+	run := &sympoziumv1alpha1.AgentRun{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: target + "-msg-",
+			Namespace:    caller.Namespace,
+			Labels: map[string]string{
+				"sympozium.ai/instance": target,
+				"sympozium.ai/source":   "agent",
+				callerInstanceLabel:     caller.Spec.InstanceRef,
+				callerRunLabel:          caller.Name,
+			},
+			Annotations: map[string]string{
+				callChainAnnotation:            strings.Join(chain, ","),
+				orchestrator.SpawnIDAnnotation: req.ID,
+			},
+		},
+		Spec: sympoziumv1alpha1.AgentRunSpec{
+			InstanceRef: target,
+			AgentID:     "primary",
+			SessionKey:  fmt.Sprintf("agent-%s-%s", caller.Name, req.ID),
+			Task: fmt.Sprintf("Message from the agent of instance %q (run %s). Reply with your answer.\n\n%s",
+				caller.Spec.InstanceRef, caller.Name, req.Task),
+			Model: sympoziumv1alpha1.ModelSpec{
+				Provider:      targetInst.Provider(),
+				Model:         targetInst.Spec.Agents.Default.Model,
+				BaseURL:       targetInst.Spec.Agents.Default.BaseURL,
+				Thinking:      targetInst.Spec.Agents.Default.Thinking,
+				AuthSecretRef: authSecret,
+				Fallbacks:     targetInst.Spec.Agents.Fallbacks,
+			},
+			Skills:  targetInst.Spec.Skills,
+			Timeout: &metav1.Duration{Duration: 10 * time.Minute},
+		},
+	}
+	if err := sr.Client.Create(ctx, run); err != nil {
+		return fmt.Errorf("creating run on %q: %w", target, err)
+	}
+
+	sr.Log.Info("Sent agent message", "from", caller.Spec.InstanceRef, "to", target,
+		"callerRun", caller.Name, "run", run.Name, "chain", len(chain))
+	return nil
+}
+
+// agentMessagingPolicy returns the AgentMessaging section of an instance's
+// SympoziumPolicy, or nil when it has none.
+func (sr *SpawnRouter) agentMessagingPolicy(ctx context.Context, inst *sympoziumv1alpha1.SympoziumInstance) (*sympoziumv1alpha1.AgentMessagingSpec, error) {
+	if inst.Spec.PolicyRef == "" {
+		return nil, nil
+	}
+	policy := &sympoziumv1alpha1.SympoziumPolicy{}
+	if err := sr.Client.Get(ctx, client.ObjectKey{Namespace: inst.Namespace, Name: inst.Spec.PolicyRef}, policy); err != nil {
+		return nil, fmt.Errorf("policy %q not found for instance %q", inst.Spec.PolicyRef, inst.Name)
+	}
+	return policy.Spec.AgentMessaging, nil
+}
+
+// callChain returns the call chain for a run started by caller: caller's own
+// chain followed by caller itself.
+func callChain(caller *sympoziumv1alpha1.AgentRun) []string {
+	var chain []string
+	if prev := caller.Annotations[callChainAnnotation]; prev != "" {
+		chain = strings.Split(prev, ",")
+	}
+	return append(chain, caller.Spec.InstanceRef+"/"+caller.Name)
+}
+
+// checkAgentMessaging reports whether callerInst may message targetInst.
+// The caller's policy must list the target, the target's policy (if it
+// restricts callers) must list the caller, and the call must neither loop
+// back to an instance already in the chain nor exceed MaxChainLength.
+func checkAgentMessaging(callerInst string, callerPolicy *sympoziumv1alpha1.AgentMessagingSpec, targetInst string, targetPolicy *sympoziumv1alpha1.AgentMessagingSpec, chain []string) error {
+	if callerPolicy == nil || !matchesInstance(callerPolicy.AllowedTargets, targetInst) {
+		return fmt.Errorf("policy for instance %q does not allow messaging %q", callerInst, targetInst)
+	}
+	if targetPolicy != nil && len(targetPolicy.AllowedCallers) > 0 && !matchesInstance(targetPolicy.AllowedCallers, callerInst) {
+		return fmt.Errorf("policy for instance %q does not accept messages from %q", targetInst, callerInst)
+	}
+	for _, hop := range chain {
+		if inst, _, _ := strings.Cut(hop, "/"); inst == targetInst {
+			return fmt.Errorf("instance %q is already in the call chain %s", targetInst, strings.Join(chain, " -> "))
+		}
+	}
+	maxLen := callerPolicy.MaxChainLength
+	if maxLen <= 0 {
+		maxLen = defaultMaxChainLength
+	}
+	// The chain holds the callers; the target makes it one longer.
+	if len(chain)+1 > maxLen {
+		return fmt.Errorf("call chain would reach %d agents (max %d)", len(chain)+1, maxLen)
+	}
+	return nil
+}
+
+// matchesInstance reports whether names contains name or "*".
+func matchesInstance(names []string, name string) bool {
+	return slices.Contains(names, "*") || slices.Contains(names, name)
+}
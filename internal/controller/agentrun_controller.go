@@ -27,6 +27,10 @@ import (
 )
 
 const agentRunFinalizer = "sympozium.ai/agentrun-finalizer"
+
+// cancelAnnotation on an AgentRun asks the controller to stop it. Its value
+// says who cancelled the run.
+const cancelAnnotation = "sympozium.ai/cancel"
 const systemNamespace = "sympozium-system"
 
 // DefaultRunHistoryLimit is how many completed AgentRuns to keep per instance
@@ -124,6 +128,10 @@ func (r *AgentRunReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 func (r *AgentRunReconciler) reconcilePending(ctx context.Context, log logr.Logger, agentRun *sympoziumv1alpha1.AgentRun) (ctrl.Result, error) {
 	log.Info("Reconciling pending AgentRun")
 
+	if by, ok := agentRun.Annotations[cancelAnnotation]; ok {
+		return ctrl.Result{}, r.failRun(ctx, agentRun, "cancelled by "+by)
+	}
+
 	// Validate against policy
 	if err := r.validatePolicy(ctx, agentRun); err != nil {
 		return ctrl.Result{}, r.failRun(ctx, agentRun, fmt.Sprintf("policy validation failed: %v", err))
@@ -143,11 +151,13 @@ func (r *AgentRunReconciler) reconcilePending(ctx context.Context, log logr.Logg
 	instance := &sympoziumv1alpha1.SympoziumInstance{}
 	memoryEnabled := false
 	var observability *sympoziumv1alpha1.ObservabilitySpec
+	var toolGating *sympoziumv1alpha1.ToolGatingSpec
 	if err := r.Get(ctx, client.ObjectKey{
 		Namespace: agentRun.Namespace,
 		Name:      agentRun.Spec.InstanceRef,
 	}, instance); err == nil {
-		if instance.Spec.Memory != nil && instance.Spec.Memory.Enabled {
+		// Memory task runs are given the memory in their task instead.
+		if instance.Spec.Memory != nil && instance.Spec.Memory.Enabled && memoryTask(agentRun) == "" {
 			memoryEnabled = true
 		}
 		if instance.Spec.Observability != nil && instance.Spec.Observability.Enabled {
@@ -163,9 +173,28 @@ func (r *AgentRunReconciler) reconcilePending(ctx context.Context, log logr.Logg
 		// If the AgentRun has no skills, inherit from the SympoziumInstance.
 		// This is a safety net — tuiCreateRun and the schedule controller
 		// should already copy skills, but older runs or manual CRs may not.
-		if len(agentRun.Spec.Skills) == 0 && len(instance.Spec.Skills) > 0 {
+		if len(agentRun.Spec.Skills) == 0 && len(instance.Spec.Skills) > 0 && memoryTask(agentRun) == "" {
 			agentRun.Spec.Skills = instance.Spec.Skills
 		}
+		// The policy's tool gating is enforced by the agent-runner at call
+		// time, not only at admission.
+		if instance.Spec.PolicyRef != "" {
+			policy := &sympoziumv1alpha1.SympoziumPolicy{}
+			if err := r.Get(ctx, client.ObjectKey{
+				Namespace: agentRun.Namespace,
+				Name:      instance.Spec.PolicyRef,
+			}, policy); err == nil {
+				toolGating = policy.Spec.ToolGating
+			}
+		}
+	}
+
+	// Record the memory revision the run reads so concurrent updates can
+	// be merged when it finishes.
+	if memoryEnabled {
+		if err := (memoryUpdater{r.Client}).recordMemoryRevision(ctx, agentRun); err != nil {
+			log.V(1).Info("could not record memory revision", "err", err)
+		}
 	}
 
 	// Resolve skill sidecars from SkillPack CRDs.
@@ -184,6 +213,8 @@ func (r *AgentRunReconciler) reconcilePending(ctx context.Context, log logr.Logg
 
 	// Build and create the Job
 	job := r.buildJob(agentRun, memoryEnabled, observability, sidecars)
+	setToolGatingEnv(job, toolGating)
+	setMCPServersEnv(job, r.resolveMCPServers(ctx, log, agentRun))
 	if err := controllerutil.SetControllerReference(agentRun, job, r.Scheme); err != nil {
 		return ctrl.Result{}, fmt.Errorf("setting owner reference: %w", err)
 	}
@@ -241,8 +272,8 @@ func (r *AgentRunReconciler) reconcileRunning(ctx context.Context, log logr.Logg
 	if job.Status.Succeeded > 0 {
 		// Extract the LLM response from pod logs before the pod is gone.
 		result, _, usage := r.extractResultFromPod(ctx, log, agentRun)
-		// Extract and persist memory updates if applicable.
-		r.extractAndPersistMemory(ctx, log, agentRun)
+		// A memory task run's result is the new memory.
+		memoryUpdater{r.Client}.completeMemoryTask(ctx, log, agentRun, result)
 		return r.succeedRun(ctx, agentRun, result, usage)
 	}
 	if job.Status.Failed > 0 {
@@ -265,7 +296,7 @@ func (r *AgentRunReconciler) reconcileRunning(ctx context.Context, log logr.Logg
 			if exitCode == 0 {
 				log.Info("Agent container terminated successfully; cleaning up lingering sidecars")
 				result, _, usage := r.extractResultFromPod(ctx, log, agentRun)
-				r.extractAndPersistMemory(ctx, log, agentRun)
+				memoryUpdater{r.Client}.completeMemoryTask(ctx, log, agentRun, result)
 				// Delete the Job so Kubernetes kills remaining sidecar containers.
 				_ = r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground))
 				return r.succeedRun(ctx, agentRun, result, usage)
@@ -284,6 +315,12 @@ func (r *AgentRunReconciler) reconcileRunning(ctx context.Context, log logr.Logg
 		}
 	}
 
+	if by, ok := agentRun.Annotations[cancelAnnotation]; ok {
+		log.Info("AgentRun cancelled", "by", by)
+		_ = r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationForeground))
+		return ctrl.Result{}, r.failRun(ctx, agentRun, "cancelled by "+by)
+	}
+
 	// Check timeout (explicit spec timeout or hard default for scheduled runs).
 	if agentRun.Status.StartedAt != nil {
 		elapsed := time.Since(agentRun.Status.StartedAt.Time)
@@ -586,6 +623,24 @@ func (r *AgentRunReconciler) buildJob(
 	}
 }
 
+// setToolGatingEnv passes the SympoziumPolicy tool gating to the agent
+// container of job as TOOL_GATING.
+func setToolGatingEnv(job *batchv1.Job, gating *sympoziumv1alpha1.ToolGatingSpec) {
+	if gating == nil {
+		return
+	}
+	raw, err := json.Marshal(gating)
+	if err != nil {
+		return
+	}
+	containers := job.Spec.Template.Spec.Containers
+	for i := range containers {
+		if containers[i].Name == "agent" {
+			containers[i].Env = append(containers[i].Env, corev1.EnvVar{Name: "TOOL_GATING", Value: string(raw)})
+		}
+	}
+}
+
 // buildContainers constructs the container list for an agent pod.
 func (r *AgentRunReconciler) buildContainers(
 	agentRun *sympoziumv1alpha1.AgentRun,
@@ -615,7 +670,7 @@ func (r *AgentRunReconciler) buildContainers(
 				{Name: "SESSION_KEY", Value: agentRun.Spec.SessionKey},
 				{Name: "INSTANCE_NAME", Value: agentRun.Spec.InstanceRef},
 				{Name: "AGENT_NAMESPACE", Value: agentRun.Namespace},
-				{Name: "TASK", Value: agentRun.Spec.Task},
+				{Name: "TASK", Value: taskEnvValue(agentRun)},
 				{Name: "SYSTEM_PROMPT", Value: agentRun.Spec.SystemPrompt},
 				{Name: "MODEL_PROVIDER", Value: agentRun.Spec.Model.Provider},
 				{Name: "MODEL_NAME", Value: agentRun.Spec.Model.Model},
@@ -679,6 +734,73 @@ func (r *AgentRunReconciler) buildContainers(
 		}
 	}
 
+	// Pass the run's tool policy so the agent-runner can enforce it.
+	if agentRun.Spec.ToolPolicy != nil {
+		if raw, err := json.Marshal(agentRun.Spec.ToolPolicy); err == nil {
+			containers[0].Env = append(containers[0].Env,
+				corev1.EnvVar{Name: "TOOL_POLICY", Value: string(raw)},
+			)
+		}
+	}
+
+	// Pass the model failover chain. Each fallback's auth secret is mounted
+	// as files (see buildVolumes) since providers use different key names.
+	if fallbacks := agentRun.Spec.Model.Fallbacks; len(fallbacks) > 0 {
+		if raw, err := json.Marshal(fallbacks); err == nil {
+			containers[0].Env = append(containers[0].Env,
+				corev1.EnvVar{Name: "MODEL_FALLBACKS", Value: string(raw)},
+			)
+		}
+		for i, fb := range fallbacks {
+			if fb.AuthSecretRef == "" {
+				continue
+			}
+			containers[0].VolumeMounts = append(containers[0].VolumeMounts, corev1.VolumeMount{
+				Name:      modelFallbackVolumeName(i),
+				MountPath: "/secrets/" + modelFallbackVolumeName(i),
+				ReadOnly:  true,
+			})
+		}
+	}
+
+	// A task too long for an environment variable is read from the input
+	// ConfigMap instead.
+	if taskEnvValue(agentRun) == "" && agentRun.Spec.Task != "" {
+		containers[0].VolumeMounts = append(containers[0].VolumeMounts,
+			corev1.VolumeMount{Name: "input", MountPath: "/input", ReadOnly: true},
+		)
+	}
+
+	// Mount the task's attachments; the agent-runner copies them into the
+	// workspace.
+	if len(agentRun.Spec.Attachments) > 0 {
+		if raw, err := json.Marshal(agentRun.Spec.Attachments); err == nil {
+			containers[0].Env = append(containers[0].Env,
+				corev1.EnvVar{Name: "ATTACHMENTS", Value: string(raw)},
+			)
+		}
+		containers[0].VolumeMounts = append(containers[0].VolumeMounts,
+			corev1.VolumeMount{Name: "attachments", MountPath: "/attachments", ReadOnly: true},
+		)
+	}
+
+	// Configure speech-to-text for audio attachments.
+	if t := agentRun.Spec.Transcription; t != nil {
+		containers[0].Env = append(containers[0].Env,
+			corev1.EnvVar{Name: "TRANSCRIPTION_ENABLED", Value: "true"},
+			corev1.EnvVar{Name: "TRANSCRIPTION_BASE_URL", Value: t.BaseURL},
+			corev1.EnvVar{Name: "TRANSCRIPTION_MODEL", Value: t.Model},
+			corev1.EnvVar{Name: "TRANSCRIPTION_LANGUAGE", Value: t.Language},
+		)
+		if t.AuthSecretRef != "" {
+			containers[0].VolumeMounts = append(containers[0].VolumeMounts, corev1.VolumeMount{
+				Name:      "transcription-auth",
+				MountPath: "/secrets/transcription-auth",
+				ReadOnly:  true,
+			})
+		}
+	}
+
 	// Add memory volume mount if memory is enabled.
 	if memoryEnabled {
 		containers[0].VolumeMounts = append(containers[0].VolumeMounts,
@@ -752,6 +874,7 @@ func (r *AgentRunReconciler) buildContainers(
 	}
 
 	// Inject skill sidecar containers.
+	var skillTools []skillToolConfig
 	for _, sc := range sidecars {
 		cmd := sc.sidecar.Command
 
@@ -759,6 +882,27 @@ func (r *AgentRunReconciler) buildContainers(
 		for _, e := range sc.sidecar.Env {
 			envVars = append(envVars, corev1.EnvVar{Name: e.Name, Value: e.Value})
 		}
+		// Lets the sidecar pick out exec requests addressed to its SkillPack.
+		envVars = append(envVars, corev1.EnvVar{Name: "SKILL_PACK", Value: sc.skillPackName})
+		// The sidecar renders and runs its own tools' command templates;
+		// the agent only learns their names and schemas.
+		if len(sc.tools) > 0 {
+			if raw, err := json.Marshal(sc.tools); err == nil {
+				envVars = append(envVars, corev1.EnvVar{Name: "SKILL_TOOLS", Value: string(raw)})
+			}
+			for _, t := range sc.tools {
+				cfg := skillToolConfig{
+					Name:           t.Name,
+					Description:    t.Description,
+					TimeoutSeconds: t.TimeoutSeconds,
+					SkillPack:      sc.skillPackName,
+				}
+				if t.Parameters != nil {
+					cfg.Parameters = t.Parameters.Raw
+				}
+				skillTools = append(skillTools, cfg)
+			}
+		}
 
 		mounts := []corev1.VolumeMount{
 			{Name: "ipc", MountPath: "/ipc"},
@@ -803,6 +947,13 @@ func (r *AgentRunReconciler) buildContainers(
 		}
 		containers = append(containers, container)
 	}
+	if len(skillTools) > 0 {
+		if raw, err := json.Marshal(skillTools); err == nil {
+			containers[0].Env = append(containers[0].Env,
+				corev1.EnvVar{Name: "SKILL_TOOLS", Value: string(raw)},
+			)
+		}
+	}
 
 	return containers
 }
@@ -935,6 +1086,20 @@ func (r *AgentRunReconciler) buildVolumes(agentRun *sympoziumv1alpha1.AgentRun,
 		})
 	}
 
+	if taskEnvValue(agentRun) == "" && agentRun.Spec.Task != "" {
+		volumes = append(volumes, corev1.Volume{
+			Name: "input",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: fmt.Sprintf("%s-input", agentRun.Name),
+					},
+					Items: []corev1.KeyToPath{{Key: "task", Path: "task"}},
+				},
+			},
+		})
+	}
+
 	// Add memory ConfigMap volume if memory is enabled.
 	if memoryEnabled {
 		cmName := fmt.Sprintf("%s-memory", agentRun.Spec.InstanceRef)
@@ -951,12 +1116,74 @@ func (r *AgentRunReconciler) buildVolumes(agentRun *sympoziumv1alpha1.AgentRun,
 		})
 	}
 
+	// The attachments ConfigMap is created by the channel router right
+	// after the run, so it is not optional: the pod waits for it.
+	if len(agentRun.Spec.Attachments) > 0 {
+		volumes = append(volumes, corev1.Volume{
+			Name: "attachments",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: attachmentsConfigMapName(agentRun.Name),
+					},
+				},
+			},
+		})
+	}
+
+	if t := agentRun.Spec.Transcription; t != nil && t.AuthSecretRef != "" {
+		volumes = append(volumes, corev1.Volume{
+			Name: "transcription-auth",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: t.AuthSecretRef,
+					Optional:   boolPtr(true),
+				},
+			},
+		})
+	}
+
+	// Add a secret volume per model fallback that needs credentials.
+	for i, fb := range agentRun.Spec.Model.Fallbacks {
+		if fb.AuthSecretRef == "" {
+			continue
+		}
+		volumes = append(volumes, corev1.Volume{
+			Name: modelFallbackVolumeName(i),
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: fb.AuthSecretRef,
+					Optional:   boolPtr(true),
+				},
+			},
+		})
+	}
+
 	return volumes
 }
 
+// modelFallbackVolumeName returns the volume (and /secrets subdirectory)
+// name for the i-th model fallback's auth secret.
+func modelFallbackVolumeName(i int) string {
+	return fmt.Sprintf("model-fallback-%d", i)
+}
+
 // boolPtr returns a pointer to a bool.
 func boolPtr(b bool) *bool { return &b }
 
+// maxTaskEnvBytes is the longest task passed in the TASK environment
+// variable; Linux rejects a single variable over 128 KiB.
+const maxTaskEnvBytes = 64 * 1024
+
+// taskEnvValue returns the TASK variable for the run, which is empty when
+// the task must be read from the input ConfigMap.
+func taskEnvValue(agentRun *sympoziumv1alpha1.AgentRun) string {
+	if len(agentRun.Spec.Task) > maxTaskEnvBytes {
+		return ""
+	}
+	return agentRun.Spec.Task
+}
+
 // createInputConfigMap creates a ConfigMap with the agent's task input.
 func (r *AgentRunReconciler) createInputConfigMap(ctx context.Context, agentRun *sympoziumv1alpha1.AgentRun) error {
 	cm := &corev1.ConfigMap{
@@ -1031,25 +1258,58 @@ func (r *AgentRunReconciler) extractResultFromPod(ctx context.Context, log logr.
 		return "", "", nil
 	}
 
-	return parseAgentResultFromLogs(string(raw), log)
+	logs := string(raw)
+	agentRun.Status.ServedModel, agentRun.Status.FailedAttempts = parseModelFailoverFromLogs(logs)
+	return parseAgentResultFromLogs(logs, log)
+}
+
+// resultMarkerPayload returns the JSON between the last result markers.
+func resultMarkerPayload(logs string) (string, bool) {
+	startIdx := strings.LastIndex(logs, resultMarkerStart)
+	if startIdx < 0 {
+		return "", false
+	}
+	payload := logs[startIdx+len(resultMarkerStart):]
+	endIdx := strings.Index(payload, resultMarkerEnd)
+	if endIdx < 0 {
+		return "", false
+	}
+	return strings.TrimSpace(payload[:endIdx]), true
+}
+
+// parseModelFailoverFromLogs extracts the model that served the run and the
+// failed failover attempts from the result marker.
+func parseModelFailoverFromLogs(logs string) (string, []sympoziumv1alpha1.ModelAttempt) {
+	jsonStr, ok := resultMarkerPayload(logs)
+	if !ok {
+		return "", nil
+	}
+	var parsed struct {
+		Model          string                           `json:"model"`
+		FailedAttempts []sympoziumv1alpha1.ModelAttempt `json:"failedAttempts"`
+	}
+	if err := json.Unmarshal([]byte(jsonStr), &parsed); err != nil {
+		return "", nil
+	}
+	for i := range parsed.FailedAttempts {
+		parsed.FailedAttempts[i].Error = truncateForStatus(parsed.FailedAttempts[i].Error, 300)
+	}
+	return parsed.Model, parsed.FailedAttempts
 }
 
 // parseAgentResultFromLogs parses the structured result marker emitted by the
 // agent-runner and extracts either the success response or the failure message.
 func parseAgentResultFromLogs(logs string, log logr.Logger) (string, string, *sympoziumv1alpha1.TokenUsage) {
-	startIdx := strings.LastIndex(logs, resultMarkerStart)
-	if startIdx < 0 {
+	if !strings.Contains(logs, resultMarkerStart) {
 		if fallbackErr := extractLikelyProviderErrorFromLogs(logs); fallbackErr != "" {
 			return "", fallbackErr, nil
 		}
 		return "", "", nil
 	}
-	payload := logs[startIdx+len(resultMarkerStart):]
-	endIdx := strings.Index(payload, resultMarkerEnd)
-	if endIdx < 0 {
+	jsonStr, ok := resultMarkerPayload(logs)
+	if !ok {
 		return "", "", nil
 	}
-	jsonStr := strings.TrimSpace(payload[:endIdx])
 
 	// Parse the full agent result including metrics.
 	var parsed struct {
@@ -1057,10 +1317,14 @@ func parseAgentResultFromLogs(logs string, log logr.Logger) (string, string, *sy
 		Response string `json:"response"`
 		Error    string `json:"error"`
 		Metrics  struct {
-			DurationMs   int64 `json:"durationMs"`
-			InputTokens  int   `json:"inputTokens"`
-			OutputTokens int   `json:"outputTokens"`
-			ToolCalls    int   `json:"toolCalls"`
+			DurationMs     int64 `json:"durationMs"`
+			InputTokens    int   `json:"inputTokens"`
+			OutputTokens   int   `json:"outputTokens"`
+			ThinkingTokens int   `json:"thinkingTokens"`
+			// Set instead of ThinkingTokens when the provider
+			// doesn't report the thinking share.
+			ThinkingTokensEstimated int `json:"thinkingTokensEstimated"`
+			ToolCalls               int `json:"toolCalls"`
 		} `json:"metrics"`
 	}
 	if err := json.Unmarshal([]byte(jsonStr), &parsed); err != nil {
@@ -1071,15 +1335,19 @@ func parseAgentResultFromLogs(logs string, log logr.Logger) (string, string, *sy
 	var usage *sympoziumv1alpha1.TokenUsage
 	if parsed.Metrics.InputTokens > 0 || parsed.Metrics.OutputTokens > 0 {
 		usage = &sympoziumv1alpha1.TokenUsage{
-			InputTokens:  parsed.Metrics.InputTokens,
-			OutputTokens: parsed.Metrics.OutputTokens,
-			TotalTokens:  parsed.Metrics.InputTokens + parsed.Metrics.OutputTokens,
-			ToolCalls:    parsed.Metrics.ToolCalls,
-			DurationMs:   parsed.Metrics.DurationMs,
+			InputTokens:             parsed.Metrics.InputTokens,
+			OutputTokens:            parsed.Metrics.OutputTokens,
+			ThinkingTokens:          parsed.Metrics.ThinkingTokens,
+			ThinkingTokensEstimated: parsed.Metrics.ThinkingTokensEstimated,
+			TotalTokens:             parsed.Metrics.InputTokens + parsed.Metrics.OutputTokens,
+			ToolCalls:               parsed.Metrics.ToolCalls,
+			DurationMs:              parsed.Metrics.DurationMs,
 		}
 		log.Info("extracted token usage",
 			"inputTokens", usage.InputTokens,
 			"outputTokens", usage.OutputTokens,
+			"thinkingTokens", usage.ThinkingTokens,
+			"thinkingTokensEstimated", usage.ThinkingTokensEstimated,
 			"totalTokens", usage.TotalTokens,
 			"toolCalls", usage.ToolCalls,
 			"durationMs", usage.DurationMs)
@@ -1133,71 +1401,11 @@ func truncateForStatus(s string, max int) string {
 	return s[:max-3] + "..."
 }
 
-const (
-	memoryMarkerStart = "__SYMPOZIUM_MEMORY__"
-	memoryMarkerEnd   = "__SYMPOZIUM_MEMORY_END__"
-)
-
-// extractAndPersistMemory reads the agent container logs for a memory update
-// marker and patches the instance's memory ConfigMap with the new content.
-func (r *AgentRunReconciler) extractAndPersistMemory(ctx context.Context, log logr.Logger, agentRun *sympoziumv1alpha1.AgentRun) {
-	if r.Clientset == nil || agentRun.Status.PodName == "" {
-		return
-	}
-
-	tailLines := int64(100)
-	opts := &corev1.PodLogOptions{
-		Container: "agent",
-		TailLines: &tailLines,
-	}
-	req := r.Clientset.CoreV1().Pods(agentRun.Namespace).GetLogs(agentRun.Status.PodName, opts)
-	stream, err := req.Stream(ctx)
-	if err != nil {
-		return
-	}
-	defer stream.Close()
-
-	raw, err := io.ReadAll(stream)
-	if err != nil {
-		return
-	}
-
-	logs := string(raw)
-	startIdx := strings.LastIndex(logs, memoryMarkerStart)
-	if startIdx < 0 {
-		return
-	}
-	payload := logs[startIdx+len(memoryMarkerStart):]
-	endIdx := strings.Index(payload, memoryMarkerEnd)
-	if endIdx < 0 {
-		return
-	}
-	memoryContent := strings.TrimSpace(payload[:endIdx])
-	if memoryContent == "" {
-		return
-	}
-
-	// Patch the memory ConfigMap.
-	cmName := fmt.Sprintf("%s-memory", agentRun.Spec.InstanceRef)
-	var cm corev1.ConfigMap
-	if err := r.Get(ctx, client.ObjectKey{
-		Namespace: agentRun.Namespace,
-		Name:      cmName,
-	}, &cm); err != nil {
-		log.V(1).Info("memory ConfigMap not found, skipping memory update", "err", err)
-		return
-	}
-
-	cm.Data["MEMORY.md"] = memoryContent
-	if err := r.Update(ctx, &cm); err != nil {
-		log.V(1).Info("failed to update memory ConfigMap", "err", err)
-		return
-	}
-	log.Info("Updated memory ConfigMap", "configmap", cmName, "bytes", len(memoryContent))
-}
-
 // failRun marks an AgentRun as failed.
 func (r *AgentRunReconciler) failRun(ctx context.Context, agentRun *sympoziumv1alpha1.AgentRun, reason string) error {
+	if memoryTask(agentRun) != "" {
+		memoryUpdater{r.Client}.failMemoryTask(ctx, r.Log.WithValues("agentrun", client.ObjectKeyFromObject(agentRun)), agentRun, reason)
+	}
 	now := metav1.Now()
 	agentRun.Status.Phase = sympoziumv1alpha1.AgentRunPhaseFailed
 	agentRun.Status.CompletedAt = &now
@@ -1207,16 +1415,46 @@ func (r *AgentRunReconciler) failRun(ctx context.Context, agentRun *sympoziumv1a
 
 // --- Skill sidecar resolution and RBAC ---
 
-// resolvedSidecar pairs a SkillPack name with its sidecar spec.
+// resolvedSidecar pairs a SkillPack name with its sidecar spec and the
+// declarative tools it runs.
 type resolvedSidecar struct {
 	skillPackName string
 	sidecar       sympoziumv1alpha1.SkillSidecar
+	tools         []sympoziumv1alpha1.SkillTool
+}
+
+// skillToolConfig is an entry of the agent container's SKILL_TOOLS env var.
+// The command template is only given to the sidecar that runs it.
+type skillToolConfig struct {
+	Name           string          `json:"name"`
+	Description    string          `json:"description"`
+	Parameters     json.RawMessage `json:"parameters,omitempty"`
+	TimeoutSeconds int             `json:"timeoutSeconds,omitempty"`
+	SkillPack      string          `json:"skillPack"`
 }
 
 // resolveSkillSidecars looks up SkillPack CRDs for the AgentRun's active
 // skills and returns any that have a sidecar defined.
 func (r *AgentRunReconciler) resolveSkillSidecars(ctx context.Context, log logr.Logger, agentRun *sympoziumv1alpha1.AgentRun) []resolvedSidecar {
 	var sidecars []resolvedSidecar
+	for _, sp := range r.resolveSkillPacks(ctx, log, agentRun) {
+		if sp.Spec.Sidecar != nil && sp.Spec.Sidecar.Image != "" {
+			sidecars = append(sidecars, resolvedSidecar{
+				skillPackName: sp.Name,
+				sidecar:       *sp.Spec.Sidecar,
+				tools:         sp.Spec.Tools,
+			})
+		} else if len(sp.Spec.Tools) > 0 {
+			log.Info("SkillPack declares tools but no sidecar to run them, skipping tools", "skillPack", sp.Name)
+		}
+	}
+	return sidecars
+}
+
+// resolveSkillPacks returns the SkillPack CRDs for the AgentRun's active
+// skills, skipping any that cannot be found.
+func (r *AgentRunReconciler) resolveSkillPacks(ctx context.Context, log logr.Logger, agentRun *sympoziumv1alpha1.AgentRun) []*sympoziumv1alpha1.SkillPack {
+	var packs []*sympoziumv1alpha1.SkillPack
 	for _, ref := range agentRun.Spec.Skills {
 		if ref.SkillPackRef == "" {
 			continue
@@ -1240,19 +1478,70 @@ func (r *AgentRunReconciler) resolveSkillSidecars(ctx context.Context, log logr.
 				Namespace: systemNamespace,
 				Name:      spName,
 			}, sp); err2 != nil {
-				log.V(1).Info("SkillPack not found, skipping sidecar", "name", spName)
+				log.V(1).Info("SkillPack not found, skipping", "name", spName)
 				continue
 			}
 		}
+		packs = append(packs, sp)
+	}
+	return packs
+}
 
-		if sp.Spec.Sidecar != nil && sp.Spec.Sidecar.Image != "" {
-			sidecars = append(sidecars, resolvedSidecar{
-				skillPackName: spName,
-				sidecar:       *sp.Spec.Sidecar,
-			})
+// mcpServerConfig is an entry of the MCP_SERVERS env var read by the
+// agent-runner.
+type mcpServerConfig struct {
+	sympoziumv1alpha1.MCPServer
+	// SkillPack names the pack that declared the server; stdio servers run
+	// in its sidecar container.
+	SkillPack string `json:"skillPack"`
+}
+
+// resolveMCPServers collects the MCP servers declared by the AgentRun's
+// SkillPacks. Stdio servers are dropped when their pack has no sidecar to
+// run them in, and later servers reusing a name are ignored.
+func (r *AgentRunReconciler) resolveMCPServers(ctx context.Context, log logr.Logger, agentRun *sympoziumv1alpha1.AgentRun) []mcpServerConfig {
+	var servers []mcpServerConfig
+	seen := map[string]bool{}
+	for _, sp := range r.resolveSkillPacks(ctx, log, agentRun) {
+		hasSidecar := sp.Spec.Sidecar != nil && sp.Spec.Sidecar.Image != ""
+		for _, srv := range sp.Spec.MCPServers {
+			if srv.Transport == "" {
+				srv.Transport = "stdio"
+			}
+			switch {
+			case seen[srv.Name]:
+				log.Info("Duplicate MCP server name, skipping", "skillPack", sp.Name, "server", srv.Name)
+				continue
+			case srv.Transport == "stdio" && (!hasSidecar || len(srv.Command) == 0):
+				log.Info("Stdio MCP server needs a command and a SkillPack sidecar, skipping", "skillPack", sp.Name, "server", srv.Name)
+				continue
+			case srv.Transport == "http" && srv.URL == "":
+				log.Info("HTTP MCP server has no URL, skipping", "skillPack", sp.Name, "server", srv.Name)
+				continue
+			}
+			seen[srv.Name] = true
+			servers = append(servers, mcpServerConfig{MCPServer: srv, SkillPack: sp.Name})
+		}
+	}
+	return servers
+}
+
+// setMCPServersEnv passes the run's MCP servers to the agent container of
+// job as MCP_SERVERS.
+func setMCPServersEnv(job *batchv1.Job, servers []mcpServerConfig) {
+	if len(servers) == 0 {
+		return
+	}
+	raw, err := json.Marshal(servers)
+	if err != nil {
+		return
+	}
+	containers := job.Spec.Template.Spec.Containers
+	for i := range containers {
+		if containers[i].Name == "agent" {
+			containers[i].Env = append(containers[i].Env, corev1.EnvVar{Name: "MCP_SERVERS", Value: string(raw)})
 		}
 	}
-	return sidecars
 }
 
 // mirrorSkillConfigMaps copies skill ConfigMaps from sympozium-system into the
@@ -1,7 +1,12 @@
 package controller
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -10,8 +15,18 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	sympoziumv1alpha1 "github.com/alexsjones/sympozium/api/v1alpha1"
+	channelpkg "github.com/alexsjones/sympozium/internal/channel"
+	"github.com/alexsjones/sympozium/internal/eventbus"
+	"github.com/alexsjones/sympozium/internal/ipc"
+	"github.com/alexsjones/sympozium/internal/orchestrator"
+	"github.com/alexsjones/sympozium/internal/session"
 )
 
 // helper builds a minimal AgentRun for testing.
@@ -240,6 +255,88 @@ func TestBuildContainers_AgentVolumeMounts(t *testing.T) {
 	}
 }
 
+func TestBuildContainers_LongTaskFromConfigMap(t *testing.T) {
+	r := &AgentRunReconciler{}
+	run := newTestRun()
+	run.Spec.Task = strings.Repeat("x", maxTaskEnvBytes+1)
+
+	cs := r.buildContainers(run, false, nil, nil)
+	for _, e := range cs[0].Env {
+		if e.Name == "TASK" && e.Value != "" {
+			t.Errorf("TASK env is %d bytes, want it empty", len(e.Value))
+		}
+	}
+	mounted := false
+	for _, m := range cs[0].VolumeMounts {
+		mounted = mounted || (m.Name == "input" && m.MountPath == "/input")
+	}
+	if !mounted {
+		t.Error("input ConfigMap not mounted at /input")
+	}
+	found := false
+	for _, v := range r.buildVolumes(run, false) {
+		if v.Name == "input" && v.ConfigMap != nil && v.ConfigMap.Name == "test-run-input" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("missing input ConfigMap volume")
+	}
+}
+
+func TestBuildContainers_Attachments(t *testing.T) {
+	r := &AgentRunReconciler{}
+	run := newTestRun()
+	run.Spec.Attachments = []sympoziumv1alpha1.AgentRunAttachment{{Name: "voice.oga", Type: "audio", MimeType: "audio/ogg"}}
+	run.Spec.Transcription = &sympoziumv1alpha1.TranscriptionSpec{Model: "whisper-1", AuthSecretRef: "stt-key"}
+
+	cs := r.buildContainers(run, false, nil, nil)
+	env := map[string]string{}
+	for _, e := range cs[0].Env {
+		env[e.Name] = e.Value
+	}
+	if env["ATTACHMENTS"] != `[{"name":"voice.oga","type":"audio","mimeType":"audio/ogg"}]` {
+		t.Errorf("ATTACHMENTS = %q", env["ATTACHMENTS"])
+	}
+	if env["TRANSCRIPTION_ENABLED"] != "true" || env["TRANSCRIPTION_MODEL"] != "whisper-1" {
+		t.Errorf("transcription env = %v", env)
+	}
+	mounts := map[string]string{}
+	for _, m := range cs[0].VolumeMounts {
+		mounts[m.Name] = m.MountPath
+	}
+	if mounts["attachments"] != "/attachments" || mounts["transcription-auth"] != "/secrets/transcription-auth" {
+		t.Errorf("mounts = %v", mounts)
+	}
+	vols := map[string]corev1.Volume{}
+	for _, v := range r.buildVolumes(run, false) {
+		vols[v.Name] = v
+	}
+	if v := vols["attachments"]; v.ConfigMap == nil || v.ConfigMap.Name != "test-run-attachments" || v.ConfigMap.Optional != nil {
+		t.Errorf("attachments volume = %+v", v.VolumeSource)
+	}
+	if v := vols["transcription-auth"]; v.Secret == nil || v.Secret.SecretName != "stt-key" {
+		t.Errorf("transcription-auth volume = %+v", v.VolumeSource)
+	}
+}
+
+func TestRunAttachments(t *testing.T) {
+	list, data := runAttachments(channelpkg.InboundMessage{Attachments: []channelpkg.Attachment{
+		{Filename: "photo.jpg", MimeType: "image/jpeg", Data: []byte("a")},
+		{Filename: "photo.jpg", MimeType: "image/jpeg", Data: []byte("b")},
+		{Filename: "../../etc/my report (1).pdf", Type: "file", Data: []byte("c")},
+		{Filename: "..", Data: []byte("d")},
+	}})
+	var names []string
+	for _, a := range list {
+		names = append(names, a.Name+":"+a.Type+":"+string(data[a.Name]))
+	}
+	want := []string{"photo.jpg:image:a", "photo-2.jpg:image:b", "my_report__1_.pdf:file:c", "attachment-4:file:d"}
+	if !slices.Equal(names, want) {
+		t.Errorf("attachments = %v, want %v", names, want)
+	}
+}
+
 func TestBuildContainers_AgentResources(t *testing.T) {
 	r := &AgentRunReconciler{}
 	cs := r.buildContainers(newTestRun(), false, nil, nil)
@@ -365,6 +462,37 @@ func TestParseAgentResultFromLogs_Success(t *testing.T) {
 	}
 }
 
+func TestParseAgentResultFromLogs_ThinkingTokens(t *testing.T) {
+	logs := "__SYMPOZIUM_RESULT__" +
+		`{"status":"success","response":"ok","metrics":{"durationMs":900,"inputTokens":10,"outputTokens":50,"thinkingTokens":35,"toolCalls":0}}` +
+		"__SYMPOZIUM_END__\n"
+
+	_, _, usage := parseAgentResultFromLogs(logs, logr.Discard())
+	if usage == nil {
+		t.Fatal("expected token usage, got nil")
+	}
+	if usage.ThinkingTokens != 35 {
+		t.Fatalf("thinking tokens = %d, want 35", usage.ThinkingTokens)
+	}
+	if usage.TotalTokens != 60 {
+		t.Fatalf("total tokens = %d, want 60 (thinking is part of output)", usage.TotalTokens)
+	}
+}
+
+func TestParseAgentResultFromLogs_EstimatedThinkingTokens(t *testing.T) {
+	logs := "__SYMPOZIUM_RESULT__" +
+		`{"status":"success","response":"ok","metrics":{"durationMs":900,"inputTokens":10,"outputTokens":50,"thinkingTokensEstimated":30,"toolCalls":0}}` +
+		"__SYMPOZIUM_END__\n"
+
+	_, _, usage := parseAgentResultFromLogs(logs, logr.Discard())
+	if usage == nil {
+		t.Fatal("expected token usage, got nil")
+	}
+	if usage.ThinkingTokens != 0 || usage.ThinkingTokensEstimated != 30 {
+		t.Fatalf("thinking tokens = %d (estimated %d), want 0 (estimated 30)", usage.ThinkingTokens, usage.ThinkingTokensEstimated)
+	}
+}
+
 func TestParseAgentResultFromLogs_Error(t *testing.T) {
 	want := "OpenAI API error (HTTP 429): insufficient_quota"
 	logs := "__SYMPOZIUM_RESULT__" +
@@ -540,6 +668,49 @@ func TestBuildContainers_SkillSidecarInjected(t *testing.T) {
 	if !hasWorkspace {
 		t.Error("sidecar should mount /workspace when MountWorkspace=true")
 	}
+	var skillPack string
+	for _, e := range sc.Env {
+		if e.Name == "SKILL_PACK" {
+			skillPack = e.Value
+		}
+	}
+	if skillPack != "k8s-ops" {
+		t.Errorf("sidecar SKILL_PACK = %q, want k8s-ops", skillPack)
+	}
+}
+
+func TestBuildContainers_SkillTools(t *testing.T) {
+	r := &AgentRunReconciler{}
+	sidecars := []resolvedSidecar{{
+		skillPackName: "k8s-ops",
+		sidecar:       sympoziumv1alpha1.SkillSidecar{Image: "skill-k8s-ops:latest"},
+		tools: []sympoziumv1alpha1.SkillTool{{
+			Name:        "k8s_get_pods",
+			Description: "List pods in a namespace",
+			Parameters:  &runtime.RawExtension{Raw: []byte(`{"type":"object","properties":{"namespace":{"type":"string"}}}`)},
+			Command:     "kubectl get pods -n {{namespace}}",
+		}},
+	}}
+	cs := r.buildContainers(newTestRun(), false, nil, sidecars)
+
+	envOf := func(c corev1.Container) map[string]string {
+		env := map[string]string{}
+		for _, e := range c.Env {
+			env[e.Name] = e.Value
+		}
+		return env
+	}
+	agent := envOf(cs[0])["SKILL_TOOLS"]
+	want := `[{"name":"k8s_get_pods","description":"List pods in a namespace","parameters":{"type":"object","properties":{"namespace":{"type":"string"}}},"skillPack":"k8s-ops"}]`
+	if agent != want {
+		t.Errorf("agent SKILL_TOOLS = %s, want %s", agent, want)
+	}
+	if strings.Contains(agent, "kubectl") {
+		t.Error("the agent should not receive command templates")
+	}
+	if sidecar := envOf(cs[2])["SKILL_TOOLS"]; !strings.Contains(sidecar, `"command":"kubectl get pods -n {{namespace}}"`) {
+		t.Errorf("sidecar SKILL_TOOLS = %s", sidecar)
+	}
 }
 
 func TestBuildContainers_SkillSidecarDefaultCommand(t *testing.T) {
@@ -638,3 +809,784 @@ func TestBuildContainers_ObservabilityEnv(t *testing.T) {
 		t.Fatalf("missing run id in resource attributes: %q", agentEnv["SYMPOZIUM_OTEL_RESOURCE_ATTRIBUTES"])
 	}
 }
+
+// ── Model failover tests ─────────────────────────────────────────────────────
+
+func TestBuildContainers_ModelFallbacks(t *testing.T) {
+	r := &AgentRunReconciler{}
+	run := newTestRun()
+	run.Spec.Model.Fallbacks = []sympoziumv1alpha1.ModelFallback{
+		{Provider: "anthropic", Model: "claude-sonnet-4-20250514", AuthSecretRef: "anthropic-key"},
+		{Provider: "ollama", Model: "llama3", BaseURL: "http://ollama:11434/v1"},
+	}
+	cs := r.buildContainers(run, false, nil, nil)
+
+	var fallbacksEnv string
+	for _, e := range cs[0].Env {
+		if e.Name == "MODEL_FALLBACKS" {
+			fallbacksEnv = e.Value
+		}
+	}
+	if !strings.Contains(fallbacksEnv, `"authSecretRef":"anthropic-key"`) || !strings.Contains(fallbacksEnv, `"model":"llama3"`) {
+		t.Errorf("MODEL_FALLBACKS = %q", fallbacksEnv)
+	}
+
+	mounts := map[string]string{}
+	for _, m := range cs[0].VolumeMounts {
+		mounts[m.Name] = m.MountPath
+	}
+	if mounts["model-fallback-0"] != "/secrets/model-fallback-0" {
+		t.Errorf("fallback 0 mount = %q", mounts["model-fallback-0"])
+	}
+	if _, ok := mounts["model-fallback-1"]; ok {
+		t.Error("fallback without auth secret should not be mounted")
+	}
+
+	vols := r.buildVolumes(run, false)
+	var found bool
+	for _, v := range vols {
+		if v.Name == "model-fallback-0" {
+			found = true
+			if v.Secret == nil || v.Secret.SecretName != "anthropic-key" {
+				t.Errorf("fallback volume = %+v", v.VolumeSource)
+			}
+		}
+	}
+	if !found {
+		t.Error("model-fallback-0 volume not found")
+	}
+}
+
+func TestParseModelFailoverFromLogs(t *testing.T) {
+	logs := "__SYMPOZIUM_RESULT__" +
+		`{"status":"success","response":"ok","model":"anthropic/claude-sonnet-4-20250514",` +
+		`"failedAttempts":[{"provider":"openai","model":"gpt-4o","reason":"rate_limit","error":"OpenAI API error (HTTP 429): quota"}],` +
+		`"metrics":{"durationMs":10,"inputTokens":1,"outputTokens":2,"toolCalls":0}}` +
+		"__SYMPOZIUM_END__\n"
+
+	served, attempts := parseModelFailoverFromLogs(logs)
+	if served != "anthropic/claude-sonnet-4-20250514" {
+		t.Errorf("served = %q", served)
+	}
+	if len(attempts) != 1 || attempts[0].Reason != "rate_limit" || attempts[0].Model != "gpt-4o" {
+		t.Fatalf("attempts = %+v", attempts)
+	}
+
+	if served, attempts := parseModelFailoverFromLogs("no marker here"); served != "" || attempts != nil {
+		t.Errorf("expected nothing without a marker, got %q %+v", served, attempts)
+	}
+}
+
+// ── Tool policy tests ────────────────────────────────────────────────────────
+
+func TestBuildJob_ToolPolicyEnv(t *testing.T) {
+	r := &AgentRunReconciler{}
+	run := newTestRun()
+	run.Spec.ToolPolicy = &sympoziumv1alpha1.ToolPolicySpec{Deny: []string{"execute_command"}}
+	job := r.buildJob(run, false, nil, nil)
+	setToolGatingEnv(job, &sympoziumv1alpha1.ToolGatingSpec{
+		DefaultAction: "allow",
+		Rules:         []sympoziumv1alpha1.ToolGatingRule{{Tool: "fetch_url", Action: "deny"}},
+	})
+
+	env := map[string]string{}
+	for _, e := range job.Spec.Template.Spec.Containers[0].Env {
+		env[e.Name] = e.Value
+	}
+	if env["TOOL_POLICY"] != `{"deny":["execute_command"]}` {
+		t.Errorf("TOOL_POLICY = %q", env["TOOL_POLICY"])
+	}
+	if !strings.Contains(env["TOOL_GATING"], `{"tool":"fetch_url","action":"deny"}`) {
+		t.Errorf("TOOL_GATING = %q", env["TOOL_GATING"])
+	}
+	for _, e := range job.Spec.Template.Spec.Containers[1].Env {
+		if e.Name == "TOOL_GATING" {
+			t.Error("TOOL_GATING should only be set on the agent container")
+		}
+	}
+}
+
+func TestBuildJob_MCPServersEnv(t *testing.T) {
+	r := &AgentRunReconciler{}
+	job := r.buildJob(newTestRun(), false, nil, nil)
+	setMCPServersEnv(job, []mcpServerConfig{{
+		MCPServer: sympoziumv1alpha1.MCPServer{Name: "github", Transport: "http", URL: "http://mcp.example:8080/mcp"},
+		SkillPack: "github-tools",
+	}})
+
+	var got string
+	for _, e := range job.Spec.Template.Spec.Containers[0].Env {
+		if e.Name == "MCP_SERVERS" {
+			got = e.Value
+		}
+	}
+	want := `[{"name":"github","transport":"http","url":"http://mcp.example:8080/mcp","skillPack":"github-tools"}]`
+	if got != want {
+		t.Errorf("MCP_SERVERS = %s, want %s", got, want)
+	}
+
+	job = r.buildJob(newTestRun(), false, nil, nil)
+	setMCPServersEnv(job, nil)
+	for _, e := range job.Spec.Template.Spec.Containers[0].Env {
+		if e.Name == "MCP_SERVERS" {
+			t.Error("MCP_SERVERS should not be set without servers")
+		}
+	}
+}
+
+func TestParseApprovalCommand(t *testing.T) {
+	tests := []struct {
+		text                 string
+		decision, id, reason string
+		ok                   bool
+	}{
+		{"/approve a1b2c3d4", sympoziumv1alpha1.ApprovalApproved, "a1b2c3d4", "", true},
+		{"/deny a1b2c3d4 too risky", sympoziumv1alpha1.ApprovalDenied, "a1b2c3d4", "too risky", true},
+		{"/approve", "", "", "", false},
+		{"please approve a1b2c3d4", "", "", "", false},
+	}
+	for _, tt := range tests {
+		decision, id, reason, ok := parseApprovalCommand(tt.text)
+		if decision != tt.decision || id != tt.id || reason != tt.reason || ok != tt.ok {
+			t.Errorf("parseApprovalCommand(%q) = %q, %q, %q, %v", tt.text, decision, id, reason, ok)
+		}
+	}
+}
+
+func TestParseChatCommand(t *testing.T) {
+	tests := []struct {
+		text string
+		cmd  string
+		args []string
+		ok   bool
+	}{
+		{"/status", chatCmdStatus, []string{}, true},
+		{"/model gpt-4o", chatCmdModel, []string{"gpt-4o"}, true},
+		{"/Cancel@sympozium_bot", chatCmdCancel, []string{}, true},
+		{"  /new  ", chatCmdNew, []string{}, true},
+		{"/tmp/report.txt is empty", "", nil, false},
+		{"what is /status?", "", nil, false},
+	}
+	for _, tt := range tests {
+		cmd, args, ok := parseChatCommand(tt.text)
+		if cmd != tt.cmd || ok != tt.ok || fmt.Sprint(args) != fmt.Sprint(tt.args) {
+			t.Errorf("parseChatCommand(%q) = %q, %q, %v", tt.text, cmd, args, ok)
+		}
+	}
+
+	run := newTestRun()
+	run.Spec.Task = conversationTask([]session.TranscriptEvent{{EventType: session.EventUserMessage, Content: "hi"}}, "restart the api")
+	if got := chatMessageOf(run); got != "restart the api" {
+		t.Errorf("chatMessageOf = %q", got)
+	}
+}
+
+func TestDecideApproval(t *testing.T) {
+	status := sympoziumv1alpha1.AgentRunStatus{
+		Approvals: []sympoziumv1alpha1.ToolApproval{{ID: "a1", Tool: "execute_command"}},
+	}
+	if !status.DecideApproval("a1", sympoziumv1alpha1.ApprovalApproved, "tui:alice", "", metav1.Now()) {
+		t.Fatal("expected pending approval to be decided")
+	}
+	if status.Approvals[0].Approver != "tui:alice" || status.Approvals[0].DecidedAt == nil {
+		t.Errorf("approval = %+v", status.Approvals[0])
+	}
+	if status.DecideApproval("a1", sympoziumv1alpha1.ApprovalDenied, "api:bob", "", metav1.Now()) {
+		t.Error("a decided approval must not be overwritten")
+	}
+	if status.DecideApproval("missing", sympoziumv1alpha1.ApprovalDenied, "api:bob", "", metav1.Now()) {
+		t.Error("unknown approval ID should not be decided")
+	}
+}
+
+func TestCheckSpawnLimits(t *testing.T) {
+	limits := sympoziumv1alpha1.SubagentsSpec{MaxDepth: 2, MaxConcurrent: 4, MaxChildrenPerAgent: 2}
+	parent := &sympoziumv1alpha1.AgentRun{ObjectMeta: metav1.ObjectMeta{Name: "root"}}
+	child := func(name, phase string) sympoziumv1alpha1.AgentRun {
+		return sympoziumv1alpha1.AgentRun{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       sympoziumv1alpha1.AgentRunSpec{Parent: &sympoziumv1alpha1.ParentRunRef{RunName: "root", SpawnDepth: 1}},
+			Status:     sympoziumv1alpha1.AgentRunStatus{Phase: sympoziumv1alpha1.AgentRunPhase(phase)},
+		}
+	}
+
+	if err := checkSpawnLimits(parent, []sympoziumv1alpha1.AgentRun{*parent, child("a", "Succeeded")}, limits); err != nil {
+		t.Errorf("expected spawn to be allowed: %v", err)
+	}
+	if err := checkSpawnLimits(parent, []sympoziumv1alpha1.AgentRun{*parent, child("a", "Succeeded"), child("b", "Running")}, limits); err == nil {
+		t.Error("expected MaxChildrenPerAgent to be enforced")
+	}
+	// Top-level runs of the instance do not count towards MaxConcurrent.
+	other := sympoziumv1alpha1.AgentRun{ObjectMeta: metav1.ObjectMeta{Name: "other"}}
+	if err := checkSpawnLimits(parent, []sympoziumv1alpha1.AgentRun{*parent, other, other, other, other, child("a", "Running")}, limits); err != nil {
+		t.Errorf("expected other runs to be ignored: %v", err)
+	}
+	// Active sub-agents anywhere under the same top-level run do.
+	grandchild := func(name, parentName string) sympoziumv1alpha1.AgentRun {
+		return sympoziumv1alpha1.AgentRun{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       sympoziumv1alpha1.AgentRunSpec{Parent: &sympoziumv1alpha1.ParentRunRef{RunName: parentName, SpawnDepth: 2}},
+		}
+	}
+	busy := []sympoziumv1alpha1.AgentRun{*parent, child("a", "Running"), grandchild("a1", "a"), grandchild("a2", "a"), grandchild("a3", "a")}
+	if err := checkSpawnLimits(parent, busy, limits); err == nil {
+		t.Error("expected MaxConcurrent to be enforced")
+	}
+	nested := &sympoziumv1alpha1.AgentRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "grandchild"},
+		Spec:       sympoziumv1alpha1.AgentRunSpec{Parent: &sympoziumv1alpha1.ParentRunRef{RunName: "a", SpawnDepth: 2}},
+	}
+	if err := checkSpawnLimits(nested, nil, limits); err == nil {
+		t.Error("expected MaxDepth to be enforced")
+	}
+}
+
+func TestChildSkills(t *testing.T) {
+	parent := []sympoziumv1alpha1.SkillRef{{SkillPackRef: "k8s-ops"}, {ConfigMapRef: "notes"}}
+	if got := childSkills(parent, nil); len(got) != 2 {
+		t.Errorf("childSkills(nil) = %v, want all parent skills", got)
+	}
+	got := childSkills(parent, []string{"k8s-ops", "not-mounted"})
+	if len(got) != 1 || got[0].SkillPackRef != "k8s-ops" {
+		t.Errorf("childSkills = %v, want only k8s-ops", got)
+	}
+}
+
+func TestCheckAgentMessaging(t *testing.T) {
+	sreCanAsk := &sympoziumv1alpha1.AgentMessagingSpec{AllowedTargets: []string{"platform"}}
+	platformOnlyFromSRE := &sympoziumv1alpha1.AgentMessagingSpec{AllowedCallers: []string{"sre"}}
+
+	if err := checkAgentMessaging("sre", sreCanAsk, "platform", platformOnlyFromSRE, []string{"sre/run-1"}); err != nil {
+		t.Errorf("expected sre -> platform to be allowed: %v", err)
+	}
+	if err := checkAgentMessaging("sre", nil, "platform", nil, []string{"sre/run-1"}); err == nil {
+		t.Error("expected messaging to be denied without a policy")
+	}
+	if err := checkAgentMessaging("dev", &sympoziumv1alpha1.AgentMessagingSpec{AllowedTargets: []string{"*"}}, "platform", platformOnlyFromSRE, []string{"dev/run-1"}); err == nil {
+		t.Error("expected target's AllowedCallers to be enforced")
+	}
+	loop := []string{"platform/run-0", "sre/run-1"}
+	if err := checkAgentMessaging("sre", sreCanAsk, "platform", nil, loop); err == nil {
+		t.Error("expected a call back into the chain to be rejected")
+	}
+	long := []string{"a/1", "b/2", "sre/3"}
+	if err := checkAgentMessaging("sre", sreCanAsk, "platform", nil, long); err == nil {
+		t.Error("expected MaxChainLength to be enforced")
+	}
+}
+
+func TestCallChain(t *testing.T) {
+	run := &sympoziumv1alpha1.AgentRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "platform-msg-abcde",
+			Annotations: map[string]string{callChainAnnotation: "sre/sre-ch-12345"},
+		},
+		Spec: sympoziumv1alpha1.AgentRunSpec{InstanceRef: "platform"},
+	}
+	got := callChain(run)
+	if len(got) != 2 || got[0] != "sre/sre-ch-12345" || got[1] != "platform/platform-msg-abcde" {
+		t.Errorf("callChain = %v", got)
+	}
+}
+
+func TestCallChain_SubAgentOfMessageRun(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = sympoziumv1alpha1.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	// sre messaged platform; platform's run spawns a sub-agent.
+	msgRun := &sympoziumv1alpha1.AgentRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "platform-msg-abcde",
+			Namespace:   "default",
+			Annotations: map[string]string{callChainAnnotation: "sre/sre-ch-12345"},
+		},
+		Spec: sympoziumv1alpha1.AgentRunSpec{InstanceRef: "platform"},
+	}
+	spawner := orchestrator.Spawner{Client: c, Log: logr.Discard()}
+	res, err := spawner.Spawn(context.Background(), orchestrator.SpawnRequest{
+		SpawnID:       "s1",
+		ParentRunName: msgRun.Name,
+		InstanceName:  "platform",
+		Namespace:     "default",
+		Task:          "look it up",
+		CallChain:     msgRun.Annotations[callChainAnnotation],
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	child := &sympoziumv1alpha1.AgentRun{}
+	if err := c.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: res.RunName}, child); err != nil {
+		t.Fatal(err)
+	}
+
+	platformCanAsk := &sympoziumv1alpha1.AgentMessagingSpec{AllowedTargets: []string{"*"}}
+	if err := checkAgentMessaging("platform", platformCanAsk, "sre", nil, callChain(child)); err == nil {
+		t.Error("expected the sub-agent's message back to sre to be rejected")
+	}
+}
+
+func TestSpawn_NestedRunNamesFitAJob(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = sympoziumv1alpha1.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	spawner := orchestrator.Spawner{Client: c, Log: logr.Discard()}
+
+	parent := "platform-engineering-ch-1a2b3c4d"
+	seen := map[string]bool{parent: true}
+	for depth := range 4 {
+		var next string
+		for _, id := range []string{"s1", "s2"} {
+			res, err := spawner.Spawn(context.Background(), orchestrator.SpawnRequest{
+				SpawnID:       id,
+				ParentRunName: parent,
+				InstanceName:  "platform-engineering",
+				Namespace:     "default",
+				Task:          "dig deeper",
+				CurrentDepth:  depth,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(res.RunName) > sympoziumv1alpha1.MaxRunNameLength {
+				t.Errorf("depth %d: run name %q is longer than %d", depth+1, res.RunName, sympoziumv1alpha1.MaxRunNameLength)
+			}
+			if seen[res.RunName] {
+				t.Errorf("depth %d: run name %q reused", depth+1, res.RunName)
+			}
+			seen[res.RunName] = true
+			if next == "" {
+				next = res.RunName
+			}
+		}
+		parent = next
+	}
+}
+
+func TestHandleApprovalCommand(t *testing.T) {
+	inst := &sympoziumv1alpha1.SympoziumInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: "bot", Namespace: "default"},
+		Spec: sympoziumv1alpha1.SympoziumInstanceSpec{
+			Channels: []sympoziumv1alpha1.ChannelSpec{
+				{Type: "slack", Admins: []string{"U-ADMIN"}},
+				{Type: "telegram", Admins: []string{"U-ADMIN"}},
+			},
+		},
+	}
+	newRun := func(name, namespace, channel string) *sympoziumv1alpha1.AgentRun {
+		return &sympoziumv1alpha1.AgentRun{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels: map[string]string{
+					"sympozium.ai/instance": "bot",
+					"sympozium.ai/source":   "channel",
+				},
+				Annotations: map[string]string{
+					"sympozium.ai/reply-channel": channel,
+					"sympozium.ai/reply-chat-id": "C1",
+				},
+			},
+			Status: sympoziumv1alpha1.AgentRunStatus{
+				Approvals: []sympoziumv1alpha1.ToolApproval{{ID: "a1", Tool: "execute_command"}},
+			},
+		}
+	}
+	decision := func(c client.Client, name, namespace string) string {
+		run := &sympoziumv1alpha1.AgentRun{}
+		if err := c.Get(context.Background(), client.ObjectKey{Namespace: namespace, Name: name}, run); err != nil {
+			t.Fatal(err)
+		}
+		return run.Status.Approvals[0].Decision
+	}
+
+	tests := []struct {
+		name      string
+		sender    string
+		channel   string
+		decided   string // run expected to be decided, if any
+		undecided []string
+	}{
+		{name: "admin", sender: "U-ADMIN", channel: "slack", decided: "slack-run", undecided: []string{"telegram-run", "other-ns-run"}},
+		{name: "non-admin", sender: "U-ASKER", channel: "slack", undecided: []string{"slack-run", "telegram-run", "other-ns-run"}},
+		{name: "unconfigured channel", sender: "U-ADMIN", channel: "discord", undecided: []string{"slack-run", "telegram-run", "other-ns-run"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			_ = sympoziumv1alpha1.AddToScheme(scheme)
+			runs := []client.Object{
+				newRun("slack-run", "default", "slack"),
+				newRun("telegram-run", "default", "telegram"),
+				newRun("other-ns-run", "elsewhere", "slack"),
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).
+				WithObjects(runs...).WithStatusSubresource(&sympoziumv1alpha1.AgentRun{}).Build()
+			bus := &recordingBus{}
+			cr := &ChannelRouter{Client: c, EventBus: bus, Log: logr.Discard()}
+
+			cr.handleApprovalCommand(context.Background(), channelpkg.InboundMessage{
+				Channel:      tt.channel,
+				InstanceName: "bot",
+				SenderID:     tt.sender,
+				ChatID:       "C1",
+			}, inst, "approved", "a1", "")
+
+			if tt.decided != "" {
+				if got := decision(c, tt.decided, "default"); got != "approved" {
+					t.Errorf("%s decision = %q, want approved", tt.decided, got)
+				}
+			}
+			for _, name := range tt.undecided {
+				namespace := "default"
+				if name == "other-ns-run" {
+					namespace = "elsewhere"
+				}
+				if got := decision(c, name, namespace); got != "" {
+					t.Errorf("%s decision = %q, want none", name, got)
+				}
+			}
+			if len(bus.events) != 1 {
+				t.Errorf("published %d replies, want 1", len(bus.events))
+			}
+		})
+	}
+}
+
+func TestApprovalRouter_ForgetsFinishedRuns(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = sympoziumv1alpha1.AddToScheme(scheme)
+	done := &sympoziumv1alpha1.AgentRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "done", Namespace: "default"},
+		Status:     sympoziumv1alpha1.AgentRunStatus{Phase: sympoziumv1alpha1.AgentRunPhaseSucceeded},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(done).Build()
+	ar := &ApprovalRouter{Client: c, Log: logr.Discard()}
+	for _, key := range []string{"default/done/a1", "default/gone/a1", "default/gone/a2", "default/gone-2/a1"} {
+		ar.delivered.Store(key, struct{}{})
+	}
+
+	for _, name := range []string{"done", "gone"} {
+		req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: name}}
+		if _, err := ar.Reconcile(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var left []string
+	ar.delivered.Range(func(key, _ any) bool {
+		left = append(left, key.(string))
+		return true
+	})
+	if len(left) != 1 || left[0] != "default/gone-2/a1" {
+		t.Errorf("delivered keys left = %v, want [default/gone-2/a1]", left)
+	}
+}
+
+func TestMemoryCompaction_TaskAndTruncation(t *testing.T) {
+	inst := &sympoziumv1alpha1.SympoziumInstance{
+		Spec: sympoziumv1alpha1.SympoziumInstanceSpec{
+			Memory: &sympoziumv1alpha1.MemorySpec{Enabled: true, MaxSizeKB: 1},
+		},
+	}
+	if got := memoryLimit(inst); got != 1024 {
+		t.Errorf("memoryLimit = %d, want 1024", got)
+	}
+	if got := memoryLimit(&sympoziumv1alpha1.SympoziumInstance{}); got != defaultMemoryMaxSizeKB*1024 {
+		t.Errorf("default memoryLimit = %d", got)
+	}
+
+	memory := "# Agent Memory\n\n" + strings.Repeat("- the cluster runs on arm64 nodes\n", 60)
+	if got, _ := taskSection(memoryCompactionTask(memory, 1024), "MEMORY"); got != memory {
+		t.Errorf("taskSection did not round-trip the memory:\n%q", got)
+	}
+
+	truncated := truncateMemoryContent(memory, 1024)
+	if len(truncated) > 1024 {
+		t.Errorf("truncated memory is %d bytes, want <= 1024", len(truncated))
+	}
+	if !strings.HasPrefix(truncated, "# Agent Memory") || !strings.Contains(truncated, "memory truncated") {
+		t.Errorf("unexpected truncated memory:\n%s", truncated)
+	}
+	if !strings.Contains(truncated, "arm64 nodes\n\n<!--") {
+		t.Errorf("memory was not cut at a line boundary:\n%s", truncated)
+	}
+	if got := truncateMemoryContent("short", 1024); got != "short" {
+		t.Errorf("memory within the limit was changed: %q", got)
+	}
+}
+
+func TestMergeMemory3(t *testing.T) {
+	base := "# Agent Memory\n\n## Cluster\n- prod runs on arm64\n\n## People\n- alice owns billing\n"
+	ours := "# Agent Memory\n\n## Cluster\n- prod runs on arm64\n- staging was upgraded to 1.31\n\n## People\n- alice owns billing\n"
+	theirs := "# Agent Memory\n\n## Cluster\n- prod runs on arm64\n\n## People\n- alice owns billing\n- bob is on call this week\n"
+
+	got, clean := mergeMemory3(base, ours, theirs)
+	want := "# Agent Memory\n\n## Cluster\n- prod runs on arm64\n- staging was upgraded to 1.31\n\n## People\n- alice owns billing\n- bob is on call this week\n"
+	if !clean || got != want {
+		t.Errorf("mergeMemory3 = %q (clean=%v), want %q", got, clean, want)
+	}
+
+	// Both sides rewrote the same line: theirs is kept and the merge is
+	// reported as conflicting.
+	ours = strings.Replace(base, "arm64", "amd64", 1)
+	theirs = strings.Replace(base, "arm64", "graviton", 1)
+	if got, clean := mergeMemory3(base, ours, theirs); clean || got != theirs {
+		t.Errorf("conflicting merge = %q (clean=%v), want theirs and not clean", got, clean)
+	}
+	if got, clean := mergeMemory3(base, ours, ours); !clean || got != ours {
+		t.Errorf("identical edits = %q (clean=%v)", got, clean)
+	}
+
+	task := memoryMergeTask(base, true, ours, theirs)
+	for name, want := range map[string]string{"BASE": base, "CURRENT": ours, "INCOMING": theirs} {
+		if got, ok := taskSection(task, name); !ok || got != want {
+			t.Errorf("taskSection(%s) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestConversationTask(t *testing.T) {
+	if got := conversationTask(nil, "hi"); got != "hi" {
+		t.Errorf("without history, task = %q", got)
+	}
+
+	history := []session.TranscriptEvent{
+		{EventType: session.EventUserMessage, Content: "which nodes are unhealthy?"},
+		{EventType: session.EventToolCall, ToolName: "execute_command", Content: `{"command":"kubectl get nodes"}`},
+		{EventType: session.EventToolResult, ToolName: "execute_command", Content: "node-3 NotReady"},
+		{EventType: session.EventAgentMessage, Content: "node-3 is NotReady."},
+		{EventType: session.EventError, Content: "ignored"},
+	}
+	got := conversationTask(history, "why?")
+	want := "## Conversation so far\n\n" +
+		"User: which nodes are unhealthy?\n\n" +
+		`Assistant called execute_command({"command":"kubectl get nodes"})` + "\n\n" +
+		"Result of execute_command: node-3 NotReady\n\n" +
+		"Assistant: node-3 is NotReady.\n\n" +
+		"## New message\n\nwhy?"
+	if got != want {
+		t.Errorf("conversationTask =\n%s\nwant\n%s", got, want)
+	}
+
+	// Nothing before a reset is replayed.
+	reset := append(history, session.TranscriptEvent{EventType: session.EventReset}, session.TranscriptEvent{EventType: session.EventUserMessage, Content: "hello again"})
+	if got := conversationTask(reset, "why?"); strings.Contains(got, "node-3") || !strings.Contains(got, "User: hello again") {
+		t.Errorf("history before the reset was replayed:\n%s", got)
+	}
+
+	// Older events are dropped to stay within the size limit.
+	long := []session.TranscriptEvent{
+		{EventType: session.EventUserMessage, Content: "first " + strings.Repeat("x", channelHistoryMaxBytes)},
+		{EventType: session.EventAgentMessage, Content: "latest reply"},
+	}
+	got = conversationTask(long, "next")
+	if strings.Contains(got, "first") || !strings.Contains(got, "Assistant: latest reply") {
+		t.Errorf("history was not bounded:\n%s", truncateForLog(got, 200))
+	}
+	if key := session.ChannelKey("slack", "C1", "171.2"); key != "channel:slack:C1:171.2" {
+		t.Errorf("ChannelKey = %q", key)
+	}
+}
+
+func TestChannelAccess(t *testing.T) {
+	spec := &sympoziumv1alpha1.ChannelSpec{
+		Type:       "telegram",
+		Activation: sympoziumv1alpha1.ChannelActivationMention,
+		AllowChats: []string{"-100", "42"},
+		DenyFrom:   []string{"99"},
+	}
+	group := channelpkg.InboundMessage{SenderID: "7", ChatID: "-100"}
+	if channelActivates(spec, group) {
+		t.Error("unmentioned group message must not activate in mention mode")
+	}
+	group.Mentioned = true
+	if !channelActivates(spec, group) || channelAllows(spec, group) != "" {
+		t.Error("mention in an allowed chat must activate")
+	}
+	dm := channelpkg.InboundMessage{SenderID: "42", ChatID: "42", IsDirect: true}
+	if !channelActivates(spec, dm) {
+		t.Error("direct messages must activate in mention mode")
+	}
+	if got := channelAllows(spec, channelpkg.InboundMessage{SenderID: "99", ChatID: "-100"}); got != "sender denied" {
+		t.Errorf("denied sender: %q", got)
+	}
+	if got := channelAllows(spec, channelpkg.InboundMessage{SenderID: "7", ChatID: "-200"}); got != "chat not allowed" {
+		t.Errorf("unlisted chat: %q", got)
+	}
+
+	spec.Activation = sympoziumv1alpha1.ChannelActivationReply
+	if !channelActivates(spec, channelpkg.InboundMessage{ReplyToBot: true}) {
+		t.Error("replies to the bot must activate in reply mode")
+	}
+	spec.Activation = sympoziumv1alpha1.ChannelActivationDM
+	if channelActivates(spec, group) {
+		t.Error("group messages must not activate in dm mode")
+	}
+	if !channelActivates(nil, group) || channelAllows(nil, group) != "" {
+		t.Error("channels without a spec must accept every message")
+	}
+}
+
+func TestChannelPairing(t *testing.T) {
+	now := time.Now()
+	inst := &sympoziumv1alpha1.SympoziumInstance{}
+	inst.Spec.Channels = []sympoziumv1alpha1.ChannelSpec{{Type: "telegram", Pairing: true}}
+	inst.Status.PendingPairings = []sympoziumv1alpha1.PairingRequest{
+		{Code: "ABCD2345", Channel: "telegram", SenderID: "42", ExpiresAt: metav1.NewTime(now.Add(time.Hour))},
+		{Code: "EXPIRED2", Channel: "telegram", SenderID: "43", ExpiresAt: metav1.NewTime(now.Add(-time.Minute))},
+	}
+
+	dm := channelpkg.InboundMessage{Channel: "telegram", SenderID: "42", ChatID: "42", IsDirect: true}
+	if got := channelAllows(&inst.Spec.Channels[0], dm); got != refusedUnpaired {
+		t.Fatalf("unknown sender: %q", got)
+	}
+	if got := channelAllows(&inst.Spec.Channels[0], channelpkg.InboundMessage{SenderID: "42", ChatID: "-100"}); got != "" {
+		t.Errorf("pairing must not gate group messages: %q", got)
+	}
+
+	if inst.ApprovePairing("EXPIRED2", now) != nil {
+		t.Error("expired codes must not be approved")
+	}
+	if p := inst.ApprovePairing("abcd2345", now); p == nil || p.SenderID != "42" {
+		t.Fatalf("ApprovePairing = %+v", p)
+	}
+	if got := channelAllows(&inst.Spec.Channels[0], dm); got != "" {
+		t.Errorf("paired sender: %q", got)
+	}
+	if live := livePairings(inst, now); len(live) != 0 {
+		t.Errorf("approved and expired pairings must be dropped: %+v", live)
+	}
+
+	if !inst.RevokePairing("telegram", "42") || inst.RevokePairing("telegram", "42") {
+		t.Error("RevokePairing must remove the sender once")
+	}
+	if got := channelAllows(&inst.Spec.Channels[0], dm); got != refusedUnpaired {
+		t.Errorf("revoked sender: %q", got)
+	}
+	if code := newPairingCode(); len(code) != 8 || strings.Trim(code, pairingCodeAlphabet) != "" {
+		t.Errorf("newPairingCode = %q", code)
+	}
+}
+
+// recordingBus records published events.
+type recordingBus struct{ events []*eventbus.Event }
+
+func (b *recordingBus) Publish(_ context.Context, _ string, event *eventbus.Event) error {
+	b.events = append(b.events, event)
+	return nil
+}
+
+func (b *recordingBus) Subscribe(context.Context, string) (<-chan *eventbus.Event, error) {
+	return nil, nil
+}
+
+func (b *recordingBus) Consume(context.Context, string, string, func(*eventbus.Event) error) error {
+	return nil
+}
+
+func (b *recordingBus) Close() error { return nil }
+
+func TestChatCommands_AdminOnly(t *testing.T) {
+	bus := &recordingBus{}
+	cr := &ChannelRouter{EventBus: bus, Log: logr.Discard()}
+	inst := &sympoziumv1alpha1.SympoziumInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: "ops", Namespace: "default"},
+		Spec: sympoziumv1alpha1.SympoziumInstanceSpec{
+			Channels: []sympoziumv1alpha1.ChannelSpec{{Type: "webchat", Admins: []string{"alice"}}},
+		},
+	}
+	inst.Spec.Agents.Default.Model = "gpt-4o"
+	msg := channelpkg.InboundMessage{Channel: "webchat", ChatID: "c1", SenderID: "visitor-1", InstanceName: "ops", IsDirect: true}
+
+	for _, tt := range []struct {
+		cmd  string
+		args []string
+	}{
+		{chatCmdModel, []string{"gpt-5"}},
+		{chatCmdReset, nil},
+	} {
+		bus.events = nil
+		cr.handleChatCommand(context.Background(), msg, inst, tt.cmd, tt.args)
+		var out channelpkg.OutboundMessage
+		if len(bus.events) != 1 || json.Unmarshal(bus.events[0].Data, &out) != nil || !strings.Contains(out.Text, "Only admins") {
+			t.Errorf("%s from a non-admin: replies = %d, text %q", tt.cmd, len(bus.events), out.Text)
+		}
+	}
+	if inst.Spec.Agents.Default.Model != "gpt-4o" {
+		t.Errorf("model changed to %q", inst.Spec.Agents.Default.Model)
+	}
+
+	// Anyone may still look at the model.
+	bus.events = nil
+	cr.handleChatCommand(context.Background(), msg, inst, chatCmdModel, nil)
+	var out channelpkg.OutboundMessage
+	if len(bus.events) != 1 || json.Unmarshal(bus.events[0].Data, &out) != nil || out.Text != "Model: openai/gpt-4o" {
+		t.Errorf("/model reply = %q", out.Text)
+	}
+}
+
+func TestCheckModel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/models/llama3" {
+			_, _ = w.Write([]byte(`{"id":"llama3"}`))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	cr := &ChannelRouter{Log: logr.Discard()}
+	inst := &sympoziumv1alpha1.SympoziumInstance{}
+	inst.Spec.Agents.Default.BaseURL = srv.URL + "/v1"
+	if err := cr.checkModel(context.Background(), inst, "ollama", "llama3"); err != nil {
+		t.Errorf("served model refused: %v", err)
+	}
+	if err := cr.checkModel(context.Background(), inst, "ollama", "gpt-nonsense"); err == nil {
+		t.Error("unknown model accepted")
+	}
+	if err := cr.checkModel(context.Background(), &sympoziumv1alpha1.SympoziumInstance{}, "azure-openai", "prod"); err == nil {
+		t.Error("model of a provider that cannot be checked accepted")
+	}
+}
+
+func TestChannelActivity(t *testing.T) {
+	ctx := context.Background()
+	bus := &recordingBus{}
+	cr := &ChannelRouter{EventBus: bus, Log: logr.Discard(), activity: map[string]*channelActivity{}}
+	typing := func(i int) channelpkg.TypingEvent {
+		var ev channelpkg.TypingEvent
+		if err := json.Unmarshal(bus.events[i].Data, &ev); err != nil {
+			t.Fatal(err)
+		}
+		return ev
+	}
+	status := func(run string, st ipc.StatusUpdate) {
+		event, _ := eventbus.NewEvent(eventbus.TopicAgentStatusUpdate, map[string]string{"agentRunID": run}, st)
+		cr.handleStatus(ctx, event)
+	}
+
+	run := newTestRun()
+	msg := channelpkg.InboundMessage{Channel: "slack", InstanceName: "my-instance", ChatID: "C1",
+		Metadata: map[string]string{"messageId": "1.2"}}
+	cr.startActivity(ctx, run, msg, nil)
+	if ev := typing(0); !ev.Active || ev.MessageID != "1.2" || ev.RunName != run.Name {
+		t.Fatalf("start = %+v", ev)
+	}
+
+	status(run.Name, ipc.StatusUpdate{Phase: "thinking"})
+	status("other-run", ipc.StatusUpdate{Phase: "tool_use", Message: "execute_command"})
+	if len(bus.events) != 1 {
+		t.Fatalf("unchanged or unknown status must not publish, got %d events", len(bus.events))
+	}
+	status(run.Name, ipc.StatusUpdate{Phase: "tool_use", Message: "execute_command"})
+	if ev := typing(1); ev.Phase != "tool_use" || ev.Tool != "execute_command" {
+		t.Errorf("tool status = %+v", ev)
+	}
+
+	cr.stopActivity(ctx, run.Name)
+	cr.stopActivity(ctx, run.Name)
+	if len(bus.events) != 3 || typing(2).Active {
+		t.Errorf("stop must publish one inactive event, got %d events", len(bus.events))
+	}
+}
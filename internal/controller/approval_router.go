@@ -0,0 +1,264 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sympoziumv1alpha1 "github.com/alexsjones/sympozium/api/v1alpha1"
+	channelpkg "github.com/alexsjones/sympozium/internal/channel"
+	"github.com/alexsjones/sympozium/internal/eventbus"
+	"github.com/alexsjones/sympozium/internal/ipc"
+)
+
+// ApprovalRouter implements human approval for tool calls that match a
+// ToolGating "ask" rule. Requests from agent pods are recorded on
+// AgentRun.status.approvals; approvers (TUI, API server, channel messages)
+// write their decision there, and the router delivers it back to the pod
+// through the event bus.
+type ApprovalRouter struct {
+	Client   client.Client
+	EventBus eventbus.EventBus
+	Log      logr.Logger
+
+	// delivered holds "<namespace>/<run>/<id>" of decisions already
+	// published, until the run stops running.
+	delivered sync.Map
+}
+
+// Start listens for approval requests and timeouts from agent pods. It
+// blocks until ctx is cancelled.
+func (ar *ApprovalRouter) Start(ctx context.Context) error {
+	ar.Log.Info("Starting approval router")
+
+	requestCh, err := ar.EventBus.Subscribe(ctx, eventbus.TopicToolApprovalRequest)
+	if err != nil {
+		return fmt.Errorf("subscribing to %s: %w", eventbus.TopicToolApprovalRequest, err)
+	}
+	responseCh, err := ar.EventBus.Subscribe(ctx, eventbus.TopicToolApprovalResponse)
+	if err != nil {
+		return fmt.Errorf("subscribing to %s: %w", eventbus.TopicToolApprovalResponse, err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			ar.Log.Info("Approval router shutting down")
+			return nil
+		case event := <-requestCh:
+			if event != nil {
+				ar.handleRequest(ctx, event)
+			}
+		case event := <-responseCh:
+			if event != nil {
+				ar.handleResponse(ctx, event)
+			}
+		}
+	}
+}
+
+// handleRequest records a new pending approval on the AgentRun and, for
+// channel-sourced runs, asks the originating chat for a decision.
+func (ar *ApprovalRouter) handleRequest(ctx context.Context, event *eventbus.Event) {
+	var req ipc.ApprovalRequest
+	if err := json.Unmarshal(event.Data, &req); err != nil || req.ID == "" {
+		ar.Log.Info("Ignoring malformed approval request")
+		return
+	}
+
+	run, err := findAgentRun(ctx, ar.Client, event.Metadata["instanceName"], event.Metadata["agentRunID"])
+	if err != nil {
+		ar.Log.Error(err, "failed to find AgentRun for approval request", "run", event.Metadata["agentRunID"])
+		return
+	}
+
+	now := metav1.Now()
+	expires := metav1.NewTime(now.Add(time.Duration(req.TimeoutSeconds) * time.Second))
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := ar.Client.Get(ctx, client.ObjectKeyFromObject(run), run); err != nil {
+			return err
+		}
+		for _, a := range run.Status.Approvals {
+			if a.ID == req.ID {
+				return nil
+			}
+		}
+		run.Status.Approvals = append(run.Status.Approvals, sympoziumv1alpha1.ToolApproval{
+			ID:          req.ID,
+			Tool:        req.ToolName,
+			Arguments:   req.Arguments,
+			RequestedAt: &now,
+			ExpiresAt:   &expires,
+		})
+		return ar.Client.Status().Update(ctx, run)
+	})
+	if err != nil {
+		ar.Log.Error(err, "failed to record approval request", "run", run.Name, "id", req.ID)
+		return
+	}
+
+	ar.Log.Info("Tool call awaiting approval", "run", run.Name, "tool", req.ToolName, "id", req.ID)
+	ar.notifyChannel(ctx, run, req)
+}
+
+// notifyChannel posts the approval request to the chat a channel-sourced
+// run came from, so the user can reply with /approve or /deny.
+func (ar *ApprovalRouter) notifyChannel(ctx context.Context, run *sympoziumv1alpha1.AgentRun, req ipc.ApprovalRequest) {
+	replyChannel := run.Annotations["sympozium.ai/reply-channel"]
+	if replyChannel == "" {
+		return
+	}
+	text := fmt.Sprintf("Approval needed: the agent wants to call %s", req.ToolName)
+	if req.Arguments != "" {
+		text += fmt.Sprintf(" with %s", truncateForLog(req.Arguments, 300))
+	}
+	text += fmt.Sprintf(".\nReply \"/approve %s\" or \"/deny %s <reason>\" within %s.",
+		req.ID, req.ID, time.Duration(req.TimeoutSeconds)*time.Second)
+
+	outEvent, err := eventbus.NewEvent(eventbus.TopicChannelMessageSend, map[string]string{
+		"instanceName": run.Spec.InstanceRef,
+		"channel":      replyChannel,
+	}, channelpkg.OutboundMessage{
+		Channel: replyChannel,
+		ChatID:  run.Annotations["sympozium.ai/reply-chat-id"],
+		Text:    text,
+		Format:  channelpkg.FormatPlain,
+	})
+	if err != nil {
+		return
+	}
+	if err := ar.EventBus.Publish(ctx, eventbus.TopicChannelMessageSend, outEvent); err != nil {
+		ar.Log.Error(err, "failed to send approval request to channel", "channel", replyChannel)
+	}
+}
+
+// handleResponse records decisions published on the bus, such as timeouts
+// reported by the agent pod.
+func (ar *ApprovalRouter) handleResponse(ctx context.Context, event *eventbus.Event) {
+	var resp ipc.ApprovalResponse
+	if err := json.Unmarshal(event.Data, &resp); err != nil || resp.ID == "" || resp.Decision == "" {
+		return
+	}
+	run, err := findAgentRun(ctx, ar.Client, event.Metadata["instanceName"], event.Metadata["agentRunID"])
+	if err != nil {
+		return
+	}
+	// Already on the bus, so the reconciler must not publish it again.
+	ar.delivered.Store(approvalKey(run, resp.ID), true)
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := ar.Client.Get(ctx, client.ObjectKeyFromObject(run), run); err != nil {
+			return err
+		}
+		if !run.Status.DecideApproval(resp.ID, resp.Decision, resp.Approver, resp.Reason, metav1.Now()) {
+			return nil
+		}
+		return ar.Client.Status().Update(ctx, run)
+	})
+	if err != nil {
+		ar.Log.Error(err, "failed to record approval decision", "run", run.Name, "id", resp.ID)
+	}
+}
+
+// Reconcile publishes decisions recorded on a running AgentRun that have
+// not been delivered to its pod yet.
+func (ar *ApprovalRouter) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	run := &sympoziumv1alpha1.AgentRun{}
+	if err := ar.Client.Get(ctx, req.NamespacedName, run); err != nil {
+		if errors.IsNotFound(err) {
+			ar.forget(req.NamespacedName.String())
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if run.Status.Phase != sympoziumv1alpha1.AgentRunPhaseRunning {
+		// A finished run's pod no longer waits for decisions.
+		if run.Status.Phase == sympoziumv1alpha1.AgentRunPhaseSucceeded || run.Status.Phase == sympoziumv1alpha1.AgentRunPhaseFailed {
+			ar.forget(req.NamespacedName.String())
+		}
+		return ctrl.Result{}, nil
+	}
+
+	for _, a := range run.Status.Approvals {
+		if a.Decision == "" {
+			continue
+		}
+		key := approvalKey(run, a.ID)
+		if _, done := ar.delivered.Load(key); done {
+			continue
+		}
+		event, err := eventbus.NewEvent(eventbus.TopicToolApprovalResponse, map[string]string{
+			"agentRunID":   run.Name,
+			"instanceName": run.Spec.InstanceRef,
+		}, ipc.ApprovalResponse{
+			ID:       a.ID,
+			Decision: a.Decision,
+			Approver: a.Approver,
+			Reason:   a.Reason,
+		})
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := ar.EventBus.Publish(ctx, eventbus.TopicToolApprovalResponse, event); err != nil {
+			return ctrl.Result{}, fmt.Errorf("publishing approval decision: %w", err)
+		}
+		ar.delivered.Store(key, true)
+		ar.Log.Info("Delivered approval decision", "run", run.Name, "tool", a.Tool,
+			"decision", a.Decision, "approver", a.Approver)
+	}
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager registers the router's event bus listener and its
+// AgentRun reconciler with the manager.
+func (ar *ApprovalRouter) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.Add(ar); err != nil {
+		return err
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("agentrun-approvals").
+		For(&sympoziumv1alpha1.AgentRun{}).
+		Complete(ar)
+}
+
+// forget drops the delivered decisions of the run "<namespace>/<run>".
+func (ar *ApprovalRouter) forget(run string) {
+	ar.delivered.Range(func(key, _ any) bool {
+		if strings.HasPrefix(key.(string), run+"/") {
+			ar.delivered.Delete(key)
+		}
+		return true
+	})
+}
+
+func approvalKey(run *sympoziumv1alpha1.AgentRun, id string) string {
+	return run.Namespace + "/" + run.Name + "/" + id
+}
+
+// findAgentRun looks up an AgentRun by name among the runs of an instance.
+// Bridge events carry the run and instance names but not the namespace.
+func findAgentRun(ctx context.Context, c client.Client, instanceName, runName string) (*sympoziumv1alpha1.AgentRun, error) {
+	var runs sympoziumv1alpha1.AgentRunList
+	opts := []client.ListOption{}
+	if instanceName != "" {
+		opts = append(opts, client.MatchingLabels{"sympozium.ai/instance": instanceName})
+	}
+	if err := c.List(ctx, &runs, opts...); err != nil {
+		return nil, err
+	}
+	for i := range runs.Items {
+		if runs.Items[i].Name == runName {
+			return &runs.Items[i], nil
+		}
+	}
+	return nil, fmt.Errorf("AgentRun %q not found", runName)
+}
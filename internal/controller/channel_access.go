@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"slices"
+
+	sympoziumv1alpha1 "github.com/alexsjones/sympozium/api/v1alpha1"
+	channelpkg "github.com/alexsjones/sympozium/internal/channel"
+)
+
+// channelSpecFor returns the instance's configuration of a channel type, or
+// nil if it has none.
+func channelSpecFor(inst *sympoziumv1alpha1.SympoziumInstance, channelType string) *sympoziumv1alpha1.ChannelSpec {
+	for i := range inst.Spec.Channels {
+		if inst.Spec.Channels[i].Type == channelType {
+			return &inst.Spec.Channels[i]
+		}
+	}
+	return nil
+}
+
+// refusedUnpaired is the reason channelAllows gives for a direct message
+// from a sender who has to pair first.
+const refusedUnpaired = "sender not paired"
+
+// channelAllows checks a message's sender and chat against the channel's
+// allow and deny lists and pairing. It returns why the message is refused,
+// or "".
+func channelAllows(spec *sympoziumv1alpha1.ChannelSpec, msg channelpkg.InboundMessage) string {
+	if spec == nil {
+		return ""
+	}
+	switch {
+	case slices.Contains(spec.DenyFrom, msg.SenderID):
+		return "sender denied"
+	case slices.Contains(spec.DenyChats, msg.ChatID):
+		return "chat denied"
+	case len(spec.AllowChats) > 0 && !slices.Contains(spec.AllowChats, msg.ChatID):
+		return "chat not allowed"
+	case spec.Pairing && msg.IsDirect:
+		if !spec.Paired(msg.SenderID) {
+			return refusedUnpaired
+		}
+	case len(spec.AllowFrom) > 0 && !slices.Contains(spec.AllowFrom, msg.SenderID):
+		return "sender not allowed"
+	}
+	return ""
+}
+
+// channelActivates reports whether a message starts a run under the
+// channel's activation mode.
+func channelActivates(spec *sympoziumv1alpha1.ChannelSpec, msg channelpkg.InboundMessage) bool {
+	if spec == nil {
+		return true
+	}
+	switch spec.Activation {
+	case sympoziumv1alpha1.ChannelActivationMention:
+		return msg.IsDirect || msg.Mentioned
+	case sympoziumv1alpha1.ChannelActivationReply:
+		return msg.IsDirect || msg.Mentioned || msg.ReplyToBot
+	case sympoziumv1alpha1.ChannelActivationDM:
+		return msg.IsDirect
+	default:
+		return true
+	}
+}
@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	sympoziumv1alpha1 "github.com/alexsjones/sympozium/api/v1alpha1"
+	channelpkg "github.com/alexsjones/sympozium/internal/channel"
+)
+
+// attachmentsConfigMapName returns the ConfigMap holding a run's
+// attachments.
+func attachmentsConfigMapName(runName string) string {
+	return runName + "-attachments"
+}
+
+// runAttachments returns the attachments of a channel message for a run's
+// spec, and their contents by file name.
+func runAttachments(msg channelpkg.InboundMessage) ([]sympoziumv1alpha1.AgentRunAttachment, map[string][]byte) {
+	if len(msg.Attachments) == 0 {
+		return nil, nil
+	}
+	var list []sympoziumv1alpha1.AgentRunAttachment
+	data := make(map[string][]byte, len(msg.Attachments))
+	for i, a := range msg.Attachments {
+		base := attachmentFileName(a.Filename, i)
+		name := base
+		for n := 2; ; n++ {
+			if _, taken := data[name]; !taken {
+				break
+			}
+			ext := path.Ext(base)
+			name = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(base, ext), n, ext)
+		}
+		typ := a.Type
+		if typ == "" {
+			typ = channelpkg.AttachmentType(a.MimeType)
+		}
+		list = append(list, sympoziumv1alpha1.AgentRunAttachment{Name: name, Type: typ, MimeType: a.MimeType})
+		data[name] = a.Data
+	}
+	return list, data
+}
+
+// attachmentFileName turns a file name from a chat into a ConfigMap key:
+// letters, digits, '-', '_' and '.', not starting with a dot.
+func attachmentFileName(name string, i int) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, path.Base(name))
+	name = strings.TrimLeft(name, ".")
+	if len(name) > 100 {
+		ext := path.Ext(name)
+		if len(ext) > 10 {
+			ext = ""
+		}
+		name = name[:100-len(ext)] + ext
+	}
+	if strings.Trim(name, "_") == "" {
+		return fmt.Sprintf("attachment-%d", i+1)
+	}
+	return name
+}
+
+// createAttachments stores the contents of a run's attachments in the
+// ConfigMap its pod mounts.
+func (cr *ChannelRouter) createAttachments(ctx context.Context, run *sympoziumv1alpha1.AgentRun, data map[string][]byte) error {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      attachmentsConfigMapName(run.Name),
+			Namespace: run.Namespace,
+			Labels: map[string]string{
+				"sympozium.ai/agent-run": run.Name,
+			},
+		},
+		BinaryData: data,
+	}
+	if err := controllerutil.SetControllerReference(run, cm, cr.Client.Scheme()); err != nil {
+		return err
+	}
+	return cr.Client.Create(ctx, cm)
+}
+
+// attachmentNames lists a message's attachments for its transcript entry.
+func attachmentNames(list []sympoziumv1alpha1.AgentRunAttachment) string {
+	names := make([]string, len(list))
+	for i, a := range list {
+		names[i] = a.Name
+	}
+	return "[attached: " + strings.Join(names, ", ") + "]"
+}
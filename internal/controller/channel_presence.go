@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sympoziumv1alpha1 "github.com/alexsjones/sympozium/api/v1alpha1"
+	channelpkg "github.com/alexsjones/sympozium/internal/channel"
+	"github.com/alexsjones/sympozium/internal/eventbus"
+	"github.com/alexsjones/sympozium/internal/ipc"
+)
+
+// activityCheckInterval is how often the router sends progress notes and
+// looks for runs that ended without a result.
+const activityCheckInterval = 15 * time.Second
+
+// activityQuietAfter is how long a run can go without a status update
+// before the router checks that it is still running.
+const activityQuietAfter = 2 * time.Minute
+
+// channelActivity is a channel run the router shows a typing indicator
+// for. Only runs created by this router are tracked.
+type channelActivity struct {
+	namespace string
+	instance  string
+	typing    channelpkg.TypingEvent
+	started   time.Time
+	lastSeen  time.Time
+	// noteAfter is when to send a progress note; zero sends none.
+	noteAfter time.Duration
+	noted     bool
+}
+
+// startActivity shows a typing indicator for a run created for msg.
+func (cr *ChannelRouter) startActivity(ctx context.Context, run *sympoziumv1alpha1.AgentRun, msg channelpkg.InboundMessage, spec *sympoziumv1alpha1.ChannelSpec) {
+	now := time.Now()
+	a := &channelActivity{
+		namespace: run.Namespace,
+		instance:  msg.InstanceName,
+		typing: channelpkg.TypingEvent{
+			Channel:   msg.Channel,
+			ChatID:    msg.ChatID,
+			ThreadID:  msg.ThreadID,
+			MessageID: msg.Metadata["messageId"],
+			RunName:   run.Name,
+			Phase:     "thinking",
+			Active:    true,
+		},
+		started:  now,
+		lastSeen: now,
+	}
+	if spec != nil && spec.ProgressNoteAfter != nil {
+		a.noteAfter = spec.ProgressNoteAfter.Duration
+	}
+	cr.activity[run.Name] = a
+	cr.publishTyping(ctx, a.instance, a.typing)
+}
+
+// handleStatus updates the typing indicator of a run from its status.
+func (cr *ChannelRouter) handleStatus(ctx context.Context, event *eventbus.Event) {
+	a := cr.activity[event.Metadata["agentRunID"]]
+	if a == nil {
+		return
+	}
+	var st ipc.StatusUpdate
+	if err := json.Unmarshal(event.Data, &st); err != nil {
+		return
+	}
+	a.lastSeen = time.Now()
+	tool := ""
+	if st.Phase == "tool_use" {
+		tool = st.Message
+	}
+	if st.Phase == a.typing.Phase && tool == a.typing.Tool {
+		return
+	}
+	a.typing.Phase, a.typing.Tool = st.Phase, tool
+	cr.publishTyping(ctx, a.instance, a.typing)
+}
+
+// stopActivity clears the typing indicator of a run, if it has one.
+func (cr *ChannelRouter) stopActivity(ctx context.Context, runName string) {
+	a := cr.activity[runName]
+	if a == nil {
+		return
+	}
+	delete(cr.activity, runName)
+	a.typing.Active = false
+	cr.publishTyping(ctx, a.instance, a.typing)
+}
+
+// checkActivity sends progress notes for long runs and stops the
+// indicators of runs that ended without a result, e.g. cancelled or timed
+// out.
+func (cr *ChannelRouter) checkActivity(ctx context.Context) {
+	now := time.Now()
+	for name, a := range cr.activity {
+		if a.noteAfter > 0 && !a.noted && now.Sub(a.started) >= a.noteAfter {
+			a.noted = true
+			note := "Still working on it…"
+			if a.typing.Tool != "" {
+				note = "Still working on it (running " + a.typing.Tool + ")…"
+			}
+			cr.reply(ctx, channelpkg.InboundMessage{
+				Channel:      a.typing.Channel,
+				InstanceName: a.instance,
+				ChatID:       a.typing.ChatID,
+				ThreadID:     a.typing.ThreadID,
+			}, note)
+		}
+		if now.Sub(a.lastSeen) < activityQuietAfter {
+			continue
+		}
+		a.lastSeen = now
+		var run sympoziumv1alpha1.AgentRun
+		err := cr.Client.Get(ctx, client.ObjectKey{Namespace: a.namespace, Name: name}, &run)
+		switch {
+		case apierrors.IsNotFound(err):
+			cr.stopActivity(ctx, name)
+		case err != nil:
+			cr.Log.V(1).Info("Could not check channel run", "run", name, "err", err)
+		case run.Status.Phase == sympoziumv1alpha1.AgentRunPhaseSucceeded, run.Status.Phase == sympoziumv1alpha1.AgentRunPhaseFailed:
+			cr.stopActivity(ctx, name)
+		}
+	}
+}
+
+// publishTyping sends a typing event to the run's channel.
+func (cr *ChannelRouter) publishTyping(ctx context.Context, instanceName string, ev channelpkg.TypingEvent) {
+	event, err := eventbus.NewEvent(eventbus.TopicChannelTyping, map[string]string{
+		"instanceName": instanceName,
+		"channel":      ev.Channel,
+	}, ev)
+	if err != nil {
+		return
+	}
+	if err := cr.EventBus.Publish(ctx, eventbus.TopicChannelTyping, event); err != nil {
+		cr.Log.Error(err, "failed to publish typing event", "run", ev.RunName)
+	}
+}
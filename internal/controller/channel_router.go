@@ -12,11 +12,15 @@ import (
 
 	"github.com/go-logr/logr"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	sympoziumv1alpha1 "github.com/alexsjones/sympozium/api/v1alpha1"
 	channelpkg "github.com/alexsjones/sympozium/internal/channel"
 	"github.com/alexsjones/sympozium/internal/eventbus"
+	"github.com/alexsjones/sympozium/internal/session"
 )
 
 // ChannelRouter subscribes to channel.message.received on the event bus,
@@ -25,7 +29,14 @@ import (
 type ChannelRouter struct {
 	Client   client.Client
 	EventBus eventbus.EventBus
+	// Sessions, when set, records each chat's messages and replays the
+	// recent conversation into the run for a new message.
+	Sessions *session.Store
 	Log      logr.Logger
+
+	// activity holds the channel runs showing a typing indicator, by run
+	// name. It is only used from Start's goroutine.
+	activity map[string]*channelActivity
 }
 
 // Start begins listening for inbound channel messages and completed agent runs.
@@ -45,6 +56,16 @@ func (cr *ChannelRouter) Start(ctx context.Context) error {
 		return fmt.Errorf("subscribing to %s: %w", eventbus.TopicAgentRunCompleted, err)
 	}
 
+	// Subscribe to agent status updates to show typing indicators.
+	statusCh, err := cr.EventBus.Subscribe(ctx, eventbus.TopicAgentStatusUpdate)
+	if err != nil {
+		return fmt.Errorf("subscribing to %s: %w", eventbus.TopicAgentStatusUpdate, err)
+	}
+
+	cr.activity = map[string]*channelActivity{}
+	ticker := time.NewTicker(activityCheckInterval)
+	defer ticker.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -56,28 +77,14 @@ func (cr *ChannelRouter) Start(ctx context.Context) error {
 
 		case event := <-completedCh:
 			cr.handleCompleted(ctx, event)
-		}
-	}
-}
 
-// resolveProvider returns the AI provider for the instance.
-// It prefers the explicit Provider field on AuthRefs, falling back to
-// guessing from the auth secret names.
-func resolveProvider(inst *sympoziumv1alpha1.SympoziumInstance) string {
-	for _, ref := range inst.Spec.AuthRefs {
-		if ref.Provider != "" {
-			return ref.Provider
-		}
-	}
-	// Fallback: guess from secret name (e.g. "<inst>-openai-key").
-	for _, ref := range inst.Spec.AuthRefs {
-		for _, p := range []string{"anthropic", "azure-openai", "ollama", "openai"} {
-			if strings.Contains(ref.Secret, p) {
-				return p
-			}
+		case event := <-statusCh:
+			cr.handleStatus(ctx, event)
+
+		case <-ticker.C:
+			cr.checkActivity(ctx)
 		}
 	}
-	return "openai"
 }
 
 // handleInbound processes an inbound channel message by creating an AgentRun.
@@ -88,44 +95,89 @@ func (cr *ChannelRouter) handleInbound(ctx context.Context, event *eventbus.Even
 		return
 	}
 
-	if msg.Text == "" || msg.InstanceName == "" {
+	if (msg.Text == "" && len(msg.Attachments) == 0) || msg.InstanceName == "" {
 		cr.Log.Info("Skipping empty inbound message", "instance", msg.InstanceName)
 		return
 	}
 
-	cr.Log.Info("Received channel message",
-		"channel", msg.Channel,
-		"instance", msg.InstanceName,
-		"sender", msg.SenderName,
-		"text", truncateForLog(msg.Text, 80),
-	)
-
 	// Look up the SympoziumInstance to get config and namespace.
-	var instances sympoziumv1alpha1.SympoziumInstanceList
-	if err := cr.Client.List(ctx, &instances); err != nil {
+	inst, err := cr.findInstance(ctx, msg.InstanceName)
+	if err != nil {
 		cr.Log.Error(err, "failed to list SympoziumInstances")
 		return
 	}
+	if inst == nil {
+		cr.Log.Info("SympoziumInstance not found for channel message", "instance", msg.InstanceName)
+		return
+	}
 
-	var inst *sympoziumv1alpha1.SympoziumInstance
-	for i := range instances.Items {
-		if instances.Items[i].Name == msg.InstanceName {
-			inst = &instances.Items[i]
-			break
+	// The allow and deny lists apply to commands too; activation only
+	// decides which other messages reach the agent.
+	spec := channelSpecFor(inst, msg.Channel)
+	if reason := channelAllows(spec, msg); reason != "" {
+		if reason == refusedUnpaired {
+			cr.requestPairing(ctx, msg, inst)
+			return
 		}
+		cr.Log.V(1).Info("Ignoring channel message", "channel", msg.Channel, "instance", msg.InstanceName,
+			"sender", msg.SenderID, "chat", msg.ChatID, "reason", reason)
+		return
 	}
-	if inst == nil {
-		cr.Log.Info("SympoziumInstance not found for channel message", "instance", msg.InstanceName)
+
+	if decision, id, reason, ok := parseApprovalCommand(msg.Text); ok {
+		cr.handleApprovalCommand(ctx, msg, inst, decision, id, reason)
+		return
+	}
+	if cmd, args, ok := parseChatCommand(msg.Text); ok {
+		cr.handleChatCommand(ctx, msg, inst, cmd, args)
 		return
 	}
 
+	if !channelActivates(spec, msg) {
+		cr.Log.V(1).Info("Ignoring channel message", "channel", msg.Channel, "instance", msg.InstanceName,
+			"chat", msg.ChatID, "reason", "activation "+string(spec.Activation))
+		return
+	}
+
+	cr.Log.Info("Received channel message",
+		"channel", msg.Channel,
+		"instance", msg.InstanceName,
+		"sender", msg.SenderName,
+		"text", truncateForLog(msg.Text, 80),
+	)
+
 	// Resolve model configuration from the SympoziumInstance (same logic as TUI).
-	provider := resolveProvider(inst)
+	provider := inst.Provider()
 	authSecret := ""
 	if len(inst.Spec.AuthRefs) > 0 {
 		authSecret = inst.Spec.AuthRefs[0].Secret
 	}
 
+	// Messages in the same chat and thread share a session, so the agent
+	// sees the conversation so far.
+	sessionKey := session.ChannelKey(msg.Channel, msg.ChatID, msg.ThreadID)
+	task := msg.Text
+	var sess *session.Session
+	if cr.Sessions != nil {
+		sess = &session.Session{
+			InstanceName: msg.InstanceName,
+			Namespace:    inst.Namespace,
+			SessionKey:   sessionKey,
+			ChannelType:  msg.Channel,
+			SenderID:     msg.SenderID,
+		}
+		if err := cr.Sessions.GetOrCreateSession(ctx, sess); err != nil {
+			cr.Log.Error(err, "failed to open session, running without history", "session", sessionKey)
+			sess = nil
+		} else if history, err := cr.Sessions.RecentTranscript(ctx, sess.ID, channelHistoryEvents); err != nil {
+			cr.Log.Error(err, "failed to load conversation history", "session", sessionKey)
+		} else {
+			task = conversationTask(history, msg.Text)
+		}
+	}
+
+	attachments, attachmentData := runAttachments(msg)
+
 	// Create an AgentRun for the inbound message.
 	run := &sympoziumv1alpha1.AgentRun{
 		ObjectMeta: metav1.ObjectMeta{
@@ -137,39 +189,77 @@ func (cr *ChannelRouter) handleInbound(ctx context.Context, event *eventbus.Even
 				"sympozium.ai/source-channel": msg.Channel,
 			},
 			Annotations: map[string]string{
-				"sympozium.ai/reply-channel": msg.Channel,
-				"sympozium.ai/reply-chat-id": msg.ChatID,
-				"sympozium.ai/sender-name":   msg.SenderName,
-				"sympozium.ai/sender-id":     msg.SenderID,
+				"sympozium.ai/reply-channel":   msg.Channel,
+				"sympozium.ai/reply-chat-id":   msg.ChatID,
+				"sympozium.ai/reply-thread-id": msg.ThreadID,
+				"sympozium.ai/sender-name":     msg.SenderName,
+				"sympozium.ai/sender-id":       msg.SenderID,
 			},
 		},
 		Spec: sympoziumv1alpha1.AgentRunSpec{
 			InstanceRef: msg.InstanceName,
 			AgentID:     "primary",
-			SessionKey:  fmt.Sprintf("channel-%s-%s-%d", msg.Channel, msg.ChatID, time.Now().UnixNano()),
-			Task:        msg.Text,
+			SessionKey:  sessionKey,
+			Task:        task,
+			Attachments: attachments,
 			Model: sympoziumv1alpha1.ModelSpec{
 				Provider:      provider,
 				Model:         inst.Spec.Agents.Default.Model,
 				BaseURL:       inst.Spec.Agents.Default.BaseURL,
 				AuthSecretRef: authSecret,
+				Fallbacks:     inst.Spec.Agents.Fallbacks,
 			},
 			Skills:  inst.Spec.Skills,
 			Timeout: &metav1.Duration{Duration: 10 * time.Minute},
 		},
 	}
+	for _, a := range attachments {
+		if a.Type == channelpkg.AttachmentAudio {
+			run.Spec.Transcription = inst.Spec.Transcription.DeepCopy()
+			break
+		}
+	}
 
 	if err := cr.Client.Create(ctx, run); err != nil {
 		cr.Log.Error(err, "failed to create AgentRun from channel message",
 			"instance", msg.InstanceName, "channel", msg.Channel)
 		return
 	}
+	// The run's pod waits for this ConfigMap to mount it, so a run whose
+	// attachments could not be stored is removed again.
+	if len(attachmentData) > 0 {
+		if err := cr.createAttachments(ctx, run, attachmentData); err != nil {
+			cr.Log.Error(err, "failed to store channel message attachments", "run", run.Name)
+			if err := cr.Client.Delete(ctx, run); err != nil {
+				cr.Log.Error(err, "failed to delete AgentRun without attachments", "run", run.Name)
+			}
+			cr.reply(ctx, msg, "Sorry, I could not receive your attachments. Please try again.")
+			return
+		}
+	}
 
 	cr.Log.Info("Created AgentRun from channel message",
 		"run", run.Name,
 		"instance", msg.InstanceName,
 		"channel", msg.Channel,
 	)
+	cr.startActivity(ctx, run, msg, spec)
+
+	if sess != nil {
+		content := msg.Text
+		if len(attachments) > 0 {
+			content = strings.TrimSpace(content + "\n" + attachmentNames(attachments))
+		}
+		if err := cr.Sessions.AppendTranscript(ctx, &session.TranscriptEvent{
+			SessionID:    sess.ID,
+			EventType:    session.EventUserMessage,
+			Role:         "user",
+			Content:      content,
+			AgentRunName: run.Name,
+		}); err != nil {
+			cr.Log.Error(err, "failed to record channel message", "session", sessionKey)
+		}
+	}
 }
 
 // agentResult matches the result structure emitted by the agent-runner.
@@ -177,6 +267,11 @@ type agentResult struct {
 	Status   string `json:"status"`
 	Response string `json:"response,omitempty"`
 	Error    string `json:"error,omitempty"`
+	Model    string `json:"model,omitempty"`
+	Metrics  struct {
+		InputTokens  int `json:"inputTokens"`
+		OutputTokens int `json:"outputTokens"`
+	} `json:"metrics"`
 }
 
 // handleCompleted processes a completed AgentRun and routes the response
@@ -188,11 +283,14 @@ func (cr *ChannelRouter) handleCompleted(ctx context.Context, event *eventbus.Ev
 	if agentRunID == "" {
 		return
 	}
+	cr.stopActivity(ctx, agentRunID)
 
-	// Find the AgentRun to check if it originated from a channel.
+	// Find the AgentRun to check if it originated from a channel, or from
+	// a trigger that delivers its results to one.
+	sources, _ := labels.NewRequirement("sympozium.ai/source", selection.In, []string{"channel", "trigger"})
 	var runs sympoziumv1alpha1.AgentRunList
-	if err := cr.Client.List(ctx, &runs, client.MatchingLabels{
-		"sympozium.ai/source": "channel",
+	if err := cr.Client.List(ctx, &runs, client.MatchingLabelsSelector{
+		Selector: labels.NewSelector().Add(*sources),
 	}); err != nil {
 		cr.Log.Error(err, "failed to list channel-sourced AgentRuns")
 		return
@@ -222,6 +320,7 @@ func (cr *ChannelRouter) handleCompleted(ctx context.Context, event *eventbus.Ev
 
 	replyChannel := run.Annotations["sympozium.ai/reply-channel"]
 	replyChatID := run.Annotations["sympozium.ai/reply-chat-id"]
+	replyThreadID := run.Annotations["sympozium.ai/reply-thread-id"]
 
 	if replyChannel == "" {
 		return
@@ -244,9 +343,11 @@ func (cr *ChannelRouter) handleCompleted(ctx context.Context, event *eventbus.Ev
 
 	// Publish outbound message to the channel.
 	outMsg := channelpkg.OutboundMessage{
-		Channel: replyChannel,
-		ChatID:  replyChatID,
-		Text:    responseText,
+		Channel:  replyChannel,
+		ChatID:   replyChatID,
+		ThreadID: replyThreadID,
+		Text:     responseText,
+		Format:   channelpkg.FormatMarkdown,
 	}
 
 	outEvent, err := eventbus.NewEvent(eventbus.TopicChannelMessageSend, map[string]string{
@@ -271,6 +372,77 @@ func (cr *ChannelRouter) handleCompleted(ctx context.Context, event *eventbus.Ev
 	)
 }
 
+// parseApprovalCommand parses "/approve <id>" and "/deny <id> [reason]".
+func parseApprovalCommand(text string) (decision, id, reason string, ok bool) {
+	fields := strings.Fields(strings.TrimSpace(text))
+	if len(fields) < 2 {
+		return "", "", "", false
+	}
+	switch strings.ToLower(fields[0]) {
+	case "/approve":
+		decision = sympoziumv1alpha1.ApprovalApproved
+	case "/deny":
+		decision = sympoziumv1alpha1.ApprovalDenied
+	default:
+		return "", "", "", false
+	}
+	return decision, fields[1], strings.Join(fields[2:], " "), true
+}
+
+// handleApprovalCommand records an approval decision sent from a chat. Only
+// the chat the run was started from may decide on its tool calls.
+func (cr *ChannelRouter) handleApprovalCommand(ctx context.Context, msg channelpkg.InboundMessage, inst *sympoziumv1alpha1.SympoziumInstance, decision, id, reason string) {
+	// Otherwise whoever asked for a gated tool call could approve it.
+	if spec := channelSpecFor(inst, msg.Channel); spec == nil || !spec.Admin(msg.SenderID) {
+		cr.Log.Info("Refused approval decision from non-admin", "channel", msg.Channel, "sender", msg.SenderID, "id", id)
+		cr.reply(ctx, msg, "Only admins of this channel can approve or deny tool calls.")
+		return
+	}
+
+	var runs sympoziumv1alpha1.AgentRunList
+	if err := cr.Client.List(ctx, &runs, client.InNamespace(inst.Namespace), client.MatchingLabels{
+		"sympozium.ai/instance": msg.InstanceName,
+		"sympozium.ai/source":   "channel",
+	}); err != nil {
+		cr.Log.Error(err, "failed to list channel-sourced AgentRuns")
+		return
+	}
+
+	approver := chatSender(msg)
+	reply := fmt.Sprintf("No pending approval %q in this chat.", id)
+	for i := range runs.Items {
+		run := &runs.Items[i]
+		if run.Annotations["sympozium.ai/reply-channel"] != msg.Channel ||
+			run.Annotations["sympozium.ai/reply-chat-id"] != msg.ChatID {
+			continue
+		}
+		decided := false
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			if err := cr.Client.Get(ctx, client.ObjectKeyFromObject(run), run); err != nil {
+				return err
+			}
+			decided = run.Status.DecideApproval(id, decision, approver, reason, metav1.Now())
+			if !decided {
+				return nil
+			}
+			return cr.Client.Status().Update(ctx, run)
+		})
+		if err != nil {
+			cr.Log.Error(err, "failed to record approval decision", "run", run.Name, "id", id)
+			reply = fmt.Sprintf("Could not record the decision for %q, please try again.", id)
+			break
+		}
+		if decided {
+			cr.Log.Info("Recorded approval decision from channel",
+				"run", run.Name, "id", id, "decision", decision, "approver", approver)
+			reply = fmt.Sprintf("Tool call %s %s.", id, decision)
+			break
+		}
+	}
+
+	cr.reply(ctx, msg, reply)
+}
+
 func truncateForLog(s string, n int) string {
 	if len(s) <= n {
 		return s
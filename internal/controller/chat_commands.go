@@ -0,0 +1,371 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sympoziumv1alpha1 "github.com/alexsjones/sympozium/api/v1alpha1"
+	channelpkg "github.com/alexsjones/sympozium/internal/channel"
+	"github.com/alexsjones/sympozium/internal/eventbus"
+	"github.com/alexsjones/sympozium/internal/session"
+)
+
+// Chat commands the channel router handles itself instead of starting a run.
+const (
+	chatCmdHelp   = "/help"
+	chatCmdStatus = "/status"
+	chatCmdNew    = "/new"
+	chatCmdReset  = "/reset"
+	chatCmdCancel = "/cancel"
+	chatCmdModel  = "/model"
+)
+
+const chatHelp = "Commands:\n" +
+	"/status — instance status and active runs\n" +
+	"/new — start a new conversation\n" +
+	"/reset — clear the agent's memory and start a new conversation (admins)\n" +
+	"/cancel — stop your running requests in this chat\n" +
+	"/model [name] — show the model, or switch it (admins)\n" +
+	"/approve <id>, /deny <id> [reason] — decide on a tool call (admins)"
+
+// parseChatCommand recognises a chat command. Telegram-style "@bot"
+// suffixes are ignored; other text, including unknown commands, is left for
+// the agent.
+func parseChatCommand(text string) (cmd string, args []string, ok bool) {
+	fields := strings.Fields(strings.TrimSpace(text))
+	if len(fields) == 0 {
+		return "", nil, false
+	}
+	cmd, _, _ = strings.Cut(strings.ToLower(fields[0]), "@")
+	switch cmd {
+	case chatCmdHelp, chatCmdStatus, chatCmdNew, chatCmdReset, chatCmdCancel, chatCmdModel:
+		return cmd, fields[1:], true
+	}
+	return "", nil, false
+}
+
+// handleChatCommand runs a chat command and replies in the chat.
+func (cr *ChannelRouter) handleChatCommand(ctx context.Context, msg channelpkg.InboundMessage, inst *sympoziumv1alpha1.SympoziumInstance, cmd string, args []string) {
+	cr.Log.Info("Received chat command", "command", cmd, "channel", msg.Channel, "instance", msg.InstanceName, "sender", msg.SenderID)
+
+	// /reset and switching the model change the instance for every
+	// channel, schedule and trigger, so only the channel's admins may.
+	if cmd == chatCmdReset || (cmd == chatCmdModel && len(args) > 0) {
+		if spec := channelSpecFor(inst, msg.Channel); spec == nil || !spec.Admin(msg.SenderID) {
+			cr.Log.Info("Refused chat command from non-admin", "command", cmd, "channel", msg.Channel, "sender", msg.SenderID)
+			cr.reply(ctx, msg, "Only admins of this channel can use "+cmd+".")
+			return
+		}
+	}
+
+	var reply string
+	switch cmd {
+	case chatCmdHelp:
+		reply = chatHelp
+	case chatCmdStatus:
+		reply = cr.chatStatus(ctx, msg, inst)
+	case chatCmdNew:
+		reply = cr.chatNewConversation(ctx, msg, inst)
+	case chatCmdReset:
+		reply = cr.chatResetMemory(ctx, msg, inst) + "\n" + cr.chatNewConversation(ctx, msg, inst)
+	case chatCmdCancel:
+		reply = cr.chatCancel(ctx, msg)
+	case chatCmdModel:
+		reply = cr.chatModel(ctx, inst, args)
+	}
+	cr.reply(ctx, msg, reply)
+}
+
+// chatStatus describes the instance and its active runs.
+func (cr *ChannelRouter) chatStatus(ctx context.Context, msg channelpkg.InboundMessage, inst *sympoziumv1alpha1.SympoziumInstance) string {
+	phase := inst.Status.Phase
+	if phase == "" {
+		phase = "Pending"
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Instance %s: %s\n", inst.Name, phase)
+	fmt.Fprintf(&sb, "Model: %s/%s\n", inst.Provider(), inst.Spec.Agents.Default.Model)
+
+	runs, err := cr.activeRuns(ctx, inst.Name)
+	if err != nil {
+		cr.Log.Error(err, "failed to list active runs", "instance", inst.Name)
+		sb.WriteString("Active runs: unknown")
+		return sb.String()
+	}
+	fmt.Fprintf(&sb, "Active runs: %d", len(runs))
+	for _, run := range runs {
+		if run.Annotations["sympozium.ai/reply-chat-id"] != msg.ChatID {
+			continue
+		}
+		phase := string(run.Status.Phase)
+		if phase == "" {
+			phase = "Pending"
+		}
+		age := time.Since(run.CreationTimestamp.Time).Round(time.Second)
+		fmt.Fprintf(&sb, "\n- %s %s %s: %s", run.Name, phase, age, truncateForLog(chatMessageOf(&run), 60))
+	}
+	return sb.String()
+}
+
+// chatMessageOf returns the message a channel run was started for, without
+// the replayed conversation.
+func chatMessageOf(run *sympoziumv1alpha1.AgentRun) string {
+	if _, msg, ok := strings.Cut(run.Spec.Task, "## New message\n\n"); ok {
+		return msg
+	}
+	return run.Spec.Task
+}
+
+// activeRuns returns the instance's runs that have not finished.
+func (cr *ChannelRouter) activeRuns(ctx context.Context, instanceName string) ([]sympoziumv1alpha1.AgentRun, error) {
+	var runs sympoziumv1alpha1.AgentRunList
+	if err := cr.Client.List(ctx, &runs, client.MatchingLabels{"sympozium.ai/instance": instanceName}); err != nil {
+		return nil, err
+	}
+	var active []sympoziumv1alpha1.AgentRun
+	for _, run := range runs.Items {
+		switch run.Status.Phase {
+		case sympoziumv1alpha1.AgentRunPhaseSucceeded, sympoziumv1alpha1.AgentRunPhaseFailed:
+			continue
+		}
+		active = append(active, run)
+	}
+	return active, nil
+}
+
+// chatNewConversation marks the start of a new conversation in the chat's
+// session, so earlier messages are no longer replayed.
+func (cr *ChannelRouter) chatNewConversation(ctx context.Context, msg channelpkg.InboundMessage, inst *sympoziumv1alpha1.SympoziumInstance) string {
+	if cr.Sessions == nil {
+		return "Conversation history is not enabled; every message starts a new conversation."
+	}
+	sess := &session.Session{
+		InstanceName: inst.Name,
+		Namespace:    inst.Namespace,
+		SessionKey:   session.ChannelKey(msg.Channel, msg.ChatID, msg.ThreadID),
+		ChannelType:  msg.Channel,
+		SenderID:     msg.SenderID,
+	}
+	if err := cr.Sessions.GetOrCreateSession(ctx, sess); err != nil {
+		cr.Log.Error(err, "failed to open session", "session", sess.SessionKey)
+		return "Could not start a new conversation, please try again."
+	}
+	if err := cr.Sessions.AppendTranscript(ctx, &session.TranscriptEvent{
+		SessionID: sess.ID,
+		EventType: session.EventReset,
+		Role:      "system",
+		Content:   "new conversation started by " + chatSender(msg),
+	}); err != nil {
+		cr.Log.Error(err, "failed to reset conversation", "session", sess.SessionKey)
+		return "Could not start a new conversation, please try again."
+	}
+	return "Started a new conversation."
+}
+
+// chatResetMemory replaces the instance's memory with an empty one. The old
+// memory stays in the revision history.
+func (cr *ChannelRouter) chatResetMemory(ctx context.Context, msg channelpkg.InboundMessage, inst *sympoziumv1alpha1.SympoziumInstance) string {
+	if inst.Spec.Memory == nil || !inst.Spec.Memory.Enabled {
+		return "Memory is not enabled for this instance."
+	}
+	store := memoryStore{client: cr.Client, namespace: inst.Namespace, instance: inst.Name}
+	var rev int64
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := store.current(ctx)
+		if err != nil {
+			return err
+		}
+		rev, err = store.write(ctx, cm, initialMemory, "reset:"+chatSender(msg))
+		return err
+	})
+	if err != nil && rev == 0 {
+		cr.Log.Error(err, "failed to reset memory", "instance", inst.Name)
+		return "Could not clear memory, please try again."
+	}
+	cr.Log.Info("Memory reset from chat", "instance", inst.Name, "revision", rev, "by", chatSender(msg))
+	if rev <= 1 {
+		return "Cleared memory."
+	}
+	return fmt.Sprintf("Cleared memory. The previous memory can be restored with /memory %s rollback %d in the TUI.", inst.Name, rev-1)
+}
+
+// chatCancel cancels the sender's unfinished runs in the chat.
+func (cr *ChannelRouter) chatCancel(ctx context.Context, msg channelpkg.InboundMessage) string {
+	runs, err := cr.activeRuns(ctx, msg.InstanceName)
+	if err != nil {
+		cr.Log.Error(err, "failed to list active runs", "instance", msg.InstanceName)
+		return "Could not look up your runs, please try again."
+	}
+	var cancelled []string
+	for i := range runs {
+		run := &runs[i]
+		if run.Labels["sympozium.ai/source"] != "channel" ||
+			run.Annotations["sympozium.ai/reply-chat-id"] != msg.ChatID ||
+			run.Annotations["sympozium.ai/sender-id"] != msg.SenderID {
+			continue
+		}
+		if _, ok := run.Annotations[cancelAnnotation]; ok {
+			continue
+		}
+		patch := client.MergeFrom(run.DeepCopy())
+		run.Annotations[cancelAnnotation] = chatSender(msg)
+		if err := cr.Client.Patch(ctx, run, patch); err != nil {
+			cr.Log.Error(err, "failed to cancel run", "run", run.Name)
+			continue
+		}
+		cancelled = append(cancelled, run.Name)
+	}
+	if len(cancelled) == 0 {
+		return "You have no running requests in this chat."
+	}
+	cr.Log.Info("Cancelled runs from chat", "runs", cancelled, "by", chatSender(msg))
+	return "Cancelled " + strings.Join(cancelled, ", ") + "."
+}
+
+// chatModel shows the instance's model, or switches it.
+func (cr *ChannelRouter) chatModel(ctx context.Context, inst *sympoziumv1alpha1.SympoziumInstance, args []string) string {
+	current := inst.Spec.Agents.Default.Model
+	if len(args) == 0 {
+		return fmt.Sprintf("Model: %s/%s", inst.Provider(), current)
+	}
+	if len(args) > 1 {
+		return "Usage: /model [name]"
+	}
+	model := args[0]
+	if model == current {
+		return fmt.Sprintf("Already using %s.", model)
+	}
+	provider := inst.Provider()
+	if err := cr.checkModel(ctx, inst, provider, model); err != nil {
+		cr.Log.Info("Refused model switch from chat", "instance", inst.Name, "model", model, "reason", err.Error())
+		return fmt.Sprintf("Could not switch to %s: %v", model, err)
+	}
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := cr.Client.Get(ctx, client.ObjectKeyFromObject(inst), inst); err != nil {
+			return err
+		}
+		inst.Spec.Agents.Default.Model = model
+		return cr.Client.Update(ctx, inst)
+	})
+	if err != nil {
+		cr.Log.Error(err, "failed to switch model", "instance", inst.Name, "model", model)
+		return fmt.Sprintf("Could not switch the model: %v", err)
+	}
+	cr.Log.Info("Switched model from chat", "instance", inst.Name, "from", current, "to", model)
+	return fmt.Sprintf("Switched %s from %s to %s. New messages will use it.", inst.Name, current, model)
+}
+
+// modelCheckClient asks providers whether they serve a model.
+var modelCheckClient = &http.Client{Timeout: 10 * time.Second}
+
+// checkModel asks the instance's provider, with the instance's API key,
+// whether it serves model. Providers whose models cannot be looked up are
+// refused.
+func (cr *ChannelRouter) checkModel(ctx context.Context, inst *sympoziumv1alpha1.SympoziumInstance, provider, model string) error {
+	baseURL := strings.TrimRight(inst.Spec.Agents.Default.BaseURL, "/")
+	switch {
+	case baseURL != "":
+	case provider == "openai":
+		baseURL = "https://api.openai.com/v1"
+	case provider == "anthropic":
+		baseURL = "https://api.anthropic.com/v1"
+	case provider == "ollama":
+		baseURL = "http://ollama.default.svc:11434/v1"
+	default:
+		return fmt.Errorf("models of %s cannot be looked up; change spec.agents.default.model instead", provider)
+	}
+	if provider == "anthropic" && !strings.HasSuffix(baseURL, "/v1") {
+		baseURL += "/v1"
+	}
+
+	apiKey := ""
+	if len(inst.Spec.AuthRefs) > 0 {
+		var secret corev1.Secret
+		key := client.ObjectKey{Namespace: inst.Namespace, Name: inst.Spec.AuthRefs[0].Secret}
+		if err := cr.Client.Get(ctx, key, &secret); err != nil {
+			return fmt.Errorf("reading API key: %w", err)
+		}
+		for _, k := range []string{"API_KEY", "OPENAI_API_KEY", "ANTHROPIC_API_KEY", "AZURE_OPENAI_API_KEY"} {
+			if v := secret.Data[k]; len(v) > 0 {
+				apiKey = string(v)
+				break
+			}
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/models/"+url.PathEscape(model), nil)
+	if err != nil {
+		return err
+	}
+	if provider == "anthropic" {
+		req.Header.Set("x-api-key", apiKey)
+		req.Header.Set("anthropic-version", "2023-06-01")
+	} else if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	resp, err := modelCheckClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("looking up the model: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return nil
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%s does not serve this model", provider)
+	default:
+		return fmt.Errorf("looking up the model: %s returned %s", provider, resp.Status)
+	}
+}
+
+// chatSender identifies the sender of a chat message.
+func chatSender(msg channelpkg.InboundMessage) string {
+	sender := msg.Channel + ":" + msg.SenderID
+	if msg.SenderName != "" {
+		sender += " (" + msg.SenderName + ")"
+	}
+	return sender
+}
+
+// findInstance looks up a SympoziumInstance by name in any namespace. It
+// returns nil if there is none.
+func (cr *ChannelRouter) findInstance(ctx context.Context, name string) (*sympoziumv1alpha1.SympoziumInstance, error) {
+	var instances sympoziumv1alpha1.SympoziumInstanceList
+	if err := cr.Client.List(ctx, &instances); err != nil {
+		return nil, err
+	}
+	for i := range instances.Items {
+		if instances.Items[i].Name == name {
+			return &instances.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// reply sends text to the chat msg came from.
+func (cr *ChannelRouter) reply(ctx context.Context, msg channelpkg.InboundMessage, text string) {
+	outEvent, err := eventbus.NewEvent(eventbus.TopicChannelMessageSend, map[string]string{
+		"instanceName": msg.InstanceName,
+		"channel":      msg.Channel,
+	}, channelpkg.OutboundMessage{
+		Channel:  msg.Channel,
+		ChatID:   msg.ChatID,
+		ThreadID: msg.ThreadID,
+		Text:     text,
+		Format:   channelpkg.FormatPlain,
+	})
+	if err != nil {
+		return
+	}
+	if err := cr.EventBus.Publish(ctx, eventbus.TopicChannelMessageSend, outEvent); err != nil {
+		cr.Log.Error(err, "failed to publish chat reply", "channel", msg.Channel)
+	}
+}
@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sympoziumv1alpha1 "github.com/alexsjones/sympozium/api/v1alpha1"
+	"github.com/alexsjones/sympozium/internal/eventbus"
+	"github.com/alexsjones/sympozium/internal/ipc"
+)
+
+// FollowUpRouter delivers messages sent to a running agent. The TUI and API
+// server append them to AgentRun.status.followUps; the router publishes each
+// one on agent.followup.<run> once the pod's IPC bridge is up to receive it.
+type FollowUpRouter struct {
+	Client   client.Client
+	EventBus eventbus.EventBus
+	Log      logr.Logger
+}
+
+// Reconcile delivers pending follow-ups on a running AgentRun and records
+// when each was delivered.
+func (fr *FollowUpRouter) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	run := &sympoziumv1alpha1.AgentRun{}
+	if err := fr.Client.Get(ctx, req.NamespacedName, run); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	pending := pendingFollowUps(run)
+	if len(pending) == 0 {
+		return ctrl.Result{}, nil
+	}
+	switch run.Status.Phase {
+	case sympoziumv1alpha1.AgentRunPhaseSucceeded, sympoziumv1alpha1.AgentRunPhaseFailed:
+		return ctrl.Result{}, nil
+	}
+	// The bus only delivers to current subscribers, so wait until the
+	// bridge container is running.
+	if !fr.bridgeRunning(ctx, run) {
+		return ctrl.Result{RequeueAfter: 2 * time.Second}, nil
+	}
+
+	topic := eventbus.TopicAgentFollowUp + "." + run.Name
+	for _, m := range pending {
+		event, err := eventbus.NewEvent(topic, map[string]string{
+			"agentRunID":   run.Name,
+			"instanceName": run.Spec.InstanceRef,
+		}, ipc.FollowUp{ID: m.ID, Text: m.Text, Sender: m.Sender})
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := fr.EventBus.Publish(ctx, topic, event); err != nil {
+			return ctrl.Result{}, fmt.Errorf("publishing follow-up: %w", err)
+		}
+		fr.Log.Info("Delivered follow-up", "run", run.Name, "id", m.ID, "sender", m.Sender)
+	}
+
+	// The agent ignores IDs it has already seen, so a retry after a
+	// conflict that publishes again is harmless.
+	now := metav1.Now()
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := fr.Client.Get(ctx, req.NamespacedName, run); err != nil {
+			return err
+		}
+		delivered := make(map[string]bool, len(pending))
+		for _, m := range pending {
+			delivered[m.ID] = true
+		}
+		for i := range run.Status.FollowUps {
+			if m := &run.Status.FollowUps[i]; delivered[m.ID] && m.DeliveredAt == nil {
+				m.DeliveredAt = &now
+			}
+		}
+		return fr.Client.Status().Update(ctx, run)
+	})
+	return ctrl.Result{}, err
+}
+
+// bridgeRunning reports whether the run's ipc-bridge container is running.
+func (fr *FollowUpRouter) bridgeRunning(ctx context.Context, run *sympoziumv1alpha1.AgentRun) bool {
+	if run.Status.PodName == "" {
+		return false
+	}
+	pod := &corev1.Pod{}
+	if err := fr.Client.Get(ctx, client.ObjectKey{Namespace: run.Namespace, Name: run.Status.PodName}, pod); err != nil {
+		return false
+	}
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.Name == "ipc-bridge" {
+			return cs.State.Running != nil
+		}
+	}
+	return false
+}
+
+// SetupWithManager registers the router's AgentRun reconciler.
+func (fr *FollowUpRouter) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("agentrun-followups").
+		For(&sympoziumv1alpha1.AgentRun{}).
+		Complete(fr)
+}
+
+// pendingFollowUps returns the run's follow-ups not yet delivered.
+func pendingFollowUps(run *sympoziumv1alpha1.AgentRun) []sympoziumv1alpha1.FollowUpMessage {
+	var out []sympoziumv1alpha1.FollowUpMessage
+	for _, m := range run.Status.FollowUps {
+		if m.DeliveredAt == nil {
+			out = append(out, m)
+		}
+	}
+	return out
+}
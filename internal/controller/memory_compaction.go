@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sympoziumv1alpha1 "github.com/alexsjones/sympozium/api/v1alpha1"
+)
+
+// memoryTruncatedCondition is set on a SympoziumInstance whose memory had to
+// be truncated to fit MemorySpec.MaxSizeKB.
+const memoryTruncatedCondition = "MemoryTruncated"
+
+// defaultMemoryMaxSizeKB applies when MemorySpec.MaxSizeKB is unset.
+const defaultMemoryMaxSizeKB = 256
+
+const memoryCompactionPrompt = "You compact the persistent memory file of an AI agent. " +
+	"Rewrite the MEMORY.md you are given so it fits the size limit: merge duplicates, " +
+	"drop stale or superseded details and summarise verbose sections, keeping the facts, " +
+	"preferences and decisions the agent will need in future runs. " +
+	"Reply with the compacted markdown only, without commentary or code fences."
+
+// memoryLimit returns the instance's memory limit in bytes.
+func memoryLimit(inst *sympoziumv1alpha1.SympoziumInstance) int {
+	kb := defaultMemoryMaxSizeKB
+	if inst.Spec.Memory != nil && inst.Spec.Memory.MaxSizeKB > 0 {
+		kb = inst.Spec.Memory.MaxSizeKB
+	}
+	return kb * 1024
+}
+
+// memoryCompactionTask builds the task of a compaction run. It aims below
+// the limit to leave room for the model misjudging the length of its reply.
+func memoryCompactionTask(content string, limit int) string {
+	return fmt.Sprintf("Compact the following MEMORY.md to under %d KB.\n\n", limit*3/4/1024) +
+		memorySection("MEMORY", content)
+}
+
+// truncateMemoryContent cuts content to at most limit bytes at a line
+// boundary and notes that it was truncated.
+func truncateMemoryContent(content string, limit int) string {
+	notice := fmt.Sprintf("\n\n<!-- memory truncated to the %d KB limit -->\n", limit/1024)
+	if len(content) <= limit {
+		return content
+	}
+	keep := max(limit-len(notice), 0)
+	cut := content[:keep]
+	if i := strings.LastIndex(cut, "\n"); i > 0 {
+		cut = cut[:i]
+	} else {
+		for len(cut) > 0 && !utf8.RuneStart(content[len(cut)]) {
+			cut = cut[:len(cut)-1]
+		}
+	}
+	return strings.TrimRight(cut, " \t\n") + notice
+}
+
+// setMemoryTruncated records the MemoryTruncated condition on the instance.
+// A False condition is only written to clear an earlier True one.
+func (m memoryUpdater) setMemoryTruncated(ctx context.Context, log logr.Logger, inst *sympoziumv1alpha1.SympoziumInstance, status metav1.ConditionStatus, reason, msg string) {
+	key := client.ObjectKeyFromObject(inst)
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := m.Get(ctx, key, inst); err != nil {
+			return err
+		}
+		if status == metav1.ConditionFalse && !meta.IsStatusConditionTrue(inst.Status.Conditions, memoryTruncatedCondition) {
+			return nil
+		}
+		meta.SetStatusCondition(&inst.Status.Conditions, metav1.Condition{
+			Type:               memoryTruncatedCondition,
+			Status:             status,
+			ObservedGeneration: inst.Generation,
+			Reason:             reason,
+			Message:            msg,
+		})
+		return m.Status().Update(ctx, inst)
+	})
+	if err != nil {
+		log.Error(err, "Failed to record memory condition", "instance", inst.Name)
+	}
+}
@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"slices"
+	"strings"
+)
+
+const memoryMergePrompt = "You merge concurrent edits to the persistent memory file of an AI agent. " +
+	"Two runs updated MEMORY.md at the same time and their changes conflict. " +
+	"Combine them into one MEMORY.md that keeps the information from both, resolving " +
+	"contradictions in favour of the newer update. " +
+	"Reply with the merged markdown only, without commentary or code fences."
+
+// memoryMergeTask builds the task of a merge run. base is the revision both
+// edits started from, when it is still in the history.
+func memoryMergeTask(base string, haveBase bool, current, incoming string) string {
+	var sb strings.Builder
+	sb.WriteString("Merge these two versions of MEMORY.md.\n\n")
+	if haveBase {
+		sb.WriteString("The version both were edited from:\n")
+		sb.WriteString(memorySection("BASE", base))
+		sb.WriteString("\n")
+	}
+	sb.WriteString("The current version:\n")
+	sb.WriteString(memorySection("CURRENT", current))
+	sb.WriteString("\nThe newer update:\n")
+	sb.WriteString(memorySection("INCOMING", incoming))
+	return sb.String()
+}
+
+// maxMergeCells bounds the line-matching table of a three-way merge. Edits
+// spread over larger regions than this are treated as conflicting.
+const maxMergeCells = 4_000_000
+
+// mergeMemory3 merges the concurrent edits ours and theirs made to base, line
+// by line. Where both changed the same lines differently, theirs is kept and
+// clean is false.
+func mergeMemory3(base, ours, theirs string) (merged string, clean bool) {
+	b, o, t := splitLines(base), splitLines(ours), splitLines(theirs)
+	mo, mt := matchLines(b, o), matchLines(b, t)
+
+	var out []string
+	clean = true
+	i, a, c := 0, 0, 0
+	for {
+		// Find the next base line kept by both sides.
+		k := i
+		for k < len(b) && (mo[k] < 0 || mt[k] < 0) {
+			k++
+		}
+		endO, endT := len(o), len(t)
+		if k < len(b) {
+			endO, endT = mo[k], mt[k]
+		}
+
+		bc, oc, tc := b[i:k], o[a:endO], t[c:endT]
+		switch {
+		case slices.Equal(oc, bc):
+			out = append(out, tc...)
+		case slices.Equal(tc, bc), slices.Equal(oc, tc):
+			out = append(out, oc...)
+		default:
+			out = append(out, tc...)
+			clean = false
+		}
+
+		if k == len(b) {
+			break
+		}
+		out = append(out, b[k])
+		i, a, c = k+1, endO+1, endT+1
+	}
+	return strings.Join(out, ""), clean
+}
+
+// splitLines splits s into lines, keeping their line endings.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// matchLines returns, for each line of a, the index of the line of b it is
+// matched to in a longest common subsequence, or -1.
+func matchLines(a, b []string) []int {
+	m := make([]int, len(a))
+	for i := range m {
+		m[i] = -1
+	}
+
+	// Lines shared at the start and end need no table.
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		m[pre] = pre
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		m[len(a)-1-suf] = len(b) - 1 - suf
+		suf++
+	}
+	ma, mb := a[pre:len(a)-suf], b[pre:len(b)-suf]
+	n, w := len(ma), len(mb)
+	if n == 0 || w == 0 || (n+1)*(w+1) > maxMergeCells {
+		return m
+	}
+
+	// lcs[i][j] is the LCS length of ma[i:] and mb[j:].
+	lcs := make([]int32, (n+1)*(w+1))
+	at := func(i, j int) int32 { return lcs[i*(w+1)+j] }
+	for i := n - 1; i >= 0; i-- {
+		for j := w - 1; j >= 0; j-- {
+			if ma[i] == mb[j] {
+				lcs[i*(w+1)+j] = at(i+1, j+1) + 1
+			} else {
+				lcs[i*(w+1)+j] = max(at(i+1, j), at(i, j+1))
+			}
+		}
+	}
+	for i, j := 0, 0; i < n && j < w; {
+		switch {
+		case ma[i] == mb[j]:
+			m[pre+i] = pre + j
+			i++
+			j++
+		case at(i+1, j) >= at(i, j+1):
+			i++
+		default:
+			j++
+		}
+	}
+	return m
+}
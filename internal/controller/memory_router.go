@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/alexsjones/sympozium/internal/eventbus"
+	"github.com/alexsjones/sympozium/internal/ipc"
+)
+
+// MemoryRouter commits the memory updates agents make with their memory
+// tools. The IPC bridge publishes the updated MEMORY.md on
+// agent.memory.update when a run ends; the router merges it into the
+// instance's memory ConfigMap.
+type MemoryRouter struct {
+	Client   client.Client
+	EventBus eventbus.EventBus
+	Log      logr.Logger
+}
+
+// Start begins listening for memory updates. It blocks until ctx is cancelled.
+func (mr *MemoryRouter) Start(ctx context.Context) error {
+	mr.Log.Info("Starting memory router")
+
+	// A durable consumer keeps updates published while the controller is
+	// down or restarting; the run has usually ended by then.
+	err := mr.EventBus.Consume(ctx, eventbus.TopicAgentMemoryUpdate, "memory-router", func(event *eventbus.Event) error {
+		return mr.handleMemoryUpdate(ctx, event)
+	})
+	if err != nil {
+		return fmt.Errorf("consuming %s: %w", eventbus.TopicAgentMemoryUpdate, err)
+	}
+	mr.Log.Info("Memory router shutting down")
+	return nil
+}
+
+// handleMemoryUpdate commits one run's updated memory. It returns an error
+// only when the update should be redelivered.
+func (mr *MemoryRouter) handleMemoryUpdate(ctx context.Context, event *eventbus.Event) error {
+	runName := event.Metadata["agentRunID"]
+	log := mr.Log.WithValues("agentRun", runName)
+
+	var upd ipc.MemoryUpdate
+	if err := json.Unmarshal(event.Data, &upd); err != nil {
+		log.Error(err, "failed to unmarshal memory update")
+		return nil
+	}
+	run, err := findAgentRun(ctx, mr.Client, event.Metadata["instanceName"], runName)
+	if err != nil {
+		log.Error(err, "dropping memory update")
+		return nil
+	}
+	if memoryTask(run) != "" {
+		return nil
+	}
+	return memoryUpdater{mr.Client}.persistMemory(ctx, log, run, upd.Content, nil)
+}
@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Memory revisions. Every write to an instance's memory ConfigMap bumps the
+// revision annotation and saves the content to a history ConfigMap, so runs
+// can record the revision they started from and a bad update can be rolled
+// back.
+const (
+	// memoryRevisionAnnotation is the revision of the memory ConfigMap and
+	// of each history entry, and the revision an AgentRun started from.
+	memoryRevisionAnnotation = "sympozium.ai/memory-revision"
+	// memorySourceAnnotation names what wrote a history entry: an AgentRun,
+	// or "rollback:<revision>".
+	memorySourceAnnotation = "sympozium.ai/memory-source"
+	// memoryRollbackAnnotation on a SympoziumInstance asks the controller to
+	// restore the memory revision it names.
+	memoryRollbackAnnotation = "sympozium.ai/memory-rollback"
+)
+
+// initialMemory is the content of a new or reset memory ConfigMap.
+const initialMemory = "# Agent Memory\n\nNo memories recorded yet.\n"
+
+// memoryHistoryLimit is how many revisions of an instance's memory are kept.
+const memoryHistoryLimit = 10
+
+// memoryStore reads and writes the memory of one instance.
+type memoryStore struct {
+	client    client.Client
+	namespace string
+	instance  string
+}
+
+func (s memoryStore) configMapName() string {
+	return fmt.Sprintf("%s-memory", s.instance)
+}
+
+// revisionOf returns the memory revision recorded on obj, or 0.
+func revisionOf(obj metav1.Object) int64 {
+	rev, _ := strconv.ParseInt(obj.GetAnnotations()[memoryRevisionAnnotation], 10, 64)
+	return rev
+}
+
+// current returns the memory ConfigMap.
+func (s memoryStore) current(ctx context.Context) (*corev1.ConfigMap, error) {
+	cm := &corev1.ConfigMap{}
+	if err := s.client.Get(ctx, client.ObjectKey{Namespace: s.namespace, Name: s.configMapName()}, cm); err != nil {
+		return nil, err
+	}
+	return cm, nil
+}
+
+// revision returns the content of a revision still in the history.
+func (s memoryStore) revision(ctx context.Context, rev int64) (string, bool) {
+	cm := &corev1.ConfigMap{}
+	name := fmt.Sprintf("%s-r%d", s.configMapName(), rev)
+	if err := s.client.Get(ctx, client.ObjectKey{Namespace: s.namespace, Name: name}, cm); err != nil {
+		return "", false
+	}
+	return cm.Data["MEMORY.md"], true
+}
+
+// write replaces the memory in cm and records it as the next revision. It
+// fails with a conflict if cm has changed since it was read.
+func (s memoryStore) write(ctx context.Context, cm *corev1.ConfigMap, content, source string) (int64, error) {
+	rev := revisionOf(cm) + 1
+	if cm.Annotations == nil {
+		cm.Annotations = map[string]string{}
+	}
+	cm.Annotations[memoryRevisionAnnotation] = strconv.FormatInt(rev, 10)
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data["MEMORY.md"] = content
+	if err := s.client.Update(ctx, cm); err != nil {
+		return 0, err
+	}
+
+	// The history is owned by the memory ConfigMap, so it is removed with it.
+	entry := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-r%d", s.configMapName(), rev),
+			Namespace: s.namespace,
+			Labels: map[string]string{
+				"sympozium.ai/instance":  s.instance,
+				"sympozium.ai/component": "memory-history",
+			},
+			Annotations: map[string]string{
+				memoryRevisionAnnotation: strconv.FormatInt(rev, 10),
+				memorySourceAnnotation:   source,
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(cm, corev1.SchemeGroupVersion.WithKind("ConfigMap")),
+			},
+		},
+		Data: map[string]string{"MEMORY.md": content},
+	}
+	if err := s.client.Create(ctx, entry); err != nil && !errors.IsAlreadyExists(err) {
+		return rev, fmt.Errorf("saving memory revision %d: %w", rev, err)
+	}
+	return rev, s.prune(ctx)
+}
+
+// history returns the saved revisions, newest first.
+func (s memoryStore) history(ctx context.Context) ([]corev1.ConfigMap, error) {
+	var list corev1.ConfigMapList
+	if err := s.client.List(ctx, &list,
+		client.InNamespace(s.namespace),
+		client.MatchingLabels{"sympozium.ai/instance": s.instance, "sympozium.ai/component": "memory-history"},
+	); err != nil {
+		return nil, err
+	}
+	sort.Slice(list.Items, func(i, j int) bool {
+		return revisionOf(&list.Items[i]) > revisionOf(&list.Items[j])
+	})
+	return list.Items, nil
+}
+
+// prune deletes revisions beyond memoryHistoryLimit.
+func (s memoryStore) prune(ctx context.Context) error {
+	entries, err := s.history(ctx)
+	if err != nil {
+		return err
+	}
+	for i := memoryHistoryLimit; i < len(entries); i++ {
+		if err := s.client.Delete(ctx, &entries[i]); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sympoziumv1alpha1 "github.com/alexsjones/sympozium/api/v1alpha1"
+)
+
+// memoryTaskLabel marks the AgentRuns the controller starts to maintain an
+// instance's memory. Its value is the kind of task.
+const memoryTaskLabel = "sympozium.ai/memory-task"
+
+const (
+	// memoryTaskCompaction runs shrink memory that exceeds MaxSizeKB.
+	memoryTaskCompaction = "compaction"
+	// memoryTaskMerge runs combine concurrent edits that conflict.
+	memoryTaskMerge = "merge"
+)
+
+// memoryTask returns the kind of memory task run performs, or "".
+func memoryTask(run *sympoziumv1alpha1.AgentRun) string {
+	return run.Labels[memoryTaskLabel]
+}
+
+// memoryUpdater commits the memory updates of agent runs. It is shared by
+// the AgentRun reconciler and the memory router.
+type memoryUpdater struct {
+	client.Client
+}
+
+// memoryTruncation allows persistMemory to truncate oversized memory rather
+// than start a compaction run, and says why.
+type memoryTruncation struct {
+	reason string
+	detail string
+}
+
+// persistMemory commits content written by agentRun to the instance's
+// memory. Edits made since the run read its memory are merged in, and
+// memory over MaxSizeKB is compacted, or truncated when trunc is set. It
+// returns the error that kept the memory from being written, if any.
+func (m memoryUpdater) persistMemory(ctx context.Context, log logr.Logger, agentRun *sympoziumv1alpha1.AgentRun, content string, trunc *memoryTruncation) error {
+	inst := &sympoziumv1alpha1.SympoziumInstance{}
+	if err := m.Get(ctx, client.ObjectKey{Namespace: agentRun.Namespace, Name: agentRun.Spec.InstanceRef}, inst); err != nil {
+		log.V(1).Info("instance not found, skipping memory update", "err", err)
+		return nil
+	}
+	content = strings.TrimSpace(content) + "\n"
+	store := memoryStore{client: m.Client, namespace: agentRun.Namespace, instance: agentRun.Spec.InstanceRef}
+	limit := memoryLimit(inst)
+	base, hasBase := runMemoryRevision(agentRun)
+
+	var truncated *memoryTruncation
+	written := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := store.current(ctx)
+		if err != nil {
+			return err
+		}
+		rev := revisionOf(cm)
+		current := cm.Data["MEMORY.md"]
+
+		merged := content
+		if hasBase && rev != base {
+			baseContent, ok := store.revision(ctx, base)
+			clean := false
+			if ok {
+				merged, clean = mergeMemory3(baseContent, current, content)
+			}
+			if !clean {
+				run, err := m.startMemoryRun(ctx, agentRun, inst, memoryTaskMerge, rev,
+					memoryMergeTask(baseContent, ok, current, content))
+				if err == nil {
+					log.Info("Memory edited concurrently, started merge", "base", base, "current", rev, "run", run.Name)
+					return nil
+				}
+				log.Error(err, "Failed to start memory merge, keeping this run's side of conflicting edits")
+			} else {
+				log.Info("Merged concurrent memory edits", "base", base, "current", rev)
+			}
+		}
+
+		truncated = nil
+		if len(merged) > limit {
+			if trunc == nil {
+				run, err := m.startMemoryRun(ctx, agentRun, inst, memoryTaskCompaction, rev, memoryCompactionTask(merged, limit))
+				if err == nil {
+					log.Info("Memory exceeds limit, started compaction", "bytes", len(merged), "limitKB", limit/1024, "run", run.Name)
+					return nil
+				}
+				trunc = &memoryTruncation{reason: "CompactionFailed", detail: fmt.Sprintf("could not start compaction: %v", err)}
+			}
+			truncated = &memoryTruncation{
+				reason: trunc.reason,
+				detail: fmt.Sprintf("Memory of %d bytes exceeded the %d KB limit and was truncated (%s)", len(merged), limit/1024, trunc.detail),
+			}
+			merged = truncateMemoryContent(merged, limit)
+		}
+
+		newRev, err := store.write(ctx, cm, merged, agentRun.Name)
+		if errors.IsConflict(err) {
+			return err
+		}
+		if newRev > 0 {
+			written = true
+			log.Info("Updated memory ConfigMap", "configmap", store.configMapName(), "revision", newRev, "bytes", len(merged))
+		}
+		return err
+	})
+	if err != nil {
+		log.Error(err, "Failed to persist memory")
+		return err
+	}
+
+	switch {
+	case truncated != nil && written:
+		log.Info("Truncated memory", "reason", truncated.reason)
+		m.setMemoryTruncated(ctx, log, inst, metav1.ConditionTrue, truncated.reason, truncated.detail)
+	case written:
+		m.setMemoryTruncated(ctx, log, inst, metav1.ConditionFalse, "WithinLimit",
+			fmt.Sprintf("Memory is within the %d KB limit", limit/1024))
+	}
+	return nil
+}
+
+// runMemoryRevision returns the memory revision the run started from.
+func runMemoryRevision(run *sympoziumv1alpha1.AgentRun) (int64, bool) {
+	v, ok := run.Annotations[memoryRevisionAnnotation]
+	if !ok {
+		return 0, false
+	}
+	rev, err := strconv.ParseInt(v, 10, 64)
+	return rev, err == nil
+}
+
+// recordMemoryRevision annotates a run with the memory revision it is about
+// to read, so its update can later be merged with concurrent ones.
+func (m memoryUpdater) recordMemoryRevision(ctx context.Context, agentRun *sympoziumv1alpha1.AgentRun) error {
+	if _, ok := runMemoryRevision(agentRun); ok {
+		return nil
+	}
+	store := memoryStore{client: m.Client, namespace: agentRun.Namespace, instance: agentRun.Spec.InstanceRef}
+	cm, err := store.current(ctx)
+	if err != nil {
+		return err
+	}
+	patch := client.MergeFrom(agentRun.DeepCopy())
+	if agentRun.Annotations == nil {
+		agentRun.Annotations = map[string]string{}
+	}
+	agentRun.Annotations[memoryRevisionAnnotation] = strconv.FormatInt(revisionOf(cm), 10)
+	return m.Patch(ctx, agentRun, patch)
+}
+
+// startMemoryRun creates an AgentRun on the instance's model for a memory
+// task. rev is the revision of the memory the task works on.
+func (m memoryUpdater) startMemoryRun(ctx context.Context, agentRun *sympoziumv1alpha1.AgentRun, inst *sympoziumv1alpha1.SympoziumInstance, kind string, rev int64, task string) (*sympoziumv1alpha1.AgentRun, error) {
+	systemPrompt := memoryCompactionPrompt
+	if kind == memoryTaskMerge {
+		systemPrompt = memoryMergePrompt
+	}
+	authSecret := ""
+	if len(inst.Spec.AuthRefs) > 0 {
+		authSecret = inst.Spec.AuthRefs[0].Secret
+	}
+	run := &sympoziumv1alpha1.AgentRun{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: inst.Name + "-memory-",
+			Namespace:    inst.Namespace,
+			Labels: map[string]string{
+				"sympozium.ai/instance": inst.Name,
+				"sympozium.ai/source":   "memory",
+				memoryTaskLabel:         kind,
+			},
+			Annotations: map[string]string{
+				memoryRevisionAnnotation: strconv.FormatInt(rev, 10),
+			},
+		},
+		Spec: sympoziumv1alpha1.AgentRunSpec{
+			InstanceRef:  inst.Name,
+			AgentID:      "primary",
+			SessionKey:   "memory-" + agentRun.Name,
+			Task:         task,
+			SystemPrompt: systemPrompt,
+			Model: sympoziumv1alpha1.ModelSpec{
+				Provider:      inst.Provider(),
+				Model:         inst.Spec.Agents.Default.Model,
+				BaseURL:       inst.Spec.Agents.Default.BaseURL,
+				AuthSecretRef: authSecret,
+				Fallbacks:     inst.Spec.Agents.Fallbacks,
+			},
+			Timeout: &metav1.Duration{Duration: 10 * time.Minute},
+		},
+	}
+	if err := m.Create(ctx, run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// completeMemoryTask persists the result of a succeeded memory task run.
+// Other runs send their memory updates through IPC instead.
+func (m memoryUpdater) completeMemoryTask(ctx context.Context, log logr.Logger, agentRun *sympoziumv1alpha1.AgentRun, result string) {
+	if memoryTask(agentRun) == "" {
+		return
+	}
+	content := strings.TrimSpace(result)
+	if content == "" {
+		m.failMemoryTask(ctx, log, agentRun, "the model returned no memory")
+		return
+	}
+	var trunc *memoryTruncation
+	if memoryTask(agentRun) == memoryTaskCompaction {
+		trunc = &memoryTruncation{reason: "CompactionInsufficient", detail: "compaction did not make it small enough"}
+	}
+	_ = m.persistMemory(ctx, log, agentRun, content, trunc)
+}
+
+// failMemoryTask falls back to what the controller can do without the model
+// when a memory task run fails: memory that could not be compacted is
+// truncated, and conflicting edits keep the side of the later run.
+func (m memoryUpdater) failMemoryTask(ctx context.Context, log logr.Logger, agentRun *sympoziumv1alpha1.AgentRun, reason string) {
+	switch memoryTask(agentRun) {
+	case memoryTaskCompaction:
+		content, ok := taskSection(agentRun.Spec.Task, "MEMORY")
+		if !ok {
+			return
+		}
+		_ = m.persistMemory(ctx, log, agentRun, content, &memoryTruncation{reason: "CompactionFailed", detail: reason})
+	case memoryTaskMerge:
+		current, ok1 := taskSection(agentRun.Spec.Task, "CURRENT")
+		incoming, ok2 := taskSection(agentRun.Spec.Task, "INCOMING")
+		if !ok1 || !ok2 {
+			return
+		}
+		merged := incoming
+		if base, ok := taskSection(agentRun.Spec.Task, "BASE"); ok {
+			merged, _ = mergeMemory3(base, current, incoming)
+		}
+		log.Info("Memory merge failed, keeping the later run's side of conflicting edits", "reason", reason)
+		_ = m.persistMemory(ctx, log, agentRun, merged, nil)
+	}
+}
+
+// memorySection wraps content in delimiter lines, so it can be read back
+// from a memory task with taskSection.
+func memorySection(name, content string) string {
+	return fmt.Sprintf("-----BEGIN %s-----\n%s\n-----END %s-----\n", name, strings.TrimSuffix(content, "\n"), name)
+}
+
+// taskSection returns the named section of a memory task.
+func taskSection(task, name string) (string, bool) {
+	_, rest, ok := strings.Cut(task, "-----BEGIN "+name+"-----\n")
+	if !ok {
+		return "", false
+	}
+	content, _, ok := strings.Cut(rest, "\n-----END "+name+"-----\n")
+	if !ok {
+		return "", false
+	}
+	return content + "\n", true
+}
@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sympoziumv1alpha1 "github.com/alexsjones/sympozium/api/v1alpha1"
+	channelpkg "github.com/alexsjones/sympozium/internal/channel"
+)
+
+// maxPendingPairings bounds the pairing codes an instance has outstanding,
+// so unknown senders cannot grow its status without limit.
+const maxPendingPairings = 20
+
+// pairingCodeAlphabet leaves out characters that are easy to confuse.
+const pairingCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+var errTooManyPairings = errors.New("too many pending pairings")
+
+// newPairingCode returns a random 8 character pairing code.
+func newPairingCode() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	for i := range b {
+		b[i] = pairingCodeAlphabet[int(b[i])%len(pairingCodeAlphabet)]
+	}
+	return string(b)
+}
+
+// livePairings returns the pending pairings that have not expired and whose
+// senders have not been paired since.
+func livePairings(inst *sympoziumv1alpha1.SympoziumInstance, now time.Time) []sympoziumv1alpha1.PairingRequest {
+	var live []sympoziumv1alpha1.PairingRequest
+	for _, p := range inst.Status.PendingPairings {
+		if !now.Before(p.ExpiresAt.Time) {
+			continue
+		}
+		if spec := channelSpecFor(inst, p.Channel); spec != nil && spec.Paired(p.SenderID) {
+			continue
+		}
+		live = append(live, p)
+	}
+	return live
+}
+
+// requestPairing sends an unknown sender a pairing code for the owner to
+// approve. A sender with an unexpired code is not sent another one.
+func (cr *ChannelRouter) requestPairing(ctx context.Context, msg channelpkg.InboundMessage, inst *sympoziumv1alpha1.SympoziumInstance) {
+	var code string
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		code = ""
+		if err := cr.Client.Get(ctx, client.ObjectKeyFromObject(inst), inst); err != nil {
+			return err
+		}
+		now := time.Now()
+		pending := livePairings(inst, now)
+		for _, p := range pending {
+			if p.Channel == msg.Channel && p.SenderID == msg.SenderID {
+				return nil
+			}
+		}
+		if len(pending) >= maxPendingPairings {
+			return errTooManyPairings
+		}
+		code = newPairingCode()
+		inst.Status.PendingPairings = append(pending, sympoziumv1alpha1.PairingRequest{
+			Code:       code,
+			Channel:    msg.Channel,
+			SenderID:   msg.SenderID,
+			SenderName: msg.SenderName,
+			ChatID:     msg.ChatID,
+			ExpiresAt:  metav1.NewTime(now.Add(sympoziumv1alpha1.PairingTTL)),
+		})
+		return cr.Client.Status().Update(ctx, inst)
+	})
+	if err != nil {
+		cr.Log.Error(err, "dropping message from unpaired sender", "instance", inst.Name, "channel", msg.Channel, "sender", msg.SenderID)
+		return
+	}
+	if code == "" {
+		cr.Log.V(1).Info("Dropping message from sender awaiting pairing", "instance", inst.Name, "channel", msg.Channel, "sender", msg.SenderID)
+		return
+	}
+	cr.Log.Info("Sent pairing code", "instance", inst.Name, "channel", msg.Channel, "sender", chatSender(msg))
+	approve := fmt.Sprintf("sympozium pairing approve %s %s", inst.Name, code)
+	if inst.Namespace != "default" {
+		approve += " -n " + inst.Namespace
+	}
+	cr.reply(ctx, msg, fmt.Sprintf("I don't know you yet. Ask my owner to approve pairing code %s within %d minutes:\n\n%s",
+		code, int(sympoziumv1alpha1.PairingTTL.Minutes()), approve))
+}
@@ -0,0 +1,351 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sympoziumv1alpha1 "github.com/alexsjones/sympozium/api/v1alpha1"
+	"github.com/alexsjones/sympozium/internal/eventbus"
+	"github.com/alexsjones/sympozium/internal/ipc"
+	"github.com/alexsjones/sympozium/internal/orchestrator"
+)
+
+// Default sub-agent limits, matching the SubagentsSpec kubebuilder defaults,
+// for instances that do not configure subagents.
+var defaultSubagentLimits = sympoziumv1alpha1.SubagentsSpec{
+	MaxDepth:            2,
+	MaxConcurrent:       5,
+	MaxChildrenPerAgent: 3,
+}
+
+// Spawn result statuses, matching ipc.SpawnResult.Status.
+const (
+	spawnSucceeded = "succeeded"
+	spawnFailed    = "failed"
+	spawnRejected  = "rejected"
+)
+
+// SpawnRouter creates sub-agent AgentRuns for spawn_agent tool calls and
+// delivers their results to the parent run. Requests arrive from the
+// parent's IPC bridge on agent.spawn.request; the router enforces the
+// instance's SubagentsSpec limits, creates the child through
+// orchestrator.Spawner, and publishes the child's result on
+// agent.spawn.result. send_agent_message requests take the same path (see
+// agent_messaging.go).
+type SpawnRouter struct {
+	Client   client.Client
+	EventBus eventbus.EventBus
+	Log      logr.Logger
+
+	spawner orchestrator.Spawner
+
+	// created holds children this router created that may not be in the
+	// informer cache yet, so back-to-back requests cannot exceed the limits.
+	created sync.Map // run name -> *sympoziumv1alpha1.AgentRun
+	// delivered holds "<namespace>/<run>" of children whose result was
+	// published, until the child is deleted.
+	delivered sync.Map
+}
+
+// Start listens for spawn requests. It blocks until ctx is cancelled.
+func (sr *SpawnRouter) Start(ctx context.Context) error {
+	sr.Log.Info("Starting spawn router")
+	sr.spawner = orchestrator.Spawner{Client: sr.Client, Log: sr.Log}
+
+	ch, err := sr.EventBus.Subscribe(ctx, eventbus.TopicAgentSpawnRequest)
+	if err != nil {
+		return fmt.Errorf("subscribing to %s: %w", eventbus.TopicAgentSpawnRequest, err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			sr.Log.Info("Spawn router shutting down")
+			return nil
+		case event := <-ch:
+			if event != nil {
+				sr.handleSpawnRequest(ctx, event)
+			}
+		}
+	}
+}
+
+// handleSpawnRequest validates a spawn request against the instance's
+// limits and creates the sub-agent. Rejections are reported to the parent
+// straight away.
+func (sr *SpawnRouter) handleSpawnRequest(ctx context.Context, event *eventbus.Event) {
+	var req ipc.SpawnRequest
+	if err := json.Unmarshal(event.Data, &req); err != nil || req.ID == "" {
+		sr.Log.Info("Ignoring malformed spawn request")
+		return
+	}
+
+	parent, err := findAgentRun(ctx, sr.Client, event.Metadata["instanceName"], event.Metadata["agentRunID"])
+	if err != nil {
+		sr.Log.Error(err, "failed to find parent AgentRun for spawn request", "run", event.Metadata["agentRunID"])
+		return
+	}
+	reject := func(msg string) {
+		sr.Log.Info("Rejected spawn request", "parent", parent.Name, "id", req.ID, "reason", msg)
+		sr.publishResult(ctx, parent, ipc.SpawnResult{ID: req.ID, Status: spawnRejected, Error: msg})
+	}
+	if req.Task == "" {
+		reject("task is required")
+		return
+	}
+	if req.TargetInstance != "" {
+		if err := sr.sendAgentMessage(ctx, parent, req); err != nil {
+			reject(err.Error())
+		}
+		return
+	}
+
+	instance := &sympoziumv1alpha1.SympoziumInstance{}
+	if err := sr.Client.Get(ctx, client.ObjectKey{Namespace: parent.Namespace, Name: parent.Spec.InstanceRef}, instance); err != nil {
+		reject(fmt.Sprintf("instance %q not found", parent.Spec.InstanceRef))
+		return
+	}
+
+	var runs sympoziumv1alpha1.AgentRunList
+	if err := sr.Client.List(ctx, &runs,
+		client.InNamespace(parent.Namespace),
+		client.MatchingLabels{"sympozium.ai/instance": parent.Spec.InstanceRef},
+	); err != nil {
+		reject(fmt.Sprintf("listing agent runs: %v", err))
+		return
+	}
+	if err := checkSpawnLimits(parent, sr.withCreated(runs.Items), subagentLimits(instance)); err != nil {
+		reject(err.Error())
+		return
+	}
+
+	depth := 0
+	if parent.Spec.Parent != nil {
+		depth = parent.Spec.Parent.SpawnDepth
+	}
+	agentID := req.AgentID
+	if agentID == "" {
+		agentID = parent.Spec.AgentID
+	}
+	result, err := sr.spawner.Spawn(ctx, orchestrator.SpawnRequest{
+		SpawnID:          req.ID,
+		ParentRunName:    parent.Name,
+		ParentSessionKey: parent.Spec.SessionKey,
+		InstanceName:     parent.Spec.InstanceRef,
+		Namespace:        parent.Namespace,
+		Task:             req.Task,
+		SystemPrompt:     req.SystemPrompt,
+		AgentID:          agentID,
+		CurrentDepth:     depth,
+		Model:            parent.Spec.Model,
+		Skills:           childSkills(parent.Spec.Skills, req.Skills),
+		ToolPolicy:       parent.Spec.ToolPolicy,
+		CallChain:        parent.Annotations[callChainAnnotation],
+	})
+	if err != nil {
+		reject(fmt.Sprintf("creating sub-agent: %v", err))
+		return
+	}
+
+	sr.created.Store(result.RunName, &sympoziumv1alpha1.AgentRun{
+		Spec: sympoziumv1alpha1.AgentRunSpec{
+			Parent: &sympoziumv1alpha1.ParentRunRef{RunName: parent.Name, SpawnDepth: depth + 1},
+		},
+	})
+	sr.Log.Info("Spawned sub-agent", "parent", parent.Name, "child", result.RunName, "id", req.ID)
+}
+
+// withCreated adds recently created children missing from the cached list.
+func (sr *SpawnRouter) withCreated(runs []sympoziumv1alpha1.AgentRun) []sympoziumv1alpha1.AgentRun {
+	listed := make(map[string]bool, len(runs))
+	for _, r := range runs {
+		listed[r.Name] = true
+	}
+	sr.created.Range(func(key, value any) bool {
+		if name := key.(string); listed[name] {
+			sr.created.Delete(name)
+		} else {
+			run := *value.(*sympoziumv1alpha1.AgentRun)
+			run.Name = name
+			runs = append(runs, run)
+		}
+		return true
+	})
+	return runs
+}
+
+// Reconcile publishes the result of a finished sub-agent, or of a run
+// started by send_agent_message, to the run that requested it.
+func (sr *SpawnRouter) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	run := &sympoziumv1alpha1.AgentRun{}
+	if err := sr.Client.Get(ctx, req.NamespacedName, run); err != nil {
+		if errors.IsNotFound(err) {
+			sr.delivered.Delete(req.NamespacedName.String())
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	// Sub-agents report to their parent, agent messages to the caller.
+	spawnID := run.Annotations[orchestrator.SpawnIDAnnotation]
+	requester := run.Labels[callerRunLabel]
+	if run.Spec.Parent != nil {
+		requester = run.Spec.Parent.RunName
+	}
+	if requester == "" || spawnID == "" {
+		return ctrl.Result{}, nil
+	}
+
+	res := ipc.SpawnResult{ID: spawnID, RunName: run.Name}
+	switch run.Status.Phase {
+	case sympoziumv1alpha1.AgentRunPhaseSucceeded:
+		res.Status, res.Result = spawnSucceeded, run.Status.Result
+	case sympoziumv1alpha1.AgentRunPhaseFailed:
+		res.Status, res.Error = spawnFailed, run.Status.Error
+	default:
+		return ctrl.Result{}, nil
+	}
+
+	key := req.NamespacedName.String()
+	if _, done := sr.delivered.Load(key); done {
+		return ctrl.Result{}, nil
+	}
+	parent := &sympoziumv1alpha1.AgentRun{}
+	if err := sr.Client.Get(ctx, client.ObjectKey{Namespace: run.Namespace, Name: requester}, parent); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if err := sr.publishResult(ctx, parent, res); err != nil {
+		return ctrl.Result{}, err
+	}
+	sr.delivered.Store(key, true)
+	sr.Log.Info("Delivered sub-agent result", "parent", parent.Name, "child", run.Name, "status", res.Status)
+	return ctrl.Result{}, nil
+}
+
+// publishResult sends a spawn result to the parent run's IPC bridge.
+func (sr *SpawnRouter) publishResult(ctx context.Context, parent *sympoziumv1alpha1.AgentRun, res ipc.SpawnResult) error {
+	event, err := eventbus.NewEvent(eventbus.TopicAgentSpawnResult, map[string]string{
+		"agentRunID":   parent.Name,
+		"instanceName": parent.Spec.InstanceRef,
+	}, res)
+	if err != nil {
+		return err
+	}
+	if err := sr.EventBus.Publish(ctx, eventbus.TopicAgentSpawnResult, event); err != nil {
+		sr.Log.Error(err, "failed to publish spawn result", "parent", parent.Name, "id", res.ID)
+		return fmt.Errorf("publishing spawn result: %w", err)
+	}
+	return nil
+}
+
+// SetupWithManager registers the router's event bus listener and its
+// AgentRun reconciler with the manager.
+func (sr *SpawnRouter) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.Add(sr); err != nil {
+		return err
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("agentrun-spawns").
+		For(&sympoziumv1alpha1.AgentRun{}).
+		Complete(sr)
+}
+
+// subagentLimits returns the instance's sub-agent limits, filling unset
+// fields with the defaults.
+func subagentLimits(instance *sympoziumv1alpha1.SympoziumInstance) sympoziumv1alpha1.SubagentsSpec {
+	limits := defaultSubagentLimits
+	if s := instance.Spec.Agents.Default.Subagents; s != nil {
+		if s.MaxDepth > 0 {
+			limits.MaxDepth = s.MaxDepth
+		}
+		if s.MaxConcurrent > 0 {
+			limits.MaxConcurrent = s.MaxConcurrent
+		}
+		if s.MaxChildrenPerAgent > 0 {
+			limits.MaxChildrenPerAgent = s.MaxChildrenPerAgent
+		}
+	}
+	return limits
+}
+
+// checkSpawnLimits reports whether parent may spawn another child, given
+// all runs of its instance. MaxConcurrent counts the active sub-agents under
+// parent's top-level run; other runs of the instance do not count.
+func checkSpawnLimits(parent *sympoziumv1alpha1.AgentRun, runs []sympoziumv1alpha1.AgentRun, limits sympoziumv1alpha1.SubagentsSpec) error {
+	depth := 1
+	if parent.Spec.Parent != nil {
+		depth = parent.Spec.Parent.SpawnDepth + 1
+	}
+	if depth > limits.MaxDepth {
+		return fmt.Errorf("sub-agent depth %d exceeds max %d", depth, limits.MaxDepth)
+	}
+
+	parents := make(map[string]string, len(runs))
+	for _, r := range runs {
+		if r.Spec.Parent != nil {
+			parents[r.Name] = r.Spec.Parent.RunName
+		}
+	}
+	if parent.Spec.Parent != nil {
+		parents[parent.Name] = parent.Spec.Parent.RunName
+	}
+	root := rootRun(parent.Name, parents)
+
+	children, active := 0, 0
+	for _, r := range runs {
+		if r.Spec.Parent == nil {
+			continue
+		}
+		if r.Spec.Parent.RunName == parent.Name {
+			children++
+		}
+		if r.Status.Phase != sympoziumv1alpha1.AgentRunPhaseSucceeded && r.Status.Phase != sympoziumv1alpha1.AgentRunPhaseFailed &&
+			rootRun(r.Name, parents) == root {
+			active++
+		}
+	}
+	if children >= limits.MaxChildrenPerAgent {
+		return fmt.Errorf("run %s already has %d sub-agents (max %d)", parent.Name, children, limits.MaxChildrenPerAgent)
+	}
+	if active >= limits.MaxConcurrent {
+		return fmt.Errorf("concurrency limit reached: %d/%d sub-agents active", active, limits.MaxConcurrent)
+	}
+	return nil
+}
+
+// rootRun follows parents, which maps a sub-agent run to its parent, from
+// name up to the top-level run.
+func rootRun(name string, parents map[string]string) string {
+	for range len(parents) {
+		p, ok := parents[name]
+		if !ok {
+			break
+		}
+		name = p
+	}
+	return name
+}
+
+// childSkills returns the parent's skills, narrowed to the named ones when
+// the request lists any. A child can never mount a skill its parent lacks.
+func childSkills(parent []sympoziumv1alpha1.SkillRef, names []string) []sympoziumv1alpha1.SkillRef {
+	if len(names) == 0 {
+		return parent
+	}
+	want := make(map[string]bool, len(names))
+	for _, n := range names {
+		want[n] = true
+	}
+	var out []sympoziumv1alpha1.SkillRef
+	for _, s := range parent {
+		if want[s.SkillPackRef] || want[s.ConfigMapRef] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -4,12 +4,15 @@ package controller
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -106,6 +109,7 @@ func (r *SympoziumInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Re
 	statusBase := instance.DeepCopy()
 	instance.Status.Phase = "Running"
 	instance.Status.ActiveAgentPods = activeCount
+	instance.Status.PendingPairings = livePairings(&instance, time.Now())
 	if err := r.Status().Patch(ctx, &instance, client.MergeFrom(statusBase)); err != nil {
 		return ctrl.Result{}, err
 	}
@@ -118,6 +122,16 @@ func (r *SympoziumInstanceReconciler) reconcileChannels(ctx context.Context, ins
 	channelStatuses := make([]sympoziumv1alpha1.ChannelStatus, 0, len(instance.Spec.Channels))
 
 	for _, ch := range instance.Spec.Channels {
+		// The API server serves web chats itself; there is no pod to run.
+		if ch.Type == "webchat" {
+			channelStatuses = append(channelStatuses, sympoziumv1alpha1.ChannelStatus{
+				Type:    ch.Type,
+				Status:  "Connected",
+				Message: "Served by the API server",
+			})
+			continue
+		}
+
 		deployName := fmt.Sprintf("%s-channel-%s", instance.Name, ch.Type)
 
 		// WhatsApp channels need a PVC for credential persistence (QR link survives restarts)
@@ -149,6 +163,15 @@ func (r *SympoziumInstanceReconciler) reconcileChannels(ctx context.Context, ins
 		} else if err != nil {
 			return err
 		} else {
+			// Keep settings passed in the environment, such as the
+			// auto-join lists, in step with the instance.
+			want := r.buildChannelDeployment(instance, ch, deployName).Spec.Template.Spec.Containers[0].Env
+			if cs := deploy.Spec.Template.Spec.Containers; len(cs) > 0 && !equality.Semantic.DeepEqual(cs[0].Env, want) {
+				cs[0].Env = want
+				if err := r.Update(ctx, &deploy); err != nil {
+					return err
+				}
+			}
 			status := "Connected"
 			if deploy.Status.ReadyReplicas == 0 {
 				status = "Disconnected"
@@ -234,6 +257,16 @@ func (r *SympoziumInstanceReconciler) buildChannelDeployment(
 		}
 	}
 
+	// Invitations are accepted by the channel pod, so it needs the lists
+	// that decide whose to accept.
+	if ch.AutoJoin {
+		deploy.Spec.Template.Spec.Containers[0].Env = append(deploy.Spec.Template.Spec.Containers[0].Env,
+			corev1.EnvVar{Name: "AUTO_JOIN", Value: "true"},
+			corev1.EnvVar{Name: "ALLOW_FROM", Value: strings.Join(ch.AllowFrom, ",")},
+			corev1.EnvVar{Name: "DENY_FROM", Value: strings.Join(ch.DenyFrom, ",")},
+		)
+	}
+
 	// WhatsApp channels need a persistent volume for credential storage
 	if ch.Type == "whatsapp" {
 		pvcName := fmt.Sprintf("%s-data", name)
@@ -351,14 +384,13 @@ func (r *SympoziumInstanceReconciler) reconcileMemoryConfigMap(ctx context.Conte
 	var cm corev1.ConfigMap
 	err := r.Get(ctx, types.NamespacedName{Name: cmName, Namespace: instance.Namespace}, &cm)
 	if err == nil {
-		return nil // Already exists.
+		return r.rollbackMemory(ctx, log, instance, &cm)
 	}
 	if !errors.IsNotFound(err) {
 		return err
 	}
 
 	// Create the memory ConfigMap with initial content.
-	initialContent := "# Agent Memory\n\nNo memories recorded yet.\n"
 	cm = corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      cmName,
@@ -369,7 +401,7 @@ func (r *SympoziumInstanceReconciler) reconcileMemoryConfigMap(ctx context.Conte
 			},
 		},
 		Data: map[string]string{
-			"MEMORY.md": initialContent,
+			"MEMORY.md": initialMemory,
 		},
 	}
 
@@ -381,6 +413,34 @@ func (r *SympoziumInstanceReconciler) reconcileMemoryConfigMap(ctx context.Conte
 	return r.Create(ctx, &cm)
 }
 
+// rollbackMemory restores the revision named by the instance's
+// memory-rollback annotation as a new revision, then removes the annotation.
+func (r *SympoziumInstanceReconciler) rollbackMemory(ctx context.Context, log logr.Logger, instance *sympoziumv1alpha1.SympoziumInstance, cm *corev1.ConfigMap) error {
+	v, ok := instance.Annotations[memoryRollbackAnnotation]
+	if !ok {
+		return nil
+	}
+	store := memoryStore{client: r.Client, namespace: instance.Namespace, instance: instance.Name}
+	rev, err := strconv.ParseInt(v, 10, 64)
+	content, found := "", false
+	if err == nil {
+		content, found = store.revision(ctx, rev)
+	}
+	if found {
+		newRev, err := store.write(ctx, cm, content, fmt.Sprintf("rollback:%d", rev))
+		if err != nil {
+			return fmt.Errorf("rolling back memory to revision %d: %w", rev, err)
+		}
+		log.Info("Rolled back memory", "to", rev, "revision", newRev)
+	} else {
+		log.Info("Memory revision not in history, ignoring rollback", "revision", v)
+	}
+
+	patch := client.MergeFrom(instance.DeepCopy())
+	delete(instance.Annotations, memoryRollbackAnnotation)
+	return r.Patch(ctx, instance, patch)
+}
+
 // cleanupMemoryConfigMap deletes the memory ConfigMap for an instance.
 func (r *SympoziumInstanceReconciler) cleanupMemoryConfigMap(ctx context.Context, instance *sympoziumv1alpha1.SympoziumInstance) error {
 	cmName := fmt.Sprintf("%s-memory", instance.Name)
@@ -170,6 +170,7 @@ func (r *SympoziumScheduleReconciler) Reconcile(ctx context.Context, req ctrl.Re
 	if instance.Spec.Agents.Default.Thinking != "" {
 		agentRun.Spec.Model.Thinking = instance.Spec.Agents.Default.Thinking
 	}
+	agentRun.Spec.Model.Fallbacks = instance.Spec.Agents.Fallbacks
 
 	// Resolve auth secret.
 	if len(instance.Spec.AuthRefs) > 0 {
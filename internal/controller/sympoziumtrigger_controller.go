@@ -0,0 +1,125 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sympoziumv1alpha1 "github.com/alexsjones/sympozium/api/v1alpha1"
+	"github.com/alexsjones/sympozium/internal/trigger"
+)
+
+// SympoziumTriggerReconciler checks SympoziumTrigger objects and reports
+// whether they can accept deliveries. The API server receives webhook
+// deliveries and the WatchTriggerReconciler watches objects; they create
+// the runs.
+type SympoziumTriggerReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Log    logr.Logger
+}
+
+// +kubebuilder:rbac:groups=sympozium.ai,resources=sympoziumtriggers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=sympozium.ai,resources=sympoziumtriggers/status,verbs=get;update;patch
+
+// Reconcile validates a SympoziumTrigger and updates its status.
+func (r *SympoziumTriggerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	t := &sympoziumv1alpha1.SympoziumTrigger{}
+	if err := r.Get(ctx, req.NamespacedName, t); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	phase, message := "Ready", ""
+	if err := r.check(ctx, t); err != nil {
+		phase, message = "Error", err.Error()
+	} else if t.Spec.Suspend {
+		phase = "Suspended"
+	}
+
+	path := ""
+	if t.Spec.Auth != nil {
+		path = trigger.Path(t.Namespace, t.Name)
+	}
+	if t.Status.Phase != phase || t.Status.Message != message || t.Status.WebhookPath != path {
+		t.Status.Phase = phase
+		t.Status.Message = message
+		t.Status.WebhookPath = path
+		if phase == "Error" {
+			r.Log.Info("SympoziumTrigger cannot accept deliveries", "trigger", req.NamespacedName, "reason", message)
+		}
+		if err := r.Status().Update(ctx, t); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// The instance or secret may be created later.
+	if phase == "Error" {
+		return ctrl.Result{RequeueAfter: 60 * time.Second}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// check reports what keeps a trigger from accepting deliveries.
+func (r *SympoziumTriggerReconciler) check(ctx context.Context, t *sympoziumv1alpha1.SympoziumTrigger) error {
+	if _, err := trigger.Parse("task", t.Spec.Task); err != nil {
+		return fmt.Errorf("invalid task template: %w", err)
+	}
+	if _, err := trigger.Parse("dedupeKey", t.Spec.DedupeKey); err != nil {
+		return fmt.Errorf("invalid dedupe key template: %w", err)
+	}
+
+	inst := &sympoziumv1alpha1.SympoziumInstance{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: t.Namespace, Name: t.Spec.InstanceRef}, inst); err != nil {
+		return fmt.Errorf("instance %q: %w", t.Spec.InstanceRef, err)
+	}
+	if d := t.Spec.Deliver; d != nil && channelSpecFor(inst, d.Channel) == nil {
+		return fmt.Errorf("instance %q has no %s channel to deliver to", inst.Name, d.Channel)
+	}
+
+	switch {
+	case t.Spec.Auth != nil && t.Spec.Watch != nil:
+		return fmt.Errorf("only one of auth and watch may be set")
+	case t.Spec.Watch != nil:
+		if _, err := metav1.LabelSelectorAsSelector(t.Spec.Watch.Selector); err != nil {
+			return fmt.Errorf("invalid watch selector: %w", err)
+		}
+		if t.Namespace != systemNamespace {
+			for _, ns := range t.Spec.Watch.Namespaces {
+				if ns != t.Namespace {
+					return fmt.Errorf("only triggers in %s may watch namespace %q", systemNamespace, ns)
+				}
+			}
+		}
+		return nil
+	case t.Spec.Auth == nil:
+		return fmt.Errorf("one of auth and watch must be set")
+	}
+
+	var secret corev1.Secret
+	if err := r.Get(ctx, client.ObjectKey{Namespace: t.Namespace, Name: t.Spec.Auth.SecretRef}, &secret); err != nil {
+		return fmt.Errorf("secret %q: %w", t.Spec.Auth.SecretRef, err)
+	}
+	if len(secret.Data[trigger.SecretKey]) == 0 {
+		return fmt.Errorf("secret %q has no %s", t.Spec.Auth.SecretRef, trigger.SecretKey)
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *SympoziumTriggerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&sympoziumv1alpha1.SympoziumTrigger{}).
+		Complete(r)
+}
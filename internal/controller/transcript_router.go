@@ -0,0 +1,274 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/alexsjones/sympozium/internal/eventbus"
+	"github.com/alexsjones/sympozium/internal/ipc"
+	"github.com/alexsjones/sympozium/internal/session"
+)
+
+// Bounds on the prior conversation replayed into a channel message's run.
+const (
+	channelHistoryEvents   = 40
+	channelHistoryMaxBytes = 16 * 1024
+	// historyToolResultBytes caps each tool result in the replay.
+	historyToolResultBytes = 500
+)
+
+// transcriptIdle is how long a run's transcript state is kept without
+// events, so runs whose pods die without a result are eventually dropped.
+const transcriptIdle = 30 * time.Minute
+
+// TranscriptRouter records agent runs in the session store. It follows each
+// run's stream chunks and result on the event bus and writes every reply,
+// tool call and tool result to the transcript of the run's session.
+type TranscriptRouter struct {
+	Client   client.Client
+	EventBus eventbus.EventBus
+	Sessions *session.Store
+	Log      logr.Logger
+
+	runs map[string]*runTranscript
+}
+
+// runTranscript is the state of a run being recorded.
+type runTranscript struct {
+	sessionID string // empty if the run is not recorded
+	model     string
+	text      strings.Builder
+	done      bool
+	lastSeen  time.Time
+}
+
+// Start begins recording runs. It blocks until ctx is cancelled.
+func (tr *TranscriptRouter) Start(ctx context.Context) error {
+	tr.Log.Info("Starting transcript router")
+	tr.runs = map[string]*runTranscript{}
+
+	chunkCh, err := tr.EventBus.Subscribe(ctx, eventbus.TopicAgentStreamChunk)
+	if err != nil {
+		return fmt.Errorf("subscribing to %s: %w", eventbus.TopicAgentStreamChunk, err)
+	}
+	completedCh, err := tr.EventBus.Subscribe(ctx, eventbus.TopicAgentRunCompleted)
+	if err != nil {
+		return fmt.Errorf("subscribing to %s: %w", eventbus.TopicAgentRunCompleted, err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			tr.Log.Info("Transcript router shutting down")
+			return nil
+		case event := <-chunkCh:
+			tr.handleChunk(ctx, event)
+		case event := <-completedCh:
+			tr.handleCompleted(ctx, event)
+		}
+	}
+}
+
+// transcript returns the state of the event's run, looking up and opening
+// its session the first time the run is seen.
+func (tr *TranscriptRouter) transcript(ctx context.Context, event *eventbus.Event) *runTranscript {
+	runName := event.Metadata["agentRunID"]
+	if runName == "" {
+		return nil
+	}
+	now := time.Now()
+	if rt, ok := tr.runs[runName]; ok {
+		rt.lastSeen = now
+		return rt
+	}
+	for name, rt := range tr.runs {
+		if now.Sub(rt.lastSeen) > transcriptIdle {
+			delete(tr.runs, name)
+		}
+	}
+
+	rt := &runTranscript{lastSeen: now}
+	tr.runs[runName] = rt
+	run, err := findAgentRun(ctx, tr.Client, event.Metadata["instanceName"], runName)
+	if err != nil {
+		tr.Log.V(1).Info("Not recording transcript", "run", runName, "err", err)
+		return rt
+	}
+	// Memory maintenance runs are not conversations.
+	if run.Spec.SessionKey == "" || memoryTask(run) != "" {
+		return rt
+	}
+	sess := &session.Session{
+		InstanceName: run.Spec.InstanceRef,
+		Namespace:    run.Namespace,
+		SessionKey:   run.Spec.SessionKey,
+		ChannelType:  run.Labels["sympozium.ai/source-channel"],
+		SenderID:     run.Annotations["sympozium.ai/sender-id"],
+	}
+	if err := tr.Sessions.GetOrCreateSession(ctx, sess); err != nil {
+		tr.Log.Error(err, "failed to open session", "run", runName, "session", run.Spec.SessionKey)
+		return rt
+	}
+	rt.sessionID = sess.ID
+	rt.model = run.Spec.Model.Model
+
+	// The channel router records channel messages itself, without the
+	// replayed conversation.
+	if run.Labels["sympozium.ai/source"] != "channel" {
+		tr.append(ctx, runName, rt, session.TranscriptEvent{
+			EventType: session.EventUserMessage,
+			Role:      "user",
+			Content:   run.Spec.Task,
+		})
+	}
+	return rt
+}
+
+// append writes an event to the run's transcript.
+func (tr *TranscriptRouter) append(ctx context.Context, runName string, rt *runTranscript, e session.TranscriptEvent) {
+	e.SessionID = rt.sessionID
+	e.AgentRunName = runName
+	if e.Model == "" && e.Role == "assistant" {
+		e.Model = rt.model
+	}
+	if err := tr.Sessions.AppendTranscript(ctx, &e); err != nil {
+		tr.Log.Error(err, "failed to record transcript event", "run", runName, "type", e.EventType)
+	}
+}
+
+// flushText records the reply text streamed since the last tool call.
+func (tr *TranscriptRouter) flushText(ctx context.Context, runName string, rt *runTranscript) {
+	text := strings.TrimSpace(rt.text.String())
+	rt.text.Reset()
+	if text != "" {
+		tr.append(ctx, runName, rt, session.TranscriptEvent{EventType: session.EventAgentMessage, Role: "assistant", Content: text})
+	}
+}
+
+// handleChunk records one stream chunk of a run.
+func (tr *TranscriptRouter) handleChunk(ctx context.Context, event *eventbus.Event) {
+	rt := tr.transcript(ctx, event)
+	if rt == nil || rt.sessionID == "" || rt.done {
+		return
+	}
+	var chunk ipc.StreamChunk
+	if err := json.Unmarshal(event.Data, &chunk); err != nil {
+		return
+	}
+	runName := event.Metadata["agentRunID"]
+
+	switch chunk.Type {
+	case "text":
+		rt.text.WriteString(chunk.Content)
+	case "tool_use":
+		tr.flushText(ctx, runName, rt)
+		tr.append(ctx, runName, rt, session.TranscriptEvent{
+			EventType: session.EventToolCall,
+			Role:      "assistant",
+			Content:   chunk.Content,
+			ToolName:  chunk.ToolName,
+			ToolInput: json.RawMessage(chunk.Content),
+		})
+	case "tool_result":
+		tr.append(ctx, runName, rt, session.TranscriptEvent{
+			EventType: session.EventToolResult,
+			Role:      "tool",
+			Content:   chunk.Content,
+			ToolName:  chunk.ToolName,
+		})
+	}
+}
+
+// handleCompleted records a run's final reply or error.
+func (tr *TranscriptRouter) handleCompleted(ctx context.Context, event *eventbus.Event) {
+	rt := tr.transcript(ctx, event)
+	if rt == nil || rt.sessionID == "" || rt.done {
+		return
+	}
+	rt.done = true
+	runName := event.Metadata["agentRunID"]
+
+	var result agentResult
+	if err := json.Unmarshal(event.Data, &result); err != nil {
+		tr.Log.Error(err, "failed to unmarshal agent result", "run", runName)
+		return
+	}
+	model := result.Model
+	if model == "" {
+		model = rt.model
+	}
+
+	if result.Status == "error" {
+		tr.flushText(ctx, runName, rt)
+		tr.append(ctx, runName, rt, session.TranscriptEvent{EventType: session.EventError, Role: "system", Content: result.Error})
+		return
+	}
+	// The result holds the final reply, so it replaces the streamed text of
+	// the last turn.
+	text := strings.TrimSpace(result.Response)
+	if text == "" {
+		text = strings.TrimSpace(rt.text.String())
+	}
+	rt.text.Reset()
+	if text == "" {
+		return
+	}
+	tr.append(ctx, runName, rt, session.TranscriptEvent{
+		EventType:  session.EventAgentMessage,
+		Role:       "assistant",
+		Content:    text,
+		Model:      model,
+		TokensUsed: result.Metrics.InputTokens + result.Metrics.OutputTokens,
+	})
+}
+
+// conversationTask prepends the prior conversation of a chat to a new
+// message, keeping the most recent events since the last reset that fit
+// channelHistoryMaxBytes.
+func conversationTask(history []session.TranscriptEvent, text string) string {
+	var lines []string
+	size := 0
+	for i := len(history) - 1; i >= 0; i-- {
+		e := history[i]
+		if e.EventType == session.EventReset {
+			break
+		}
+		var line string
+		switch e.EventType {
+		case session.EventUserMessage:
+			line = "User: " + e.Content
+		case session.EventAgentMessage:
+			line = "Assistant: " + e.Content
+		case session.EventToolCall:
+			line = fmt.Sprintf("Assistant called %s(%s)", e.ToolName, truncateForLog(e.Content, historyToolResultBytes))
+		case session.EventToolResult:
+			line = fmt.Sprintf("Result of %s: %s", e.ToolName, truncateForLog(e.Content, historyToolResultBytes))
+		default:
+			continue
+		}
+		if size+len(line) > channelHistoryMaxBytes {
+			break
+		}
+		size += len(line)
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return text
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## Conversation so far\n\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		sb.WriteString(lines[i])
+		sb.WriteString("\n\n")
+	}
+	sb.WriteString("## New message\n\n")
+	sb.WriteString(text)
+	return sb.String()
+}
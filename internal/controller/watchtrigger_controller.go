@@ -0,0 +1,261 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	sympoziumv1alpha1 "github.com/alexsjones/sympozium/api/v1alpha1"
+	"github.com/alexsjones/sympozium/internal/trigger"
+)
+
+// WatchTriggerReconciler watches pods, nodes, jobs and events for the
+// conditions of SympoziumTriggers with a watch, and starts an AgentRun with
+// the object and its recent events as context when one matches. Each
+// trigger starts at most one run per object per cooldown; when it last did
+// is kept in the trigger's status.
+//
+// Watching means caching every pod, node, job and event in the cluster, so
+// the watches only start once the first watch trigger is created.
+type WatchTriggerReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Log    logr.Logger
+
+	mgr ctrl.Manager
+	mu  sync.Mutex
+	// watching holds the conditions whose objects are watched.
+	watching map[string]bool
+}
+
+// +kubebuilder:rbac:groups="",resources=pods;nodes;events,verbs=get;list;watch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=sympozium.ai,resources=agentruns,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=sympozium.ai,resources=sympoziumtriggers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=sympozium.ai,resources=sympoziumtriggers/status,verbs=get;update;patch
+
+// reconcileObject matches the object req names against every watch trigger
+// for condition.
+func (r *WatchTriggerReconciler) reconcileObject(ctx context.Context, req ctrl.Request, condition string, obj client.Object) (ctrl.Result, error) {
+	var triggers sympoziumv1alpha1.SympoziumTriggerList
+	if err := r.List(ctx, &triggers); err != nil {
+		return ctrl.Result{}, err
+	}
+	var watching []*sympoziumv1alpha1.SympoziumTrigger
+	for i := range triggers.Items {
+		t := &triggers.Items[i]
+		if w := t.Spec.Watch; w != nil && w.Condition == condition && t.Spec.Auth == nil && !t.Spec.Suspend {
+			watching = append(watching, t)
+		}
+	}
+	if len(watching) == 0 {
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !obj.GetDeletionTimestamp().IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	// An event is about another object, which is what the run looks at.
+	subject := obj
+	if ev, ok := obj.(*corev1.Event); ok {
+		var err error
+		if subject, err = r.involvedObject(ctx, ev); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	// Runs that fail would otherwise start runs about themselves.
+	if _, ok := subject.GetLabels()["sympozium.ai/agent-run"]; ok {
+		return ctrl.Result{}, nil
+	}
+
+	for _, t := range watching {
+		if !trigger.Watches(t, obj, systemNamespace) {
+			continue
+		}
+		reason, ok := trigger.Match(t.Spec.Watch, obj, time.Now())
+		if !ok {
+			continue
+		}
+		if err := r.fire(ctx, t, subject, reason); err != nil {
+			r.Log.Error(err, "failed to start run for watch trigger", "trigger", t.Namespace+"/"+t.Name, "object", req.NamespacedName)
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+// involvedObject returns the pod, job or node an event is about, or the
+// event itself for other kinds or objects that no longer exist.
+func (r *WatchTriggerReconciler) involvedObject(ctx context.Context, ev *corev1.Event) (client.Object, error) {
+	var obj client.Object
+	switch ev.InvolvedObject.Kind {
+	case "Pod":
+		obj = &corev1.Pod{}
+	case "Job":
+		obj = &batchv1.Job{}
+	case "Node":
+		obj = &corev1.Node{}
+	default:
+		return ev, nil
+	}
+	key := types.NamespacedName{Namespace: ev.InvolvedObject.Namespace, Name: ev.InvolvedObject.Name}
+	if err := r.Get(ctx, key, obj); err != nil {
+		if errors.IsNotFound(err) {
+			return ev, nil
+		}
+		return nil, err
+	}
+	return obj, nil
+}
+
+// fire starts a run of t for obj unless one was started within the
+// trigger's cooldown.
+func (r *WatchTriggerReconciler) fire(ctx context.Context, t *sympoziumv1alpha1.SympoziumTrigger, obj client.Object, reason string) error {
+	gvk, err := apiutil.GVKForObject(obj, r.Scheme)
+	if err != nil {
+		return err
+	}
+	obj = obj.DeepCopyObject().(client.Object)
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+
+	// Events about an object that could not be fetched cool down with the
+	// object rather than with each event.
+	kind, ns, name := gvk.Kind, obj.GetNamespace(), obj.GetName()
+	if ev, ok := obj.(*corev1.Event); ok {
+		kind, ns, name = ev.InvolvedObject.Kind, ev.InvolvedObject.Namespace, ev.InvolvedObject.Name
+	}
+	objKey := trigger.ObjectKey(kind, ns, name)
+	cooldown := trigger.Cooldown(t.Spec.Watch)
+	if last := trigger.LastFired(&t.Status, objKey); time.Since(last) < cooldown {
+		return nil
+	}
+
+	dedupeHash := trigger.DedupeHash(objKey)
+	recent, err := trigger.RecentRun(ctx, r.Client, t, dedupeHash, &metav1.Duration{Duration: cooldown})
+	if err != nil {
+		return err
+	}
+	if recent != "" {
+		return nil
+	}
+
+	d, err := trigger.NewWatchDelivery(obj, reason)
+	if err != nil {
+		return err
+	}
+	task, err := trigger.Render("task", t.Spec.Task, d)
+	if err != nil {
+		return fmt.Errorf("rendering task: %w", err)
+	}
+	if strings.TrimSpace(task) == "" {
+		task = reason
+	}
+
+	var events corev1.EventList
+	if err := r.List(ctx, &events, client.InNamespace(ns)); err != nil {
+		return err
+	}
+	details, err := trigger.Context(obj, trigger.RelatedEvents(events.Items, kind, ns, name))
+	if err != nil {
+		return err
+	}
+
+	run, err := trigger.NewRun(ctx, r.Client, t, task+"\n\n"+details, dedupeHash, &metav1.Duration{Duration: cooldown})
+	if err != nil {
+		return err
+	}
+	if err := r.Create(ctx, run); err != nil {
+		if errors.IsAlreadyExists(err) {
+			return nil
+		}
+		return fmt.Errorf("creating run: %w", err)
+	}
+	r.Log.Info("Created AgentRun from watch trigger", "trigger", t.Name, "namespace", t.Namespace, "object", objKey, "run", run.Name, "reason", reason)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var latest sympoziumv1alpha1.SympoziumTrigger
+		if err := r.Get(ctx, client.ObjectKeyFromObject(t), &latest); err != nil {
+			return err
+		}
+		now := metav1.Now()
+		latest.Status.LastTriggerTime = &now
+		latest.Status.LastRunName = run.Name
+		latest.Status.TotalRuns++
+		trigger.RecordFiring(&latest.Status, objKey, now.Time, cooldown)
+		return r.Status().Update(ctx, &latest)
+	})
+}
+
+// SetupWithManager sets up the controller with the Manager. The watches
+// on pods, nodes, jobs and events are added when a watch trigger appears.
+func (r *WatchTriggerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.mgr = mgr
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("watchtrigger").
+		For(&sympoziumv1alpha1.SympoziumTrigger{}).
+		Complete(reconcile.Func(func(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+			var t sympoziumv1alpha1.SympoziumTrigger
+			if err := r.Get(ctx, req.NamespacedName, &t); err != nil {
+				return ctrl.Result{}, client.IgnoreNotFound(err)
+			}
+			if t.Spec.Watch == nil {
+				return ctrl.Result{}, nil
+			}
+			return ctrl.Result{}, r.startWatches()
+		}))
+}
+
+// startWatches adds a controller for each kind of object watch triggers
+// look at, once.
+func (r *WatchTriggerReconciler) startWatches() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.watching == nil {
+		r.watching = make(map[string]bool)
+	}
+	watches := []struct {
+		condition string
+		newObject func() client.Object
+	}{
+		{sympoziumv1alpha1.WatchPodRestarts, func() client.Object { return &corev1.Pod{} }},
+		{sympoziumv1alpha1.WatchNodeNotReady, func() client.Object { return &corev1.Node{} }},
+		{sympoziumv1alpha1.WatchJobFailed, func() client.Object { return &batchv1.Job{} }},
+		{sympoziumv1alpha1.WatchEvent, func() client.Object { return &corev1.Event{} }},
+	}
+	for _, w := range watches {
+		if r.watching[w.condition] {
+			continue
+		}
+		err := ctrl.NewControllerManagedBy(r.mgr).
+			Named("watchtrigger-" + strings.ToLower(w.condition)).
+			For(w.newObject()).
+			Complete(reconcile.Func(func(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+				return r.reconcileObject(ctx, req, w.condition, w.newObject())
+			}))
+		if err != nil {
+			return err
+		}
+		r.watching[w.condition] = true
+		r.Log.Info("Started watching for watch triggers", "condition", w.condition)
+	}
+	return nil
+}
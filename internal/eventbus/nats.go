@@ -133,6 +133,50 @@ func (n *NATSEventBus) Subscribe(ctx context.Context, topic string) (<-chan *Eve
 	return ch, nil
 }
 
+// consumeMaxDeliver and consumeRetryDelay bound how often, and how quickly,
+// Consume redelivers an event whose handler failed.
+const (
+	consumeMaxDeliver = 30
+	consumeRetryDelay = 10 * time.Second
+)
+
+// Consume passes events for the given topic to handle through the durable
+// consumer named durable.
+func (n *NATSEventBus) Consume(ctx context.Context, topic, durable string, handle func(*Event) error) error {
+	subject := topicToSubject(topic)
+
+	consumer, err := n.stream.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
+		Durable:       durable,
+		FilterSubject: subject,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		DeliverPolicy: jetstream.DeliverNewPolicy,
+		MaxDeliver:    consumeMaxDeliver,
+	})
+	if err != nil {
+		return fmt.Errorf("creating consumer %s for %s: %w", durable, subject, err)
+	}
+
+	for ctx.Err() == nil {
+		msgs, err := consumer.Fetch(1, jetstream.FetchMaxWait(5*time.Second))
+		if err != nil {
+			continue
+		}
+		for msg := range msgs.Messages() {
+			var event Event
+			if err := json.Unmarshal(msg.Data(), &event); err != nil {
+				msg.Term()
+				continue
+			}
+			if err := handle(&event); err != nil {
+				msg.NakWithDelay(consumeRetryDelay)
+				continue
+			}
+			msg.Ack()
+		}
+	}
+	return nil
+}
+
 // Close shuts down the NATS connection.
 func (n *NATSEventBus) Close() error {
 	n.conn.Close()
@@ -32,6 +32,12 @@ type EventBus interface {
 	// Subscribe returns a channel that receives events for the given topic.
 	Subscribe(ctx context.Context, topic string) (<-chan *Event, error)
 
+	// Consume passes events for the given topic to handle through a durable
+	// consumer, so events published while no one consumes are kept until
+	// someone does. An event is acknowledged once handle returns nil and
+	// redelivered otherwise. Consume blocks until ctx is cancelled.
+	Consume(ctx context.Context, topic, durable string, handle func(*Event) error) error
+
 	// Close shuts down the event bus connection.
 	Close() error
 }
@@ -43,10 +49,15 @@ const (
 	TopicAgentRunCompleted    = "agent.run.completed"
 	TopicAgentRunFailed       = "agent.run.failed"
 	TopicAgentStreamChunk     = "agent.stream.chunk"
+	TopicAgentStatusUpdate    = "agent.status.update"
 	TopicAgentSpawnRequest    = "agent.spawn.request"
+	TopicAgentSpawnResult     = "agent.spawn.result"
+	TopicAgentFollowUp        = "agent.followup" // suffixed with ".<run name>"
+	TopicAgentMemoryUpdate    = "agent.memory.update"
 	TopicChannelMessageRecv   = "channel.message.received"
 	TopicChannelMessageSend   = "channel.message.send"
 	TopicChannelHealthUpdate  = "channel.health.update"
+	TopicChannelTyping        = "channel.typing"
 	TopicToolExecRequest      = "tool.exec.request"
 	TopicToolExecResult       = "tool.exec.result"
 	TopicToolApprovalRequest  = "tool.approval.request"
@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -28,6 +29,8 @@ const (
 	DirTools     = "tools"
 	DirMessages  = "messages"
 	DirSchedules = "schedules"
+	DirApprovals = "approvals"
+	DirMemory    = "memory"
 )
 
 // Bridge is the IPC bridge sidecar process.
@@ -40,6 +43,8 @@ type Bridge struct {
 	Watcher        *Watcher
 	agentDone      chan struct{} // signalled when result.json is received
 	processedFiles sync.Map      // dedup fsnotify Create+Write for the same file
+	streamMu       sync.Mutex    // guards nextStream
+	nextStream     int           // index of the next stream chunk to publish
 }
 
 // NewBridge creates a new IPC bridge.
@@ -63,7 +68,7 @@ func (b *Bridge) Start(ctx context.Context) error {
 	)
 
 	// Create IPC directory structure
-	dirs := []string{DirInput, DirOutput, DirSpawn, DirTools, DirMessages, DirSchedules}
+	dirs := []string{DirInput, DirOutput, DirSpawn, DirTools, DirMessages, DirSchedules, DirApprovals, DirMemory}
 	for _, dir := range dirs {
 		path := filepath.Join(b.BasePath, dir)
 		if err := os.MkdirAll(path, 0750); err != nil {
@@ -93,6 +98,12 @@ func (b *Bridge) Start(ctx context.Context) error {
 	// Watch for schedule requests
 	go b.watchSchedules(ctx)
 
+	// Watch for tool approval requests
+	go b.watchApprovals(ctx)
+
+	// Watch for memory updates
+	go b.watchMemory(ctx)
+
 	// Subscribe to inbound events from the control plane
 	go b.subscribeToInbound(ctx)
 
@@ -129,6 +140,20 @@ func (b *Bridge) watchOutput(ctx context.Context) {
 
 // handleOutputFile processes a file created in /ipc/output/.
 func (b *Bridge) handleOutputFile(ctx context.Context, fe FileEvent) {
+	filename := filepath.Base(fe.Path)
+
+	// Stream chunks are published strictly in index order, so they are
+	// read from disk by index rather than in fsnotify event order.
+	if strings.HasPrefix(filename, "stream-") {
+		b.publishStreamChunks(ctx)
+		return
+	}
+
+	if filename == "status.json" {
+		b.publishStatus(ctx, fe.Path)
+		return
+	}
+
 	// fsnotify fires both Create and Write for the same file; deduplicate.
 	if _, loaded := b.processedFiles.LoadOrStore(fe.Path, true); loaded {
 		return
@@ -141,7 +166,6 @@ func (b *Bridge) handleOutputFile(ctx context.Context, fe FileEvent) {
 		return
 	}
 
-	filename := filepath.Base(fe.Path)
 	metadata := map[string]string{
 		"agentRunID":   b.AgentRunID,
 		"instanceName": b.InstanceName,
@@ -149,7 +173,9 @@ func (b *Bridge) handleOutputFile(ctx context.Context, fe FileEvent) {
 
 	switch {
 	case filename == "result.json":
-		// Final result
+		// Final result. Publish any stream chunks whose events have not
+		// been seen yet so they all precede the completion event.
+		b.publishStreamChunks(ctx)
 		event, _ := eventbus.NewEvent(eventbus.TopicAgentRunCompleted, metadata, json.RawMessage(data))
 		if err := b.EventBus.Publish(ctx, eventbus.TopicAgentRunCompleted, event); err != nil {
 			b.Log.Error(err, "failed to publish completion event")
@@ -159,20 +185,50 @@ func (b *Bridge) handleOutputFile(ctx context.Context, fe FileEvent) {
 		case b.agentDone <- struct{}{}:
 		default:
 		}
+	}
+}
 
-	case filename == "status.json":
-		// Status update
-		event, _ := eventbus.NewEvent("agent.status.update", metadata, json.RawMessage(data))
-		if err := b.EventBus.Publish(ctx, "agent.status.update", event); err != nil {
-			b.Log.Error(err, "failed to publish status event")
-		}
+// publishStatus publishes the agent's current status.json. The file is
+// replaced on every phase change, so unlike the one-shot output files it is
+// not deduplicated; a repeated event republishes the same status.
+func (b *Bridge) publishStatus(ctx context.Context, path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	metadata := map[string]string{
+		"agentRunID":   b.AgentRunID,
+		"instanceName": b.InstanceName,
+	}
+	event, _ := eventbus.NewEvent(eventbus.TopicAgentStatusUpdate, metadata, json.RawMessage(data))
+	if err := b.EventBus.Publish(ctx, eventbus.TopicAgentStatusUpdate, event); err != nil {
+		b.Log.Error(err, "failed to publish status event")
+	}
+}
 
-	case len(filename) > 7 && filename[:7] == "stream-":
-		// Streaming chunk
+// publishStreamChunks publishes stream-<n>.json files starting at the next
+// unpublished index and stops at the first gap. The agent-runner renames
+// each chunk into place, so a file that exists is complete. A chunk whose
+// event was dropped or arrived early is picked up on a later call.
+func (b *Bridge) publishStreamChunks(ctx context.Context) {
+	b.streamMu.Lock()
+	defer b.streamMu.Unlock()
+
+	metadata := map[string]string{
+		"agentRunID":   b.AgentRunID,
+		"instanceName": b.InstanceName,
+	}
+	for {
+		path := filepath.Join(b.BasePath, DirOutput, fmt.Sprintf("stream-%d.json", b.nextStream))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return
+		}
 		event, _ := eventbus.NewEvent(eventbus.TopicAgentStreamChunk, metadata, json.RawMessage(data))
 		if err := b.EventBus.Publish(ctx, eventbus.TopicAgentStreamChunk, event); err != nil {
-			b.Log.Error(err, "failed to publish stream chunk")
+			b.Log.Error(err, "failed to publish stream chunk", "index", b.nextStream)
 		}
+		b.nextStream++
 	}
 }
 
@@ -190,7 +246,11 @@ func (b *Bridge) watchSpawnRequests(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case fe := <-events:
-			b.handleSpawnRequest(ctx, fe)
+			// Only forward requests; result-*.json files are written here
+			// by subscribeToInbound for the agent to read.
+			if strings.HasPrefix(filepath.Base(fe.Path), "request-") {
+				b.handleSpawnRequest(ctx, fe)
+			}
 		}
 	}
 }
@@ -359,11 +419,160 @@ func (b *Bridge) handleScheduleRequest(ctx context.Context, fe FileEvent) {
 	b.Log.Info("Forwarded schedule request to control plane")
 }
 
+// watchApprovals watches /ipc/approvals/ for tool approval requests and
+// approval timeouts written by the agent.
+func (b *Bridge) watchApprovals(ctx context.Context) {
+	approvalsPath := filepath.Join(b.BasePath, DirApprovals)
+	events, err := b.Watcher.Watch(ctx, approvalsPath)
+	if err != nil {
+		b.Log.Error(err, "failed to watch approvals directory")
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case fe := <-events:
+			filename := filepath.Base(fe.Path)
+			switch {
+			case strings.HasPrefix(filename, "request-"):
+				b.handleApprovalFile(ctx, fe, eventbus.TopicToolApprovalRequest)
+			case strings.HasPrefix(filename, "timeout-"):
+				b.handleApprovalFile(ctx, fe, eventbus.TopicToolApprovalResponse)
+			}
+		}
+	}
+}
+
+// handleApprovalFile publishes an approval request or timeout to topic.
+func (b *Bridge) handleApprovalFile(ctx context.Context, fe FileEvent, topic string) {
+	// fsnotify fires both Create and Write for the same file; deduplicate.
+	if _, loaded := b.processedFiles.LoadOrStore(fe.Path, true); loaded {
+		return
+	}
+
+	data, err := os.ReadFile(fe.Path)
+	if err != nil {
+		b.Log.Error(err, "failed to read approval file", "path", fe.Path)
+		b.processedFiles.Delete(fe.Path)
+		return
+	}
+
+	metadata := map[string]string{
+		"agentRunID":   b.AgentRunID,
+		"instanceName": b.InstanceName,
+	}
+
+	event, _ := eventbus.NewEvent(topic, metadata, json.RawMessage(data))
+	if err := b.EventBus.Publish(ctx, topic, event); err != nil {
+		b.Log.Error(err, "failed to publish approval event", "topic", topic)
+	}
+}
+
+// watchMemory watches /ipc/memory/ for the agent's memory updates and
+// publishes them for the controller to commit.
+func (b *Bridge) watchMemory(ctx context.Context) {
+	memoryPath := filepath.Join(b.BasePath, DirMemory)
+	events, err := b.Watcher.Watch(ctx, memoryPath)
+	if err != nil {
+		b.Log.Error(err, "failed to watch memory directory")
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case fe := <-events:
+			filename := filepath.Base(fe.Path)
+			if strings.HasPrefix(filename, "update") && strings.HasSuffix(filename, ".json") {
+				b.handleMemoryUpdate(ctx, fe)
+			}
+		}
+	}
+}
+
+// handleMemoryUpdate publishes a memory update file.
+func (b *Bridge) handleMemoryUpdate(ctx context.Context, fe FileEvent) {
+	if _, loaded := b.processedFiles.LoadOrStore(fe.Path, true); loaded {
+		return
+	}
+
+	data, err := os.ReadFile(fe.Path)
+	if err != nil {
+		b.Log.Error(err, "failed to read memory update", "path", fe.Path)
+		b.processedFiles.Delete(fe.Path)
+		return
+	}
+
+	var upd MemoryUpdate
+	if err := json.Unmarshal(data, &upd); err != nil {
+		b.Log.Error(err, "failed to parse memory update", "path", fe.Path)
+		return
+	}
+
+	metadata := map[string]string{
+		"agentRunID":   b.AgentRunID,
+		"instanceName": b.InstanceName,
+	}
+
+	event, _ := eventbus.NewEvent(eventbus.TopicAgentMemoryUpdate, metadata, upd)
+	if err := b.EventBus.Publish(ctx, eventbus.TopicAgentMemoryUpdate, event); err != nil {
+		b.Log.Error(err, "failed to publish memory update")
+	}
+}
+
+// writeApprovalResponse writes a decision for this run's agent to
+// /ipc/approvals/response-<id>.json. Decisions for other runs are ignored.
+func (b *Bridge) writeApprovalResponse(event *eventbus.Event) {
+	if event == nil || event.Metadata["agentRunID"] != b.AgentRunID {
+		return
+	}
+	var resp ApprovalResponse
+	if err := json.Unmarshal(event.Data, &resp); err != nil || resp.ID == "" || filepath.Base(resp.ID) != resp.ID {
+		b.Log.Info("Ignoring malformed approval response")
+		return
+	}
+	if resp.Decision == "timeout" {
+		return // written by the agent itself
+	}
+	if err := writeFileAtomic(filepath.Join(b.BasePath, DirApprovals, "response-"+resp.ID+".json"), event.Data); err != nil {
+		b.Log.Error(err, "failed to write approval response")
+	}
+}
+
+// writeSpawnResult writes a sub-agent's result to
+// /ipc/spawn/result-<id>.json if it was spawned by this run.
+func (b *Bridge) writeSpawnResult(event *eventbus.Event) {
+	if event == nil || event.Metadata["agentRunID"] != b.AgentRunID {
+		return
+	}
+	var res SpawnResult
+	if err := json.Unmarshal(event.Data, &res); err != nil || res.ID == "" || filepath.Base(res.ID) != res.ID {
+		b.Log.Info("Ignoring malformed spawn result")
+		return
+	}
+	if err := writeFileAtomic(filepath.Join(b.BasePath, DirSpawn, "result-"+res.ID+".json"), event.Data); err != nil {
+		b.Log.Error(err, "failed to write spawn result")
+	}
+}
+
+// writeFileAtomic writes under a temp name and renames, so the agent never
+// reads a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err := os.WriteFile(tmp, data, 0640); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
 // subscribeToInbound subscribes to events from the control plane and
 // writes them as files for the agent container to consume.
 func (b *Bridge) subscribeToInbound(ctx context.Context) {
 	// Subscribe to follow-up messages
-	followupCh, err := b.EventBus.Subscribe(ctx, fmt.Sprintf("agent.followup.%s", b.AgentRunID))
+	followupCh, err := b.EventBus.Subscribe(ctx, eventbus.TopicAgentFollowUp+"."+b.AgentRunID)
 	if err != nil {
 		b.Log.Error(err, "failed to subscribe to follow-up events")
 		return
@@ -376,16 +585,39 @@ func (b *Bridge) subscribeToInbound(ctx context.Context) {
 		return
 	}
 
+	// Subscribe to tool approval decisions. The topic is shared by all
+	// runs so every approver surface can observe decisions; responses for
+	// other runs are filtered out.
+	approvalCh, err := b.EventBus.Subscribe(ctx, eventbus.TopicToolApprovalResponse)
+	if err != nil {
+		b.Log.Error(err, "failed to subscribe to approval response events")
+		return
+	}
+
+	// Subscribe to results of sub-agents spawned by this run; like
+	// approvals, the topic is shared and filtered by parent run.
+	spawnResultCh, err := b.EventBus.Subscribe(ctx, eventbus.TopicAgentSpawnResult)
+	if err != nil {
+		b.Log.Error(err, "failed to subscribe to spawn result events")
+		return
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
 
+		case event := <-approvalCh:
+			b.writeApprovalResponse(event)
+
+		case event := <-spawnResultCh:
+			b.writeSpawnResult(event)
+
 		case event := <-followupCh:
 			// Write follow-up message to /ipc/input/
 			filename := fmt.Sprintf("followup-%d.json", time.Now().UnixNano())
 			path := filepath.Join(b.BasePath, DirInput, filename)
-			if err := os.WriteFile(path, event.Data, 0640); err != nil {
+			if err := writeFileAtomic(path, event.Data); err != nil {
 				b.Log.Error(err, "failed to write follow-up message")
 			}
 
@@ -22,6 +22,15 @@ type ModelConfig struct {
 	Thinking string `json:"thinking,omitempty"`
 }
 
+// FollowUp is written to /ipc/input/followup-*.json when a message is sent
+// to a running agent. The agent injects it as a user turn between tool-loop
+// iterations.
+type FollowUp struct {
+	ID     string `json:"id"`
+	Text   string `json:"text"`
+	Sender string `json:"sender,omitempty"`
+}
+
 // AgentResult is written to /ipc/output/result.json by the agent on completion.
 type AgentResult struct {
 	Status   string `json:"status"` // "success" or "error"
@@ -37,19 +46,62 @@ type AgentResult struct {
 }
 
 // StreamChunk is written to /ipc/output/stream-*.json for streaming responses.
+// Chunks are numbered from 0 and published by the bridge in Index order.
 type StreamChunk struct {
-	Type    string `json:"type"` // "text", "thinking", "tool_use", "tool_result"
-	Content string `json:"content"`
-	ToolID  string `json:"toolId,omitempty"`
-	Index   int    `json:"index"`
+	Type     string `json:"type"` // "text", "thinking", "tool_use", "tool_result"
+	Content  string `json:"content"`
+	ToolID   string `json:"toolId,omitempty"`
+	ToolName string `json:"toolName,omitempty"`
+	Index    int    `json:"index"`
 }
 
-// SpawnRequest is written to /ipc/spawn/request-*.json to request sub-agent creation.
+// SpawnRequest is written to /ipc/spawn/request-<id>.json to request sub-agent creation.
+// When TargetInstance is set, the task is instead sent to an agent on that
+// SympoziumInstance (send_agent_message); the reply arrives as a SpawnResult.
 type SpawnRequest struct {
-	Task         string   `json:"task"`
-	SystemPrompt string   `json:"systemPrompt,omitempty"`
-	AgentID      string   `json:"agentId"`
-	Skills       []string `json:"skills,omitempty"`
+	ID             string   `json:"id"`
+	Task           string   `json:"task"`
+	SystemPrompt   string   `json:"systemPrompt,omitempty"`
+	AgentID        string   `json:"agentId"`
+	Skills         []string `json:"skills,omitempty"`
+	TargetInstance string   `json:"targetInstance,omitempty"`
+}
+
+// SpawnResult is written to /ipc/spawn/result-<id>.json when the sub-agent
+// for a SpawnRequest finishes, or when the request is rejected.
+type SpawnResult struct {
+	ID      string `json:"id"`
+	RunName string `json:"runName,omitempty"`
+	Status  string `json:"status"` // "succeeded", "failed", "rejected"
+	Result  string `json:"result,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ApprovalRequest is written to /ipc/approvals/request-<id>.json when a tool
+// call matches a ToolGating "ask" rule. The agent waits for the matching
+// response-<id>.json until TimeoutSeconds elapse.
+type ApprovalRequest struct {
+	ID             string `json:"id"`
+	ToolName       string `json:"toolName"`
+	Arguments      string `json:"arguments,omitempty"`
+	TimeoutSeconds int    `json:"timeoutSeconds"`
+}
+
+// ApprovalResponse carries a decision on an ApprovalRequest. The bridge
+// writes it to /ipc/approvals/response-<id>.json; the agent writes
+// timeout-<id>.json itself when no decision arrives in time.
+type ApprovalResponse struct {
+	ID       string `json:"id"`
+	Decision string `json:"decision"` // "approved", "denied", "timeout"
+	Approver string `json:"approver,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// MemoryUpdate is written to /ipc/memory/update.json at the end of a run
+// whose memory tools changed the instance's memory. Content is the whole
+// updated MEMORY.md; the controller merges it with concurrent updates.
+type MemoryUpdate struct {
+	Content string `json:"content"`
 }
 
 // ExecRequest is written to /ipc/tools/exec-request-*.json for sandbox execution.
@@ -61,6 +113,10 @@ type ExecRequest struct {
 	Timeout int               `json:"timeout,omitempty"` // seconds
 	Stdin   string            `json:"stdin,omitempty"`
 	Meta    map[string]string `json:"_meta,omitempty"`
+	// Tool and Arguments name a SkillPack tool whose command template the
+	// sidecar renders instead of running Command.
+	Tool      string         `json:"tool,omitempty"`
+	Arguments map[string]any `json:"arguments,omitempty"`
 }
 
 // ExecResult is written to /ipc/tools/exec-result-*.json with execution results.
@@ -81,6 +137,8 @@ type OutboundMessage struct {
 	Text     string          `json:"text"`
 	Format   string          `json:"format,omitempty"` // "plain", "markdown", "html"
 	Metadata json.RawMessage `json:"metadata,omitempty"`
+	// Attachments are files sent after the text, as channel.Attachment.
+	Attachments json.RawMessage `json:"attachments,omitempty"`
 }
 
 // StatusUpdate is written to /ipc/output/status.json for agent status.
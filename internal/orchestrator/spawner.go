@@ -21,6 +21,9 @@ type Spawner struct {
 
 // SpawnRequest represents a request from a parent agent to spawn a sub-agent.
 type SpawnRequest struct {
+	// SpawnID is the ID the parent agent uses to wait for the result.
+	SpawnID string `json:"spawnId"`
+
 	// ParentRunName is the name of the parent AgentRun.
 	ParentRunName string `json:"parentRunName"`
 
@@ -50,8 +53,23 @@ type SpawnRequest struct {
 
 	// Skills to mount.
 	Skills []sympoziumv1alpha1.SkillRef `json:"skills,omitempty"`
+
+	// ToolPolicy is inherited from the parent so a sub-agent can never use
+	// tools its parent could not.
+	ToolPolicy *sympoziumv1alpha1.ToolPolicySpec `json:"toolPolicy,omitempty"`
+
+	// CallChain is the parent's CallChainAnnotation. A sub-agent of a run
+	// started by another agent stays in that call chain.
+	CallChain string `json:"callChain,omitempty"`
 }
 
+// SpawnIDAnnotation records the parent's spawn ID on a sub-agent AgentRun.
+const SpawnIDAnnotation = "sympozium.ai/spawn-id"
+
+// CallChainAnnotation lists every "<instance>/<run>" of the agent-to-agent
+// calls that led to a run, oldest first, separated by commas.
+const CallChainAnnotation = "sympozium.ai/call-chain"
+
 // SpawnResult is the result of a spawn operation.
 type SpawnResult struct {
 	// RunName is the name of the created AgentRun.
@@ -69,7 +87,8 @@ func (s *Spawner) Spawn(ctx context.Context, req SpawnRequest) (*SpawnResult, er
 		"depth", req.CurrentDepth+1,
 	)
 
-	runName := fmt.Sprintf("sub-%s-%d", req.ParentRunName, req.CurrentDepth+1)
+	// The name grows with each level, so deep sub-agents get a shortened one.
+	runName := sympoziumv1alpha1.ShortenRunName(fmt.Sprintf("sub-%s-%s", req.ParentRunName, req.SpawnID))
 	sessionKey := fmt.Sprintf("%s:sub:%s", req.ParentSessionKey, runName)
 
 	log.Info("Spawning sub-agent", "runName", runName)
@@ -84,6 +103,9 @@ func (s *Spawner) Spawn(ctx context.Context, req SpawnRequest) (*SpawnResult, er
 				"sympozium.ai/parent-run": req.ParentRunName,
 				"sympozium.ai/component":  "agent-run",
 			},
+			Annotations: map[string]string{
+				SpawnIDAnnotation: req.SpawnID,
+			},
 		},
 		Spec: sympoziumv1alpha1.AgentRunSpec{
 			InstanceRef: req.InstanceName,
@@ -98,10 +120,15 @@ func (s *Spawner) Spawn(ctx context.Context, req SpawnRequest) (*SpawnResult, er
 			SystemPrompt: req.SystemPrompt,
 			Model:        req.Model,
 			Skills:       req.Skills,
+			ToolPolicy:   req.ToolPolicy,
 			Cleanup:      "delete",
 		},
 	}
 
+	if req.CallChain != "" {
+		agentRun.Annotations[CallChainAnnotation] = req.CallChain
+	}
+
 	if err := s.Client.Create(ctx, agentRun); err != nil {
 		return &SpawnResult{Error: err.Error()}, err
 	}
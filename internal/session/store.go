@@ -5,32 +5,64 @@ package session
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-// Session represents an active conversation session.
+// Session represents a conversation, such as one channel chat or thread.
 type Session struct {
 	ID           string            `json:"id"`
 	InstanceName string            `json:"instanceName"`
-	ChannelType  string            `json:"channelType"`
-	ChatID       string            `json:"chatId"`
+	Namespace    string            `json:"namespace"`
+	SessionKey   string            `json:"sessionKey"`
+	ChannelType  string            `json:"channelType,omitempty"`
+	SenderID     string            `json:"senderId,omitempty"`
 	Metadata     map[string]string `json:"metadata,omitempty"`
 	CreatedAt    time.Time         `json:"createdAt"`
 	UpdatedAt    time.Time         `json:"updatedAt"`
 }
 
+// Transcript event types.
+const (
+	EventUserMessage  = "user_message"
+	EventAgentMessage = "agent_message"
+	EventToolCall     = "tool_call"
+	EventToolResult   = "tool_result"
+	EventError        = "error"
+	// EventReset starts a new conversation in the session; earlier events
+	// are not replayed.
+	EventReset = "reset"
+)
+
 // TranscriptEvent represents a single event in the conversation transcript.
 type TranscriptEvent struct {
-	ID        string          `json:"id"`
-	SessionID string          `json:"sessionId"`
-	Role      string          `json:"role"` // user, assistant, system, tool
-	Content   string          `json:"content"`
-	ToolName  string          `json:"toolName,omitempty"`
-	ToolInput json.RawMessage `json:"toolInput,omitempty"`
-	Timestamp time.Time       `json:"timestamp"`
+	ID           string          `json:"id"`
+	SessionID    string          `json:"sessionId"`
+	Sequence     int64           `json:"sequence"`
+	EventType    string          `json:"eventType"`
+	Role         string          `json:"role"` // user, assistant, system, tool
+	Content      string          `json:"content"`
+	ToolName     string          `json:"toolName,omitempty"`
+	ToolInput    json.RawMessage `json:"toolInput,omitempty"`
+	AgentRunName string          `json:"agentRunName,omitempty"`
+	Model        string          `json:"model,omitempty"`
+	TokensUsed   int             `json:"tokensUsed,omitempty"`
+	Timestamp    time.Time       `json:"timestamp"`
+}
+
+// ChannelKey returns the session key of a channel conversation. Messages in
+// the same chat and thread share a session.
+func ChannelKey(channel, chatID, threadID string) string {
+	parts := []string{"channel", channel, chatID}
+	if threadID != "" {
+		parts = append(parts, threadID)
+	}
+	return strings.Join(parts, ":")
 }
 
 // Store provides session and transcript persistence using PostgreSQL.
@@ -58,64 +90,54 @@ func (s *Store) Close() {
 	s.pool.Close()
 }
 
-// CreateSession creates a new session.
-func (s *Store) CreateSession(ctx context.Context, sess *Session) error {
-	now := time.Now()
-	sess.CreatedAt = now
-	sess.UpdatedAt = now
-
+// GetOrCreateSession returns the session with sess's instance, namespace
+// and key, creating it from sess if there is none. sess is filled in from
+// the stored row.
+func (s *Store) GetOrCreateSession(ctx context.Context, sess *Session) error {
+	if sess.Namespace == "" {
+		sess.Namespace = "default"
+	}
 	metadataJSON, err := json.Marshal(sess.Metadata)
 	if err != nil {
 		return fmt.Errorf("marshalling metadata: %w", err)
 	}
 
-	_, err = s.pool.Exec(ctx,
-		`INSERT INTO sessions (id, instance_name, channel_type, chat_id, metadata, created_at, updated_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
-		sess.ID, sess.InstanceName, sess.ChannelType, sess.ChatID, metadataJSON, sess.CreatedAt, sess.UpdatedAt,
-	)
+	var channelType, senderID *string
+	err = s.pool.QueryRow(ctx,
+		`INSERT INTO sessions (instance_name, namespace, session_key, channel_type, sender_id, metadata)
+		 VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6)
+		 ON CONFLICT (instance_name, namespace, session_key) DO UPDATE SET updated_at = NOW()
+		 RETURNING id, channel_type, sender_id, metadata, created_at, updated_at`,
+		sess.InstanceName, sess.Namespace, sess.SessionKey, sess.ChannelType, sess.SenderID, metadataJSON,
+	).Scan(&sess.ID, &channelType, &senderID, &metadataJSON, &sess.CreatedAt, &sess.UpdatedAt)
 	if err != nil {
-		return fmt.Errorf("inserting session: %w", err)
-	}
-
-	return nil
-}
-
-// GetSession retrieves a session by ID.
-func (s *Store) GetSession(ctx context.Context, id string) (*Session, error) {
-	sess := &Session{}
-	var metadataJSON []byte
-
-	err := s.pool.QueryRow(ctx,
-		`SELECT id, instance_name, channel_type, chat_id, metadata, created_at, updated_at
-		 FROM sessions WHERE id = $1`, id,
-	).Scan(&sess.ID, &sess.InstanceName, &sess.ChannelType, &sess.ChatID, &metadataJSON, &sess.CreatedAt, &sess.UpdatedAt)
-	if err != nil {
-		return nil, fmt.Errorf("querying session: %w", err)
+		return fmt.Errorf("upserting session: %w", err)
 	}
+	sess.ChannelType, sess.SenderID = deref(channelType), deref(senderID)
 
 	if metadataJSON != nil {
 		if err := json.Unmarshal(metadataJSON, &sess.Metadata); err != nil {
-			return nil, fmt.Errorf("unmarshalling metadata: %w", err)
+			return fmt.Errorf("unmarshalling metadata: %w", err)
 		}
 	}
-
-	return sess, nil
+	return nil
 }
 
-// FindSessionByChatID looks up a session by channel type and chat ID.
-func (s *Store) FindSessionByChatID(ctx context.Context, channelType, chatID string) (*Session, error) {
-	sess := &Session{}
+// FindSession looks up a session by instance, namespace and key.
+func (s *Store) FindSession(ctx context.Context, instanceName, namespace, sessionKey string) (*Session, error) {
+	sess := &Session{InstanceName: instanceName, Namespace: namespace, SessionKey: sessionKey}
+	var channelType, senderID *string
 	var metadataJSON []byte
 
 	err := s.pool.QueryRow(ctx,
-		`SELECT id, instance_name, channel_type, chat_id, metadata, created_at, updated_at
-		 FROM sessions WHERE channel_type = $1 AND chat_id = $2
-		 ORDER BY updated_at DESC LIMIT 1`, channelType, chatID,
-	).Scan(&sess.ID, &sess.InstanceName, &sess.ChannelType, &sess.ChatID, &metadataJSON, &sess.CreatedAt, &sess.UpdatedAt)
+		`SELECT id, channel_type, sender_id, metadata, created_at, updated_at
+		 FROM sessions WHERE instance_name = $1 AND namespace = $2 AND session_key = $3`,
+		instanceName, namespace, sessionKey,
+	).Scan(&sess.ID, &channelType, &senderID, &metadataJSON, &sess.CreatedAt, &sess.UpdatedAt)
 	if err != nil {
-		return nil, fmt.Errorf("querying session by chat: %w", err)
+		return nil, fmt.Errorf("querying session: %w", err)
 	}
+	sess.ChannelType, sess.SenderID = deref(channelType), deref(senderID)
 
 	if metadataJSON != nil {
 		if err := json.Unmarshal(metadataJSON, &sess.Metadata); err != nil {
@@ -126,16 +148,37 @@ func (s *Store) FindSessionByChatID(ctx context.Context, channelType, chatID str
 	return sess, nil
 }
 
-// AppendTranscript appends an event to the session transcript.
+// appendAttempts bounds how often AppendTranscript renumbers an event
+// whose sequence number another writer took first.
+const appendAttempts = 5
+
+// AppendTranscript appends an event to the session transcript, numbering it
+// after the session's last event.
 func (s *Store) AppendTranscript(ctx context.Context, event *TranscriptEvent) error {
 	event.Timestamp = time.Now()
 
-	_, err := s.pool.Exec(ctx,
-		`INSERT INTO transcript_events (id, session_id, role, content, tool_name, tool_input, created_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
-		event.ID, event.SessionID, event.Role, event.Content,
-		event.ToolName, event.ToolInput, event.Timestamp,
-	)
+	var toolInput any
+	if len(event.ToolInput) > 0 && json.Valid(event.ToolInput) {
+		toolInput = event.ToolInput
+	}
+
+	// The channel and transcript routers write to the same session, so two
+	// inserts can pick the same number; the unique constraint rejects one.
+	var err error
+	for range appendAttempts {
+		err = s.pool.QueryRow(ctx,
+			`INSERT INTO transcript_events
+			   (session_id, sequence_num, event_type, role, content, tool_name, tool_input, agent_run_name, model, tokens_used, created_at)
+			 SELECT $1, COALESCE(MAX(sequence_num), 0) + 1, $2, $3, $4, NULLIF($5, ''), $6, NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, 0), $10
+			 FROM transcript_events WHERE session_id = $1
+			 RETURNING id, sequence_num`,
+			event.SessionID, event.EventType, event.Role, event.Content, event.ToolName, toolInput,
+			event.AgentRunName, event.Model, event.TokensUsed, event.Timestamp,
+		).Scan(&event.ID, &event.Sequence)
+		if !isUniqueViolation(err) {
+			break
+		}
+	}
 	if err != nil {
 		return fmt.Errorf("inserting transcript event: %w", err)
 	}
@@ -150,12 +193,26 @@ func (s *Store) AppendTranscript(ctx context.Context, event *TranscriptEvent) er
 	return nil
 }
 
-// GetTranscript retrieves all transcript events for a session, ordered by time.
+// GetTranscript retrieves all transcript events for a session, in order.
 func (s *Store) GetTranscript(ctx context.Context, sessionID string) ([]TranscriptEvent, error) {
-	rows, err := s.pool.Query(ctx,
-		`SELECT id, session_id, role, content, tool_name, tool_input, created_at
-		 FROM transcript_events WHERE session_id = $1 ORDER BY created_at ASC`, sessionID,
-	)
+	return s.queryTranscript(ctx,
+		`SELECT id, session_id, sequence_num, event_type, role, COALESCE(content, ''), COALESCE(tool_name, ''),
+		        tool_input, COALESCE(agent_run_name, ''), COALESCE(model, ''), COALESCE(tokens_used, 0), created_at
+		 FROM transcript_events WHERE session_id = $1 ORDER BY sequence_num ASC`, sessionID)
+}
+
+// RecentTranscript retrieves the last limit events of a session, in order.
+func (s *Store) RecentTranscript(ctx context.Context, sessionID string, limit int) ([]TranscriptEvent, error) {
+	return s.queryTranscript(ctx,
+		`SELECT * FROM (
+		   SELECT id, session_id, sequence_num, event_type, role, COALESCE(content, ''), COALESCE(tool_name, ''),
+		          tool_input, COALESCE(agent_run_name, ''), COALESCE(model, ''), COALESCE(tokens_used, 0), created_at
+		   FROM transcript_events WHERE session_id = $1 ORDER BY sequence_num DESC LIMIT $2
+		 ) recent ORDER BY sequence_num ASC`, sessionID, limit)
+}
+
+func (s *Store) queryTranscript(ctx context.Context, query string, args ...any) ([]TranscriptEvent, error) {
+	rows, err := s.pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("querying transcript: %w", err)
 	}
@@ -164,43 +221,25 @@ func (s *Store) GetTranscript(ctx context.Context, sessionID string) ([]Transcri
 	var events []TranscriptEvent
 	for rows.Next() {
 		var e TranscriptEvent
-		if err := rows.Scan(&e.ID, &e.SessionID, &e.Role, &e.Content, &e.ToolName, &e.ToolInput, &e.Timestamp); err != nil {
+		if err := rows.Scan(&e.ID, &e.SessionID, &e.Sequence, &e.EventType, &e.Role, &e.Content, &e.ToolName,
+			&e.ToolInput, &e.AgentRunName, &e.Model, &e.TokensUsed, &e.Timestamp); err != nil {
 			return nil, fmt.Errorf("scanning transcript event: %w", err)
 		}
 		events = append(events, e)
 	}
 
-	return events, nil
-}
-
-// Manager provides higher-level session management operations.
-type Manager struct {
-	store *Store
-}
-
-// NewManager creates a new session Manager.
-func NewManager(store *Store) *Manager {
-	return &Manager{store: store}
+	return events, rows.Err()
 }
 
-// GetOrCreateSession finds an existing session or creates a new one.
-func (m *Manager) GetOrCreateSession(ctx context.Context, instanceName, channelType, chatID string) (*Session, error) {
-	sess, err := m.store.FindSessionByChatID(ctx, channelType, chatID)
-	if err == nil {
-		return sess, nil
-	}
-
-	// Create new session
-	newSess := &Session{
-		ID:           fmt.Sprintf("sess-%s-%s-%d", channelType, chatID, time.Now().UnixNano()),
-		InstanceName: instanceName,
-		ChannelType:  channelType,
-		ChatID:       chatID,
-	}
-
-	if err := m.store.CreateSession(ctx, newSess); err != nil {
-		return nil, err
+func deref(s *string) string {
+	if s == nil {
+		return ""
 	}
+	return *s
+}
 
-	return newSess, nil
+// isUniqueViolation reports whether err is a PostgreSQL unique_violation.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
 }
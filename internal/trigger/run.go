@@ -0,0 +1,126 @@
+package trigger
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	sympoziumv1alpha1 "github.com/alexsjones/sympozium/api/v1alpha1"
+)
+
+// NewRun builds the AgentRun a trigger starts for task, with the model
+// settings of the trigger's instance. A non-empty dedupeHash is set as the
+// run's sympozium.ai/dedupe-key label and names the run (see RunName), so
+// creating a second run for the same key within window fails with
+// AlreadyExists.
+func NewRun(ctx context.Context, c client.Client, t *sympoziumv1alpha1.SympoziumTrigger, task, dedupeHash string, window *metav1.Duration) (*sympoziumv1alpha1.AgentRun, error) {
+	var inst sympoziumv1alpha1.SympoziumInstance
+	if err := c.Get(ctx, types.NamespacedName{Name: t.Spec.InstanceRef, Namespace: t.Namespace}, &inst); err != nil {
+		return nil, fmt.Errorf("instance %q: %w", t.Spec.InstanceRef, err)
+	}
+
+	if t.Spec.IncludeMemory {
+		var cm corev1.ConfigMap
+		if err := c.Get(ctx, types.NamespacedName{Name: t.Spec.InstanceRef + "-memory", Namespace: t.Namespace}, &cm); err == nil {
+			if memory := cm.Data["MEMORY.md"]; memory != "" {
+				task = fmt.Sprintf("## Memory Context\n%s\n\n## Task\n%s", memory, task)
+			}
+		}
+	}
+
+	authSecret := ""
+	if len(inst.Spec.AuthRefs) > 0 {
+		authSecret = inst.Spec.AuthRefs[0].Secret
+	}
+	if authSecret == "" {
+		return nil, fmt.Errorf("instance %q has no API key configured (authRefs is empty)", inst.Name)
+	}
+
+	run := &sympoziumv1alpha1.AgentRun{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: t.Name + "-",
+			Namespace:    t.Namespace,
+			Labels: map[string]string{
+				"sympozium.ai/instance": t.Spec.InstanceRef,
+				"sympozium.ai/source":   "trigger",
+				"sympozium.ai/trigger":  t.Name,
+			},
+		},
+		Spec: sympoziumv1alpha1.AgentRunSpec{
+			InstanceRef: t.Spec.InstanceRef,
+			AgentID:     "trigger-" + t.Name,
+			SessionKey:  fmt.Sprintf("trigger-%s-%d", t.Name, time.Now().UnixNano()),
+			Task:        task,
+			Model: sympoziumv1alpha1.ModelSpec{
+				Provider:      inst.Provider(),
+				Model:         inst.Spec.Agents.Default.Model,
+				BaseURL:       inst.Spec.Agents.Default.BaseURL,
+				Thinking:      inst.Spec.Agents.Default.Thinking,
+				AuthSecretRef: authSecret,
+				Fallbacks:     inst.Spec.Agents.Fallbacks,
+			},
+			Skills: inst.Spec.Skills,
+		},
+	}
+	if dedupeHash != "" {
+		run.Name = RunName(t.Name, dedupeHash, window, time.Now())
+		run.GenerateName = ""
+		run.Labels["sympozium.ai/dedupe-key"] = dedupeHash
+	}
+	// The channel router sends the result of runs with reply annotations
+	// to the chat they name.
+	if d := t.Spec.Deliver; d != nil {
+		run.Annotations = map[string]string{
+			"sympozium.ai/reply-channel":   d.Channel,
+			"sympozium.ai/reply-chat-id":   d.ChatID,
+			"sympozium.ai/reply-thread-id": d.ThreadID,
+		}
+	}
+	if err := controllerutil.SetControllerReference(t, run, c.Scheme()); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// RecentRun returns the newest run of t labelled with dedupeHash that was
+// created within window, or within the run's lifetime if window is nil.
+func RecentRun(ctx context.Context, c client.Reader, t *sympoziumv1alpha1.SympoziumTrigger, dedupeHash string, window *metav1.Duration) (string, error) {
+	var runs sympoziumv1alpha1.AgentRunList
+	if err := c.List(ctx, &runs, client.InNamespace(t.Namespace), client.MatchingLabels{
+		"sympozium.ai/trigger":    t.Name,
+		"sympozium.ai/dedupe-key": dedupeHash,
+	}); err != nil {
+		return "", err
+	}
+	newest := ""
+	var newestTime time.Time
+	for _, run := range runs.Items {
+		created := run.CreationTimestamp.Time
+		if window != nil && time.Since(created) >= window.Duration {
+			continue
+		}
+		if newest == "" || created.After(newestTime) {
+			newest, newestTime = run.Name, created
+		}
+	}
+	return newest, nil
+}
+
+// RunName names the run of trigger for dedupeHash. Within a window the name
+// is the same, so concurrent deliveries of the same key cannot both create
+// a run; without a window it never changes. Names of long triggers are
+// shortened to fit a Job.
+func RunName(trigger, dedupeHash string, window *metav1.Duration, now time.Time) string {
+	name := trigger + "-" + dedupeHash[:10]
+	if window != nil && window.Duration > 0 {
+		name += "-" + strconv.FormatInt(now.UnixNano()/int64(window.Duration), 36)
+	}
+	return sympoziumv1alpha1.ShortenRunName(name)
+}
@@ -0,0 +1,140 @@
+// Package trigger verifies and renders the webhook deliveries and watched
+// objects that start the AgentRuns of SympoziumTriggers, and builds the runs.
+package trigger
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+
+	sympoziumv1alpha1 "github.com/alexsjones/sympozium/api/v1alpha1"
+)
+
+// SecretKey is the key of a trigger's Secret that holds the HMAC key or
+// bearer token.
+const SecretKey = "WEBHOOK_SECRET"
+
+// MaxPayloadBytes caps the body of a delivery.
+const MaxPayloadBytes = 1 << 20
+
+// Auth types.
+const (
+	AuthHMAC   = "hmac"
+	AuthBearer = "bearer"
+)
+
+// ErrUnauthorized is returned for deliveries that fail verification.
+var ErrUnauthorized = errors.New("signature or token missing or invalid")
+
+// Path returns the API server path a trigger receives deliveries on.
+func Path(namespace, name string) string {
+	return "/webhooks/" + namespace + "/" + name
+}
+
+// Verify checks that a delivery was signed with, or carries, secret.
+func Verify(auth sympoziumv1alpha1.TriggerAuth, secret []byte, header http.Header, body []byte) error {
+	name, prefix := auth.Header, auth.Prefix
+	if name == "" {
+		switch auth.Type {
+		case AuthHMAC:
+			name, prefix = "X-Hub-Signature-256", "sha256="
+		case AuthBearer:
+			name, prefix = "Authorization", "Bearer "
+		}
+	}
+	value, ok := strings.CutPrefix(header.Get(name), prefix)
+	if !ok || value == "" {
+		return ErrUnauthorized
+	}
+
+	switch auth.Type {
+	case AuthHMAC:
+		sig, err := hex.DecodeString(value)
+		if err != nil {
+			return ErrUnauthorized
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(body)
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return ErrUnauthorized
+		}
+	case AuthBearer:
+		if subtle.ConstantTimeCompare([]byte(value), secret) != 1 {
+			return ErrUnauthorized
+		}
+	default:
+		return fmt.Errorf("unknown auth type %q", auth.Type)
+	}
+	return nil
+}
+
+// Delivery is a verified webhook request or a watched object, as the
+// templates see it.
+type Delivery struct {
+	// Payload is the decoded JSON body, or the body as a string if it is
+	// not JSON. For watch triggers it is the object.
+	Payload any
+
+	// Reason says why a watch trigger matched the object.
+	Reason string
+
+	header http.Header
+}
+
+// NewDelivery decodes a delivery's body.
+func NewDelivery(header http.Header, body []byte) Delivery {
+	d := Delivery{header: header}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&d.Payload); err != nil {
+		d.Payload = string(body)
+	}
+	return d
+}
+
+// Parse parses a task or dedupe key template, so a trigger can be checked
+// before its first delivery.
+func Parse(name, text string) (*template.Template, error) {
+	return newTemplate(name, nil).Parse(text)
+}
+
+// Render renders a task or dedupe key template over d. Fields missing from
+// the payload render as empty text, so a key made from them is empty too.
+func Render(name, text string, d Delivery) (string, error) {
+	t, err := newTemplate(name, d.header).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := t.Execute(&sb, d); err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(sb.String(), "<no value>", ""), nil
+}
+
+// newTemplate returns a template with the functions triggers can use.
+func newTemplate(name string, header http.Header) *template.Template {
+	return template.New(name).Funcs(template.FuncMap{
+		"header": func(key string) string {
+			return header.Get(key)
+		},
+		"json": func(v any) (string, error) {
+			b, err := json.MarshalIndent(v, "", "  ")
+			return string(b), err
+		},
+	})
+}
+
+// DedupeHash shortens a dedupe key so it fits in a label value.
+func DedupeHash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:16])
+}
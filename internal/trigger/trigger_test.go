@@ -0,0 +1,366 @@
+package trigger
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sympoziumv1alpha1 "github.com/alexsjones/sympozium/api/v1alpha1"
+)
+
+func TestVerify(t *testing.T) {
+	secret := []byte("s3cret")
+	body := []byte(`{"action":"opened"}`)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	hmacAuth := sympoziumv1alpha1.TriggerAuth{Type: AuthHMAC}
+	bearerAuth := sympoziumv1alpha1.TriggerAuth{Type: AuthBearer}
+	customAuth := sympoziumv1alpha1.TriggerAuth{Type: AuthBearer, Header: "X-Token"}
+
+	tests := []struct {
+		name   string
+		auth   sympoziumv1alpha1.TriggerAuth
+		header http.Header
+		body   []byte
+		ok     bool
+	}{
+		{"github signature", hmacAuth, http.Header{"X-Hub-Signature-256": {signature}}, body, true},
+		{"tampered body", hmacAuth, http.Header{"X-Hub-Signature-256": {signature}}, []byte(`{"action":"closed"}`), false},
+		{"missing signature", hmacAuth, http.Header{}, body, false},
+		{"signature without prefix", hmacAuth, http.Header{"X-Hub-Signature-256": {signature[len("sha256="):]}}, body, false},
+		{"bearer token", bearerAuth, http.Header{"Authorization": {"Bearer s3cret"}}, body, true},
+		{"wrong bearer token", bearerAuth, http.Header{"Authorization": {"Bearer nope"}}, body, false},
+		{"custom header without prefix", customAuth, http.Header{"X-Token": {"s3cret"}}, body, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.auth, secret, tt.header, tt.body)
+			if tt.ok && err != nil {
+				t.Fatalf("Verify() = %v, want nil", err)
+			}
+			if !tt.ok && err == nil {
+				t.Fatal("Verify() = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	header := http.Header{"X-Github-Event": {"issues"}}
+	d := NewDelivery(header, []byte(`{"issue":{"number":42,"title":"Crash on start"},"labels":["bug"]}`))
+
+	got, err := Render("task", `{{ header "X-GitHub-Event" }} #{{ .Payload.issue.number }}: {{ .Payload.issue.title }} {{ json .Payload.labels }}`, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "issues #42: Crash on start [\n  \"bug\"\n]"
+	if got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+
+	if got, _ := Render("dedupeKey", "{{ .Payload.missing }}", d); got != "" {
+		t.Errorf("Render() of a missing field = %q, want empty", got)
+	}
+
+	raw := NewDelivery(header, []byte("not json"))
+	if got, _ := Render("task", "{{ .Payload }}", raw); got != "not json" {
+		t.Errorf("Render() of a non-JSON payload = %q, want the body", got)
+	}
+
+	if _, err := Parse("task", "{{ .Payload.x "); err == nil {
+		t.Error("Parse() of a broken template succeeded")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	recent, old := metav1.NewTime(now.Add(-5*time.Minute)), metav1.NewTime(now.Add(-2*time.Hour))
+	terminated := func(at metav1.Time) corev1.ContainerState {
+		return corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, FinishedAt: at}}
+	}
+	crashing := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-0", Namespace: "prod"},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+			{Name: "sidecar", RestartCount: 1, LastTerminationState: terminated(recent)},
+			{Name: "web", RestartCount: 5, State: corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"},
+			}, LastTerminationState: terminated(recent)},
+		}},
+	}
+	recovered := crashing.DeepCopy()
+	for i := range recovered.Status.ContainerStatuses {
+		recovered.Status.ContainerStatuses[i].LastTerminationState = terminated(old)
+	}
+	notReady := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-1"},
+		Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+			{Type: corev1.NodeReady, Status: corev1.ConditionUnknown, Reason: "NodeStatusUnknown", LastTransitionTime: recent},
+		}},
+	}
+	failed := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "prod"},
+		Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+			{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded", LastTransitionTime: recent},
+		}},
+	}
+	failedLongAgo := failed.DeepCopy()
+	failedLongAgo.Status.Conditions[0].LastTransitionTime = old
+	backoff := &corev1.Event{
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Namespace: "prod", Name: "web-0"},
+		Type:           corev1.EventTypeWarning,
+		Reason:         "BackOff",
+		Count:          4,
+		LastTimestamp:  recent,
+	}
+	oldBackoff := backoff.DeepCopy()
+	oldBackoff.LastTimestamp = old
+
+	watch := func(condition string, threshold int32, reasons ...string) *sympoziumv1alpha1.TriggerWatch {
+		return &sympoziumv1alpha1.TriggerWatch{Condition: condition, Threshold: threshold, Reasons: reasons}
+	}
+	tests := []struct {
+		name  string
+		watch *sympoziumv1alpha1.TriggerWatch
+		obj   client.Object
+		want  string
+	}{
+		{"restarts over default threshold", watch(sympoziumv1alpha1.WatchPodRestarts, 0), crashing,
+			`Pod prod/web-0: container "web" restarted 5 times (CrashLoopBackOff), last exit code 1`},
+		{"restarts under threshold", watch(sympoziumv1alpha1.WatchPodRestarts, 10), crashing, ""},
+		{"no restart within the cooldown", watch(sympoziumv1alpha1.WatchPodRestarts, 0), recovered, ""},
+		{"node not ready", watch(sympoziumv1alpha1.WatchNodeNotReady, 0), notReady, "Node worker-1 is NotReady: NodeStatusUnknown"},
+		{"failed job", watch(sympoziumv1alpha1.WatchJobFailed, 0), failed, "Job prod/backup failed: BackoffLimitExceeded"},
+		{"job failed before the cooldown", watch(sympoziumv1alpha1.WatchJobFailed, 0), failedLongAgo, ""},
+		{"warning event", watch(sympoziumv1alpha1.WatchEvent, 0), backoff, "Warning event BackOff on Pod prod/web-0 (x4): "},
+		{"event count under threshold", watch(sympoziumv1alpha1.WatchEvent, 5), backoff, ""},
+		{"event before the cooldown", watch(sympoziumv1alpha1.WatchEvent, 0), oldBackoff, ""},
+		{"event reason not listed", watch(sympoziumv1alpha1.WatchEvent, 0, "FailedScheduling"), backoff, ""},
+		{"condition for another kind", watch(sympoziumv1alpha1.WatchJobFailed, 0), crashing, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Match(tt.watch, tt.obj, now)
+			if ok != (tt.want != "") || got != tt.want {
+				t.Errorf("Match() = %q, %v, want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatches(t *testing.T) {
+	tr := &sympoziumv1alpha1.SympoziumTrigger{
+		ObjectMeta: metav1.ObjectMeta{Name: "crashes", Namespace: "sre"},
+		Spec: sympoziumv1alpha1.SympoziumTriggerSpec{Watch: &sympoziumv1alpha1.TriggerWatch{
+			Condition: sympoziumv1alpha1.WatchPodRestarts,
+			Selector:  &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+		}},
+	}
+	pod := func(ns string, labels map[string]string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p", Namespace: ns, Labels: labels}}
+	}
+	web := map[string]string{"app": "web"}
+
+	if !Watches(tr, pod("sre", web), "sympozium-system") {
+		t.Error("pod in the trigger's namespace was not watched")
+	}
+	if Watches(tr, pod("prod", web), "sympozium-system") {
+		t.Error("pod in another namespace was watched")
+	}
+	if Watches(tr, pod("sre", map[string]string{"app": "db"}), "sympozium-system") {
+		t.Error("pod not matching the selector was watched")
+	}
+	tr.Spec.Watch.Namespaces = []string{"*"}
+	if Watches(tr, pod("prod", web), "sympozium-system") {
+		t.Error(`pod in another namespace was watched by a trigger outside the system namespace`)
+	}
+	tr.Namespace = "sympozium-system"
+	if !Watches(tr, pod("prod", web), "sympozium-system") {
+		t.Error(`pod was not watched with namespaces ["*"]`)
+	}
+}
+
+func TestRecordFiring(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	status := &sympoziumv1alpha1.SympoziumTriggerStatus{WatchFirings: []sympoziumv1alpha1.WatchFiring{
+		{Object: "Pod/prod/web-0", Time: metav1.NewTime(now.Add(-time.Hour))},
+		{Object: "Pod/prod/web-1", Time: metav1.NewTime(now.Add(-10 * time.Minute))},
+	}}
+
+	RecordFiring(status, "Pod/prod/web-1", now, 30*time.Minute)
+	if len(status.WatchFirings) != 1 {
+		t.Fatalf("WatchFirings = %+v, want only web-1", status.WatchFirings)
+	}
+	if got := LastFired(status, "Pod/prod/web-1"); !got.Equal(now) {
+		t.Errorf("LastFired(web-1) = %s, want %s", got, now)
+	}
+	if got := LastFired(status, "Pod/prod/web-0"); !got.IsZero() {
+		t.Errorf("LastFired(web-0) = %s, want zero", got)
+	}
+
+	for i := range MaxWatchFirings + 5 {
+		RecordFiring(status, fmt.Sprintf("Pod/prod/p-%d", i), now.Add(time.Duration(i)*time.Second), time.Hour)
+	}
+	if len(status.WatchFirings) != MaxWatchFirings || LastFired(status, "Pod/prod/web-1").After(time.Time{}) {
+		t.Errorf("WatchFirings has %d entries, want the newest %d", len(status.WatchFirings), MaxWatchFirings)
+	}
+}
+
+func TestRelatedEvents(t *testing.T) {
+	at := func(min int) metav1.Time {
+		return metav1.NewTime(time.Date(2026, 1, 1, 0, min, 0, 0, time.UTC))
+	}
+	var events []corev1.Event
+	for i := range MaxContextEvents + 2 {
+		events = append(events, corev1.Event{
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Namespace: "prod", Name: "web-0"},
+			LastTimestamp:  at(i),
+		})
+	}
+	events = append(events, corev1.Event{
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Namespace: "prod", Name: "web-1"},
+		LastTimestamp:  at(59),
+	})
+
+	got := RelatedEvents(events, "Pod", "prod", "web-0")
+	if len(got) != MaxContextEvents {
+		t.Fatalf("RelatedEvents() returned %d events, want %d", len(got), MaxContextEvents)
+	}
+	if got[0].InvolvedObject.Name != "web-0" || !got[0].LastTimestamp.Equal(&events[MaxContextEvents+1].LastTimestamp) {
+		t.Errorf("RelatedEvents()[0] = %v, want the newest web-0 event", got[0])
+	}
+}
+
+func TestContext_Strips(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "web-0", Namespace: "prod",
+			Annotations: map[string]string{
+				"kubectl.kubernetes.io/last-applied-configuration": `{"spec":{}}`,
+				"team": "web",
+			},
+			ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl"}},
+		},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "init", Env: []corev1.EnvVar{{Name: "TOKEN", Value: "init-secret"}}}},
+			Containers: []corev1.Container{{Name: "web", Env: []corev1.EnvVar{
+				{Name: "DB_PASSWORD", Value: "hunter2"},
+				{Name: "API_KEY", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "api"}, Key: "key",
+				}}},
+			}}},
+		},
+	}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "prod"},
+		Spec: batchv1.JobSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "backup", Env: []corev1.EnvVar{{Name: "S3_SECRET", Value: "job-secret"}}}},
+		}}},
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-1"},
+		Status:     corev1.NodeStatus{Images: []corev1.ContainerImage{{Names: []string{"registry.example/huge-image:v1"}}}},
+	}
+
+	for _, obj := range []client.Object{pod, job, node} {
+		got, err := Context(obj, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, leaked := range []string{"last-applied-configuration", "managedFields", "init-secret", "hunter2", "job-secret", "huge-image"} {
+			if strings.Contains(got, leaked) {
+				t.Errorf("Context(%s) contains %q:\n%s", obj.GetName(), leaked, got)
+			}
+		}
+	}
+
+	got, _ := Context(pod, nil)
+	for _, kept := range []string{`"team": "web"`, `"DB_PASSWORD"`, `"value": "[redacted]"`, `"secretKeyRef"`} {
+		if !strings.Contains(got, kept) {
+			t.Errorf("Context(web-0) is missing %s:\n%s", kept, got)
+		}
+	}
+	if pod.Spec.Containers[0].Env[0].Value != "hunter2" {
+		t.Error("Context modified the watched object")
+	}
+
+	d, err := NewWatchDelivery(pod, "restarting")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := json.Marshal(d.Payload); strings.Contains(string(b), "hunter2") {
+		t.Errorf("delivery payload contains an env value: %s", b)
+	}
+}
+
+// runLister lists fixed AgentRuns.
+type runLister struct {
+	client.Reader
+	runs []sympoziumv1alpha1.AgentRun
+}
+
+func (l runLister) List(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+	list.(*sympoziumv1alpha1.AgentRunList).Items = l.runs
+	return nil
+}
+
+func TestRecentRun(t *testing.T) {
+	run := func(name string, age time.Duration) sympoziumv1alpha1.AgentRun {
+		return sympoziumv1alpha1.AgentRun{ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+		}}
+	}
+	tr := &sympoziumv1alpha1.SympoziumTrigger{ObjectMeta: metav1.ObjectMeta{Name: "alerts", Namespace: "ops"}}
+	c := runLister{runs: []sympoziumv1alpha1.AgentRun{run("old", 3*time.Hour), run("new", time.Minute), run("mid", time.Hour)}}
+
+	if got, _ := RecentRun(context.Background(), c, tr, "h", nil); got != "new" {
+		t.Errorf("RecentRun() = %q, want the newest run", got)
+	}
+	if got, _ := RecentRun(context.Background(), c, tr, "h", &metav1.Duration{Duration: 30 * time.Second}); got != "" {
+		t.Errorf("RecentRun() within 30s = %q, want none", got)
+	}
+}
+
+func TestRunName(t *testing.T) {
+	hash := DedupeHash("delivery-1")
+	now := time.Date(2026, 1, 1, 12, 10, 0, 0, time.UTC)
+	if got := RunName("alerts", hash, nil, now); got != "alerts-"+hash[:10] {
+		t.Errorf("RunName() = %q", got)
+	}
+	window := &metav1.Duration{Duration: time.Hour}
+	if RunName("alerts", hash, window, now) != RunName("alerts", hash, window, now.Add(20*time.Minute)) {
+		t.Error("RunName() changed within the window")
+	}
+	if RunName("alerts", hash, window, now) == RunName("alerts", hash, window, now.Add(time.Hour)) {
+		t.Error("RunName() did not change after the window")
+	}
+
+	long := strings.Repeat("payments-", 6) + "alerts"
+	name := RunName(long, hash, window, now)
+	if len(name) > sympoziumv1alpha1.MaxRunNameLength {
+		t.Errorf("RunName() = %q, longer than %d", name, sympoziumv1alpha1.MaxRunNameLength)
+	}
+	if name != RunName(long, hash, window, now.Add(20*time.Minute)) {
+		t.Error("RunName() of a long trigger changed within the window")
+	}
+	if name == RunName(long, hash, window, now.Add(time.Hour)) {
+		t.Error("RunName() of a long trigger did not change after the window")
+	}
+	if name == RunName(long, strings.Repeat("b", 64), window, now) {
+		t.Error("RunName() of a long trigger is the same for different keys")
+	}
+}
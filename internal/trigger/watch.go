@@ -0,0 +1,325 @@
+package trigger
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sympoziumv1alpha1 "github.com/alexsjones/sympozium/api/v1alpha1"
+)
+
+// DefaultCooldown is the least time between two runs of a watch trigger for
+// the same object when the trigger does not set one.
+const DefaultCooldown = 30 * time.Minute
+
+// MaxContextEvents caps the events injected as context for a run.
+const MaxContextEvents = 10
+
+// MaxWatchFirings caps the firings kept in a trigger's status.
+const MaxWatchFirings = 100
+
+// Cooldown returns the least time between two runs for the same object.
+func Cooldown(w *sympoziumv1alpha1.TriggerWatch) time.Duration {
+	if w.Cooldown != nil && w.Cooldown.Duration > 0 {
+		return w.Cooldown.Duration
+	}
+	return DefaultCooldown
+}
+
+// Watches reports whether a watch trigger covers obj: its namespace is
+// watched and, except for Events, its labels match the selector. Only
+// triggers in systemNamespace watch namespaces other than their own.
+func Watches(t *sympoziumv1alpha1.SympoziumTrigger, obj client.Object, systemNamespace string) bool {
+	w := t.Spec.Watch
+	if ns := obj.GetNamespace(); ns != "" {
+		if ns != t.Namespace && t.Namespace != systemNamespace {
+			return false
+		}
+		if len(w.Namespaces) == 0 {
+			if ns != t.Namespace {
+				return false
+			}
+		} else if !slices.Contains(w.Namespaces, "*") && !slices.Contains(w.Namespaces, ns) {
+			return false
+		}
+	}
+	if w.Condition == sympoziumv1alpha1.WatchEvent || w.Selector == nil {
+		return true
+	}
+	sel, err := metav1.LabelSelectorAsSelector(w.Selector)
+	if err != nil {
+		return false
+	}
+	return sel.Matches(labels.Set(obj.GetLabels()))
+}
+
+// Match reports whether obj changed into the state a watch trigger's
+// condition describes within the trigger's cooldown before now, and if so
+// why. Objects that got there earlier, such as a pod that has not
+// restarted since, do not match.
+func Match(w *sympoziumv1alpha1.TriggerWatch, obj client.Object, now time.Time) (string, bool) {
+	since := now.Add(-Cooldown(w))
+	switch o := obj.(type) {
+	case *corev1.Pod:
+		if w.Condition != sympoziumv1alpha1.WatchPodRestarts {
+			return "", false
+		}
+		return matchPod(o, threshold(w, 3), since)
+	case *corev1.Node:
+		if w.Condition != sympoziumv1alpha1.WatchNodeNotReady {
+			return "", false
+		}
+		for _, c := range o.Status.Conditions {
+			if c.Type == corev1.NodeReady && c.Status != corev1.ConditionTrue && c.LastTransitionTime.Time.After(since) {
+				return fmt.Sprintf("Node %s is NotReady: %s", o.Name, describe(c.Reason, c.Message)), true
+			}
+		}
+	case *batchv1.Job:
+		if w.Condition != sympoziumv1alpha1.WatchJobFailed {
+			return "", false
+		}
+		for _, c := range o.Status.Conditions {
+			if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue && c.LastTransitionTime.Time.After(since) {
+				return fmt.Sprintf("Job %s/%s failed: %s", o.Namespace, o.Name, describe(c.Reason, c.Message)), true
+			}
+		}
+	case *corev1.Event:
+		if w.Condition != sympoziumv1alpha1.WatchEvent {
+			return "", false
+		}
+		if !eventTime(o).After(since) {
+			return "", false
+		}
+		if len(w.Reasons) == 0 && o.Type != corev1.EventTypeWarning {
+			return "", false
+		}
+		if len(w.Reasons) > 0 && !slices.Contains(w.Reasons, o.Reason) {
+			return "", false
+		}
+		count := eventCount(o)
+		if count < threshold(w, 1) {
+			return "", false
+		}
+		ref := o.InvolvedObject
+		return fmt.Sprintf("%s event %s on %s %s (x%d): %s", o.Type, o.Reason, ref.Kind,
+			ObjectKey("", ref.Namespace, ref.Name), count, o.Message), true
+	}
+	return "", false
+}
+
+// matchPod matches a pod with a container that restarted at least restarts
+// times, last after since. The most restarted such container is reported.
+func matchPod(pod *corev1.Pod, restarts int32, since time.Time) (string, bool) {
+	var worst *corev1.ContainerStatus
+	statuses := append(slices.Clone(pod.Status.InitContainerStatuses), pod.Status.ContainerStatuses...)
+	for i := range statuses {
+		st := &statuses[i]
+		if last := st.LastTerminationState.Terminated; last == nil || !last.FinishedAt.Time.After(since) {
+			continue
+		}
+		if worst == nil || st.RestartCount > worst.RestartCount {
+			worst = st
+		}
+	}
+	if worst == nil || worst.RestartCount < restarts {
+		return "", false
+	}
+	reason := fmt.Sprintf("Pod %s/%s: container %q restarted %d times", pod.Namespace, pod.Name, worst.Name, worst.RestartCount)
+	if s := worst.State.Waiting; s != nil && s.Reason != "" {
+		reason += " (" + s.Reason + ")"
+	}
+	if s := worst.LastTerminationState.Terminated; s != nil {
+		reason += fmt.Sprintf(", last exit code %d", s.ExitCode)
+		if s.Reason != "" {
+			reason += " (" + s.Reason + ")"
+		}
+	}
+	return reason, true
+}
+
+func threshold(w *sympoziumv1alpha1.TriggerWatch, def int32) int32 {
+	if w.Threshold > 0 {
+		return w.Threshold
+	}
+	return def
+}
+
+func describe(reason, message string) string {
+	switch {
+	case reason == "":
+		return message
+	case message == "":
+		return reason
+	}
+	return reason + ": " + message
+}
+
+func eventCount(ev *corev1.Event) int32 {
+	count := ev.Count
+	if ev.Series != nil && ev.Series.Count > count {
+		count = ev.Series.Count
+	}
+	return max(count, 1)
+}
+
+// LastFired returns when a watch trigger last started a run for the object
+// with the given key, or the zero time.
+func LastFired(status *sympoziumv1alpha1.SympoziumTriggerStatus, key string) time.Time {
+	for _, f := range status.WatchFirings {
+		if f.Object == key {
+			return f.Time.Time
+		}
+	}
+	return time.Time{}
+}
+
+// RecordFiring records that a watch trigger started a run for the object
+// with the given key at now. Firings whose cooldown has passed are dropped,
+// as are the oldest beyond MaxWatchFirings.
+func RecordFiring(status *sympoziumv1alpha1.SympoziumTriggerStatus, key string, now time.Time, cooldown time.Duration) {
+	status.WatchFirings = slices.DeleteFunc(status.WatchFirings, func(f sympoziumv1alpha1.WatchFiring) bool {
+		return f.Object == key || now.Sub(f.Time.Time) >= cooldown
+	})
+	status.WatchFirings = append(status.WatchFirings, sympoziumv1alpha1.WatchFiring{Object: key, Time: metav1.NewTime(now)})
+	if n := len(status.WatchFirings) - MaxWatchFirings; n > 0 {
+		sort.SliceStable(status.WatchFirings, func(i, j int) bool {
+			return status.WatchFirings[i].Time.Before(&status.WatchFirings[j].Time)
+		})
+		status.WatchFirings = status.WatchFirings[n:]
+	}
+}
+
+// ObjectKey identifies a watched object for cooldowns, e.g.
+// "Pod/default/web-0" or "Node/worker-1".
+func ObjectKey(kind, namespace, name string) string {
+	parts := []string{kind, namespace, name}
+	parts = slices.DeleteFunc(parts, func(s string) bool { return s == "" })
+	return strings.Join(parts, "/")
+}
+
+// NewWatchDelivery returns obj, as its JSON fields, and the reason it
+// matched as the templates see them. obj is stripped as by Context.
+func NewWatchDelivery(obj client.Object, reason string) (Delivery, error) {
+	b, err := json.Marshal(strip(obj))
+	if err != nil {
+		return Delivery{}, err
+	}
+	d := Delivery{Reason: reason}
+	if err := json.Unmarshal(b, &d.Payload); err != nil {
+		return Delivery{}, err
+	}
+	return d, nil
+}
+
+// RelatedEvents returns the newest of events about the named object, at
+// most MaxContextEvents, newest first.
+func RelatedEvents(events []corev1.Event, kind, namespace, name string) []corev1.Event {
+	var related []corev1.Event
+	for _, ev := range events {
+		ref := ev.InvolvedObject
+		if ref.Kind == kind && ref.Namespace == namespace && ref.Name == name {
+			related = append(related, ev)
+		}
+	}
+	sort.SliceStable(related, func(i, j int) bool {
+		return eventTime(&related[i]).After(eventTime(&related[j]))
+	})
+	if len(related) > MaxContextEvents {
+		related = related[:MaxContextEvents]
+	}
+	return related
+}
+
+func eventTime(ev *corev1.Event) time.Time {
+	switch {
+	case ev.Series != nil && !ev.Series.LastObservedTime.IsZero():
+		return ev.Series.LastObservedTime.Time
+	case !ev.LastTimestamp.IsZero():
+		return ev.LastTimestamp.Time
+	case !ev.EventTime.IsZero():
+		return ev.EventTime.Time
+	}
+	return ev.CreationTimestamp.Time
+}
+
+// Context renders a watched object and its recent events as the context
+// section appended to a watch trigger's task. See strip for what of the
+// object is left out.
+func Context(obj client.Object, events []corev1.Event) (string, error) {
+	b, err := json.MarshalIndent(strip(obj), "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## Context\n\n### Object\n```json\n")
+	sb.Write(b)
+	sb.WriteString("\n```\n\n### Recent events\n")
+	if len(events) == 0 {
+		sb.WriteString("None.\n")
+	}
+	for i := range events {
+		ev := &events[i]
+		fmt.Fprintf(&sb, "- %s %s %s (x%d): %s\n", eventTime(ev).UTC().Format(time.RFC3339),
+			ev.Type, ev.Reason, eventCount(ev), ev.Message)
+	}
+	return sb.String(), nil
+}
+
+// lastAppliedAnnotation holds kubectl's copy of the whole applied object.
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// redactedValue replaces literal env values in a stripped object.
+const redactedValue = "[redacted]"
+
+// strip returns a copy of obj without what a run's task has no use for or
+// should not carry to the provider: managed fields, the last-applied
+// annotation, a Node's image list and literal container env values.
+func strip(obj client.Object) client.Object {
+	obj = obj.DeepCopyObject().(client.Object)
+	obj.SetManagedFields(nil)
+	if ann := obj.GetAnnotations(); ann[lastAppliedAnnotation] != "" {
+		delete(ann, lastAppliedAnnotation)
+		obj.SetAnnotations(ann)
+	}
+	switch o := obj.(type) {
+	case *corev1.Pod:
+		redactEnv(&o.Spec)
+	case *batchv1.Job:
+		redactEnv(&o.Spec.Template.Spec)
+	case *corev1.Node:
+		o.Status.Images = nil
+	}
+	return obj
+}
+
+// redactEnv replaces the literal env values of spec's containers.
+// References to Secrets and ConfigMaps are kept.
+func redactEnv(spec *corev1.PodSpec) {
+	for i := range spec.InitContainers {
+		redactVars(spec.InitContainers[i].Env)
+	}
+	for i := range spec.Containers {
+		redactVars(spec.Containers[i].Env)
+	}
+	for i := range spec.EphemeralContainers {
+		redactVars(spec.EphemeralContainers[i].Env)
+	}
+}
+
+func redactVars(env []corev1.EnvVar) {
+	for i := range env {
+		if env[i].Value != "" {
+			env[i].Value = redactedValue
+		}
+	}
+}
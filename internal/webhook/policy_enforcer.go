@@ -217,13 +217,18 @@ func (mpe *MutatingPolicyEnforcer) Handle(ctx context.Context, req admission.Req
 		}
 	}
 
-	// Inject tool policy defaults from SympoziumPolicy
+	// Inject tool policy defaults from SympoziumPolicy. A non-empty Allow
+	// list is enforced as an allowlist, so allow rules are only copied when
+	// the policy denies unlisted tools by default.
 	if policy.Spec.ToolGating != nil && run.Spec.ToolPolicy == nil {
 		tp := &sympoziumv1alpha1.ToolPolicySpec{}
+		allowlist := policy.Spec.ToolGating.DefaultAction == "deny"
 		for _, rule := range policy.Spec.ToolGating.Rules {
 			switch rule.Action {
 			case "allow":
-				tp.Allow = append(tp.Allow, rule.Tool)
+				if allowlist {
+					tp.Allow = append(tp.Allow, rule.Tool)
+				}
 			case "deny":
 				tp.Deny = append(tp.Deny, rule.Tool)
 			}
@@ -0,0 +1,20 @@
+-- Sympozium Transcript Sequence Numbers
+-- AppendTranscript numbers events after the session's last one and retries
+-- when a concurrent writer took the number first.
+
+-- Renumber sessions that already have duplicate sequence numbers.
+UPDATE transcript_events t
+SET sequence_num = r.seq
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY session_id ORDER BY sequence_num, created_at, id) AS seq
+    FROM transcript_events
+    WHERE session_id IN (
+        SELECT session_id FROM transcript_events
+        GROUP BY session_id, sequence_num HAVING COUNT(*) > 1
+    )
+) r
+WHERE t.id = r.id;
+
+DROP INDEX IF EXISTS idx_transcript_session;
+ALTER TABLE transcript_events
+    ADD CONSTRAINT transcript_events_session_sequence_key UNIQUE (session_id, sequence_num);
@@ -0,0 +1,222 @@
+/*
+ * Sympozium web chat widget.
+ *
+ * Embed it in a page with:
+ *
+ *   <script src="https://sympozium.example.com/webchat.js"
+ *           data-instance="my-agent" data-namespace="default"
+ *           data-title="Ask the platform team" data-token="..." async></script>
+ *
+ * data-token is a visitor token, needed when the instance's webchat channel
+ * has a WEBCHAT_SECRET. Without one, visitors are anonymous and keep a random
+ * ID in localStorage, so their conversation carries over between visits.
+ */
+(function () {
+  "use strict";
+
+  var script = document.currentScript;
+  if (!script || !script.dataset.instance) {
+    console.error("sympozium webchat: data-instance is required");
+    return;
+  }
+  var opts = script.dataset;
+  var base = new URL(script.src, location.href);
+  var wsBase = (base.protocol === "https:" ? "wss:" : "ws:") + "//" + base.host;
+  var namespace = opts.namespace || "default";
+
+  function visitorID() {
+    var key = "sympozium-webchat-visitor:" + namespace + "/" + opts.instance;
+    var id = null;
+    try {
+      id = localStorage.getItem(key);
+    } catch (e) {}
+    if (!id) {
+      var bytes = new Uint8Array(16);
+      crypto.getRandomValues(bytes);
+      id = Array.prototype.map
+        .call(bytes, function (b) {
+          return ("0" + b.toString(16)).slice(-2);
+        })
+        .join("");
+      try {
+        localStorage.setItem(key, id);
+      } catch (e) {}
+    }
+    return id;
+  }
+
+  function chatURL() {
+    var q = new URLSearchParams({ namespace: namespace });
+    if (opts.token) {
+      q.set("token", opts.token);
+    } else {
+      q.set("visitor", visitorID());
+    }
+    return wsBase + "/ws/webchat/" + encodeURIComponent(opts.instance) + "?" + q.toString();
+  }
+
+  // ── UI ──────────────────────────────────────────────────────────────────
+
+  var host = document.createElement("div");
+  var root = host.attachShadow({ mode: "open" });
+  root.innerHTML =
+    "<style>" +
+    ":host{all:initial;font:14px/1.45 system-ui,-apple-system,sans-serif;color:#111}" +
+    ".toggle{position:fixed;right:20px;bottom:20px;width:52px;height:52px;border-radius:50%;border:0;" +
+    "background:#4f46e5;color:#fff;font-size:22px;cursor:pointer;box-shadow:0 4px 14px rgba(0,0,0,.25);z-index:2147483646}" +
+    ".panel{position:fixed;right:20px;bottom:84px;width:360px;max-width:calc(100vw - 40px);height:480px;" +
+    "max-height:calc(100vh - 110px);display:none;flex-direction:column;background:#fff;border-radius:12px;" +
+    "box-shadow:0 8px 30px rgba(0,0,0,.25);overflow:hidden;z-index:2147483647}" +
+    ".panel.open{display:flex}" +
+    ".head{padding:12px 14px;background:#4f46e5;color:#fff;font-weight:600}" +
+    ".status{font-weight:400;font-size:12px;opacity:.8}" +
+    ".log{flex:1;overflow-y:auto;padding:12px;display:flex;flex-direction:column;gap:8px;background:#f7f7f9}" +
+    ".msg{max-width:85%;padding:8px 11px;border-radius:10px;white-space:pre-wrap;word-wrap:break-word}" +
+    ".msg.html{white-space:normal}" +
+    ".visitor{align-self:flex-end;background:#4f46e5;color:#fff}" +
+    ".agent{align-self:flex-start;background:#fff;border:1px solid #e3e3e8}" +
+    ".agent pre{background:#f1f1f4;padding:8px;border-radius:6px;overflow-x:auto;white-space:pre}" +
+    ".agent code{font-family:ui-monospace,monospace;font-size:12px}" +
+    ".agent a,.file{color:#4f46e5}" +
+    ".typing{align-self:flex-start;color:#888;font-style:italic;display:none}" +
+    ".typing.on{display:block}" +
+    "form{display:flex;border-top:1px solid #e3e3e8}" +
+    "textarea{flex:1;border:0;padding:10px;resize:none;font:inherit;height:42px;outline:none}" +
+    "button.send{border:0;background:none;color:#4f46e5;font-weight:600;padding:0 14px;cursor:pointer}" +
+    "</style>" +
+    '<button class="toggle" type="button" aria-label="Open chat">💬</button>' +
+    '<div class="panel" role="dialog">' +
+    '<div class="head"><span class="title"></span><div class="status">Connecting…</div></div>' +
+    '<div class="log"><div class="typing">Thinking…</div></div>' +
+    '<form><textarea placeholder="Type a message…" rows="1"></textarea>' +
+    '<button class="send" type="submit">Send</button></form>' +
+    "</div>";
+
+  var panel = root.querySelector(".panel");
+  var log = root.querySelector(".log");
+  var typing = root.querySelector(".typing");
+  var status = root.querySelector(".status");
+  var input = root.querySelector("textarea");
+  root.querySelector(".title").textContent = opts.title || "Chat";
+
+  root.querySelector(".toggle").addEventListener("click", function () {
+    panel.classList.toggle("open");
+    if (panel.classList.contains("open")) {
+      input.focus();
+      connect();
+    }
+  });
+
+  function scrollDown() {
+    log.scrollTop = log.scrollHeight;
+  }
+
+  // Links in replies may only point to web pages and mail addresses.
+  function safeLinks(el) {
+    var links = el.querySelectorAll("a");
+    for (var i = 0; i < links.length; i++) {
+      var a = links[i];
+      var ok = false;
+      try {
+        ok = ["http:", "https:", "mailto:"].indexOf(new URL(a.getAttribute("href"), location.href).protocol) >= 0;
+      } catch (e) {}
+      if (ok) {
+        a.target = "_blank";
+        a.rel = "noopener noreferrer";
+      } else {
+        a.removeAttribute("href");
+      }
+    }
+  }
+
+  function addMessage(who, frame) {
+    var el = document.createElement("div");
+    el.className = "msg " + who;
+    if (frame.html) {
+      el.classList.add("html");
+      el.innerHTML = frame.html;
+      safeLinks(el);
+    } else {
+      el.textContent = frame.text || "";
+    }
+    (frame.attachments || []).forEach(function (a) {
+      if (!a.data) return;
+      var link = document.createElement("a");
+      link.className = "file";
+      link.href = "data:" + (a.mimeType || "application/octet-stream") + ";base64," + a.data;
+      link.download = a.filename || "attachment";
+      link.textContent = "📎 " + link.download;
+      el.appendChild(document.createElement("br"));
+      el.appendChild(link);
+    });
+    log.insertBefore(el, typing);
+    scrollDown();
+  }
+
+  // ── Connection ──────────────────────────────────────────────────────────
+
+  var ws = null;
+  var retry = 1000;
+
+  function connect() {
+    if (ws) return;
+    ws = new WebSocket(chatURL());
+    ws.onopen = function () {
+      retry = 1000;
+    };
+    ws.onmessage = function (e) {
+      var frame;
+      try {
+        frame = JSON.parse(e.data);
+      } catch (err) {
+        return;
+      }
+      switch (frame.type) {
+        case "ready":
+          status.textContent = "Online";
+          break;
+        case "typing":
+          typing.classList.toggle("on", !!frame.active);
+          scrollDown();
+          break;
+        case "message":
+          addMessage("agent", frame);
+          break;
+      }
+    };
+    ws.onclose = function () {
+      ws = null;
+      typing.classList.remove("on");
+      status.textContent = "Reconnecting…";
+      setTimeout(connect, retry);
+      retry = Math.min(retry * 2, 30000);
+    };
+  }
+
+  function send() {
+    var text = input.value.trim();
+    if (!text || !ws || ws.readyState !== WebSocket.OPEN) return;
+    ws.send(JSON.stringify({ type: "message", text: text }));
+    addMessage("visitor", { text: text });
+    input.value = "";
+  }
+
+  root.querySelector("form").addEventListener("submit", function (e) {
+    e.preventDefault();
+    send();
+  });
+  input.addEventListener("keydown", function (e) {
+    if (e.key === "Enter" && !e.shiftKey) {
+      e.preventDefault();
+      send();
+    }
+  });
+
+  if (document.body) {
+    document.body.appendChild(host);
+  } else {
+    document.addEventListener("DOMContentLoaded", function () {
+      document.body.appendChild(host);
+    });
+  }
+})();
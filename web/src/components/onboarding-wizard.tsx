@@ -46,8 +46,10 @@ export const PROVIDERS = [
 
 const CHANNELS = [
   { value: "discord", label: "Discord" },
+  { value: "matrix", label: "Matrix" },
   { value: "slack", label: "Slack" },
   { value: "telegram", label: "Telegram" },
+  { value: "webchat", label: "WebChat" },
   { value: "whatsapp", label: "WhatsApp" },
 ];
 
@@ -106,6 +106,27 @@ export function useDeleteRun() {
   });
 }
 
+export function useDecideApproval(runName: string) {
+  const qc = useQueryClient();
+  return useMutation({
+    mutationFn: (data: {
+      id: string;
+      decision: "approved" | "denied";
+      reason?: string;
+    }) =>
+      api.runs.decideApproval(runName, data.id, {
+        decision: data.decision,
+        approver: "web",
+        reason: data.reason,
+      }),
+    onSuccess: (_, data) => {
+      qc.invalidateQueries({ queryKey: ["runs", runName] });
+      toast.success(`Tool call ${data.decision}`);
+    },
+    onError: toastError,
+  });
+}
+
 // ── Policies ─────────────────────────────────────────────────────────────────
 
 export function usePolicies() {
@@ -43,8 +43,16 @@ export interface AgentConfig {
   thinking?: string;
 }
 
+export interface ModelFallback {
+  provider: string;
+  model: string;
+  baseURL?: string;
+  authSecretRef?: string;
+}
+
 export interface AgentsSpec {
   default: AgentConfig;
+  fallbacks?: ModelFallback[];
 }
 
 export interface SkillRef {
@@ -91,6 +99,7 @@ export interface ModelSpec {
   baseURL?: string;
   thinking?: string;
   authSecretRef?: string;
+  fallbacks?: ModelFallback[];
 }
 
 export interface ToolPolicySpec {
@@ -101,11 +110,40 @@ export interface ToolPolicySpec {
 export interface TokenUsage {
   inputTokens: number;
   outputTokens: number;
+  thinkingTokens?: number;
+  thinkingTokensEstimated?: number;
   totalTokens: number;
   toolCalls: number;
   durationMs: number;
 }
 
+export interface ModelAttempt {
+  provider: string;
+  model: string;
+  reason?: string;
+  error?: string;
+}
+
+export interface ToolApproval {
+  id: string;
+  tool: string;
+  arguments?: string;
+  requestedAt?: string;
+  expiresAt?: string;
+  decision?: "approved" | "denied" | "timeout";
+  approver?: string;
+  reason?: string;
+  decidedAt?: string;
+}
+
+export interface FollowUpMessage {
+  id: string;
+  text: string;
+  sender?: string;
+  sentAt?: string;
+  deliveredAt?: string;
+}
+
 export interface AgentRunSpec {
   instanceRef: string;
   agentId: string;
@@ -128,6 +166,10 @@ export interface AgentRunStatus {
   error?: string;
   exitCode?: number;
   tokenUsage?: TokenUsage;
+  servedModel?: string;
+  failedAttempts?: ModelAttempt[];
+  approvals?: ToolApproval[];
+  followUps?: FollowUpMessage[];
   conditions?: Condition[];
 }
 
@@ -210,12 +252,32 @@ export interface SkillSidecar {
   clusterRBAC?: RBACRule[];
 }
 
+export interface MCPServer {
+  name: string;
+  transport?: "stdio" | "http";
+  command?: string[];
+  url?: string;
+  headers?: Record<string, string>;
+  tools?: string[];
+  timeoutSeconds?: number;
+}
+
+export interface SkillTool {
+  name: string;
+  description: string;
+  parameters?: Record<string, unknown>;
+  command: string;
+  timeoutSeconds?: number;
+}
+
 export interface SkillPackSpec {
   skills: Skill[];
   category?: string;
   source?: string;
   version?: string;
   sidecar?: SkillSidecar;
+  mcpServers?: MCPServer[];
+  tools?: SkillTool[];
 }
 
 export interface SkillPackStatus {
@@ -523,6 +585,20 @@ export const api = {
       }),
     delete: (name: string) =>
       apiFetch<void>(`/api/v1/runs/${name}`, { method: "DELETE" }),
+    decideApproval: (
+      name: string,
+      id: string,
+      data: { decision: "approved" | "denied"; approver: string; reason?: string },
+    ) =>
+      apiFetch<AgentRun>(`/api/v1/runs/${name}/approvals/${id}`, {
+        method: "POST",
+        body: JSON.stringify(data),
+      }),
+    sendMessage: (name: string, data: { text: string; sender?: string }) =>
+      apiFetch<FollowUpMessage>(`/api/v1/runs/${name}/messages`, {
+        method: "POST",
+        body: JSON.stringify(data),
+      }),
   },
 
   policies: {
@@ -1,5 +1,5 @@
 import { useParams, Link } from "react-router-dom";
-import { useRun, useRunTelemetry } from "@/hooks/use-api";
+import { useDecideApproval, useRun, useRunTelemetry } from "@/hooks/use-api";
 import { StatusBadge } from "@/components/status-badge";
 import {
   Card,
@@ -8,6 +8,7 @@ import {
   CardContent,
 } from "@/components/ui/card";
 import { Badge } from "@/components/ui/badge";
+import { Button } from "@/components/ui/button";
 import { Separator } from "@/components/ui/separator";
 import { Skeleton } from "@/components/ui/skeleton";
 import { Tabs, TabsList, TabsTrigger, TabsContent } from "@/components/ui/tabs";
@@ -18,6 +19,7 @@ export function RunDetailPage() {
   const { name } = useParams<{ name: string }>();
   const { data: run, isLoading } = useRun(name || "");
   const telemetry = useRunTelemetry(name || "");
+  const decideApproval = useDecideApproval(name || "");
 
   if (isLoading) {
     return (
@@ -57,6 +59,57 @@ export function RunDetailPage() {
         </div>
       </div>
 
+      {/* Tool approvals */}
+      {!!run.status?.approvals?.length && (
+        <Card>
+          <CardHeader className="pb-2">
+            <CardTitle className="text-sm">Tool approvals</CardTitle>
+          </CardHeader>
+          <CardContent className="space-y-2 text-sm">
+            {run.status.approvals.map((a) => (
+              <div key={a.id} className="flex items-center justify-between gap-3">
+                <div className="min-w-0">
+                  <span className="font-mono">{a.tool}</span>{" "}
+                  <span className="text-xs text-muted-foreground font-mono truncate">
+                    {a.arguments}
+                  </span>
+                </div>
+                {a.decision ? (
+                  <Badge variant="outline">
+                    {a.decision}
+                    {a.approver && <> by {a.approver}</>}
+                  </Badge>
+                ) : run.status?.phase === "Running" ? (
+                  <div className="flex gap-2 shrink-0">
+                    <Button
+                      size="sm"
+                      disabled={decideApproval.isPending}
+                      onClick={() =>
+                        decideApproval.mutate({ id: a.id, decision: "approved" })
+                      }
+                    >
+                      Approve
+                    </Button>
+                    <Button
+                      size="sm"
+                      variant="outline"
+                      disabled={decideApproval.isPending}
+                      onClick={() =>
+                        decideApproval.mutate({ id: a.id, decision: "denied" })
+                      }
+                    >
+                      Deny
+                    </Button>
+                  </div>
+                ) : (
+                  <Badge variant="outline">pending</Badge>
+                )}
+              </div>
+            ))}
+          </CardContent>
+        </Card>
+      )}
+
       {/* Stats row */}
       {usage && (
         <div className="grid gap-4 sm:grid-cols-4">
@@ -97,6 +150,16 @@ export function RunDetailPage() {
                   {usage.inputTokens.toLocaleString()} /{" "}
                   {usage.outputTokens.toLocaleString()}
                 </p>
+                {usage.thinkingTokens ? (
+                  <p className="text-xs text-muted-foreground">
+                    {usage.thinkingTokens.toLocaleString()} thinking
+                  </p>
+                ) : !!usage.thinkingTokensEstimated && (
+                  <p className="text-xs text-muted-foreground">
+                    ~{usage.thinkingTokensEstimated.toLocaleString()} thinking
+                    (estimated)
+                  </p>
+                )}
               </div>
             </CardContent>
           </Card>
@@ -253,7 +316,27 @@ export function RunDetailPage() {
                 <span className="font-mono">{run.status.exitCode}</span>
               </>
             )}
+            {run.status.servedModel && (
+              <>
+                {" "}
+                · Served by:{" "}
+                <span className="font-mono">{run.status.servedModel}</span>
+              </>
+            )}
           </div>
+          {!!run.status.failedAttempts?.length && (
+            <div className="space-y-1 text-xs text-muted-foreground">
+              {run.status.failedAttempts.map((a, i) => (
+                <p key={i}>
+                  Failed over from{" "}
+                  <span className="font-mono">
+                    {a.provider}/{a.model}
+                  </span>
+                  {a.reason && <> ({a.reason})</>}
+                </p>
+              ))}
+            </div>
+          )}
         </>
       )}
     </div>